@@ -15,6 +15,7 @@ import (
 	"os"
 	"os/user"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -49,6 +50,7 @@ func Main(r io.Reader, w io.Writer) (err error) {
 	sparklefs, root := fs.NewFS(un, gn, 0500)
 	c := fs.NewListenFile(sparklefs.NewStat("ctl", un, gn, 0600))
 	root.AddChild(c)
+	root.AddChild(fs.NewDynamicFile(sparklefs.NewStat("selection", un, gn, 0400), selection))
 	lctl := (*fs.ListenFileListener)(c)
 	go AssertParent()
 	go Ctl(lctl)
@@ -91,7 +93,12 @@ func ctl(conn net.Conn) {
 	mu.Lock()
 	defer mu.Unlock()
 
-	switch l {
+	args := strings.Fields(l)
+	if len(args) == 0 {
+		log.Printf("sparklefs: empty cmd")
+		return
+	}
+	switch args[0] {
 	case "start":
 		if len(htm) > 50 {
 			log.Printf("htm=%v...", htm[:50])
@@ -128,10 +135,7 @@ func ctl(conn net.Conn) {
 		log.Printf("print calls1")
 		runner.PrintCalls()
 		log.Printf("sparklefs: processJS: changed = %v", changed)
-		if changed {
-			w.WriteString(resHtm)
-			w.Flush()
-		}
+		respond(w, resHtm, changed)
 	case "stop":
 		if d != nil {
 			d.Stop()
@@ -153,15 +157,129 @@ func ctl(conn net.Conn) {
 
 		runner.PrintCalls()
 		log.Printf("sparklefs: processJS: changed = %v", changed)
-		if changed {
-			w.WriteString(resHtm)
-			w.Flush()
+		respond(w, resHtm, changed)
+	case "input":
+		if d == nil {
+			log.Printf("sparklefs: input: not started")
+			return
+		}
+		runner.ResetCalls()
+		sel, err := r.ReadString('\n')
+		if err != nil {
+			log.Printf("sparklefs: input: read string: %v", err)
+			return
+		}
+		val, err := r.ReadString('\n')
+		if err != nil {
+			log.Printf("sparklefs: input: read string: %v", err)
+			return
+		}
+		sel = strings.TrimSpace(sel)
+		val = strings.TrimSuffix(val, "\n")
+		resHtm, changed, err := d.TriggerInput(sel, val)
+		if err != nil {
+			log.Printf("track changes: %v", err)
+			return
+		}
+
+		runner.PrintCalls()
+		log.Printf("sparklefs: processJS: changed = %v", changed)
+		respond(w, resHtm, changed)
+	case "tab":
+		if d == nil {
+			log.Printf("sparklefs: tab: not started")
+			return
 		}
+		runner.ResetCalls()
+		resHtm, changed, err := d.TriggerTab(len(args) > 1 && args[1] == "-1")
+		if err != nil {
+			log.Printf("track changes: %v", err)
+			return
+		}
+
+		runner.PrintCalls()
+		log.Printf("sparklefs: processJS: changed = %v", changed)
+		respond(w, resHtm, changed)
+	case "scroll":
+		if len(args) != 3 || d == nil {
+			log.Printf("sparklefs: usage: scroll x y")
+			return
+		}
+		x, errx := strconv.ParseFloat(args[1], 64)
+		y, erry := strconv.ParseFloat(args[2], 64)
+		if errx != nil || erry != nil {
+			log.Printf("sparklefs: scroll: invalid offsets %v", args[1:])
+			return
+		}
+		runner.ResetCalls()
+		resHtm, changed, err := d.Scroll(x, y)
+		if err != nil {
+			log.Printf("track changes: %v", err)
+			return
+		}
+
+		runner.PrintCalls()
+		log.Printf("sparklefs: processJS: changed = %v", changed)
+		respond(w, resHtm, changed)
+	case "viewport":
+		if len(args) != 3 && len(args) != 4 {
+			log.Printf("sparklefs: usage: viewport width height [dpr]")
+			return
+		}
+		width, errw := strconv.Atoi(args[1])
+		height, errh := strconv.Atoi(args[2])
+		dpr := 0.0
+		var errd error
+		if len(args) == 4 {
+			dpr, errd = strconv.ParseFloat(args[3], 64)
+		}
+		if errw != nil || errh != nil || errd != nil {
+			log.Printf("sparklefs: viewport: invalid size %v", args[1:])
+			return
+		}
+		if d == nil {
+			runner.SetViewport(width, height, dpr)
+			return
+		}
+		runner.ResetCalls()
+		resHtm, changed, err := d.Resize(width, height, dpr)
+		if err != nil {
+			log.Printf("track changes: %v", err)
+			return
+		}
+
+		runner.PrintCalls()
+		log.Printf("sparklefs: processJS: changed = %v", changed)
+		respond(w, resHtm, changed)
 	default:
 		log.Printf("unknown cmd")
 	}
 }
 
+// respond writes the html if changed. A pending form submission
+// navigates away, so its request is written instead.
+func respond(w *bufio.Writer, resHtm string, changed bool) {
+	if req := d.Submission(); req != nil {
+		if err := req.Write(w); err != nil {
+			log.Printf("write submission: %v", err)
+		}
+		w.Flush()
+	} else if changed {
+		w.WriteString(resHtm)
+		w.Flush()
+	}
+}
+
+// selection returns the selected text of the document
+func selection() []byte {
+	mu.Lock()
+	defer mu.Unlock()
+	if d == nil {
+		return nil
+	}
+	return []byte(d.Selection())
+}
+
 var reFirstCap = regexp.MustCompile("(.)([A-Z][a-z]+)")
 var reAllCap = regexp.MustCompile("([a-z0-9])([A-Z])")
 
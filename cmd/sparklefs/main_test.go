@@ -67,3 +67,143 @@ func TestClick(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestTab(t *testing.T) {
+	htm = "<html><input id=a><input id=b></html>"
+	js = []string{
+		`var focused = '';
+		document.addEventListener('focusin', function(event) {
+			focused = event.target.id;
+		});`,
+	}
+	_, err := call("ctl", "start")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, cmd := range []string{"tab", "tab", "tab -1"} {
+		if _, err = call("ctl", cmd); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	resp, err := d.Exec("focused + document.activeElement.id", false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if resp != "aa" {
+		t.Fatalf("%v", resp)
+	}
+}
+
+func TestScroll(t *testing.T) {
+	htm = `<html><body style="height: 5000px"><p id=p></p></body></html>`
+	js = []string{
+		`window.addEventListener('scroll', function() {
+			document.getElementById('p').textContent = scrollY;
+		});
+		window.addEventListener('resize', function() {
+			document.getElementById('p').textContent = innerWidth;
+		});`,
+	}
+	_, err := call("ctl", "start")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	resp, err := call("ctl", "scroll 0 120")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !strings.Contains(resp, `<p id="p">120</p>`) {
+		t.Fatalf("%v", resp)
+	}
+	defer call("ctl", "viewport 1280 720 1")
+	resp, err = call("ctl", "viewport 800 600 2")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !strings.Contains(resp, `<p id="p">800</p>`) {
+		t.Fatalf("%v", resp)
+	}
+}
+
+func TestInput(t *testing.T) {
+	htm = "<html><input id=a><p id=p></p></html>"
+	js = []string{
+		`document.getElementById('a').addEventListener('change', function(event) {
+			document.getElementById('p').textContent = event.target.value;
+		});`,
+	}
+	_, err := call("ctl", "start")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	resp, err := call("ctl", "input", "#a", "hello world")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !strings.Contains(resp, `<input id="a" value="hello world"/><p id="p">hello world</p>`) {
+		t.Fatalf("%v", resp)
+	}
+	if _, err = call("ctl", "stop"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	// ignored without a session
+	resp, err = call("ctl", "input", "#a", "hello")
+	if err != nil || resp != "" {
+		t.Fatalf("%v %v", resp, err)
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	htm = `<html><form action="/login" method="post"><input name="user" value="glenda"><button id="b">Login</button></form></html>`
+	js = []string{"var x = 1;"}
+	url = "https://example.com"
+	defer func() { url = "" }()
+	_, err := call("ctl", "start")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	resp, err := call("ctl", "click", "#b")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !strings.HasPrefix(resp, "POST /login HTTP/1.1\r\n") || !strings.HasSuffix(resp, "\r\n\r\nuser=glenda") {
+		t.Fatalf("%v", resp)
+	}
+}
+
+func TestSubmitOnStart(t *testing.T) {
+	htm = `<html><form action="/search"><input name="q" value="9p"></form><h1 id="title">hello</h1></html>`
+	js = []string{"document.querySelector('form').submit();"}
+	url = "https://example.com"
+	defer func() { url = "" }()
+	resp, err := call("ctl", "start")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !strings.HasPrefix(resp, "GET /search?q=9p HTTP/1.1\r\n") {
+		t.Fatalf("%v", resp)
+	}
+	// the submission isn't reported again by an unrelated click
+	resp, err = call("ctl", "click", "#title")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if strings.Contains(resp, "HTTP/1.1") {
+		t.Fatalf("%v", resp)
+	}
+}
+
+func TestSelection(t *testing.T) {
+	htm = "<html><p id=p>hello world</p></html>"
+	js = []string{
+		`var t = document.getElementById('p').firstChild;
+		getSelection().setBaseAndExtent(t, 6, t, 11);`,
+	}
+	_, err := call("ctl", "start")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if s := string(selection()); s != "world" {
+		t.Fatalf("%v", s)
+	}
+}
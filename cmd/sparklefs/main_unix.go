@@ -85,6 +85,9 @@ func Init() (err error) {
 }
 
 func open(fn string) (rwc io.ReadWriteCloser, err error) {
+	if fsys == nil {
+		return nil, fmt.Errorf("not mounted")
+	}
 	return fsys.Open(fn, plan9.ORDWR)
 }
 
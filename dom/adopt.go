@@ -0,0 +1,138 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"golang.org/x/net/html"
+)
+
+// adopt moves nd and its descendants into d. Their wrappers are moved
+// from the previous document so the JS objects with their expandos
+// and event listeners stay the same. nd should be detached before.
+func (d *Document) adopt(nd Node) {
+	old := nd.base().d
+	if old == d {
+		return
+	}
+	moved := make(map[*html.Node]bool)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		moved[n] = true
+		if n.Type == html.ElementNode && old.isHTML() != d.isHTML() {
+			n.Namespace = d.storedNamespace(old.namespace(n), n.Namespace)
+		}
+		if w, ok := old.ndRefs[n]; ok {
+			delete(old.ndRefs, n)
+			w.base().d = d
+			d.ndRefs[n] = w
+			if el, ok := w.(*Element); ok {
+				for _, hc := range elCollections[el] {
+					hc.d = d
+				}
+			}
+		}
+		if s, ok := old.scripts[n]; ok {
+			delete(old.scripts, n)
+			d.scripts[n] = s
+		}
+		if s, ok := old.controls[n]; ok {
+			delete(old.controls, n)
+			d.controls[n] = s
+		}
+		if nl, ok := elChildNodes[n]; ok {
+			nl.d = d
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(nd.base().n)
+	nd.base().d = d
+	for a, obj := range attrObjRefs {
+		if a.d == old && moved[a.owner] {
+			delete(attrObjRefs, a)
+			a.d = d
+			attrObjRefs[a] = obj
+		}
+	}
+	if f := old.focused; f != nil && moved[f.n] {
+		old.focused = nil
+	}
+}
+
+// storedNamespace returns how elements of d store the namespace uri.
+// An empty Namespace means the HTML namespace in HTML documents and the
+// null namespace in XML documents. Other namespaces keep the stored
+// value ns.
+func (d *Document) storedNamespace(uri, ns string) string {
+	switch uri {
+	case "":
+		if d.isHTML() {
+			return nullNS
+		}
+		return ""
+	case xhtmlNS:
+		if d.isHTML() {
+			return ""
+		}
+		return xhtmlNS
+	}
+	return ns
+}
+
+// adoptFragment moves df and its children into d
+func (d *Document) adoptFragment(df *DocumentFragment) {
+	if df.d == d {
+		return
+	}
+	for _, c := range df.children {
+		d.adopt(df.d.getNode(c))
+	}
+	df.d = d
+	if nl, ok := dfChildNodes[df]; ok {
+		nl.d = d
+	}
+	if hc, ok := dfChildren[df]; ok {
+		hc.d = d
+	}
+}
+
+// ImportNode returns a copy of the node v owned by d
+func (d *Document) ImportNode(v any, deep ...bool) js.Value {
+	switch x := v.(type) {
+	case *Document:
+		throwDOMException("NotSupportedError", "a document cannot be imported")
+	case *DocumentFragment:
+		cl := x.CloneNode(deep...)
+		d.adoptFragment(cl)
+		return cl.Obj()
+	case Node:
+		cl := x.base().CloneNode(deep...)
+		d.adopt(cl)
+		return cl.Obj()
+	}
+	panic(vm.NewTypeError("importNode: parameter 1 is not of type 'Node'"))
+}
+
+// AdoptNode removes the node v from its parent and moves it into d
+func (d *Document) AdoptNode(v any) js.Value {
+	switch x := v.(type) {
+	case *Document:
+		throwDOMException("NotSupportedError", "a document cannot be adopted")
+	case *DocumentFragment:
+		d.adoptFragment(x)
+		return x.Obj()
+	case Node:
+		x.base().d.detach(x.base().n)
+		d.adopt(x)
+		return x.Obj()
+	}
+	panic(vm.NewTypeError("adoptNode: parameter 1 is not of type 'Node'"))
+}
+
+func (d *Document) CompareDocumentPosition(o any) int {
+	return compareDocumentPosition(d, o)
+}
+
+func (df *DocumentFragment) CompareDocumentPosition(o any) int {
+	return compareDocumentPosition(df, o)
+}
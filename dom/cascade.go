@@ -0,0 +1,573 @@
+package dom
+
+import (
+	"fmt"
+	"github.com/psilva261/sparklefs/dom/sel"
+	"github.com/psilva261/sparklefs/logger"
+	"golang.org/x/net/html"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Viewport size in CSS pixels for media queries
+var (
+	ViewportWidth  = 1280
+	ViewportHeight = 720
+
+	// DevicePixelRatio is the number of device pixels per CSS pixel
+	DevicePixelRatio = 1.0
+)
+
+// User preferences and input devices for media queries
+var (
+	PrefersColorScheme   = "light"
+	PrefersReducedMotion = false
+
+	// Hover is true if the primary pointer can hover and Pointer is
+	// its accuracy: none, coarse or fine
+	Hover   = true
+	Pointer = "fine"
+)
+
+// uaStyle is the user agent style sheet
+const uaStyle = `
+html, address, blockquote, body, center, dialog, div, figure, figcaption,
+footer, form, header, hr, legend, listing, main, p, plaintext, pre, search,
+xmp, article, aside, h1, h2, h3, h4, h5, h6, hgroup, nav, section, dir, dd,
+dl, dt, menu, ol, ul, fieldset, details, summary, optgroup { display: block; }
+li { display: list-item; }
+head, link, meta, script, style, template, title, noscript, area, base,
+basefont, datalist, param, rp { display: none; }
+table { display: table; }
+caption { display: table-caption; }
+colgroup { display: table-column-group; }
+col { display: table-column; }
+thead { display: table-header-group; }
+tbody { display: table-row-group; }
+tfoot { display: table-footer-group; }
+tr { display: table-row; }
+td, th { display: table-cell; }
+input, button, select, textarea, meter, progress { display: inline-block; }
+ruby { display: ruby; }
+rt { display: ruby-text; }
+[hidden] { display: none; }
+b, strong, th, h1, h2, h3, h4, h5, h6 { font-weight: bold; }
+i, em, cite, var, dfn, address { font-style: italic; }
+pre, xmp, plaintext, listing, textarea { white-space: pre; }
+code, kbd, samp, pre, tt { font-family: monospace; }
+u, ins { text-decoration-line: underline; }
+s, strike, del { text-decoration-line: line-through; }
+a:link { color: #0000ee; text-decoration-line: underline; cursor: pointer; }
+th { text-align: center; }
+center { text-align: center; }
+body { margin: 8px; }
+p, blockquote, figure, dl, ul, ol, menu, dir, pre, listing, xmp, plaintext {
+  margin-top: 1em; margin-bottom: 1em;
+}
+blockquote, figure { margin-left: 40px; margin-right: 40px; }
+dd { margin-left: 40px; }
+ul, ol, menu, dir { padding-left: 40px; }
+ul ul, ul ol, ol ul, ol ol, ul menu, ol menu, menu ul, menu ol, menu menu {
+  margin-top: 0; margin-bottom: 0;
+}
+h1 { margin-top: 0.67em; margin-bottom: 0.67em; font-size: 2em; }
+h2 { margin-top: 0.83em; margin-bottom: 0.83em; font-size: 1.5em; }
+h3 { margin-top: 1em; margin-bottom: 1em; font-size: 1.17em; }
+h4 { margin-top: 1.33em; margin-bottom: 1.33em; font-size: 1em; }
+h5 { margin-top: 1.67em; margin-bottom: 1.67em; font-size: 0.83em; }
+h6 { margin-top: 2.33em; margin-bottom: 2.33em; font-size: 0.67em; }
+hr { margin: 0.5em auto; }
+fieldset { margin-left: 2px; margin-right: 2px; }
+small { font-size: smaller; }
+big { font-size: larger; }
+sub, sup { font-size: smaller; }
+`
+
+var uaRules []cssRule
+
+// cssProp describes the computation of a property
+type cssProp struct {
+	initial   string
+	inherited bool
+}
+
+// cssProps are the initial values of common properties. Other valid
+// properties have the empty string as initial value.
+var cssProps = map[string]cssProp{
+	"align-content":         {"normal", false},
+	"align-items":           {"normal", false},
+	"align-self":            {"auto", false},
+	"background-color":      {"rgba(0, 0, 0, 0)", false},
+	"background-image":      {"none", false},
+	"border-bottom-color":   {"currentcolor", false},
+	"border-bottom-style":   {"none", false},
+	"border-bottom-width":   {"medium", false},
+	"border-collapse":       {"separate", true},
+	"border-left-color":     {"currentcolor", false},
+	"border-left-style":     {"none", false},
+	"border-left-width":     {"medium", false},
+	"border-right-color":    {"currentcolor", false},
+	"border-right-style":    {"none", false},
+	"border-right-width":    {"medium", false},
+	"border-top-color":      {"currentcolor", false},
+	"border-top-style":      {"none", false},
+	"border-top-width":      {"medium", false},
+	"bottom":                {"auto", false},
+	"box-sizing":            {"content-box", false},
+	"clear":                 {"none", false},
+	"color":                 {"rgb(0, 0, 0)", true},
+	"content":               {"normal", false},
+	"cursor":                {"auto", true},
+	"direction":             {"ltr", true},
+	"display":               {"inline", false},
+	"flex-basis":            {"auto", false},
+	"flex-direction":        {"row", false},
+	"flex-grow":             {"0", false},
+	"flex-shrink":           {"1", false},
+	"flex-wrap":             {"nowrap", false},
+	"float":                 {"none", false},
+	"font-family":           {"serif", true},
+	"font-size":             {"16px", true},
+	"font-style":            {"normal", true},
+	"font-variant":          {"normal", true},
+	"font-weight":           {"400", true},
+	"gap":                   {"normal", false},
+	"height":                {"auto", false},
+	"justify-content":       {"normal", false},
+	"left":                  {"auto", false},
+	"letter-spacing":        {"normal", true},
+	"line-height":           {"normal", true},
+	"list-style-type":       {"disc", true},
+	"margin-bottom":         {"0px", false},
+	"margin-left":           {"0px", false},
+	"margin-right":          {"0px", false},
+	"margin-top":            {"0px", false},
+	"max-height":            {"none", false},
+	"max-width":             {"none", false},
+	"min-height":            {"auto", false},
+	"min-width":             {"auto", false},
+	"opacity":               {"1", false},
+	"order":                 {"0", false},
+	"outline-color":         {"currentcolor", false},
+	"outline-style":         {"none", false},
+	"outline-width":         {"medium", false},
+	"overflow-x":            {"visible", false},
+	"overflow-y":            {"visible", false},
+	"padding-bottom":        {"0px", false},
+	"padding-left":          {"0px", false},
+	"padding-right":         {"0px", false},
+	"padding-top":           {"0px", false},
+	"pointer-events":        {"auto", true},
+	"position":              {"static", false},
+	"right":                 {"auto", false},
+	"text-align":            {"start", true},
+	"text-decoration-color": {"currentcolor", false},
+	"text-decoration-line":  {"none", false},
+	"text-indent":           {"0px", true},
+	"text-transform":        {"none", true},
+	"top":                   {"auto", false},
+	"transform":             {"none", false},
+	"vertical-align":        {"baseline", false},
+	"visibility":            {"visible", true},
+	"white-space":           {"normal", true},
+	"width":                 {"auto", false},
+	"word-spacing":          {"0px", true},
+	"z-index":               {"auto", false},
+}
+
+// cssColors are the named colors besides the extended color keywords
+var cssColors = map[string]string{
+	"black":   "rgb(0, 0, 0)",
+	"silver":  "rgb(192, 192, 192)",
+	"gray":    "rgb(128, 128, 128)",
+	"grey":    "rgb(128, 128, 128)",
+	"white":   "rgb(255, 255, 255)",
+	"maroon":  "rgb(128, 0, 0)",
+	"red":     "rgb(255, 0, 0)",
+	"purple":  "rgb(128, 0, 128)",
+	"fuchsia": "rgb(255, 0, 255)",
+	"magenta": "rgb(255, 0, 255)",
+	"green":   "rgb(0, 128, 0)",
+	"lime":    "rgb(0, 255, 0)",
+	"olive":   "rgb(128, 128, 0)",
+	"yellow":  "rgb(255, 255, 0)",
+	"navy":    "rgb(0, 0, 128)",
+	"blue":    "rgb(0, 0, 255)",
+	"teal":    "rgb(0, 128, 128)",
+	"aqua":    "rgb(0, 255, 255)",
+	"cyan":    "rgb(0, 255, 255)",
+	"orange":  "rgb(255, 165, 0)",
+	"pink":    "rgb(255, 192, 203)",
+
+	"transparent": "rgba(0, 0, 0, 0)",
+}
+
+// cascade computes property values of the elements of a document.
+// Selector matches are memoized for the lifetime of the cascade.
+type cascade struct {
+	d       *Document
+	sheets  []*CSSStyleSheet
+	matches map[string]map[*html.Node]bool
+}
+
+func newCascade(d *Document) *cascade {
+	if uaRules == nil {
+		uaRules = parseRules(uaStyle)
+	}
+	c := &cascade{
+		d:       d,
+		matches: make(map[string]map[*html.Node]bool),
+	}
+	for _, s := range d.styleSheets() {
+		if !s.disabled && mediaMatches(s.media) {
+			c.sheets = append(c.sheets, s)
+		}
+	}
+	for _, s := range d.adoptedSheets() {
+		if !s.disabled && mediaMatches(s.media) {
+			c.sheets = append(c.sheets, s)
+		}
+	}
+	return c
+}
+
+// adoptedSheets returns the sheets assigned to adoptedStyleSheets
+func (d *Document) adoptedSheets() (ss []*CSSStyleSheet) {
+	v, ok := d.vars["adoptedStyleSheets"]
+	if !ok || v == nil {
+		return
+	}
+	if vs, ok := v.Export().([]any); ok {
+		for _, v := range vs {
+			if s, ok := v.(*CSSStyleSheet); ok {
+				ss = append(ss, s)
+			}
+		}
+	}
+	return
+}
+
+// matches is true if the element n matches the complex selector s
+func (c *cascade) match(s string, n *html.Node) bool {
+	if !strings.ContainsAny(s, " >+~") {
+		return sel.ElementMatchesSingle(s, n, false, -1)
+	}
+	m, ok := c.matches[s]
+	if !ok {
+		m = make(map[*html.Node]bool)
+		res, err := sel.Select(s, c.d.doc, false, false)
+		if err != nil {
+			log.Printf("cascade: select %v: %v", s, err)
+		}
+		for _, r := range res {
+			m[r] = true
+		}
+		c.matches[s] = m
+	}
+	return m[n]
+}
+
+// candidate is a declaration applying to an element. Candidates with
+// higher level win, then those with higher specificity and then those
+// declared later.
+type candidate struct {
+	dl    decl
+	level int
+	spec  int
+	order int
+}
+
+func (a candidate) beats(b candidate) bool {
+	if a.level != b.level {
+		return a.level > b.level
+	}
+	if a.spec != b.spec {
+		return a.spec > b.spec
+	}
+	return a.order > b.order
+}
+
+// cascade levels of the origins with normal and important declarations
+const (
+	uaNormal = iota
+	authorNormal
+	inlineNormal
+	authorImportant
+	inlineImportant
+	uaImportant
+)
+
+// declared returns the winning declaration of prop for n
+func (c *cascade) declared(n *html.Node, prop string) (win candidate, ok bool) {
+	order := 0
+	consider := func(cd candidate) {
+		order++
+		cd.order = order
+		if !ok || cd.beats(win) {
+			win, ok = cd, true
+		}
+	}
+	var walk func(rs []cssRule, ua bool)
+	walk = func(rs []cssRule, ua bool) {
+		for _, r := range rs {
+			switch v := r.(type) {
+			case *CSSStyleRule:
+				spec := -1
+				for _, s := range strings.Split(v.selector, ",") {
+					s = strings.TrimSpace(s)
+					if sp := specificity(s); sp > spec && c.match(s, n) {
+						spec = sp
+					}
+				}
+				if spec < 0 {
+					continue
+				}
+				for _, dl := range v.decls {
+					if dl.prop != prop {
+						continue
+					}
+					level := authorNormal
+					switch {
+					case ua && dl.important:
+						level = uaImportant
+					case ua:
+						level = uaNormal
+					case dl.important:
+						level = authorImportant
+					}
+					consider(candidate{dl: dl, level: level, spec: spec})
+				}
+			case *CSSMediaRule:
+				if mediaMatches(v.media) {
+					walk(v.rules, ua)
+				}
+			}
+		}
+	}
+	walk(uaRules, true)
+	for _, s := range c.sheets {
+		walk(s.rules, false)
+	}
+	for _, dl := range parseDecls(attr(*n, "style")) {
+		if dl.prop != prop {
+			continue
+		}
+		level := inlineNormal
+		if dl.important {
+			level = inlineImportant
+		}
+		consider(candidate{dl: dl, level: level})
+	}
+	return
+}
+
+// value returns the computed value of prop for the element n
+func (c *cascade) value(n *html.Node, prop string) string {
+	p := cssProps[prop]
+	inherited := p.inherited || strings.HasPrefix(prop, "--")
+	cd, ok := c.declared(n, prop)
+	v := strings.ToLower(cd.dl.val)
+	if strings.HasPrefix(prop, "--") {
+		v = cd.dl.val
+	}
+	switch {
+	case !ok && inherited, v == "inherit", inherited && (v == "unset" || v == "revert"):
+		if pa := n.Parent; pa != nil && pa.Type == html.ElementNode {
+			return c.value(pa, prop)
+		}
+		v = p.initial
+	case !ok, v == "initial", v == "unset", v == "revert":
+		v = p.initial
+	}
+	return c.compute(n, prop, v)
+}
+
+// compute converts the specified value v of prop into the computed
+// value
+func (c *cascade) compute(n *html.Node, prop, v string) string {
+	switch {
+	case v == "currentcolor":
+		if prop == "color" {
+			if pa := n.Parent; pa != nil && pa.Type == html.ElementNode {
+				return c.value(pa, "color")
+			}
+			return cssProps["color"].initial
+		}
+		return c.value(n, "color")
+	case prop == "color" || strings.HasSuffix(prop, "-color"):
+		return computedColor(v)
+	case prop == "font-size":
+		return pixels(c.fontSize(n, v))
+	case strings.HasSuffix(v, "em") && !strings.HasSuffix(v, "rem"):
+		if x, err := strconv.ParseFloat(strings.TrimSuffix(v, "em"), 64); err == nil {
+			return pixels(x * c.fontSize(n, c.value(n, "font-size")))
+		}
+	case prop == "font-weight":
+		switch v {
+		case "normal":
+			return "400"
+		case "bold":
+			return "700"
+		}
+	case strings.HasSuffix(prop, "-width") && (strings.HasPrefix(prop, "border-") || prop == "outline-width"):
+		st := strings.TrimSuffix(prop, "-width") + "-style"
+		if s := c.value(n, st); s == "none" || s == "hidden" {
+			return "0px"
+		}
+		switch v {
+		case "thin":
+			return "1px"
+		case "medium":
+			return "3px"
+		case "thick":
+			return "5px"
+		}
+	}
+	if v == "0" && cssProps[prop].initial == "0px" {
+		return "0px"
+	}
+	return v
+}
+
+// fontSize in pixels of the specified font-size v of n
+func (c *cascade) fontSize(n *html.Node, v string) float64 {
+	pfs := 16.0
+	if pa := n.Parent; pa != nil && pa.Type == html.ElementNode {
+		pfs, _ = strconv.ParseFloat(strings.TrimSuffix(c.value(pa, "font-size"), "px"), 64)
+	}
+	if x, ok := fontSizes[v]; ok {
+		return x
+	}
+	units := map[string]float64{"px": 1, "rem": 16, "em": pfs, "pt": 4.0 / 3, "%": pfs / 100}
+	for _, u := range []string{"px", "rem", "em", "pt", "%"} {
+		if x, err := strconv.ParseFloat(strings.TrimSuffix(v, u), 64); err == nil && strings.HasSuffix(v, u) {
+			return x * units[u]
+		}
+	}
+	switch v {
+	case "smaller":
+		return pfs / 1.2
+	case "larger":
+		return pfs * 1.2
+	}
+	return pfs
+}
+
+// fontSizes of the absolute size keywords in pixels
+var fontSizes = map[string]float64{
+	"xx-small": 9,
+	"x-small":  10,
+	"small":    13,
+	"medium":   16,
+	"large":    18,
+	"x-large":  24,
+	"xx-large": 32,
+}
+
+// pixels formats the length x in pixels
+func pixels(x float64) string {
+	return strconv.FormatFloat(math.Round(x*100)/100, 'f', -1, 64) + "px"
+}
+
+// computedColor converts named and hex colors into rgb() notation
+func computedColor(v string) string {
+	if rgb, ok := cssColors[v]; ok {
+		return rgb
+	}
+	if strings.HasPrefix(v, "#") {
+		h := v[1:]
+		if len(h) == 3 || len(h) == 4 {
+			var b strings.Builder
+			for _, ch := range h {
+				b.WriteRune(ch)
+				b.WriteRune(ch)
+			}
+			h = b.String()
+		}
+		if len(h) != 6 && len(h) != 8 {
+			return v
+		}
+		var cs [4]int64
+		for i := 0; i < len(h)/2; i++ {
+			x, err := strconv.ParseInt(h[2*i:2*i+2], 16, 64)
+			if err != nil {
+				return v
+			}
+			cs[i] = x
+		}
+		if len(h) == 8 {
+			a := strconv.FormatFloat(float64(cs[3])/255, 'f', -1, 64)
+			return fmt.Sprintf("rgba(%d, %d, %d, %s)", cs[0], cs[1], cs[2], a)
+		}
+		return fmt.Sprintf("rgb(%d, %d, %d)", cs[0], cs[1], cs[2])
+	}
+	if strings.HasPrefix(v, "rgb") {
+		i, j := strings.Index(v, "("), strings.LastIndex(v, ")")
+		if i < 0 || j < i {
+			return v
+		}
+		args := strings.FieldsFunc(v[i+1:j], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '/'
+		})
+		if len(args) == 4 && args[3] != "1" {
+			return "rgba(" + strings.Join(args, ", ") + ")"
+		}
+		if len(args) >= 3 {
+			return "rgb(" + strings.Join(args[:3], ", ") + ")"
+		}
+	}
+	return v
+}
+
+// specificity of a complex selector as a*1e6 + b*1e3 + c with a the
+// number of ids, b of classes, attributes and pseudo-classes and c of
+// types and pseudo-elements
+func specificity(s string) int {
+	a, b, c := 0, 0, 0
+	for _, cp := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == '>' || r == '+' || r == '~'
+	}) {
+		if cp != "" && cp[0] != '#' && cp[0] != '.' && cp[0] != '[' && cp[0] != ':' && cp[0] != '*' {
+			c++
+		}
+		depth := 0
+		for i := 0; i < len(cp); i++ {
+			switch ch := cp[i]; {
+			case ch == '(' || ch == '[':
+				if ch == '[' && depth == 0 {
+					b++
+				}
+				depth++
+			case ch == ')' || ch == ']':
+				depth--
+			case depth > 0:
+			case ch == '#':
+				a++
+			case ch == '.':
+				b++
+			case ch == ':' && i+1 < len(cp) && cp[i+1] == ':':
+				c++
+				i++
+			case ch == ':':
+				b++
+			}
+		}
+	}
+	return a*1000000 + b*1000 + c
+}
+
+// computedValue returns the computed value of prop for the connected
+// element n according to the style sheets of d
+func (d *Document) computedValue(n *html.Node, prop string) string {
+	if prop == "css-float" {
+		prop = "float"
+	}
+	if _, ok := cssProps[prop]; !ok && !allProperties[prop] && !strings.HasPrefix(prop, "--") {
+		return ""
+	}
+	if !connected(n) {
+		return ""
+	}
+	return newCascade(d).value(n, prop)
+}
@@ -0,0 +1,1232 @@
+package dom
+
+import (
+	"fmt"
+	"github.com/psilva261/sparkle/js"
+	"github.com/psilva261/sparklefs/logger"
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/parse/v2/css"
+	"golang.org/x/net/html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CSSRule.type values
+const (
+	cssStyleRuleType     = 1
+	cssCharsetRuleType   = 2
+	cssImportRuleType    = 3
+	cssMediaRuleType     = 4
+	cssFontFaceRuleType  = 5
+	cssPageRuleType      = 6
+	cssKeyframesRuleType = 7
+	cssNamespaceRuleType = 10
+	cssSupportsRuleType  = 12
+)
+
+var (
+	styleSheetCtor     *js.Object
+	cssStyleSheetCtor  *js.Object
+	cssRuleCtor        *js.Object
+	cssStyleRuleCtor   *js.Object
+	cssMediaRuleCtor   *js.Object
+	cssRuleListCtor    *js.Object
+	styleSheetListCtor *js.Object
+	mediaListCtor      *js.Object
+
+	cssStyleDeclarationCtor *js.Object
+)
+
+// initCSSOM defines the CSSOM interface objects. new CSSStyleSheet()
+// creates a constructed sheet of the document d.
+func initCSSOM(d *Document) {
+	illegal := func(call js.ConstructorCall) *js.Object {
+		panic(vm.NewTypeError("Illegal constructor"))
+	}
+	styleSheetCtor = newCtor("StyleSheet", illegal)
+	cssStyleSheetCtor = newCtor("CSSStyleSheet", func(call js.ConstructorCall) *js.Object {
+		s := &CSSStyleSheet{d: d, constructed: true}
+		if o, ok := call.Argument(0).(*js.Object); ok {
+			if m := o.Get("media"); m != nil && !js.IsUndefined(m) {
+				s.media = m.String()
+			}
+			if v := o.Get("disabled"); v != nil {
+				s.disabled = v.ToBoolean()
+			}
+		}
+		o := s.Obj()
+		o.SetPrototype(call.This.Prototype())
+		return o
+	})
+	cssRuleCtor = newCtor("CSSRule", illegal)
+	cssStyleRuleCtor = newCtor("CSSStyleRule", illegal)
+	cssMediaRuleCtor = newCtor("CSSMediaRule", illegal)
+	cssRuleListCtor = newCtor("CSSRuleList", illegal)
+	styleSheetListCtor = newCtor("StyleSheetList", illegal)
+	mediaListCtor = newCtor("MediaList", illegal)
+	cssStyleDeclarationCtor = newCtor("CSSStyleDeclaration", illegal)
+	ifaces := []struct {
+		name   string
+		c      *js.Object
+		recv   Gettable
+		parent *js.Object
+		pr     Gettable
+	}{
+		{"StyleSheet", styleSheetCtor, nil, nil, nil},
+		{"CSSStyleSheet", cssStyleSheetCtor, &CSSStyleSheet{}, styleSheetCtor, nil},
+		{"CSSRule", cssRuleCtor, &CSSRule{}, nil, nil},
+		{"CSSStyleRule", cssStyleRuleCtor, &CSSStyleRule{}, cssRuleCtor, &CSSRule{}},
+		{"CSSMediaRule", cssMediaRuleCtor, &CSSMediaRule{}, cssRuleCtor, &CSSRule{}},
+		{"CSSRuleList", cssRuleListCtor, &CSSRuleList{}, nil, nil},
+		{"StyleSheetList", styleSheetListCtor, &StyleSheetList{}, nil, nil},
+		{"MediaList", mediaListCtor, &MediaList{}, nil, nil},
+		{"CSSStyleDeclaration", cssStyleDeclarationCtor, &Style{}, nil, nil},
+	}
+	for _, i := range ifaces {
+		proto := i.c.Get("prototype").(*js.Object)
+		proto.DefineDataPropertySymbol(js.SymToStringTag, vm.ToValue(i.name), js.FLAG_FALSE, js.FLAG_TRUE, js.FLAG_FALSE)
+		if i.recv != nil {
+			protoMembers(proto, i.recv, i.pr)
+		}
+		if i.parent != nil {
+			proto.SetPrototype(i.parent.Get("prototype").(*js.Object))
+			i.c.SetPrototype(i.parent)
+		}
+	}
+	for _, c := range []*js.Object{cssRuleListCtor, styleSheetListCtor, mediaListCtor} {
+		proto := c.Get("prototype").(*js.Object)
+		iter := vm.Get("Array").(*js.Object).Get("prototype").(*js.Object).Get("values")
+		proto.DefineDataPropertySymbol(js.SymIterator, iter, js.FLAG_TRUE, js.FLAG_TRUE, js.FLAG_FALSE)
+	}
+	consts := map[string]int{
+		"STYLE_RULE":     cssStyleRuleType,
+		"CHARSET_RULE":   cssCharsetRuleType,
+		"IMPORT_RULE":    cssImportRuleType,
+		"MEDIA_RULE":     cssMediaRuleType,
+		"FONT_FACE_RULE": cssFontFaceRuleType,
+		"PAGE_RULE":      cssPageRuleType,
+		"KEYFRAMES_RULE": cssKeyframesRuleType,
+		"NAMESPACE_RULE": cssNamespaceRuleType,
+		"SUPPORTS_RULE":  cssSupportsRuleType,
+	}
+	for k, v := range consts {
+		cssRuleCtor.Set(k, v)
+		cssRuleCtor.Get("prototype").(*js.Object).Set(k, v)
+	}
+}
+
+// decl is a declaration of a property
+type decl struct {
+	prop      string
+	val       string
+	important bool
+}
+
+func (dl decl) String() string {
+	s := dl.prop + ": " + dl.val
+	if dl.important {
+		s += " !important"
+	}
+	return s + ";"
+}
+
+// newDecl returns the declaration the parser p is at
+func newDecl(p *css.Parser, data []byte) decl {
+	dl := decl{prop: string(data)}
+	vs := p.Values()
+	if n := len(vs); n >= 2 && string(vs[n-2].Data) == "!" && strings.EqualFold(string(vs[n-1].Data), "important") {
+		dl.important = true
+		vs = vs[:n-2]
+	}
+	for i, v := range vs {
+		if v.TokenType == css.WhitespaceToken && i > 0 && vs[i-1].TokenType == css.CommaToken {
+			continue
+		}
+		dl.val += string(v.Data)
+		if v.TokenType == css.CommaToken {
+			dl.val += " "
+		}
+	}
+	dl.val = strings.TrimSpace(dl.val)
+	return dl
+}
+
+// parseDecls parses a declaration block like a style attribute
+func parseDecls(s string) (ds []decl) {
+	p := css.NewParser(parse.NewInputString(s), true)
+	for {
+		gt, _, data := p.Next()
+		switch gt {
+		case css.ErrorGrammar:
+			if p.Err() == io.EOF {
+				return
+			}
+		case css.DeclarationGrammar, css.CustomPropertyGrammar:
+			ds = addDecl(ds, newDecl(p, data))
+		}
+	}
+}
+
+// serializeDecls in order, combining longhands into shorthands where
+// possible
+func serializeDecls(ds []decl) string {
+	ss := make([]string, 0, len(ds))
+	done := make(map[string]bool)
+Decls:
+	for _, dl := range ds {
+		if done[dl.prop] {
+			continue
+		}
+	Shorthands:
+		for _, sh := range shorthandsOf[dl.prop] {
+			for _, lh := range longhands[sh] {
+				if done[lh] {
+					continue Shorthands
+				}
+			}
+			if v, imp, ok := shorthandValue(ds, sh); ok {
+				ss = append(ss, decl{prop: sh, val: v, important: imp}.String())
+				for _, lh := range longhands[sh] {
+					done[lh] = true
+				}
+				continue Decls
+			}
+		}
+		ss = append(ss, dl.String())
+	}
+	return strings.Join(ss, " ")
+}
+
+// cssRule is implemented by the CSS rule types
+type cssRule interface {
+	Gettable
+	js.DynamicObject
+	Obj() *js.Object
+	CssText() string
+	base() *ruleBase
+}
+
+// ruleBase holds the parents shared by all rules
+type ruleBase struct {
+	sheet  *CSSStyleSheet
+	parent cssRule
+	obj    *js.Object
+}
+
+func (rb *ruleBase) base() *ruleBase {
+	return rb
+}
+
+func (rb *ruleBase) ParentStyleSheet() js.Value {
+	if rb.sheet == nil {
+		return js.Null()
+	}
+	return rb.sheet.Obj()
+}
+
+func (rb *ruleBase) ParentRule() js.Value {
+	if rb.parent == nil {
+		return js.Null()
+	}
+	return rb.parent.Obj()
+}
+
+func (rb *ruleBase) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+// ruleObj returns the cached object of r with the prototype of c
+func ruleObj(r cssRule, c *js.Object) *js.Object {
+	rb := r.base()
+	if rb.obj == nil {
+		rb.obj = vm.NewDynamicObject(r)
+		rb.obj.SetPrototype(c.Get("prototype").(*js.Object))
+	}
+	return rb.obj
+}
+
+// setParents sets the parent sheet and rule of rs and their
+// descendants
+func setParents(rs []cssRule, s *CSSStyleSheet, p cssRule) {
+	for _, r := range rs {
+		r.base().sheet = s
+		r.base().parent = p
+		if m, ok := r.(*CSSMediaRule); ok {
+			setParents(m.rules, s, m)
+		}
+	}
+}
+
+// CSSRule is an at-rule which is kept as text like @import or
+// @font-face
+type CSSRule struct {
+	ruleBase
+	typ  int
+	text string
+}
+
+func (r *CSSRule) Obj() *js.Object {
+	return ruleObj(r, cssRuleCtor)
+}
+
+func (r *CSSRule) Getters() map[string]bool {
+	return map[string]bool{
+		"cssText":          true,
+		"type":             true,
+		"parentRule":       true,
+		"parentStyleSheet": true,
+	}
+}
+
+func (r *CSSRule) Get(k string) js.Value {
+	if res, ok := GetCall(r, k); ok {
+		return res
+	}
+	return nil
+}
+
+func (r *CSSRule) Set(k string, desc js.PropertyDescriptor) bool {
+	return true
+}
+
+func (r *CSSRule) Has(k string) bool {
+	return HasCall(r, k)
+}
+
+func (r *CSSRule) Delete(k string) bool {
+	return false
+}
+
+func (r *CSSRule) Keys() []string {
+	return []string{""}
+}
+
+func (r *CSSRule) CssText() string {
+	return r.text
+}
+
+func (r *CSSRule) Type() int {
+	return r.typ
+}
+
+// CSSStyleRule is a style rule like p { color: red; }
+type CSSStyleRule struct {
+	ruleBase
+	selector string
+	decls    []decl
+}
+
+func (r *CSSStyleRule) Obj() *js.Object {
+	return ruleObj(r, cssStyleRuleCtor)
+}
+
+func (r *CSSStyleRule) Getters() map[string]bool {
+	return map[string]bool{
+		"cssText":          true,
+		"type":             true,
+		"parentRule":       true,
+		"parentStyleSheet": true,
+		"selectorText":     true,
+		"style":            true,
+	}
+}
+
+func (r *CSSStyleRule) Get(k string) js.Value {
+	if res, ok := GetCall(r, k); ok {
+		return res
+	}
+	return nil
+}
+
+func (r *CSSStyleRule) Set(k string, desc js.PropertyDescriptor) bool {
+	switch k {
+	case "selectorText":
+		if s := strings.Join(strings.Fields(desc.Value.String()), " "); s != "" {
+			r.selector = s
+		}
+	case "style":
+		// [PutForwards=cssText]
+		r.decls = parseDecls(desc.Value.String())
+	}
+	return true
+}
+
+func (r *CSSStyleRule) Has(k string) bool {
+	return HasCall(r, k)
+}
+
+func (r *CSSStyleRule) Delete(k string) bool {
+	return false
+}
+
+func (r *CSSStyleRule) Keys() []string {
+	return []string{""}
+}
+
+func (r *CSSStyleRule) CssText() string {
+	if len(r.decls) == 0 {
+		return r.selector + " { }"
+	}
+	return r.selector + " { " + serializeDecls(r.decls) + " }"
+}
+
+func (r *CSSStyleRule) Type() int {
+	return cssStyleRuleType
+}
+
+func (r *CSSStyleRule) SelectorText() string {
+	return r.selector
+}
+
+func (r *CSSStyleRule) Style() *js.Object {
+	return (&Style{r: r}).Obj()
+}
+
+// CSSMediaRule is a @media rule with nested rules
+type CSSMediaRule struct {
+	ruleBase
+	media string
+	rules []cssRule
+	list  *CSSRuleList
+}
+
+func (r *CSSMediaRule) Obj() *js.Object {
+	return ruleObj(r, cssMediaRuleCtor)
+}
+
+func (r *CSSMediaRule) Getters() map[string]bool {
+	return map[string]bool{
+		"cssText":          true,
+		"type":             true,
+		"parentRule":       true,
+		"parentStyleSheet": true,
+		"media":            true,
+		"conditionText":    true,
+		"cssRules":         true,
+	}
+}
+
+func (r *CSSMediaRule) Get(k string) js.Value {
+	if res, ok := GetCall(r, k); ok {
+		return res
+	}
+	return nil
+}
+
+func (r *CSSMediaRule) Set(k string, desc js.PropertyDescriptor) bool {
+	if k == "media" {
+		// [PutForwards=mediaText]
+		r.media = desc.Value.String()
+	}
+	return true
+}
+
+func (r *CSSMediaRule) Has(k string) bool {
+	return HasCall(r, k)
+}
+
+func (r *CSSMediaRule) Delete(k string) bool {
+	return false
+}
+
+func (r *CSSMediaRule) Keys() []string {
+	return []string{""}
+}
+
+func (r *CSSMediaRule) CssText() string {
+	s := "@media " + r.media + " {"
+	for _, c := range r.rules {
+		s += "\n  " + c.CssText()
+	}
+	return s + "\n}"
+}
+
+func (r *CSSMediaRule) Type() int {
+	return cssMediaRuleType
+}
+
+func (r *CSSMediaRule) Media() *js.Object {
+	return (&MediaList{text: &r.media}).Obj()
+}
+
+func (r *CSSMediaRule) ConditionText() string {
+	return r.media
+}
+
+func (r *CSSMediaRule) CssRules() *js.Object {
+	if r.list == nil {
+		r.list = &CSSRuleList{f: func() []cssRule { return r.rules }}
+	}
+	return r.list.Obj()
+}
+
+func (r *CSSMediaRule) InsertRule(rule string, i int) int {
+	return insertRule(&r.rules, rule, i, r.sheet, r)
+}
+
+func (r *CSSMediaRule) DeleteRule(i int) {
+	deleteRule(&r.rules, i)
+}
+
+// parseRules parses the rules of a style sheet. Invalid rules are
+// skipped.
+func parseRules(s string) (rules []cssRule) {
+	p := css.NewParser(parse.NewInputString(s), false)
+	// media are the open @media rules
+	var media []*CSSMediaRule
+	var sr *CSSStyleRule
+	var sels []string
+	add := func(r cssRule) {
+		if len(media) > 0 {
+			m := media[len(media)-1]
+			m.rules = append(m.rules, r)
+		} else {
+			rules = append(rules, r)
+		}
+	}
+	// raw returns the source of the last grammar
+	last := 0
+	raw := func() string {
+		off := p.Offset()
+		if off < last || off > len(s) {
+			return ""
+		}
+		return s[last:off]
+	}
+	for {
+		gt, _, data := p.Next()
+		switch gt {
+		case css.ErrorGrammar:
+			if p.Err() == io.EOF {
+				return
+			}
+			sels = nil
+		case css.AtRuleGrammar:
+			t := strings.TrimSpace(raw())
+			if !strings.HasSuffix(t, ";") {
+				t += ";"
+			}
+			add(&CSSRule{typ: atRuleType(string(data)), text: t})
+		case css.BeginAtRuleGrammar:
+			if strings.EqualFold(string(data), "@media") {
+				t := strings.TrimSuffix(strings.TrimSpace(raw()), "{")
+				t = strings.TrimSpace(t)[len(data):]
+				media = append(media, &CSSMediaRule{media: strings.Join(strings.Fields(t), " ")})
+				break
+			}
+			start, level := last, 1
+			for level > 0 {
+				switch gt, _, _ := p.Next(); gt {
+				case css.BeginAtRuleGrammar:
+					level++
+				case css.EndAtRuleGrammar:
+					level--
+				case css.ErrorGrammar:
+					if p.Err() == io.EOF {
+						level = 0
+					}
+				}
+			}
+			if off := p.Offset(); off <= len(s) {
+				add(&CSSRule{typ: atRuleType(string(data)), text: strings.TrimSpace(s[start:off])})
+			}
+		case css.EndAtRuleGrammar:
+			if n := len(media); n > 0 {
+				m := media[n-1]
+				media = media[:n-1]
+				add(m)
+			}
+		case css.QualifiedRuleGrammar:
+			sels = append(sels, strings.TrimSuffix(strings.TrimSpace(raw()), ","))
+		case css.BeginRulesetGrammar:
+			sels = append(sels, strings.TrimSuffix(strings.TrimSpace(raw()), "{"))
+			for i, sel := range sels {
+				sels[i] = strings.Join(strings.Fields(sel), " ")
+			}
+			sr = &CSSStyleRule{selector: strings.Join(sels, ", ")}
+			sels = nil
+		case css.DeclarationGrammar, css.CustomPropertyGrammar:
+			if sr != nil {
+				sr.decls = addDecl(sr.decls, newDecl(p, data))
+			}
+		case css.EndRulesetGrammar:
+			if sr != nil {
+				add(sr)
+				sr = nil
+			}
+		}
+		last = p.Offset()
+	}
+}
+
+// wellFormed is false if the parser reports an error in s or its
+// blocks aren't balanced. parseRules recovers from both by skipping or
+// closing blocks, which insertRule must not do.
+func wellFormed(s string) bool {
+	p := css.NewParser(parse.NewInputString(s), false)
+	for {
+		if gt, _, _ := p.Next(); gt == css.ErrorGrammar {
+			if p.Err() != io.EOF {
+				return false
+			}
+			break
+		}
+	}
+	closing := map[css.TokenType]css.TokenType{
+		css.LeftBraceToken:       css.RightBraceToken,
+		css.LeftParenthesisToken: css.RightParenthesisToken,
+		css.FunctionToken:        css.RightParenthesisToken,
+		css.LeftBracketToken:     css.RightBracketToken,
+	}
+	var open []css.TokenType
+	l := css.NewLexer(parse.NewInputString(s))
+	for {
+		tt, _ := l.Next()
+		switch tt {
+		case css.ErrorToken:
+			return len(open) == 0
+		case css.LeftBraceToken, css.LeftParenthesisToken, css.FunctionToken, css.LeftBracketToken:
+			open = append(open, closing[tt])
+		case css.RightBraceToken, css.RightParenthesisToken, css.RightBracketToken:
+			if len(open) == 0 || open[len(open)-1] != tt {
+				return false
+			}
+			open = open[:len(open)-1]
+		}
+	}
+}
+
+func atRuleType(name string) int {
+	switch strings.ToLower(name) {
+	case "@charset":
+		return cssCharsetRuleType
+	case "@import":
+		return cssImportRuleType
+	case "@media":
+		return cssMediaRuleType
+	case "@font-face":
+		return cssFontFaceRuleType
+	case "@page":
+		return cssPageRuleType
+	case "@keyframes", "@-webkit-keyframes":
+		return cssKeyframesRuleType
+	case "@namespace":
+		return cssNamespaceRuleType
+	case "@supports":
+		return cssSupportsRuleType
+	}
+	return 0
+}
+
+// insertRule parses rule and inserts it into rules at index i
+func insertRule(rules *[]cssRule, rule string, i int, s *CSSStyleSheet, p cssRule) int {
+	if i < 0 || i > len(*rules) {
+		throwDOMException("IndexSizeError", "index "+strconv.Itoa(i)+" is out of range")
+	}
+	rs := parseRules(rule)
+	if len(rs) != 1 || !wellFormed(rule) {
+		throwDOMException("SyntaxError", "failed to parse the rule '"+rule+"'")
+	}
+	r := rs[0]
+	if cr, ok := r.(*CSSRule); ok && cr.typ == cssImportRuleType {
+		if p != nil || s != nil && s.constructed {
+			throwDOMException("HierarchyRequestError", "@import rules can't be inserted here")
+		}
+		for _, o := range (*rules)[:i] {
+			if or, ok := o.(*CSSRule); !ok || or.typ != cssImportRuleType && or.typ != cssCharsetRuleType {
+				throwDOMException("HierarchyRequestError", "@import rules must precede all other rules")
+			}
+		}
+	}
+	setParents([]cssRule{r}, s, p)
+	*rules = append(*rules, nil)
+	copy((*rules)[i+1:], (*rules)[i:])
+	(*rules)[i] = r
+	return i
+}
+
+func deleteRule(rules *[]cssRule, i int) {
+	if i < 0 || i >= len(*rules) {
+		throwDOMException("IndexSizeError", "index "+strconv.Itoa(i)+" is out of range")
+	}
+	r := (*rules)[i]
+	r.base().sheet = nil
+	r.base().parent = nil
+	*rules = append((*rules)[:i], (*rules)[i+1:]...)
+}
+
+// CSSStyleSheet is a style sheet of a <style> or <link> element or a
+// sheet constructed from JS
+type CSSStyleSheet struct {
+	d     *Document
+	obj   *js.Object
+	owner *html.Node
+	href  string
+	media string
+	rules []cssRule
+	list  *CSSRuleList
+
+	// text is the source the rules were parsed from
+	text string
+
+	disabled    bool
+	constructed bool
+
+	// pending is set until a linked sheet is loaded
+	pending bool
+}
+
+// newStyleSheet returns the sheet of the owner node n parsed from text
+func newStyleSheet(d *Document, n *html.Node, text string) *CSSStyleSheet {
+	s := &CSSStyleSheet{d: d, owner: n, media: attr(*n, "media")}
+	s.setText(text)
+	return s
+}
+
+func (s *CSSStyleSheet) setText(text string) {
+	s.text = text
+	s.rules = parseRules(text)
+	setParents(s.rules, s, nil)
+}
+
+func (s *CSSStyleSheet) Obj() *js.Object {
+	if s.obj == nil {
+		s.obj = vm.NewDynamicObject(s)
+		s.obj.SetPrototype(cssStyleSheetCtor.Get("prototype").(*js.Object))
+	}
+	return s.obj
+}
+
+func (s *CSSStyleSheet) Getters() map[string]bool {
+	return map[string]bool{
+		"type":             true,
+		"href":             true,
+		"ownerNode":        true,
+		"parentStyleSheet": true,
+		"title":            true,
+		"media":            true,
+		"disabled":         true,
+		"ownerRule":        true,
+		"cssRules":         true,
+		"rules":            true,
+	}
+}
+
+func (s *CSSStyleSheet) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+func (s *CSSStyleSheet) Get(k string) js.Value {
+	if res, ok := GetCall(s, k); ok {
+		return res
+	}
+	return nil
+}
+
+func (s *CSSStyleSheet) Set(k string, desc js.PropertyDescriptor) bool {
+	switch k {
+	case "disabled":
+		s.disabled = desc.Value.ToBoolean()
+	case "media":
+		// [PutForwards=mediaText]
+		s.media = desc.Value.String()
+	}
+	return true
+}
+
+func (s *CSSStyleSheet) Has(k string) bool {
+	return HasCall(s, k)
+}
+
+func (s *CSSStyleSheet) Delete(k string) bool {
+	return false
+}
+
+func (s *CSSStyleSheet) Keys() []string {
+	return []string{""}
+}
+
+func (s *CSSStyleSheet) Type() string {
+	return "text/css"
+}
+
+func (s *CSSStyleSheet) Href() js.Value {
+	if s.href == "" {
+		return js.Null()
+	}
+	return vm.ToValue(s.href)
+}
+
+func (s *CSSStyleSheet) OwnerNode() js.Value {
+	if s.owner == nil {
+		return js.Null()
+	}
+	return s.d.getEl(s.owner).Obj()
+}
+
+func (s *CSSStyleSheet) ParentStyleSheet() js.Value {
+	return js.Null()
+}
+
+func (s *CSSStyleSheet) OwnerRule() js.Value {
+	return js.Null()
+}
+
+func (s *CSSStyleSheet) Title() js.Value {
+	if s.owner == nil || !hasAttr(*s.owner, "title") {
+		return js.Null()
+	}
+	return vm.ToValue(attr(*s.owner, "title"))
+}
+
+func (s *CSSStyleSheet) Media() *js.Object {
+	return (&MediaList{text: &s.media}).Obj()
+}
+
+func (s *CSSStyleSheet) Disabled() bool {
+	return s.disabled
+}
+
+func (s *CSSStyleSheet) CssRules() *js.Object {
+	if s.list == nil {
+		s.list = &CSSRuleList{f: func() []cssRule { return s.rules }}
+	}
+	return s.list.Obj()
+}
+
+func (s *CSSStyleSheet) Rules() *js.Object {
+	return s.CssRules()
+}
+
+func (s *CSSStyleSheet) InsertRule(rule string, i int) int {
+	return insertRule(&s.rules, rule, i, s, nil)
+}
+
+func (s *CSSStyleSheet) DeleteRule(i int) {
+	deleteRule(&s.rules, i)
+}
+
+// ReplaceSync replaces the rules of a constructed sheet. @import rules
+// are ignored.
+func (s *CSSStyleSheet) ReplaceSync(text string) {
+	if !s.constructed {
+		throwDOMException("NotAllowedError", "the sheet isn't constructed")
+	}
+	s.setText(text)
+	rs := s.rules[:0]
+	for _, r := range s.rules {
+		if cr, ok := r.(*CSSRule); ok && cr.typ == cssImportRuleType {
+			continue
+		}
+		rs = append(rs, r)
+	}
+	s.rules = rs
+}
+
+// Replace is like ReplaceSync but returns a promise of the sheet
+func (s *CSSStyleSheet) Replace(text string) js.Value {
+	p, resolve, reject := vm.NewPromise()
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				reject(r)
+			}
+		}()
+		s.ReplaceSync(text)
+		resolve(s.Obj())
+	}()
+	return vm.ToValue(p)
+}
+
+// CSSRuleList is a live list of the rules of a sheet or grouping rule
+type CSSRuleList struct {
+	f   func() []cssRule
+	obj *js.Object
+}
+
+func (rl *CSSRuleList) Obj() *js.Object {
+	if rl.obj == nil {
+		rl.obj = vm.NewDynamicObject(rl)
+		rl.obj.SetPrototype(cssRuleListCtor.Get("prototype").(*js.Object))
+	}
+	return rl.obj
+}
+
+func (rl *CSSRuleList) Getters() map[string]bool {
+	return map[string]bool{
+		"length": true,
+	}
+}
+
+func (rl *CSSRuleList) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+func (rl *CSSRuleList) Length() int {
+	return len(rl.f())
+}
+
+func (rl *CSSRuleList) Item(j any) js.Value {
+	i, ok := index(j)
+	rs := rl.f()
+	if !ok || i < 0 || i >= len(rs) {
+		return js.Null()
+	}
+	return rs[i].Obj()
+}
+
+func (rl *CSSRuleList) Get(k string) js.Value {
+	if i, err := strconv.Atoi(k); err == nil {
+		if rs := rl.f(); i >= 0 && i < len(rs) {
+			return rs[i].Obj()
+		}
+		return js.Undefined()
+	}
+	return nil
+}
+
+func (rl *CSSRuleList) Set(k string, desc js.PropertyDescriptor) bool {
+	return false
+}
+
+func (rl *CSSRuleList) Has(k string) bool {
+	if i, err := strconv.Atoi(k); err == nil {
+		return i >= 0 && i < rl.Length()
+	}
+	return false
+}
+
+func (rl *CSSRuleList) Delete(k string) bool {
+	return false
+}
+
+func (rl *CSSRuleList) Keys() (ks []string) {
+	for i := range rl.f() {
+		ks = append(ks, strconv.Itoa(i))
+	}
+	return
+}
+
+// StyleSheetList is the live list of the sheets of a document in tree
+// order
+type StyleSheetList struct {
+	d   *Document
+	obj *js.Object
+}
+
+func (sl *StyleSheetList) Obj() *js.Object {
+	if sl.obj == nil {
+		sl.obj = vm.NewDynamicObject(sl)
+		sl.obj.SetPrototype(styleSheetListCtor.Get("prototype").(*js.Object))
+	}
+	return sl.obj
+}
+
+func (sl *StyleSheetList) Getters() map[string]bool {
+	return map[string]bool{
+		"length": true,
+	}
+}
+
+func (sl *StyleSheetList) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+func (sl *StyleSheetList) Length() int {
+	return len(sl.d.styleSheets())
+}
+
+func (sl *StyleSheetList) Item(j any) js.Value {
+	i, ok := index(j)
+	ss := sl.d.styleSheets()
+	if !ok || i < 0 || i >= len(ss) {
+		return js.Null()
+	}
+	return ss[i].Obj()
+}
+
+func (sl *StyleSheetList) Get(k string) js.Value {
+	if i, err := strconv.Atoi(k); err == nil {
+		if ss := sl.d.styleSheets(); i >= 0 && i < len(ss) {
+			return ss[i].Obj()
+		}
+		return js.Undefined()
+	}
+	return nil
+}
+
+func (sl *StyleSheetList) Set(k string, desc js.PropertyDescriptor) bool {
+	return false
+}
+
+func (sl *StyleSheetList) Has(k string) bool {
+	if i, err := strconv.Atoi(k); err == nil {
+		return i >= 0 && i < sl.Length()
+	}
+	return false
+}
+
+func (sl *StyleSheetList) Delete(k string) bool {
+	return false
+}
+
+func (sl *StyleSheetList) Keys() (ks []string) {
+	for i := range sl.d.styleSheets() {
+		ks = append(ks, strconv.Itoa(i))
+	}
+	return
+}
+
+// MediaList is the list of media queries of a sheet or @media rule
+type MediaList struct {
+	text *string
+}
+
+func (ml *MediaList) Obj() *js.Object {
+	o := vm.NewDynamicObject(ml)
+	o.SetPrototype(mediaListCtor.Get("prototype").(*js.Object))
+	return o
+}
+
+func (ml *MediaList) Getters() map[string]bool {
+	return map[string]bool{
+		"mediaText": true,
+		"length":    true,
+	}
+}
+
+func (ml *MediaList) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+func (ml *MediaList) media() (ms []string) {
+	for _, m := range strings.Split(*ml.text, ",") {
+		if m = strings.Join(strings.Fields(m), " "); m != "" {
+			ms = append(ms, m)
+		}
+	}
+	return
+}
+
+func (ml *MediaList) MediaText() string {
+	return strings.Join(ml.media(), ", ")
+}
+
+func (ml *MediaList) Length() int {
+	return len(ml.media())
+}
+
+func (ml *MediaList) Item(j any) js.Value {
+	i, ok := index(j)
+	ms := ml.media()
+	if !ok || i < 0 || i >= len(ms) {
+		return js.Null()
+	}
+	return vm.ToValue(ms[i])
+}
+
+func (ml *MediaList) AppendMedium(m string) {
+	for _, o := range ml.media() {
+		if o == m {
+			return
+		}
+	}
+	*ml.text = strings.Join(append(ml.media(), m), ", ")
+}
+
+func (ml *MediaList) DeleteMedium(m string) {
+	ms := ml.media()
+	for i, o := range ms {
+		if o == m {
+			*ml.text = strings.Join(append(ms[:i], ms[i+1:]...), ", ")
+			return
+		}
+	}
+	throwDOMException("NotFoundError", "the medium isn't in the list")
+}
+
+func (ml *MediaList) ToString() string {
+	return ml.MediaText()
+}
+
+func (ml *MediaList) Get(k string) js.Value {
+	if i, err := strconv.Atoi(k); err == nil {
+		if ms := ml.media(); i >= 0 && i < len(ms) {
+			return vm.ToValue(ms[i])
+		}
+		return js.Undefined()
+	}
+	return nil
+}
+
+func (ml *MediaList) Set(k string, desc js.PropertyDescriptor) bool {
+	if k == "mediaText" {
+		*ml.text = desc.Value.String()
+	}
+	return true
+}
+
+func (ml *MediaList) Has(k string) bool {
+	if i, err := strconv.Atoi(k); err == nil {
+		return i >= 0 && i < ml.Length()
+	}
+	return false
+}
+
+func (ml *MediaList) Delete(k string) bool {
+	return false
+}
+
+func (ml *MediaList) Keys() (ks []string) {
+	for i := range ml.media() {
+		ks = append(ks, strconv.Itoa(i))
+	}
+	return
+}
+
+// isStyleSheetLink is true if n is a <link> to a style sheet
+func isStyleSheetLink(n *html.Node) bool {
+	if n.Type != html.ElementNode || n.Data != "link" || attr(*n, "href") == "" {
+		return false
+	}
+	for _, t := range strings.Fields(strings.ToLower(attr(*n, "rel"))) {
+		if t == "stylesheet" {
+			return true
+		}
+	}
+	return false
+}
+
+// isStyleElement is true if n is a <style> element with CSS
+func isStyleElement(n *html.Node) bool {
+	if n.Type != html.ElementNode || n.Data != "style" {
+		return false
+	}
+	t := strings.ToLower(strings.TrimSpace(attr(*n, "type")))
+	return t == "" || t == "text/css"
+}
+
+// sheetOf returns the style sheet of the <style> or <link> element n
+// or nil if it has none (yet). Linked sheets are fetched on demand.
+func (d *Document) sheetOf(n *html.Node) *CSSStyleSheet {
+	if d.sheets == nil {
+		d.sheets = make(map[*html.Node]*CSSStyleSheet)
+	}
+	switch {
+	case isStyleElement(n):
+		text := textContent(n)
+		s := d.sheets[n]
+		if s == nil || s.text != text {
+			s = newStyleSheet(d, n, text)
+			d.sheets[n] = s
+		}
+		return s
+	case isStyleSheetLink(n):
+		s := d.sheets[n]
+		if s == nil || s.href != d.resolveURL(attr(*n, "href")) {
+			s = d.fetchStyleSheet(n)
+		}
+		if s == nil || s.pending {
+			return nil
+		}
+		return s
+	}
+	return nil
+}
+
+// fetchStyleSheet requests the sheet of the <link> element n through
+// XHR. load or error is fired at n when it's done.
+func (d *Document) fetchStyleSheet(n *html.Node) *CSSStyleSheet {
+	href := d.resolveURL(attr(*n, "href"))
+	s := &CSSStyleSheet{d: d, owner: n, href: href, media: attr(*n, "media"), pending: true}
+	d.sheets[n] = s
+	if XHR == nil {
+		log.Errorf("nil XHR func: can't fetch %v", href)
+		return s
+	}
+	XHR("GET", href, make(map[string]string), "", func(data, err string, status int) {
+		if d.sheets[n] != s {
+			return
+		}
+		if err == "" && !statusOk(status) {
+			err = fmt.Sprintf("status %v", status)
+		}
+		t := "load"
+		if err != "" {
+			log.Errorf("fetch style sheet %v: %v", href, err)
+			t = "error"
+		} else {
+			s.setText(data)
+			s.pending = false
+		}
+		if d.Window != nil {
+			d.getEl(n).DispatchEvent(newEvent("Event", t, nil))
+		}
+	})
+	return s
+}
+
+// loadStyleSheets fetches the linked sheets in the subtree of n
+func (d *Document) loadStyleSheets(n *html.Node) {
+	if !connected(n) {
+		return
+	}
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if isStyleSheetLink(n) {
+			d.sheetOf(n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+	}
+	f(n)
+}
+
+// styleSheets returns the sheets of the document in tree order
+func (d *Document) styleSheets() (ss []*CSSStyleSheet) {
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if isStyleElement(n) || isStyleSheetLink(n) {
+			if s := d.sheetOf(n); s != nil {
+				ss = append(ss, s)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+	}
+	f(d.doc)
+	return
+}
+
+// StyleSheets returns the live list of the document's sheets
+func (d *Document) StyleSheets() *js.Object {
+	if d.sheetList == nil {
+		d.sheetList = &StyleSheetList{d: d}
+	}
+	return d.sheetList.Obj()
+}
+
+// AdoptedStyleSheets returns the constructed sheets applied to the
+// document. Assignments are kept as expandos.
+func (d *Document) AdoptedStyleSheets() js.Value {
+	v := vm.NewArray()
+	d.vars["adoptedStyleSheets"] = v
+	return v
+}
+
+// Sheet returns the style sheet of a <style> or <link> element
+func (el *Element) Sheet() js.Value {
+	if el.n.Data != "style" && el.n.Data != "link" {
+		return js.Undefined()
+	}
+	if !connected(el.n) {
+		return js.Null()
+	}
+	if s := el.d.sheetOf(el.n); s != nil {
+		return s.Obj()
+	}
+	return js.Null()
+}
@@ -0,0 +1,134 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"testing"
+)
+
+func TestStyleSheets(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><head><style>
+		p, a > b { color: red !important; margin:0 }
+		@import url(x.css);
+		@media screen and (min-width: 100px) { .x { display: none } }
+		@font-face { font-family: f; }
+	</style></head><body><p id="a">x</p></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var ss = document.styleSheets;
+		var s = ss[0];
+		var log = [ss.length, s instanceof CSSStyleSheet, s instanceof StyleSheet, s.ownerNode.tagName, s.href, s.cssRules.length];
+		var r = s.cssRules[0];
+		log.push(r instanceof CSSStyleRule, r.type, r.selectorText, r.cssText, r.style.color, r.parentStyleSheet === s);
+		var m = s.cssRules[2];
+		log.push(m.type, m.media.mediaText, m.cssRules[0].cssText, m.cssRules[0].parentRule === m);
+		log.push(s.cssRules[1].cssText, s.cssRules[3].type);
+		log.push(s.insertRule('div { top: 1px }', 1), s.cssRules[1].selectorText);
+		try { s.insertRule('div', 0) } catch (e) { log.push(e.name) }
+		for (var bad of ['bogus{{{', 'a {}}', 'a { b: c ) }']) {
+			try { s.insertRule(bad, 0) } catch (e) { log.push(e.name) }
+		}
+		try { s.insertRule('a {}', 9) } catch (e) { log.push(e.name) }
+		s.deleteRule(1);
+		m.insertRule('i { x: y }', 1);
+		log.push(m.cssRules.length, m.cssText);
+		var st = document.createElement('style');
+		st.textContent = 'b { top: 0 }';
+		document.body.appendChild(st);
+		log.push(ss.length, st.sheet === ss[1], st.sheet.cssRules[0].selectorText);
+		st.remove();
+		log.push(ss.length, st.sheet);
+		var c = new CSSStyleSheet();
+		c.replaceSync('@import url(y.css); .c { color: blue }');
+		log.push(c.cssRules.length, c.cssRules[0].cssText, c.ownerNode);
+		document.adoptedStyleSheets = [c];
+		log.push(document.adoptedStyleSheets[0] === c);
+		try { s.replaceSync('') } catch (e) { log.push(e.name) }
+		log.join('|');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := `1|true|true|STYLE||4|true|1|p, a > b|p, a > b { color: red !important; margin: 0; }|red|true|4|screen and (min-width: 100px)|.x { display: none; }|true|@import url(x.css);|5|1|div|SyntaxError|SyntaxError|SyntaxError|SyntaxError|IndexSizeError|2|@media screen and (min-width: 100px) {
+  .x { display: none; }
+  i { x: y; }
+}|2|true|b|1||1|.c { color: blue; }||true|NotAllowedError`
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestLinkStyleSheet(t *testing.T) {
+	XHR = func(method, uri string, h map[string]string, data string, cb func(data, err string, status int)) {
+		if uri == "https://example.com/css/a.css" {
+			cb("p { color: green }", "", 200)
+		} else {
+			cb("", "not found", 0)
+		}
+	}
+	defer func() { XHR = nil }()
+	vm := js.New()
+	_, err := Init(vm, "https://example.com/css/", `<html><head><link rel="stylesheet" href="a.css"></head><body></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var log = [document.styleSheets.length, document.styleSheets[0].href, document.styleSheets[0].cssRules[0].cssText];
+		var l = document.createElement('link');
+		l.rel = 'stylesheet';
+		l.href = '/b.css';
+		l.onerror = function() { log.push('error') };
+		document.head.appendChild(l);
+		log.push(document.styleSheets.length, l.sheet);
+		log.join('|');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != `1|https://example.com/css/a.css|p { color: green; }|error|1|` {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestComputedStyle(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><head><style>
+		#a { color: #f00; }
+		div p { color: blue !important; visibility: hidden }
+		.x { display: none }
+		@media (max-width: 100px) { p { display: flex } }
+		span { border-top-style: solid; border-top-width: thin }
+	</style></head><body><div><p id="a" style="color: green">x <b>y</b> <span>z</span></p></div><p id="c" class="x" hidden></p></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var cs = getComputedStyle(document.getElementById('a'));
+		var b = getComputedStyle(document.querySelector('b'));
+		var s = getComputedStyle(document.querySelector('span'));
+		var c = getComputedStyle(document.getElementById('c'));
+		var h = getComputedStyle(document.head);
+		var log = [cs.display, cs.color, cs.visibility, cs.getPropertyValue('margin-top'), b.display, b.color, b.fontWeight, b.visibility, s.borderTopWidth, s.borderBottomWidth, s.borderTopColor, c.display, h.display, cs.fontSize];
+		var body = getComputedStyle(document.body);
+		var h1 = getComputedStyle(document.body.appendChild(document.createElement('h1')));
+		log.push(body.marginTop, body.marginLeft, h1.fontSize, h1.marginTop, h1.marginLeft);
+		var l = document.body.appendChild(document.createElement('a'));
+		var ls = getComputedStyle(l);
+		log.push(ls.color);
+		l.href = '/x';
+		log.push(ls.color, ls.textDecorationLine);
+		var a = new CSSStyleSheet();
+		a.replaceSync('b { display: block }');
+		document.adoptedStyleSheets = [a];
+		log.push(b.display);
+		log.join('|');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != `block|rgb(0, 0, 255)|hidden|16px|inline|rgb(0, 0, 255)|700|hidden|1px|0px|rgb(0, 0, 255)|none|none|16px|8px|8px|32px|21.44px|0px|rgb(0, 0, 0)|rgb(0, 0, 238)|underline|block` {
+		t.Fatalf("%v", v)
+	}
+}
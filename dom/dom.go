@@ -8,6 +8,7 @@ import (
 	"github.com/psilva261/sparklefs/logger"
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
+	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
@@ -20,22 +21,30 @@ var (
 )
 
 var (
-	elObjRefs = make(map[*Element]*js.Object)
 	evObjRefs = make(map[*Event]*js.Object)
 	dfObjRefs = make(map[*DocumentFragment]*js.Object)
 	hcObjRefs = make(map[*HTMLCollection]*js.Object)
+	tlObjRefs = make(map[*Element]*js.Object)
+	dsObjRefs = make(map[*Element]*js.Object)
 )
 
 var (
-	elVars = make(map[*Element]map[string]js.Value)
+	elVars = make(map[*html.Node]map[string]js.Value)
 	evVars = make(map[*Event]map[string]js.Value)
 )
 
 var (
 	Geom  func(sel string) (string, error)
 	Query func(sel, prop string) (val string, err error)
+	XHR   func(method, uri string, h map[string]string, data string, cb func(data, err string, status int))
 )
 
+// statusOk is true for the 2xx HTTP status codes of successful
+// responses
+func statusOk(status int) bool {
+	return 200 <= status && status <= 299
+}
+
 func Mutations() <-chan Mutation {
 	return mutations
 }
@@ -75,11 +84,6 @@ type Navigator struct {
 
 type History struct{}
 
-var NodePrototype js.Value
-var TextPrototype js.Value
-var HTMLElementPrototype js.Value
-var HTMLInputElementPrototype js.Value
-
 type Window struct {
 	*Document
 	*Location
@@ -89,10 +93,15 @@ type Window struct {
 	obj  *js.Object
 	vars map[string]js.Value
 
-	animationFrame func(js.FunctionCall) js.Value
+	// frameCallbacks are the callbacks of requestAnimationFrame
+	frameCallbacks []frameCallback
+	frameID        int
+
+	// rendering is true while an animation frame is rendered
+	rendering bool
 
 	builtinThis    *js.Object
-	eventListeners map[string][]func(js.FunctionCall) js.Value
+	eventListeners listeners
 }
 
 func NewWindow(url string, builtinThis *js.Object, d *Document) *Window {
@@ -105,7 +114,7 @@ func NewWindow(url string, builtinThis *js.Object, d *Document) *Window {
 	}
 	w.builtinThis = builtinThis
 	w.vars = make(map[string]js.Value)
-	w.eventListeners = make(map[string][]func(js.FunctionCall) js.Value)
+	w.eventListeners = make(listeners)
 	return w
 }
 
@@ -145,14 +154,8 @@ func (w *Window) Get(k string) js.Value {
 		return vm.ToValue(w.removeEventListener)
 	case "dispatchEvent":
 		return vm.ToValue(w.dispatchEvent)
-	case "Node":
-		return NodePrototype
-	case "Text":
-		return TextPrototype
 	case "HTMLElementPrototype":
-		return HTMLElementPrototype
-	case "HTMLInputElement":
-		return HTMLInputElementPrototype
+		return nodeCtors["HTMLElement"]
 	case "getComputedStyle":
 		return vm.ToValue(func(args ...any) js.Value {
 			el := args[0].(*Element)
@@ -161,11 +164,36 @@ func (w *Window) Get(k string) js.Value {
 			return s.Obj()
 		})
 	case "requestAnimationFrame":
-		return vm.ToValue(func(f func(js.FunctionCall) js.Value) {
-			w.animationFrame = f
+		return vm.ToValue(func(v js.Value) int {
+			f, ok := js.AssertFunction(v)
+			if !ok {
+				panic(vm.NewTypeError("requestAnimationFrame: callback is not a function"))
+			}
+			w.frameID++
+			w.frameCallbacks = append(w.frameCallbacks, frameCallback{id: w.frameID, f: f})
+			return w.frameID
+		})
+	case "cancelAnimationFrame":
+		return vm.ToValue(func(id int) {
+			for i, fc := range w.frameCallbacks {
+				if fc.id == id {
+					w.frameCallbacks = append(w.frameCallbacks[:i], w.frameCallbacks[i+1:]...)
+					break
+				}
+			}
 		})
+	case "IntersectionObserver":
+		return intersectionObserverCtor
+	case "IntersectionObserverEntry":
+		return intersectionObserverEntryCtor
+	case "ResizeObserver":
+		return resizeObserverCtor
+	case "ResizeObserverEntry":
+		return resizeObserverEntryCtor
+	case "ResizeObserverSize":
+		return resizeObserverSizeCtor
 	case "SVGElement":
-		return vm.ToValue(func(call js.ConstructorCall) *js.Object {
+		return newCtor("SVGElement", func(call js.ConstructorCall) *js.Object {
 			doc := call.Argument(0).String()
 			s := NewSVG(doc)
 			sv := vm.ToValue(s).(*js.Object)
@@ -173,60 +201,116 @@ func (w *Window) Get(k string) js.Value {
 			return sv
 		})
 	case "DOMParser":
-		return vm.ToValue(func(call js.ConstructorCall) *js.Object {
-			dp := NewDOMParser()
-			dpv := vm.ToValue(dp).(*js.Object)
+		return newCtor("DOMParser", func(call js.ConstructorCall) *js.Object {
+			dp := NewDOMParser(w.Document)
+			dpv := dp.Obj()
 			dpv.SetPrototype(call.This.Prototype())
 			return dpv
 		})
+	case "XMLSerializer":
+		return newCtor("XMLSerializer", func(call js.ConstructorCall) *js.Object {
+			xs := vm.NewDynamicObject(&XMLSerializer{})
+			xs.SetPrototype(call.This.Prototype())
+			return xs
+		})
 	case "MutationObserver":
-		return vm.ToValue(func(call js.ConstructorCall) *js.Object {
+		return newCtor("MutationObserver", func(call js.ConstructorCall) *js.Object {
 			m := NewMutObserver()
 			mv := vm.ToValue(m).(*js.Object)
 			mv.SetPrototype(call.This.Prototype())
 			return mv
 		})
-	case "Event":
-		return vm.ToValue(func(call js.ConstructorCall) *js.Object {
-			var opts map[string]interface{}
-			typ := call.Argument(0).String()
-			if len(call.Arguments) >= 2 {
-				opts = call.Argument(1).Export().(map[string]interface{})
-			}
-			e := NewEvent(typ, opts)
-			ev := vm.ToValue(e).(*js.Object)
-			ev.SetPrototype(call.This.Prototype())
-			return ev
+	case "getSelection":
+		return vm.ToValue(func() js.Value {
+			return w.Document.GetSelection().Obj()
 		})
-	case "MouseEvent":
-		return vm.ToValue(func(call js.ConstructorCall) *js.Object {
-			var opts map[string]interface{}
-			if len(call.Arguments) >= 2 {
-				opts = call.Argument(1).Export().(map[string]interface{})
-			}
-			e := NewMouseEvent(call.Argument(0).String(), opts)
-			ev := vm.ToValue(e).(*js.Object)
-			ev.SetPrototype(call.This.Prototype())
-			return ev
+	case "Range":
+		return rangeCtor
+	case "StaticRange":
+		return staticRangeCtor
+	case "AbstractRange":
+		return abstractRangeCtor
+	case "Selection":
+		return selectionCtor
+	case "StyleSheet":
+		return styleSheetCtor
+	case "CSSStyleSheet":
+		return cssStyleSheetCtor
+	case "CSSRule":
+		return cssRuleCtor
+	case "CSSStyleRule":
+		return cssStyleRuleCtor
+	case "CSSMediaRule":
+		return cssMediaRuleCtor
+	case "CSSRuleList":
+		return cssRuleListCtor
+	case "StyleSheetList":
+		return styleSheetListCtor
+	case "MediaList":
+		return mediaListCtor
+	case "CSSStyleDeclaration":
+		return cssStyleDeclarationCtor
+	case "DOMRectReadOnly":
+		return domRectReadOnlyCtor
+	case "DOMRect":
+		return domRectCtor
+	case "DOMRectList":
+		return domRectListCtor
+	case "innerWidth", "outerWidth":
+		return vm.ToValue(ViewportWidth)
+	case "innerHeight", "outerHeight":
+		return vm.ToValue(ViewportHeight)
+	case "matchMedia":
+		return vm.ToValue(func(q string) *js.Object {
+			return (&MediaQueryList{d: w.Document, media: q}).Obj()
 		})
-	case "CustomEvent":
-		return vm.ToValue(func(call js.ConstructorCall) *js.Object {
-			e := NewEvent(call.Argument(0).String(), map[string]any{})
-			ev := vm.ToValue(e).(*js.Object)
-			ev.SetPrototype(call.This.Prototype())
-			return ev
+	case "MediaQueryList":
+		return mediaQueryListCtor
+	case "devicePixelRatio":
+		return vm.ToValue(DevicePixelRatio)
+	case "screen":
+		return w.screen()
+	case "scrollX", "pageXOffset":
+		x, _ := w.scrollOffset()
+		return vm.ToValue(x)
+	case "scrollY", "pageYOffset":
+		_, y := w.scrollOffset()
+		return vm.ToValue(y)
+	case "scrollTo", "scroll":
+		return vm.ToValue(func(args ...js.Value) {
+			x, y := w.scrollOffset()
+			w.ScrollTo(scrollArgs(args, x, y))
 		})
-	case "Comment":
-		return vm.ToValue(func(call js.ConstructorCall) *js.Object {
-			var data string
-			if len(call.Arguments) >= 1 {
-				data = call.Argument(0).String()
-			}
-			el := w.Document.CreateComment(data)
-			return el.Obj()
+	case "scrollBy":
+		return vm.ToValue(func(args ...js.Value) {
+			x, y := w.scrollOffset()
+			dx, dy := scrollArgs(args, 0, 0)
+			w.ScrollTo(x+dx, y+dy)
 		})
+	case "Event", "CustomEvent", "UIEvent", "FocusEvent", "InputEvent", "KeyboardEvent", "MouseEvent", "PointerEvent", "WheelEvent", "SubmitEvent", "MediaQueryListEvent":
+		return eventCtors[k]
+	case "FormData":
+		return formDataCtor
+	case "NodeList":
+		return nodeListCtor
+	case "HTMLCollection":
+		return htmlCollectionCtor
+	case "HTMLFormControlsCollection":
+		return formControlsCtor
+	case "RadioNodeList":
+		return radioNodeListCtor
+	case "ValidityState":
+		return validityStateCtor
+	case "TreeWalker":
+		return treeWalkerCtor
+	case "NodeIterator":
+		return nodeIteratorCtor
+	case "DOMTokenList":
+		return domTokenListCtor
+	case "DOMStringMap":
+		return domStringMapCtor
 	case "Image":
-		return vm.ToValue(func(call js.ConstructorCall) *js.Object {
+		return newCtor("Image", func(call js.ConstructorCall) *js.Object {
 			el := w.Document.CreateElement("img")
 			if len(call.Arguments) >= 1 {
 				w := call.Argument(0).String()
@@ -238,25 +322,63 @@ func (w *Window) Get(k string) js.Value {
 			}
 			return el.Obj()
 		})
-	case "Document":
-		return vm.ToValue(func(call js.ConstructorCall) *js.Object {
-			impl := &Implementation{}
-			return impl.CreateHTMLDocument("").Obj()
-		})
-	case "DocumentFragment":
-		return vm.ToValue(func(call js.ConstructorCall) *js.Object {
-			return w.Document.CreateDocumentFragment().Obj()
-		})
 	default:
+		if c, ok := nodeCtors[k]; ok {
+			return c
+		}
 		res := w.builtinThis.Get(k)
 		if res == nil {
-			return js.Undefined()
+			return w.namedProperty(k)
 		}
 		return res
 	}
 	return js.Undefined()
 }
 
+// namedProperty returns the window of the iframe named k or else the
+// first element with the id k (or name for embed, form, img and object
+// elements). Multiple elements aren't returned as a collection.
+func (w *Window) namedProperty(k string) js.Value {
+	if k == "" {
+		return js.Undefined()
+	}
+	var frame, el *html.Node
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		for c := n.FirstChild; c != nil && frame == nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if c.Data == "iframe" && attr(*c, "name") == k {
+				frame = c
+				return
+			}
+			if el == nil {
+				switch c.Data {
+				case "embed", "form", "img", "object":
+					if attr(*c, "name") == k {
+						el = c
+					}
+				}
+				if attr(*c, "id") == k {
+					el = c
+				}
+			}
+			f(c)
+		}
+	}
+	f(w.Document.doc)
+	if frame != nil {
+		if fr := w.Document.frame(frame); fr != nil {
+			return fr.global
+		}
+	}
+	if el != nil {
+		return w.Document.getNode(el).Obj()
+	}
+	return js.Undefined()
+}
+
 func (w *Window) Set(key string, desc js.PropertyDescriptor) bool {
 	switch key {
 	case "Promise":
@@ -286,74 +408,73 @@ func (w *Window) Keys() []string {
 	return []string{""}
 }
 
+type frameCallback struct {
+	id int
+	f  js.Callable
+}
+
+// RenderAnimationFrame fires pending resize, scroll and media query
+// change events, runs the animation frame callbacks and then updates the resize and
+// intersection observations. ok is false if there was nothing to do.
 func (w *Window) RenderAnimationFrame() (ok bool) {
-	if w.animationFrame == nil {
-		return false
+	w.rendering = true
+	defer func() { w.rendering = false }()
+	resetGeom()
+	t := float64(time.Now().UnixMilli())
+	ok = w.Document.runResizeAndScrollSteps()
+	if w.Document.evaluateMediaQueries() {
+		ok = true
 	}
-	fn, ok := js.AssertFunction(vm.ToValue(w.animationFrame))
-	if !ok {
-		log.Errorf("request animation frame assert function: %v", ok)
-		return
+	fcs := w.frameCallbacks
+	w.frameCallbacks = nil
+	for _, fc := range fcs {
+		if _, err := fc.f(nil, vm.ToValue(t)); err != nil {
+			log.Infof("run anim cb: %v", err)
+		}
 	}
-	t := time.Now().UnixMilli()
-	_, err := fn(nil, vm.ToValue(float64(t)))
-	if err != nil {
-		log.Infof("run anim cb: %v", err)
+	if w.Document.updateObservations(t) {
+		ok = true
 	}
-	return true
+	return ok || len(fcs) > 0
+}
+
+func (w *Window) listeners() listeners {
+	return w.eventListeners
 }
 
-func (w *Window) addEventListener(e string, f func(js.FunctionCall) js.Value) {
+func (w *Window) addEventListener(e string, fn js.Value, opts ...any) {
 	c := &Call{
 		recv:  "Window",
 		k:     "addEventListener",
 		found: true,
 	}
 	calls = append(calls, c)
-	w.eventListeners[e] = append(w.eventListeners[e], f)
+	w.eventListeners.add(e, fn, opts...)
 }
 
-func (w *Window) removeEventListener(e string, f func(js.FunctionCall) js.Value) {
+func (w *Window) removeEventListener(e string, fn js.Value, opts ...any) {
 	c := &Call{
 		recv:  "Window",
 		k:     "removeEventListener",
 		found: true,
 	}
 	calls = append(calls, c)
-	for i, ff := range w.eventListeners[e] {
-		if fmt.Sprintf("%p", f) == fmt.Sprintf("%p", ff) {
-			w.eventListeners[e] = append(w.eventListeners[e][:i], w.eventListeners[e][i+1:]...)
-			break
-		}
-	}
+	w.eventListeners.remove(e, fn, opts...)
 }
 
-func (w *Window) dispatchEvent(e *Event) {
+func (w *Window) dispatchEvent(ei any) bool {
+	e := wrap(ei)
+	if e == nil {
+		panic(vm.NewTypeError(fmt.Sprintf("dispatchEvent: parameter 1 is not of type 'Event' (%T)", ei)))
+	}
 	c := &Call{
 		recv:  "Window",
 		k:     "dispatchEvent",
 		found: true,
 	}
 	calls = append(calls, c)
-	for _, f := range w.eventListeners[e.Type] {
-		fn, ok := js.AssertFunction(vm.ToValue(f))
-		if !ok {
-			log.Errorf("win assert function: %v", ok)
-			e.Consumed = true
-			continue
-		}
-		that, err := vm.RunString("this")
-		if err != nil {
-			log.Fatalf("oh no: %v", err)
-			continue
-		}
-		_, err = fn(that, e.Obj())
-		if err != nil {
-			log.Errorf("win event handler fn: %v", err)
-			e.Consumed = true
-			continue
-		}
-	}
+	e.dispatch([]EventTarget{w})
+	return !e.DefaultPrevented
 }
 
 type Document struct {
@@ -361,18 +482,70 @@ type Document struct {
 	doc    *html.Node
 	obj    *js.Object
 	vars   map[string]js.Value
-	elRefs map[*html.Node]*Element
+	ndRefs map[*html.Node]Node
+
+	// focused element or nil if the focus is on the viewport
+	focused *Element
+
+	url string
+
+	// contentType is the MIME type of the document
+	contentType string
+
+	// submission is the request of a form submission
+	submission *http.Request
+
+	eventListeners listeners
+
+	// iterators are the node iterators created by the document
+	iterators []*NodeIterator
+
+	selection *Selection
+
+	// sheets are the style sheets of <style> and <link> elements
+	sheets    map[*html.Node]*CSSStyleSheet
+	sheetList *StyleSheetList
 
-	eventListeners map[string][]*js.Object
+	intersectionObservers []*IntersectionObserver
+	resizeObservers       []*ResizeObserver
+
+	// scrollOffsets of the document element (i.e. the viewport) and
+	// of scroll containers
+	scrollOffsets map[*html.Node][2]float64
+
+	// pendingScroll and pendingResize events of the next animation
+	// frame
+	pendingScroll []*html.Node
+	pendingResize bool
+
+	// mediaQueryLists with change listeners
+	mediaQueryLists []*MediaQueryList
+
+	// scripts are the flags of script elements
+	scripts map[*html.Node]*scriptFlags
+
+	// controls are the states of form controls and options
+	controls map[*html.Node]*controlState
+
+	// frames are the browsing contexts of iframes
+	frames map[*html.Node]*frame
+
+	// orderedScripts run in insertion order once fetched
+	orderedScripts []*pendingScript
+	currentScript  *Element
 }
 
 func NewDocument(doc *html.Node) (d *Document) {
 	d = &Document{
-		doc: doc,
+		doc:         doc,
+		contentType: "text/html",
 	}
 	d.vars = make(map[string]js.Value)
-	d.elRefs = make(map[*html.Node]*Element)
-	d.eventListeners = make(map[string][]*js.Object)
+	d.ndRefs = make(map[*html.Node]Node)
+	d.eventListeners = make(listeners)
+	d.scripts = make(map[*html.Node]*scriptFlags)
+	d.controls = make(map[*html.Node]*controlState)
+	d.frames = make(map[*html.Node]*frame)
 	return
 }
 
@@ -384,33 +557,67 @@ func (d *Document) Doc() *html.Node {
 	return d.doc
 }
 
+// isHTML is false for XML documents
+func (d *Document) isHTML() bool {
+	return d.contentType == "text/html"
+}
+
 func (d *Document) Obj() (o *js.Object) {
 	if d.obj == nil {
 		d.obj = vm.NewDynamicObject(d)
+		iface := "HTMLDocument"
+		if !d.isHTML() {
+			iface = "XMLDocument"
+		}
+		if c, ok := nodeCtors[iface]; ok {
+			d.obj.SetPrototype(c.Get("prototype").(*js.Object))
+		}
 	}
 	return d.obj
 }
 
 func (d *Document) Getters() map[string]bool {
 	return map[string]bool{
-		"domain":          true,
-		"location":        true,
-		"referrer":        true,
-		"cookie":          true,
-		"implementation":  true,
-		"defaultView":     true,
-		"documentElement": true,
-		"all":             true,
-		"body":            true,
-		"head":            true,
-		"title":           true,
-		"scripts":         true,
-		"styleSheets":     true,
-		"activeElement":   true,
-		"nodeType":        true,
-		"parentNode":      true,
-		"childNodes":      true,
-		"children":        true,
+		"domain":             true,
+		"location":           true,
+		"referrer":           true,
+		"cookie":             true,
+		"implementation":     true,
+		"defaultView":        true,
+		"documentElement":    true,
+		"currentScript":      true,
+		"doctype":            true,
+		"all":                true,
+		"body":               true,
+		"head":               true,
+		"title":              true,
+		"scripts":            true,
+		"styleSheets":        true,
+		"adoptedStyleSheets": true,
+		"activeElement":      true,
+		"contentType":        true,
+		"URL":                true,
+		"documentURI":        true,
+		"baseURI":            true,
+		"characterSet":       true,
+		"charset":            true,
+		"inputEncoding":      true,
+		"compatMode":         true,
+		"nodeType":           true,
+		"parentNode":         true,
+		"childNodes":         true,
+		"firstChild":         true,
+		"lastChild":          true,
+		"previousSibling":    true,
+		"nextSibling":        true,
+		"parentElement":      true,
+		"ownerDocument":      true,
+		"nodeValue":          true,
+		"textContent":        true,
+		"children":           true,
+		"firstElementChild":  true,
+		"lastElementChild":   true,
+		"childElementCount":  true,
 	}
 }
 
@@ -437,8 +644,8 @@ func (d *Document) Get(key string) js.Value {
 			found: true,
 		}
 		calls = append(calls, c)
-		return vm.ToValue(func(e string, fn *js.Object, opts ...any) {
-			d.AddEventListener(e, fn, opts)
+		return vm.ToValue(func(e string, fn js.Value, opts ...any) {
+			d.AddEventListener(e, fn, opts...)
 		})
 	}
 	if res, ok := GetCall(d, key); ok {
@@ -448,12 +655,13 @@ func (d *Document) Get(key string) js.Value {
 		// TODO: weird error preventing to factor nodeName into a function
 		return vm.ToValue("#document")
 	}
-	return js.Undefined()
+	// inherited from the prototype e.g. constants
+	return nil
 }
 
 func (d *Document) Set(key string, desc js.PropertyDescriptor) bool {
 	switch key {
-	case "nodeValue":
+	case "nodeValue", "textContent":
 		// no effect
 	default:
 		d.vars[key] = desc.Value
@@ -484,14 +692,55 @@ func (d *Document) Keys() []string {
 	return []string{""}
 }
 
+func (d *Document) ContentType() string {
+	return d.contentType
+}
+
 func (d *Document) Domain() string {
 	return d.Window.Location.Hostname
 }
 
-func (d *Document) Location() *js.Object {
+func (d *Document) Location() js.Value {
+	if d.Window == nil {
+		return js.Null()
+	}
 	return d.Window.Location.Obj()
 }
 
+func (d *Document) URL() string {
+	return d.url
+}
+
+func (d *Document) DocumentURI() string {
+	return d.url
+}
+
+// BaseURI returns the document URL resolved against the first base
+// element
+func (d *Document) BaseURI() string {
+	return d.resolveURL(d.url)
+}
+
+func (d *Document) CharacterSet() string {
+	return "UTF-8"
+}
+
+func (d *Document) Charset() string {
+	return "UTF-8"
+}
+
+func (d *Document) InputEncoding() string {
+	return "UTF-8"
+}
+
+// CompatMode is BackCompat for HTML documents without doctype
+func (d *Document) CompatMode() string {
+	if d.isHTML() && grepFirst(d.doc, html.DoctypeNode) == nil {
+		return "BackCompat"
+	}
+	return "CSS1Compat"
+}
+
 func (d *Document) Referrer() string {
 	return "https://example.com"
 }
@@ -501,15 +750,29 @@ func (d *Document) Cookie() string {
 }
 
 func (d *Document) Implementation() js.Value {
-	return vm.NewDynamicObject(&Implementation{})
+	return vm.NewDynamicObject(&Implementation{d: d})
 }
 
 func (d *Document) DefaultView() *Window {
 	return d.Window
 }
 
-func (d *Document) DocumentElement() *js.Object {
-	return d.getEl(d.doc).Obj()
+func (d *Document) DocumentElement() js.Value {
+	for c := d.doc.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			return d.getNode(c).Obj()
+		}
+	}
+	return js.Null()
+}
+
+func (d *Document) Doctype() js.Value {
+	for c := d.doc.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.DoctypeNode {
+			return d.getNode(c).Obj()
+		}
+	}
+	return js.Null()
 }
 
 func (d *Document) All() *js.Object {
@@ -522,16 +785,27 @@ func (d *Document) All() *js.Object {
 	return hc.Obj()
 }
 
-func (d *Document) Body() *js.Object {
-	return d.getEl(grep(d.doc, "body")).Obj()
+func (d *Document) Body() js.Value {
+	return d.nodeObj(grep(d.doc, "body"))
 }
 
-func (d *Document) Head() *js.Object {
-	return d.getEl(grep(d.doc, "head")).Obj()
+func (d *Document) Head() js.Value {
+	return d.nodeObj(grep(d.doc, "head"))
 }
 
-func (d *Document) Title() js.Value {
-	return vm.ToValue(grep(d.doc, "title").FirstChild.Data)
+// Title is the text of the <title> element with collapsed whitespace
+func (d *Document) Title() string {
+	t := grep(d.doc, "title")
+	if t == nil {
+		return ""
+	}
+	var s string
+	for c := t.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			s += c.Data
+		}
+	}
+	return strings.Join(strings.Fields(s), " ")
 }
 
 // Scripts just returns an empty list
@@ -539,13 +813,12 @@ func (d *Document) Scripts() *js.Object {
 	return d.GetElementsByTagName("script").Obj()
 }
 
-// StyleSheets just returns an empty list
-func (d *Document) StyleSheets() js.Value {
-	return vm.ToValue([]any{})
-}
-
-func (d *Document) ActiveElement() *js.Object {
-	return d.Body()
+func (d *Document) ActiveElement() js.Value {
+	el := d.activeElement()
+	if el == nil {
+		return js.Null()
+	}
+	return el.Obj()
 }
 
 func (d *Document) CreateDocumentFragment(opts ...any) *DocumentFragment {
@@ -561,14 +834,40 @@ func (d *Document) CreateElementNS(uri, qn string, opts ...any) *Element {
 	return CreateElementNS(d, uri, qn)
 }
 
-func (d *Document) CreateEvent(t string) *Event {
-	if t != "Event" {
-		log.Errorf("unsupported event type %v", t)
+func (d *Document) CreateAttribute(name string) js.Value {
+	if !isName(name) {
+		throwDOMException("InvalidCharacterError", "invalid attribute name "+name)
+	}
+	if d.isHTML() {
+		name = asciiLower(name)
+	}
+	return newOwnAttr(d, "", "", name, "").Obj()
+}
+
+func (d *Document) CreateAttributeNS(uri js.Value, qn string) js.Value {
+	var ns string
+	if !js.IsNull(uri) && !js.IsUndefined(uri) {
+		ns = uri.String()
+	}
+	validateQName(ns, qn)
+	prefix, local, ok := strings.Cut(qn, ":")
+	if !ok {
+		prefix, local = "", qn
+	}
+	return newOwnAttr(d, ns, prefix, local, "").Obj()
+}
+
+func (d *Document) CreateEvent(t string) js.Value {
+	iface, ok := createEventInterfaces[strings.ToLower(t)]
+	if !ok {
+		throwDOMException("NotSupportedError", "unsupported event interface "+t)
 	}
-	return &Event{}
+	e := newEvent(iface, "", nil).event()
+	e.TimeStamp = 0
+	return e.Obj()
 }
 
-func (d *Document) CreateTextNode(args ...string) *Element {
+func (d *Document) CreateTextNode(args ...string) *Text {
 	var data string
 	if len(args) > 0 {
 		data = args[0]
@@ -576,39 +875,48 @@ func (d *Document) CreateTextNode(args ...string) *Element {
 	n := &html.Node{}
 	n.Data = data
 	n.Type = html.TextNode
-	return d.getEl(n)
+	return d.getNode(n).(*Text)
 }
 
-func (d *Document) CreateComment(data string) *Element {
+func (d *Document) CreateComment(data string) *Comment {
 	n := &html.Node{}
 	n.Data = data
 	n.Type = html.CommentNode
-	return d.getEl(n)
+	return d.getNode(n).(*Comment)
+}
+
+func (d *Document) CreateCDATASection(data string) *CDATASection {
+	if d.isHTML() {
+		throwDOMException("NotSupportedError", "CDATA sections are not supported in HTML documents")
+	}
+	if strings.Contains(data, "]]>") {
+		throwDOMException("InvalidCharacterError", "data contains ']]>'")
+	}
+	return d.getNode(newCDATA(data)).(*CDATASection)
 }
 
-func (d *Document) CreateProcessingInstruction(target, data string) *Element {
+func (d *Document) CreateProcessingInstruction(target, data string) *ProcessingInstruction {
+	if strings.Contains(data, "?>") {
+		throwDOMException("InvalidCharacterError", "data contains '?>'")
+	}
 	n := &html.Node{}
-	n.Data = target
-	n.Type = html.ElementNode
+	n.Data = data
+	n.Type = html.RawNode
 	n.Attr = []html.Attribute{
 		html.Attribute{
-			Key: data,
+			Key: "target",
+			Val: target,
 		},
 	}
-	return d.getEl(n)
+	return d.getNode(n).(*ProcessingInstruction)
 }
 
-func (d *Document) CreateTreeWalker(opts ...any) *TreeWalker {
-	return NewTreeWalker()
-}
-
-func (d *Document) CloneNode(deep ...bool) *Element {
-	e := d.getEl(d.doc).CloneNode()
-	if e == nil {
-		return nil
-	} else {
-		return e
-	}
+func (d *Document) CloneNode(deep ...bool) *Document {
+	cl := NewDocument(nil)
+	cl.doc = d.cloneTree(d.doc, len(deep) > 0 && deep[0], cl)
+	cl.url = d.url
+	cl.contentType = d.contentType
+	return cl
 }
 
 func (d *Document) NodeType() int {
@@ -616,6 +924,9 @@ func (d *Document) NodeType() int {
 }
 
 func (d *Document) ToString() (s string) {
+	if !d.isHTML() {
+		return "[object XMLDocument]"
+	}
 	return "[object HTMLDocument]"
 }
 
@@ -624,7 +935,86 @@ func (d *Document) ParentNode() js.Value {
 }
 
 func (d *Document) ChildNodes() *js.Object {
-	return d.Children()
+	return d.getEl(d.doc).ChildNodes()
+}
+
+// The Node members of the document are those of the document node
+
+func (d *Document) FirstChild() js.Value {
+	return d.nodeObj(d.doc.FirstChild)
+}
+
+func (d *Document) LastChild() js.Value {
+	return d.nodeObj(d.doc.LastChild)
+}
+
+func (d *Document) PreviousSibling() js.Value {
+	return js.Null()
+}
+
+func (d *Document) NextSibling() js.Value {
+	return js.Null()
+}
+
+func (d *Document) ParentElement() js.Value {
+	return js.Null()
+}
+
+func (d *Document) OwnerDocument() js.Value {
+	return js.Null()
+}
+
+func (d *Document) NodeValue() js.Value {
+	return js.Null()
+}
+
+func (d *Document) TextContent() js.Value {
+	return js.Null()
+}
+
+func (d *Document) GetRootNode(opts ...any) js.Value {
+	return d.Obj()
+}
+
+func (d *Document) HasChildNodes() bool {
+	return d.doc.FirstChild != nil
+}
+
+func (d *Document) Normalize() {
+	d.normalize(d.doc)
+}
+
+func (d *Document) Contains(o any) bool {
+	if x, ok := o.(*Document); ok {
+		return x == d
+	}
+	return d.Element().Contains(o)
+}
+
+func (d *Document) IsSameNode(o any) bool {
+	x, ok := o.(*Document)
+	return ok && x == d
+}
+
+func (d *Document) IsEqualNode(o any) bool {
+	x, ok := o.(*Document)
+	return ok && equalNodes(d, d.doc, x, x.doc)
+}
+
+func (d *Document) AppendChild(c any) any {
+	return d.Element().AppendChild(c)
+}
+
+func (d *Document) InsertBefore(nu, old any) any {
+	return d.Element().InsertBefore(nu, old)
+}
+
+func (d *Document) RemoveChild(c any) Node {
+	return d.Element().RemoveChild(c)
+}
+
+func (d *Document) ReplaceChild(nu, ol any) Node {
+	return d.Element().ReplaceChild(nu, ol)
 }
 
 func (d *Document) Children() *js.Object {
@@ -635,12 +1025,18 @@ func (d *Document) GetElementById(id string) *Element {
 	return d.getEl(d.doc).getElementById(id)
 }
 
-func (d *Document) GetElementsByClassName(cl ...string) *HTMLCollection {
-	return d.getEl(d.doc).GetElementsByClassName(strings.Join(cl, " "))
+func (d *Document) GetElementsByClassName(cl any) *HTMLCollection {
+	// the argument is stringified so arrays are joined with commas
+	return d.getEl(d.doc).GetElementsByClassName(vm.ToValue(cl).String())
 }
 
-func (d *Document) GetElementsByName(nm string) (els []*Element) {
-	return d.getEl(d.doc).getElementsByName(nm)
+func (d *Document) GetElementsByName(nm string) *NodeList {
+	return &NodeList{
+		d: d,
+		f: func() []*html.Node {
+			return grepByName(d.doc, nm)
+		},
+	}
 }
 
 func (d *Document) GetElementsByTagName(tag string) *HTMLCollection {
@@ -656,7 +1052,7 @@ func (d *Document) QuerySelector(s string) *Element {
 	return d.getEl(d.doc).QuerySelector(s)
 }
 
-func (d *Document) QuerySelectorAll(s string) []*Element {
+func (d *Document) QuerySelectorAll(s string) *NodeList {
 	return d.getEl(d.doc).QuerySelectorAll(s)
 }
 
@@ -672,65 +1068,48 @@ func (d *Document) Write(s string) {
 		log.Printf("write: append: type=%v %v", c.Type, c.Data)
 		body.AppendChild(c)
 		addMutation(d, Insert, c)
+		// written scripts are parser-inserted and run in order
+		for _, s := range grepAll(c, "script", false) {
+			d.script(s).asyncCleared = true
+		}
+		d.prepareScripts(c)
 	}
 }
 
-func (d *Document) AttachEvent(e string, f *js.Object, opts ...any) {
+func (d *Document) AttachEvent(e string, f js.Value, opts ...any) {
 	d.AddEventListener(e, f, opts...)
 }
 
-func (d *Document) AddEventListener(e string, fn *js.Object, opts ...any) {
-	d.eventListeners[e] = append(d.eventListeners[e], fn)
+func (d *Document) listeners() listeners {
+	return d.eventListeners
 }
 
-func (d *Document) RemoveEventListener(e string, f func(js.FunctionCall) js.Value, opts ...any) {
-	for i, ff := range d.eventListeners[e] {
-		if fmt.Sprintf("%p", f) == fmt.Sprintf("%p", ff) {
-			if len(d.eventListeners[e]) > i+1 {
-				d.eventListeners[e] = append(d.eventListeners[e][:i], d.eventListeners[e][i+1:]...)
-			} else {
-				d.eventListeners[e] = d.eventListeners[e][:i]
-			}
-			break
-		}
-	}
+func (d *Document) AddEventListener(e string, fn js.Value, opts ...any) {
+	d.eventListeners.add(e, fn, opts...)
 }
 
-func (d *Document) DispatchEvent(e *Event) {
-	for _, x := range d.eventListeners[e.Type] {
-		var f func(js.FunctionCall) js.Value
-		var this = d.Obj()
-		switch v := x.Export().(type) {
-		case func(js.FunctionCall) js.Value:
-			f = v
-		case map[string]any:
-			var ok bool
-			f, ok = v["handleEvent"].(func(js.FunctionCall) js.Value)
-			if !ok {
-				log.Errorf("doc dispatch event: handleEvent is not a function")
-				continue
-			}
-			this = x
-		default:
-			log.Errorf("unexpected event handler %+v %T", x, x) // TODO Errorf
-		}
-		fn, ok := js.AssertFunction(vm.ToValue(f))
-		if !ok {
-			log.Errorf("doc assert function: %v", ok)
-			e.Consumed = true
-			continue
-		}
-		e.Consumed = true
-		_, err := fn(this, e.Obj())
-		if err != nil {
-			log.Errorf("doc event handler fn: %v", err)
-			e.Consumed = true
-			continue
-		}
+func (d *Document) RemoveEventListener(e string, fn js.Value, opts ...any) {
+	d.eventListeners.remove(e, fn, opts...)
+}
+
+// path is the event path from the document on. The window is the
+// parent of its document except for load events.
+func (d *Document) path(e *Event) []EventTarget {
+	if d.Window != nil && d.Window.Document == d && e.Type != "load" {
+		return []EventTarget{d, d.Window}
 	}
-	if e.Bubbles {
-		d.Window.dispatchEvent(e)
+	return []EventTarget{d}
+}
+
+// DispatchEvent dispatches the event to the document and returns false
+// if it was canceled
+func (d *Document) DispatchEvent(ei any) bool {
+	e := wrap(ei)
+	if e == nil {
+		panic(vm.NewTypeError(fmt.Sprintf("dispatchEvent: parameter 1 is not of type 'Event' (%T)", ei)))
 	}
+	e.dispatch(d.path(e))
+	return !e.DefaultPrevented
 }
 
 func (d *Document) Close() (err error) {
@@ -739,7 +1118,7 @@ func (d *Document) Close() (err error) {
 	d.DispatchEvent(&Event{Type: "DOMContentLoaded"})
 	d.vars["readyState"] = vm.ToValue("complete")
 	d.DispatchEvent(&Event{Type: "readystatechange"})
-	d.Window.DispatchEvent(&Event{Type: "load"})
+	d.Window.dispatchEvent(&Event{Type: "load"})
 	return
 }
 
@@ -762,6 +1141,9 @@ func (df *DocumentFragment) Obj() *js.Object {
 		return obj
 	}
 	obj = vm.NewDynamicObject(df)
+	if c, ok := nodeCtors["DocumentFragment"]; ok {
+		obj.SetPrototype(c.Get("prototype").(*js.Object))
+	}
 	dfObjRefs[df] = obj
 	return obj
 }
@@ -772,8 +1154,8 @@ func (df *DocumentFragment) CreateElement(tag string) *Element {
 
 func (df *DocumentFragment) GetElementById(id string) *Element {
 	for _, c := range df.children {
-		if el := df.d.getEl(c).getElementById(id); el != nil {
-			return el
+		if n := grepById(c, id); n != nil {
+			return df.d.getEl(n)
 		}
 	}
 	return nil
@@ -792,53 +1174,93 @@ func (df *DocumentFragment) GetElementsByTagName(tag string) *HTMLCollection {
 	return hc
 }
 
-func (df *DocumentFragment) QuerySelectorAll(s string) (els []*Element) {
+func (df *DocumentFragment) QuerySelectorAll(s string) *NodeList {
+	var ns []*html.Node
 	for _, c := range df.children {
-		els = append(els, df.d.getEl(c).QuerySelectorAll(s)...)
+		if c.Type == html.ElementNode {
+			ns = append(ns, df.d.getEl(c).querySelectorAll(s, true)...)
+		}
 	}
-	return
+	return staticNodeList(df.d, ns)
 }
 
-func (df *DocumentFragment) AppendChild(o interface{}) *Element {
-	el := o.(*Element)
-	el.df = df
-	df.children = append(df.children, el.n)
-	return df.d.getEl(el.n)
+func (df *DocumentFragment) AppendChild(o any) Node {
+	return df.InsertBefore(o, nil)
 }
 
-func (df *DocumentFragment) InsertBefore(nu, ol any) *Element {
-	nue := nu.(*Element)
-	ole, ok := ol.(*Element)
-	if ok {
-		for i, c := range df.children {
-			if c == ole.n {
-				var rest []*html.Node
-				if i+1 < len(df.children) {
-					rest = df.children[i+1:]
-				}
-				df.children = append(df.children[:i], nue.n)
-				df.children = append(df.children, rest...)
-				return nue
+func (df *DocumentFragment) InsertBefore(nu, ol any) Node {
+	if v, ok := nu.(*DocumentFragment); ok {
+		df.checkInsert(v.children)
+		for _, c := range append([]*html.Node{}, v.children...) {
+			df.InsertBefore(v.d.getNode(c), ol)
+		}
+		return nil
+	}
+	nue := asNode(nu)
+	if nue == nil {
+		panic(vm.NewTypeError(fmt.Sprintf("insertBefore: parameter 1 is not of type 'Node' (%T)", nu)))
+	}
+	nn := nue.base().n
+	df.checkInsert([]*html.Node{nn})
+	if nue.base().df != nil {
+		nue.base().df.RemoveChild(nue)
+	} else if nn.Parent != nil {
+		nue.base().d.removeChild(nn.Parent, nn)
+	}
+	df.d.adopt(nue)
+	i := len(df.children)
+	if ole := asNode(ol); ole != nil {
+		for j, c := range df.children {
+			if c == ole.base().n {
+				i = j
+				rangesInsert(ole, 1)
+				break
 			}
 		}
 	}
-	return df.AppendChild(nu)
+	df.children = append(df.children, nil)
+	copy(df.children[i+1:], df.children[i:])
+	df.children[i] = nn
+	nue.base().df = df
+	return nue
+}
+
+// checkInsert throws a HierarchyRequestError if ns contains a doctype
+func (df *DocumentFragment) checkInsert(ns []*html.Node) {
+	for _, n := range ns {
+		if n.Type == html.DoctypeNode {
+			throwDOMException("HierarchyRequestError", "a doctype can only be inserted into a document")
+		}
+	}
 }
 
 func (df *DocumentFragment) CloneNode(deep ...bool) *DocumentFragment {
-	cl := &DocumentFragment{
-		d:        df.d,
-		children: make([]*html.Node, 0, len(df.children)),
+	cl := NewDocumentFragment(df.d)
+	if len(deep) == 0 || !deep[0] {
+		return cl
 	}
 	for _, c := range df.children {
-		cel := df.d.getEl(c).CloneNode()
-		cl.children = append(cl.children, cel.n)
+		cc := df.d.cloneTree(c, true, df.d)
+		cl.children = append(cl.children, cc)
+		cl.d.getNode(cc).base().df = cl
 	}
 	return cl
 }
 
+var dfChildNodes = make(map[*DocumentFragment]*NodeList)
+
 func (df *DocumentFragment) ChildNodes() js.Value {
-	return df.Children()
+	nl, ok := dfChildNodes[df]
+	if !ok {
+		nl = &NodeList{
+			d: df.d,
+			f: func() []*html.Node {
+				return df.children
+			},
+		}
+		dfChildNodes[df] = nl
+	}
+	return nl.Obj()
 }
 
 var dfChildren = make(map[*DocumentFragment]*HTMLCollection)
@@ -857,46 +1279,102 @@ func (df *DocumentFragment) Children() js.Value {
 	return hc.Obj()
 }
 
-func (df *DocumentFragment) RemoveChild(c interface{}) *Element {
-	if c == nil {
+func (df *DocumentFragment) RemoveChild(c any) Node {
+	ce := asNode(c)
+	if ce == nil {
 		return nil
 	}
-	ce := c.(*Element)
 	for i, cc := range df.children {
-		if ce.n == cc {
-			df.children = append(df.children[:i], df.children[:i+1]...)
-			return df.d.getEl(ce.n)
+		if ce.base().n == cc {
+			df.d.preRemove(cc)
+			rangesRemove(ce)
+			df.children = append(df.children[:i], df.children[i+1:]...)
+			ce.base().df = nil
+			return ce
 		}
 	}
 	return nil
 }
 
-func (df *DocumentFragment) FirstChild() *js.Object {
+func (df *DocumentFragment) FirstChild() js.Value {
 	if len(df.children) == 0 {
-		return nil
+		return js.Null()
 	}
-	o := df.d.getEl(df.children[0]).Obj()
-	return o
+	return df.d.getNode(df.children[0]).Obj()
 }
 
-func (df *DocumentFragment) LastChild() *js.Object {
+func (df *DocumentFragment) LastChild() js.Value {
 	if len(df.children) == 0 {
-		return nil
+		return js.Null()
 	}
-	o := df.d.getEl(df.children[len(df.children)-1]).Obj()
-	return o
+	return df.d.getNode(df.children[len(df.children)-1]).Obj()
+}
+
+func (df *DocumentFragment) ParentNode() js.Value {
+	return js.Null()
+}
+
+func (df *DocumentFragment) ParentElement() js.Value {
+	return js.Null()
+}
+
+func (df *DocumentFragment) PreviousSibling() js.Value {
+	return js.Null()
+}
+
+func (df *DocumentFragment) NextSibling() js.Value {
+	return js.Null()
+}
+
+func (df *DocumentFragment) GetRootNode(opts ...any) js.Value {
+	return df.Obj()
+}
+
+func (df *DocumentFragment) HasChildNodes() bool {
+	return len(df.children) > 0
+}
+
+func (df *DocumentFragment) Contains(o any) bool {
+	return isInclusiveAncestor(df, treeNode(o))
+}
+
+func (df *DocumentFragment) IsSameNode(o any) bool {
+	x, ok := o.(*DocumentFragment)
+	return ok && x == df
+}
+
+func (df *DocumentFragment) IsEqualNode(o any) bool {
+	x, ok := o.(*DocumentFragment)
+	if !ok || len(x.children) != len(df.children) {
+		return false
+	}
+	for i, c := range df.children {
+		if !equalNodes(df.d, c, x.d, x.children[i]) {
+			return false
+		}
+	}
+	return true
 }
 
 func (df *DocumentFragment) Getters() map[string]bool {
 	return map[string]bool{
-		"ownerDocument": true,
-		"nodeName":      true,
-		"nodeType":      true,
-		"nodeValue":     true,
-		"childNodes":    true,
-		"children":      true,
-		"firstChild":    true,
-		"lastChild":     true,
+		"ownerDocument":     true,
+		"parentNode":        true,
+		"parentElement":     true,
+		"previousSibling":   true,
+		"nextSibling":       true,
+		"baseURI":           true,
+		"nodeName":          true,
+		"nodeType":          true,
+		"nodeValue":         true,
+		"childNodes":        true,
+		"children":          true,
+		"firstChild":        true,
+		"lastChild":         true,
+		"firstElementChild": true,
+		"lastElementChild":  true,
+		"childElementCount": true,
+		"textContent":       true,
 	}
 }
 
@@ -914,6 +1392,19 @@ func (df *DocumentFragment) Get(key string) js.Value {
 	return vm.ToValue(nil)
 }
 
+// TextContent concatenates the text of the descendants
+func (df *DocumentFragment) TextContent() string {
+	var b strings.Builder
+	for _, c := range df.children {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		} else if c.Type == html.ElementNode {
+			b.WriteString(df.d.getNode(c).base().text())
+		}
+	}
+	return b.String()
+}
+
 func (df *DocumentFragment) Set(key string, desc js.PropertyDescriptor) bool {
 	val := desc.Value
 	switch key {
@@ -960,6 +1451,10 @@ func (df *DocumentFragment) OwnerDocument() js.Value {
 	return df.d.Obj()
 }
 
+func (df *DocumentFragment) BaseURI() string {
+	return df.d.BaseURI()
+}
+
 func (df *DocumentFragment) NodeName() string {
 	return "#document-fragment"
 }
@@ -968,34 +1463,25 @@ func (df *DocumentFragment) NodeType() int {
 	return 11
 }
 
-func (df *DocumentFragment) NodeValue() any {
-	return nil
+func (df *DocumentFragment) ToString() string {
+	return "[object DocumentFragment]"
 }
 
-type Element struct {
-	d  *Document
-	df *DocumentFragment
-	n  *html.Node
+func (df *DocumentFragment) NodeValue() any {
+	return nil
 }
 
-func (d *Document) getEl(n *html.Node) (el *Element) {
-	if n == nil {
-		return nil
-	}
-	el, ok := d.elRefs[n]
-	if ok {
-		return
-	}
-	el = &Element{n: n}
-	if el.d == nil {
-		el.d = d
-	}
-	d.elRefs[n] = el
-	return
+type Element struct {
+	node
 }
 
 func CreateElement(d *Document, tagName string) *Element {
 	n := &html.Node{}
+	if d.isHTML() {
+		tagName = asciiLower(tagName)
+	} else if d.contentType == xhtmlMimeType {
+		n.Namespace = xhtmlNS
+	}
 	n.Data = tagName
 	n.Type = html.ElementNode
 	n.DataAtom = atom.Lookup([]byte(tagName))
@@ -1005,35 +1491,21 @@ func CreateElement(d *Document, tagName string) *Element {
 }
 
 func CreateElementNS(d *Document, uri, qn string) *Element {
+	validateQName(uri, qn)
 	n := &html.Node{}
 	n.Data = qn
 	n.Type = html.ElementNode
 	n.DataAtom = atom.Lookup([]byte(qn))
 	n.Namespace = uri
+	if uri == "" && d.isHTML() {
+		n.Namespace = nullNS
+	}
 	el := d.getEl(n)
 	el.d = d
 	return el
 }
 
-var elEventListener = make(map[*html.Node]map[string][]*js.Object)
-
-func (el *Element) Obj() (obj *js.Object) {
-	obj, ok := elObjRefs[el]
-	if ok {
-		return
-	}
-	obj = vm.NewDynamicObject(el)
-	elObjRefs[el] = obj
-	err := obj.SetPrototype(NodePrototype.(*js.Object))
-	if err != nil {
-		panic(err.Error())
-	}
-	/*err = obj.SetPrototype(HTMLElementPrototype.(*js.Object))
-	if err != nil {
-		panic(err.Error())
-	}*/
-	return
-}
+var elEventListener = make(map[*html.Node]listeners)
 
 func (el *Element) ClassName() string {
 	return attr(*el.n, "class")
@@ -1050,57 +1522,42 @@ func (el *Element) TagName() string {
 	if el.n.Type == html.DocumentNode {
 		return "HTML"
 	}
-	return strings.ToUpper(el.n.Data)
+	if el.d.isHTML() && el.d.namespace(el.n) == xhtmlNS {
+		return strings.ToUpper(el.n.Data)
+	}
+	return el.n.Data
 }
 
 func (el *Element) NodeName() string {
-	switch el.n.Type {
-	case html.CommentNode:
-		return "#comment"
-	case html.TextNode:
-		return "#text"
-	case html.DocumentNode:
-		return "HTML"
-	}
-	return strings.ToUpper(el.n.Data)
+	return el.TagName()
 }
 
-func (el *Element) NodeValue() js.Value {
-	if el.n.Type == html.CommentNode {
-		return vm.ToValue(el.n.Data)
-	} else if el.n.Type == html.TextNode {
-		return vm.ToValue(el.n.Data)
-	} else {
-		return vm.ToValue(nil)
+func (el *Element) LocalName() string {
+	if _, l, ok := strings.Cut(el.n.Data, ":"); ok {
+		return l
 	}
+	return el.n.Data
 }
 
-func (el *Element) NodeType() (i int) {
-	switch el.n.Type {
-	case html.ElementNode:
-		i = 1
-	case html.TextNode:
-		i = 3
-	case html.DocumentNode:
-		i = 9
-	case html.CommentNode:
-		i = 8
+func (el *Element) Prefix() js.Value {
+	if p, _, ok := strings.Cut(el.n.Data, ":"); ok {
+		return vm.ToValue(p)
 	}
-	return
+	return js.Null()
 }
 
-func (el *Element) LocalName() string {
-	return el.n.Data
+func (el *Element) NamespaceURI() js.Value {
+	if ns := el.d.namespace(el.n); ns != "" {
+		return vm.ToValue(ns)
+	}
+	return js.Null()
 }
 
 func (el *Element) Content() js.Value {
 	if el.n.Data == "template" {
 		df := el.d.CreateDocumentFragment()
 		for n := el.n.FirstChild; n != nil; n = n.NextSibling {
-			if n.Type != html.ElementNode {
-				continue
-			}
-			df.AppendChild(el.d.getEl(n).CloneNode(true))
+			df.AppendChild(el.d.getNode(el.d.cloneTree(n, true, el.d)))
 		}
 		return df.Obj()
 	} else {
@@ -1109,24 +1566,8 @@ func (el *Element) Content() js.Value {
 	return vm.ToValue(el.text())
 }
 
-func (el *Element) TextContent() string {
-	return el.text()
-}
-
-func (el *Element) ToString() (s string) {
-	s += "[object HTML"
-	if el.n.Data != "html" {
-		s += strings.Title(el.n.Data)
-	}
-	s += "Element]"
-	return
-}
-
 func (el *Element) Attributes() js.Value {
-	if el.n.Type == html.CommentNode || el.n.Type == html.TextNode {
-		return nil
-	}
-	nm := &NamedNodeMap{n: el.n}
+	nm := &NamedNodeMap{d: el.d, n: el.n}
 	return nm.Obj()
 }
 
@@ -1134,192 +1575,53 @@ func (el *Element) RemoveAttribute(a string) {
 	rmAttr(el.n, a)
 }
 
-func (el *Element) ContentWindow() js.Value {
-	res, err := vm.RunString("this")
-	if err != nil {
-		log.Fatalf("getting this: %v", err)
-	}
-	return res
+func (el *Element) GetAttributeNode(k string) js.Value {
+	nm := &NamedNodeMap{d: el.d, n: el.n}
+	return nm.GetNamedItem(k)
 }
 
-func (el *Element) Window() *Window {
-	return el.d.Window
+func (el *Element) GetAttributeNodeNS(ns js.Value, k string) js.Value {
+	nm := &NamedNodeMap{d: el.d, n: el.n}
+	return nm.GetNamedItemNS(ns, k)
 }
 
-func (el *Element) OwnerDocument() js.Value {
-	return el.d.Obj()
+func (el *Element) SetAttributeNode(a js.Value) js.Value {
+	nm := &NamedNodeMap{d: el.d, n: el.n}
+	return nm.SetNamedItem(a)
 }
 
-func (el *Element) AddEventListener(e string, fn *js.Object, opts ...any) {
-	id := el.n.Data
-	if a := attr(*el.n, "id"); a != "" {
-		id += " id=" + a
-	}
-	if _, ok := elEventListener[el.n]; !ok {
-		elEventListener[el.n] = make(map[string][]*js.Object)
-	}
-	elEventListener[el.n][e] = append(elEventListener[el.n][e], fn)
+func (el *Element) SetAttributeNodeNS(a js.Value) js.Value {
+	return el.SetAttributeNode(a)
 }
 
-func (el *Element) RemoveEventListener(e string, f func(js.FunctionCall) js.Value, opts ...any) {
-	_, ok := elEventListener[el.n]
-	if !ok {
-		log.Errorf("nothing to remove")
-		return
-	}
-
-	for i, ff := range elEventListener[el.n][e] {
-		if fmt.Sprintf("%p", f) == fmt.Sprintf("%p", ff) {
-			elEventListener[el.n][e] = append(elEventListener[el.n][e][:i], elEventListener[el.n][e][i+1:]...)
-			break
+// RemoveAttributeNode removes the attribute a of el and returns it
+func (el *Element) RemoveAttributeNode(v js.Value) js.Value {
+	a := asAttr(v)
+	nm := &NamedNodeMap{d: el.d, n: el.n}
+	if a.owner == el.n {
+		if i := nm.nsAttr(vm.ToValue(a.ns), a.key); i >= 0 {
+			return nm.detach(i)
 		}
 	}
+	throwDOMException("NotFoundError", "the attribute is not an attribute of this element")
+	return nil
 }
 
-func (el *Element) buttonClick(ignDisabled bool) (consumed bool) {
-	var p *html.Node
-	for p = el.n.Parent; p != nil && p.Data != "form"; p = p.Parent {
-	}
-	if p != nil {
-		form := el.d.getEl(p)
-		if evs, ok := elVars[form]; ok {
-			f, ok := evs["onsubmit"]
-			if ok {
-				consumed = true
-				fn, ok := js.AssertFunction(vm.ToValue(f))
-				if !ok {
-					log.Errorf("el assert function: %v", ok)
-				} else {
-					_, err := fn(el.Obj())
-					if err != nil {
-						log.Errorf("el event handler fn: %v", err)
-					}
-				}
-			}
-		}
+// ContentWindow returns the global object of the iframe or null if it
+// has no browsing context
+func (el *Element) ContentWindow() js.Value {
+	if el.n.Data != "iframe" {
+		return js.Undefined()
 	}
-	return consumed
-}
-
-func (el *Element) inputClick(ignDisabled bool) {
-	if attr(*el.n, "type") == "checkbox" {
-		if !hasAttr(*el.n, "disabled") || ignDisabled {
-			if hasAttr(*el.n, "checked") {
-				rmAttr(el.n, "checked")
-			} else {
-				setAttr(el.n, "checked", "true")
-			}
-		}
-	} else if attr(*el.n, "type") == "radio" {
-		setAttr(el.n, "checked", "true")
+	f := el.d.frame(el.n)
+	if f == nil {
+		return js.Null()
 	}
+	return f.global
 }
 
-// TODO: https://datastation.multiprocess.io/blog/2022-04-26-event-handler-attributes.html
-func (el *Element) attachEvent(e string, fn *js.Object) {
-	el.AddEventListener(e, fn)
-}
-
-func (el *Element) DispatchEvent(ei any) bool {
-	var e *Event
-	switch v := ei.(type) {
-	case *Event:
-		e = v
-	case *MouseEvent:
-		e = &v.Event
-	default:
-		log.Fatalf("unknown type %T", ei)
-	}
-	if e.Target == nil {
-		e.Target = el
-	}
-	e.CurrentTarget = el
-	e.SrcElement = el
-	if e.CancelBubble {
-		e.CancelBubble = false
-		return true
-	}
-	if e.propagationStopped {
-		e.propagationStopped = false
-		return true
-	}
-	if e.Type == "click" {
-		if el.n.Data == "button" {
-			if c := el.buttonClick(true); c {
-				e.Consumed = true
-			}
-		}
-		if el.n.Data == "input" && !e.DefaultPrevented {
-			if _, ok := ei.(*MouseEvent); ok {
-				el.inputClick(true)
-			} else {
-				log.Errorf("dispatch event called with wrong Event Class (type %v)", e.Type)
-				return false
-			}
-		}
-		if onclick := attr(*el.n, "onclick"); onclick != "" {
-			_, err := vm.RunString(onclick)
-			if err != nil {
-				log.Errorf("onclick '%v': %v", onclick, err)
-			}
-			e.Consumed = true
-		}
-	}
-	if evs, ok := elVars[el]; ok {
-		f, ok := evs["on"+e.Type]
-		if ok {
-			fn, ok := js.AssertFunction(vm.ToValue(f))
-			if !ok {
-				log.Errorf("el assert function: %v", ok)
-			} else {
-				_, err := fn(el.Obj())
-				if err != nil {
-					log.Errorf("el event handler fn: %v", err)
-				}
-			}
-		}
-	}
-	hs, ok := elEventListener[el.n]
-	if ok {
-		for _, x := range hs[e.Type] {
-			var f func(js.FunctionCall) js.Value
-			var this = el.Obj()
-			switch v := x.Export().(type) {
-			case func(js.FunctionCall) js.Value:
-				f = v
-			case map[string]any:
-				f, ok = v["handleEvent"].(func(js.FunctionCall) js.Value)
-				if !ok {
-					log.Errorf("el dispatch event: handleEvent is not a function")
-					continue
-				}
-				this = x
-			default:
-				log.Errorf("unexpected event handler %+v %T", x, x) // TODO Errorf
-			}
-			fn, ok := js.AssertFunction(vm.ToValue(f))
-			if !ok {
-				log.Errorf("el assert function: %v", ok)
-				e.Consumed = true
-				continue
-			}
-			e.Consumed = true
-			_, err := fn(this, e.Obj())
-			if err != nil {
-				log.Errorf("el event handler fn: %v", err)
-				e.Consumed = true
-				continue
-			}
-		}
-	}
-	if e.Bubbles {
-		if p := el.n.Parent; p != nil {
-			el.d.getEl(p).DispatchEvent(e)
-		} else if el.df == nil {
-			el.d.DispatchEvent(e)
-		}
-	}
-	return e.Consumed
+func (el *Element) Window() *Window {
+	return el.d.Window
 }
 
 func (el *Element) Click(xs ...interface{}) js.Value {
@@ -1328,21 +1630,25 @@ func (el *Element) Click(xs ...interface{}) js.Value {
 }
 
 func (el *Element) Clic() (consumed bool) {
-	e := &Event{
-		Type:    "click",
-		Target:  el,
-		Bubbles: true,
+	switch el.n.Data {
+	case "button", "input", "select", "textarea", "optgroup", "option", "fieldset":
+		if hasAttr(*el.n, "disabled") {
+			return
+		}
 	}
-	return el.DispatchEvent(e)
+	e := newEvent("MouseEvent", "click", map[string]any{
+		"bubbles":    true,
+		"cancelable": true,
+		"composed":   true,
+	})
+	e.event().Target = el
+	return el.dispatch(e)
 	/*if hasAttr(*el.n, "disabled") {
 		return
 	}
 	if el.n.Data == "button" {
 		el.buttonClick(false)
 	}
-	if el.n.Data == "input" {
-		el.inputClick(false)
-	}
 	if onclick := attr(*el.n, "onclick"); onclick == "" {
 		// noop
 	} else {
@@ -1378,44 +1684,79 @@ func (el *Element) BubbledClick() {
 
 func (el *Element) Getters() map[string]bool {
 	return map[string]bool{
-		"className":       true,
-		"name":            true,
-		"style":           true,
-		"tagName":         true,
-		"nodeValue":       true,
-		"nodeType":        true,
-		"nodeName":        true,
-		"localName":       true,
-		"attributes":      true,
-		"id":              true,
-		"type":            true,
-		"value":           true,
-		"selected":        true,
-		"checked":         true,
-		"content":         true,
-		"textContent":     true,
-		"innerHTML":       true,
-		"outerHTML":       true,
-		"contentWindow":   true,
-		"window":          true,
-		"ownerDocument":   true,
-		"parentNode":      true,
-		"parentElement":   true,
-		"firstChild":      true,
-		"previousSibling": true,
-		"nextSibling":     true,
-		"lastChild":       true,
-		"childNodes":      true,
-		"children":        true,
-		"hash":            true,
-		"href":            true,
-		"src":             true,
-		"hostname":        true,
-		"pathname":        true,
-		"data":            true,
-		"length":          true,
-		"offsetHeight":    true,
-		"offsetWidth":     true,
+		"className":              true,
+		"classList":              true,
+		"dataset":                true,
+		"name":                   true,
+		"style":                  true,
+		"tagName":                true,
+		"nodeValue":              true,
+		"nodeType":               true,
+		"nodeName":               true,
+		"localName":              true,
+		"prefix":                 true,
+		"namespaceURI":           true,
+		"attributes":             true,
+		"id":                     true,
+		"type":                   true,
+		"value":                  true,
+		"selected":               true,
+		"checked":                true,
+		"defaultValue":           true,
+		"defaultChecked":         true,
+		"defaultSelected":        true,
+		"selectedIndex":          true,
+		"options":                true,
+		"selectedOptions":        true,
+		"index":                  true,
+		"text":                   true,
+		"form":                   true,
+		"elements":               true,
+		"validity":               true,
+		"willValidate":           true,
+		"validationMessage":      true,
+		"content":                true,
+		"textContent":            true,
+		"innerHTML":              true,
+		"outerHTML":              true,
+		"contentWindow":          true,
+		"window":                 true,
+		"ownerDocument":          true,
+		"baseURI":                true,
+		"parentNode":             true,
+		"parentElement":          true,
+		"firstChild":             true,
+		"previousSibling":        true,
+		"nextSibling":            true,
+		"lastChild":              true,
+		"childNodes":             true,
+		"children":               true,
+		"firstElementChild":      true,
+		"lastElementChild":       true,
+		"childElementCount":      true,
+		"nextElementSibling":     true,
+		"previousElementSibling": true,
+		"innerText":              true,
+		"outerText":              true,
+		"hash":                   true,
+		"hostname":               true,
+		"pathname":               true,
+		"length":                 true,
+		"offsetHeight":           true,
+		"offsetWidth":            true,
+		"offsetTop":              true,
+		"offsetLeft":             true,
+		"offsetParent":           true,
+		"clientTop":              true,
+		"clientLeft":             true,
+		"clientWidth":            true,
+		"clientHeight":           true,
+		"scrollWidth":            true,
+		"scrollTop":              true,
+		"scrollLeft":             true,
+		"scrollHeight":           true,
+		"tabIndex":               true,
+		"sheet":                  true,
 	}
 }
 
@@ -1423,38 +1764,6 @@ func (el *Element) Props() map[string]bool {
 	return map[string]bool{}
 }
 
-func (el *Element) Get(key string) js.Value {
-	if el == nil {
-		log.Errorf("nil element")
-		return js.Undefined()
-	}
-	if el.n == nil {
-		log.Errorf("element with nil node")
-		return js.Undefined()
-	}
-	if vs, ok := elVars[el]; ok {
-		if v, ok := vs[key]; ok {
-			return v
-		}
-	}
-	if key == "addEventListener" || key == "attachEvent" {
-		// dispatch here because 2nd parameter can be a function or an object
-		c := &Call{
-			recv:  "*dom.Element",
-			k:     key,
-			found: true,
-		}
-		calls = append(calls, c)
-		return vm.ToValue(func(e string, fn *js.Object, opts ...any) {
-			el.AddEventListener(e, fn, opts)
-		})
-	}
-	if res, ok := GetCall(el, key); ok {
-		return res
-	}
-	return js.Undefined()
-}
-
 func (el *Element) GetAttribute(k string, opts ...any) interface{} {
 	if !hasAttr(*el.n, k) {
 		return nil
@@ -1468,6 +1777,9 @@ func (el *Element) HasAttribute(k string) bool {
 
 func (el *Element) SetAttribute(k, v string) {
 	setAttr(el.n, k, v)
+	if k == "src" && el.n.Data == "script" {
+		el.d.prepareScript(el.n)
+	}
 }
 
 func (el *Element) Id() string {
@@ -1475,47 +1787,46 @@ func (el *Element) Id() string {
 }
 
 func (el *Element) Type() string {
+	switch el.n.Data {
+	case "input":
+		return inputType(el.n)
+	case "select":
+		if hasAttr(*el.n, "multiple") {
+			return "select-multiple"
+		}
+		return "select-one"
+	case "textarea":
+		return "textarea"
+	case "button":
+		switch t := strings.ToLower(attr(*el.n, "type")); t {
+		case "reset", "button":
+			return t
+		}
+		return "submit"
+	}
 	return attr(*el.n, "type")
 }
 
 // Options of a <select> element
-func (el *Element) Options() *HTMLCollection {
-	return &HTMLCollection{
-		d: el.d,
-		f: func() []*html.Node {
-			return grepAll(el.n, "option", false)
-		},
+func (el *Element) Options() js.Value {
+	if el.n.Data != "select" {
+		return js.Undefined()
 	}
+	return el.collection("options", func() []*html.Node {
+		return selectOptions(el.n)
+	}).Obj()
 }
 
 func (el *Element) Value() string {
-	if el.n.Data == "select" {
-		opts := grepAll(el.n, "option", false)
-		var sel *html.Node
-		for _, opt := range opts {
-			if sel == nil || hasAttr(*sel, "selected") {
-				sel = opt
-			}
-		}
-		if sel == nil {
-			return ""
-		}
-		v := sel.Data
-		if hasAttr(*sel, "value") {
-			return attr(*sel, "value")
-		}
-		return v
-	}
-	v := attr(*el.n, "value")
-	return v
+	return el.d.controlValue(el.n)
 }
 
-func (el *Element) Selected() string {
-	return attr(*el.n, "selected")
+func (el *Element) Selected() bool {
+	return el.d.optionSelected(el.n)
 }
 
 func (el *Element) Checked() bool {
-	return hasAttr(*el.n, "checked")
+	return el.d.checkedness(el.n)
 }
 
 func (el *Element) Hash() js.Value {
@@ -1531,22 +1842,6 @@ func (el *Element) Hash() js.Value {
 	return vm.ToValue(nil)
 }
 
-func (el *Element) Href() js.Value {
-	href := attr(*el.n, "href")
-	if strings.HasPrefix(href, "#") {
-		return vm.ToValue("https://example.com" + href)
-	}
-	return vm.ToValue(href)
-}
-
-func (el *Element) Src() js.Value {
-	src := attr(*el.n, "src")
-	if strings.HasPrefix(src, "#") {
-		return vm.ToValue("https://example.com" + src)
-	}
-	return vm.ToValue(src)
-}
-
 func (el *Element) Hostname() js.Value {
 	h := attr(*el.n, "href")
 	if h != "" {
@@ -1571,6 +1866,16 @@ func (el *Element) Pathname() js.Value {
 	return vm.ToValue(p)
 }
 
+func (el *Element) Get(key string) js.Value {
+	if key == "async" && el.n.Data == "script" {
+		return vm.ToValue(el.scriptAsync())
+	}
+	if r, ok := el.reflected(key); ok {
+		return r.get(el)
+	}
+	return el.node.Get(key)
+}
+
 func (el *Element) Set(key string, desc js.PropertyDescriptor) bool {
 	val := desc.Value
 	c := &Call{
@@ -1579,69 +1884,80 @@ func (el *Element) Set(key string, desc js.PropertyDescriptor) bool {
 		found: true,
 	}
 	calls = append(calls, c)
+	if el.n.Data == "script" && el.n.Type == html.ElementNode {
+		defer el.scriptSet(key)
+	}
+	if r, ok := el.reflected(key); ok {
+		r.set(el, val)
+		return true
+	}
 	switch key {
-	case "nodeValue":
-		switch el.n.Type {
-		case html.ElementNode:
-			// no effect
-		case html.CommentNode, html.TextNode:
-			el.n.Data = val.String()
-		}
 	case "className":
 		setAttr(el.n, "class", val.String())
-	case "href", "id", "type", "value", "selected", "src":
+	case "classList":
+		// [PutForwards=value]
+		setAttr(el.n, "class", val.String())
+	case "style":
+		// [PutForwards=cssText]
+		(&Style{n: el.n}).setText(val.String())
+	case "scrollTop":
+		x, _ := el.scrollOffset()
+		el.scrollTo(x, val.ToFloat())
+	case "scrollLeft":
+		_, y := el.scrollOffset()
+		el.scrollTo(val.ToFloat(), y)
+	case "type":
 		setAttr(el.n, key, val.String())
-	case "textContent":
-		el.setText(val.String())
+	case "value":
+		v := val.String()
+		if js.IsNull(val) && (el.n.Data == "input" || el.n.Data == "textarea") {
+			v = ""
+		}
+		el.d.setControlValue(el.n, v)
+		addMutation(el.d, Value, el.n)
+	case "checked":
+		el.d.setChecked(el.n, val.ToBoolean())
+		addMutation(el.d, Value, el.n)
+	case "selected":
+		el.d.setSelected(el.n, val.ToBoolean())
+		addMutation(el.d, Value, el.n)
+	case "selectedIndex":
+		el.d.selectIndex(el.n, int(val.ToInteger()))
+		addMutation(el.d, Value, el.n)
+	case "defaultValue":
+		if el.n.Data == "textarea" {
+			el.setText(val.String())
+		} else {
+			setAttr(el.n, "value", val.String())
+		}
+	case "defaultChecked", "defaultSelected":
+		a := strings.TrimPrefix(strings.ToLower(key), "default")
+		if val.ToBoolean() {
+			setAttr(el.n, a, "")
+		} else {
+			rmAttr(el.n, a)
+		}
 	case "innerHTML":
 		el.setInnerHTML(val.String())
 	case "outerHTML":
 		el.setOuterHTML(val.String())
-	case "disabled":
-		if val.ToBoolean() {
-			setAttr(el.n, "disabled", "true")
-		} else {
-			rmAttr(el.n, "disabled")
-		}
+	case "innerText":
+		el.setInnerText(val.String())
+	case "outerText":
+		el.setOuterText(val.String())
+	case "text":
+		el.setText(val.String())
+	case "tabIndex":
+		setAttr(el.n, "tabindex", strconv.Itoa(int(val.ToInteger())))
 	default:
-		if _, ok := elVars[el]; !ok {
-			elVars[el] = make(map[string]js.Value)
-		}
-		elVars[el][key] = val
+		return el.node.Set(key, desc)
 	}
 	return true
 }
 
-func (el *Element) Has(key string) bool {
-	if vs, ok := elVars[el]; ok {
-		if _, ok := vs[key]; ok {
-			return true
-		}
-	}
-	return HasCall(el, key)
-}
-
-func (el *Element) Delete(key string) bool {
-	if vs, ok := elVars[el]; ok {
-		if _, ok := vs[key]; ok {
-			delete(vs, key)
-			return true
-		}
-	}
-	return false
-}
-
-func (el *Element) Keys() []string {
-	ks := Calls(el)
-	for k := range elVars[el] {
-		ks = append(ks, k)
-	}
-	return ks
-}
-
 func (el *Element) setInnerHTML(h string) {
 	for el.n.FirstChild != nil {
-		el.n.RemoveChild(el.n.FirstChild)
+		el.d.removeChild(el.n, el.n.FirstChild)
 	}
 	ns := el.n.Namespace
 	if ns != "" {
@@ -1663,6 +1979,7 @@ func (el *Element) setInnerHTML(h string) {
 		return
 	}
 	for _, c := range f {
+		el.d.markStarted(c)
 		el.n.AppendChild(c)
 	}
 	i := 0
@@ -1678,46 +1995,24 @@ func (el *Element) setOuterHTML(h string) {
 		log.Errorf("set outer html: %v", err)
 		return
 	}
+	for _, c := range f {
+		el.d.markStarted(c)
+	}
 	if len(f) != 1 {
 		panic("...")
 	}
-	el.d.getEl(el.n.Parent).ReplaceChild(el.d.getEl(f[0]), el)
+	el.d.getNode(el.n.Parent).base().ReplaceChild(el.d.getNode(f[0]), el)
 	addMutation(el.d, Value, el.n)
 }
 
-func (el *Element) text() string {
-	var f func(*html.Node, int) string
-	f = func(n *html.Node, r int) (t string) {
-		if r > 30 {
-			log.Errorf("element text: recursion limit exceeded")
-			return
-		}
-		if n.Type == html.TextNode || n.Type == html.ElementNode {
-			t += n.Data
-		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			t += f(c, r+1)
-		}
-		return
-	}
-	return f(el.n, 0)
-}
-
-func (el *Element) setText(t string) {
-	for el.n.FirstChild != nil {
-		el.n.RemoveChild(el.n.FirstChild)
-	}
-	if t != "" {
-		tn := &html.Node{
-			Type: html.TextNode,
-			Data: t,
+func (el *Element) InnerHTML() string {
+	if !el.d.isHTML() {
+		var b strings.Builder
+		for c := el.n.FirstChild; c != nil; c = c.NextSibling {
+			el.d.serializeXML(&b, c, el.d.namespace(el.n), nil)
 		}
-		el.n.AppendChild(tn)
+		return b.String()
 	}
-	addMutation(el.d, Value, el.n)
-}
-
-func (el *Element) InnerHTML() string {
 	buf := bytes.NewBufferString("")
 	for c := el.n.FirstChild; c != nil; c = c.NextSibling {
 		if err := html.Render(buf, c); err != nil {
@@ -1729,6 +2024,11 @@ func (el *Element) InnerHTML() string {
 }
 
 func (el *Element) OuterHTML() string {
+	if !el.d.isHTML() {
+		var b strings.Builder
+		el.d.serializeXML(&b, el.n, "", nil)
+		return b.String()
+	}
 	buf := bytes.NewBufferString("")
 	if err := html.Render(buf, el.n); err != nil {
 		log.Errorf("render: %v", err)
@@ -1744,7 +2044,6 @@ func (el *Element) getElementById(id string) (e *Element) {
 
 func (el *Element) GetElementsByClassName(class string) (hc *HTMLCollection) {
 	f := func() []*html.Node {
-		class = strings.TrimSpace(class)
 		return grepByClass(el.n, class, true)
 	}
 	hc = &HTMLCollection{d: el.d, f: f}
@@ -1759,27 +2058,6 @@ func (el *Element) GetElementsByTagName(tag string) (hc *HTMLCollection) {
 	return
 }
 
-func (el *Element) getElementsByName(name string) (els []*Element) {
-	ns := grepByName(el.n, name)
-	els = make([]*Element, 0, len(ns))
-	for _, n := range ns {
-		els = append(els, el.d.getEl(n))
-	}
-	return
-}
-
-func (el *Element) Contains(o *Element) bool {
-	if el == o {
-		return false
-	}
-	for ch := el.n.FirstChild; ch != nil; ch = ch.NextSibling {
-		if chEl := el.d.getEl(ch); chEl == o || chEl.Contains(o) {
-			return true
-		}
-	}
-	return false
-}
-
 func (el *Element) Matches(s string) bool {
 	res, err := sel.Select(s, el.n, false, true)
 	if err != nil {
@@ -1790,410 +2068,65 @@ func (el *Element) Matches(s string) bool {
 }
 
 func (el *Element) QuerySelector(s string) *Element {
-	es := el.QuerySelectorAll(s)
-	if len(es) == 0 {
-		return nil
-	}
-	return es[0]
-}
-
-func (el *Element) QuerySelectorAll(s string) (els []*Element) {
-	res, err := sel.Select(s, el.n, true, false)
-	if err != nil {
-		log.Errorf("select %s: %v", s, err)
-		return
-	}
-	els = make([]*Element, 0, len(res))
-	for _, n := range res {
-		els = append(els, el.d.getEl(n))
-	}
-	return
-}
-
-func (el *Element) GetRootNode(opts ...any) js.Value {
-	if el.df != nil {
-		return el.df.Obj()
-	}
-	if el.n.Parent == nil {
-		return js.Undefined()
-	}
-	r := el.n
-	for {
-		if r.Parent == nil {
-			break
-		}
-		r = r.Parent
-	}
-	return el.d.getEl(r).Obj()
-}
-
-func (el *Element) ParentNode() js.Value {
-	if el.df != nil {
-		return el.df.Obj()
-	}
-	if el.n.Type == html.DocumentNode {
-		return el.d.Obj()
-	}
-	e := el.ParentElement()
-	if e == nil {
-		return js.Null()
-	}
-	return e
-}
-
-func (el *Element) ParentElement() *js.Object {
-	if el.n.Parent == nil {
+	ns := el.querySelectorAll(s, false)
+	if len(ns) == 0 {
 		return nil
 	}
-	o := el.d.getEl(el.n.Parent).Obj()
-	return o
+	return el.d.getEl(ns[0])
 }
 
-func (el *Element) FirstChild() *js.Object {
-	if el.n.FirstChild == nil {
-		return nil
-	}
-	o := el.d.getEl(el.n.FirstChild).Obj()
-	return o
+func (el *Element) QuerySelectorAll(s string) *NodeList {
+	return staticNodeList(el.d, el.querySelectorAll(s, false))
 }
 
-func (el *Element) PreviousSibling() *js.Object {
-	if el.n.PrevSibling == nil {
+func (el *Element) querySelectorAll(s string, withRoot bool) []*html.Node {
+	res, err := sel.Select(s, el.n, !withRoot, false)
+	if err != nil {
+		log.Errorf("select %s: %v", s, err)
 		return nil
 	}
-	return el.d.getEl(el.n.PrevSibling).Obj()
+	return res
 }
 
-func (el *Element) NextSibling() *js.Object {
-	if el.n.NextSibling == nil {
-		return nil
-	}
-	return el.d.getEl(el.n.NextSibling).Obj()
-}
+// elCollections caches the live collections of elements by name
+var elCollections = make(map[*Element]map[string]*HTMLCollection)
 
-func (el *Element) LastChild() *js.Object {
-	if el.n.LastChild == nil {
-		return nil
+// collection returns the collection k of el with the nodes of f
+func (el *Element) collection(k string, f func() []*html.Node) *HTMLCollection {
+	hcs, ok := elCollections[el]
+	if !ok {
+		hcs = make(map[string]*HTMLCollection)
+		elCollections[el] = hcs
 	}
-	o := el.d.getEl(el.n.LastChild).Obj()
-	return o
-}
-
-func (el *Element) HasChildNodes() bool {
-	return el.n.FirstChild != nil
-}
-
-func (el *Element) ChildNodes() *js.Object {
-	return el.Children()
-}
-
-var elChildren = make(map[*Element]*HTMLCollection)
-
-func (el *Element) Children() *js.Object {
-	hc, ok := elChildren[el]
+	hc, ok := hcs[k]
 	if !ok {
 		hc = &HTMLCollection{
 			d: el.d,
-			f: func() []*html.Node {
-				nodes := make([]*html.Node, 0, 2)
-				for c := el.n.FirstChild; c != nil; c = c.NextSibling {
-					nodes = append(nodes, c)
-				}
-				return nodes
-			},
-		}
-		elChildren[el] = hc
-	}
-	return hc.Obj()
-}
-
-func (el *Element) Normalize() {
-	normalize(el.n)
-}
-
-func (el *Element) SplitText(i int) *Element {
-	n := &html.Node{}
-	n.Data = el.n.Data[i:]
-	n.Type = html.TextNode
-	el.n.Parent.InsertBefore(n, el.n.NextSibling)
-	el.n.Data = el.n.Data[:i]
-	addMutation(el.d, Value, el.n.Parent)
-	return el.d.getEl(n)
-}
-
-func (el *Element) Data() string {
-	return el.n.Data
-}
-
-func (el *Element) SubstringData(i, n int) string {
-	if i+n > len(el.n.Data) {
-		n = len(el.n.Data) - i
-	}
-	return el.n.Data[i : i+n]
-}
-
-func (el *Element) AppendData(s string) {
-	el.n.Data += s
-	addMutation(el.d, Value, el.n)
-}
-
-func (el *Element) DeleteData(i, n int) {
-	if i >= len(el.n.Data) {
-		i = len(el.n.Data) - 1
-	}
-	if i < 0 {
-		i = 0
-	}
-	if i+n > len(el.n.Data) {
-		n = len(el.n.Data) - i
-	}
-	if n < 0 {
-		n = 0
-	}
-	el.n.Data = el.n.Data[:i] + el.n.Data[i+n:]
-	addMutation(el.d, Value, el.n)
-}
-
-func (el *Element) InsertData(i int, s string) {
-	el.n.Data = el.n.Data[:i] + s + el.n.Data[i:]
-	addMutation(el.d, Value, el.n)
-}
-
-func (el *Element) ReplaceData(i, n int, s string) {
-	rem := i + n
-	if rem < 0 {
-		rem = 0
-	}
-	if rem > len(el.n.Data) {
-		rem = len(el.n.Data)
-	}
-	if n > len(s) {
-		n = len(s)
-	}
-	el.n.Data = el.n.Data[:i] + s[:n] + el.n.Data[rem:]
-	addMutation(el.d, Value, el.n)
-}
-
-func (el *Element) Length() int {
-	return len(el.n.Data)
-}
-
-func (el *Element) Remove() js.Value {
-	if p := el.n.Parent; p != nil {
-		el.d.getEl(p).RemoveChild(el)
-		addMutation(el.d, Value, p)
-	}
-	return js.Undefined()
-}
-
-func (el *Element) CloneNode(deep ...bool) *Element {
-	var d bool
-	if len(deep) == 1 {
-		d = deep[0]
-	}
-	if d {
-		h := render(el.n)
-		// TODO: does this create references to el.n? (it shouldn't!)
-		cs, err := html.ParseFragment(strings.NewReader(h), el.n)
-		if err != nil {
-			log.Errorf("parge fragment: %v", err)
-			return nil
-		}
-		var c *html.Node
-		for _, cc := range cs {
-			if cc.Type == html.TextNode {
-				continue
-			}
-			if c == nil {
-				c = cc
-			} else {
-				log.Errorf("parge fragment %v: has unexpected len %v", h, len(cs))
-				return nil
-			}
-		}
-		if c == nil {
-			log.Errorf("parge fragment %v: has unexpected len %v", h, len(cs))
-			return nil
-		}
-		o := el.d.getEl(cs[0])
-		return o
-	} else {
-		cl := &html.Node{
-			Type:      el.n.Type,
-			DataAtom:  el.n.DataAtom,
-			Data:      el.n.Data,
-			Namespace: el.n.Namespace,
-			Attr:      append([]html.Attribute{}, el.n.Attr...),
-		}
-		o := el.d.getEl(cl)
-		return o
-	}
-}
-
-func (el *Element) IsEqualNode(n any) bool {
-	e, ok := n.(*Element)
-	if !ok {
-		return false
-	}
-	if len(el.n.Attr) != len(e.n.Attr) {
-		return false
-	}
-	allAttrsMatch := true
-	for _, a := range el.n.Attr {
-		found := false
-		for _, b := range e.n.Attr {
-			if a.Namespace == b.Namespace && a.Key == b.Key && a.Val == b.Val {
-				found = true
-				break
-			}
-		}
-		if !found {
-			allAttrsMatch = false
+			f: f,
 		}
+		hcs[k] = hc
 	}
-	if !allAttrsMatch {
-		return false
-	}
-	if el.n.Data != e.n.Data || el.n.Type != e.n.Type || el.n.Namespace != e.n.Namespace {
-		return false
-	}
-	c := el.n.FirstChild
-	d := e.n.FirstChild
-	for {
-		if (c == nil) != (d == nil) {
-			return false
-		}
-		if c == nil {
-			break
-		}
-		if !el.d.getEl(c).IsEqualNode(el.d.getEl(d)) {
-			return false
-		}
-		c = el.d.getEl(c).n.NextSibling
-		d = el.d.getEl(d).n.NextSibling
-	}
-	return true
-}
-
-func (el *Element) IsSameNode(n ...any) bool {
-	if len(n) == 0 {
-		return false
-	}
-	e, ok := n[0].(*Element)
-	if !ok {
-		return false
-	}
-	return e == el
-}
-
-func (el *Element) InsertBefore(nu, old any) any {
-	switch v := nu.(type) {
-	case *html.Node:
-		return el.insertElement(el.d.getEl(v), old)
-	case *Element:
-		return el.insertElement(v, old)
-	case *DocumentFragment:
-		for _, cc := range v.children {
-			el.d.getEl(cc).df = nil // cache
-			el.InsertBefore(cc, old)
-		}
-		return v
-	}
-	log.Fatalf("not implemented %T", nu)
-	return nil
-}
-
-func (el *Element) insertElement(nue *Element, old any) *Element {
-	if nue.n.Parent != nil {
-		nue.n.Parent.RemoveChild(nue.n)
-	}
-	var oe *Element
-	oe, ok := old.(*Element)
-	if !ok {
-		el.n.InsertBefore(nue.n, nil)
-	} else {
-		el.n.InsertBefore(nue.n, oe.n)
-	}
-	addMutation(el.d, Insert, nue.n)
-	return nue
-}
-
-func (el *Element) ReplaceChild(nue, ole *Element) *Element {
-	for cc := el.n.FirstChild; cc != nil; cc = cc.NextSibling {
-		if cc == nue.n {
-			el.n.RemoveChild(nue.n)
-			break
-		}
-	}
-	for cc := el.n.FirstChild; cc != nil; cc = cc.NextSibling {
-		if cc == ole.n {
-			nx := ole.n.NextSibling
-			el.n.RemoveChild(ole.n)
-			el.n.InsertBefore(nue.n, nx)
-			return ole
-		}
-	}
-	return nil
-}
-
-func (el *Element) AppendChild(c any) any {
-	switch v := c.(type) {
-	case *html.Node:
-		return el.appendElement(el.d.getEl(v))
-	case *Element:
-		return el.appendElement(v)
-	case *DocumentFragment:
-		for _, cc := range v.children {
-			el.d.getEl(cc).df = nil // cache
-			el.AppendChild(cc)
-		}
-		return v
-	case map[string]any:
-		// TODO
-		log.Errorf("appendChild called with map[string]any")
-		return nil
-	}
-	log.Fatalf("not implemented %T, %+v", c, c)
-	return nil
+	return hc
 }
 
-func (el *Element) appendElement(e *Element) *Element {
-	ce := e
-	for cc := el.n.FirstChild; cc != nil; cc = cc.NextSibling {
-		if cc == ce.n {
-			el.n.RemoveChild(cc)
-			break
+func (el *Element) Children() *js.Object {
+	return el.collection("children", func() []*html.Node {
+		nodes := make([]*html.Node, 0, 2)
+		for c := el.n.FirstChild; c != nil; c = c.NextSibling {
+			nodes = append(nodes, c)
 		}
-	}
-	el.n.AppendChild(ce.n)
-	addMutation(el.d, Insert, ce.n)
-	return ce
+		return nodes
+	}).Obj()
 }
 
-func (el *Element) RemoveChild(c any) *Element {
-	ce, ok := c.(*Element)
-	if !ok || ce == nil || ce.n == nil {
-		log.Errorf("remove child: nil arg")
-		return nil
-	}
-	found := false
-	for cc := el.n.FirstChild; cc != nil; cc = cc.NextSibling {
-		if cc == ce.n {
-			found = true
-			break
-		}
-	}
-	if !found {
-		log.Errorf("child to remove not found")
-		return nil
+func (el *Element) Length() int {
+	switch el.n.Data {
+	case "form":
+		return len(formElements(el.n))
+	case "select":
+		return len(selectOptions(el.n))
 	}
-	el.n.RemoveChild(ce.n)
-	addMutation(el.d, Rm, el.n)
-	return ce
-}
-
-func (el *Element) Node() *html.Node {
-	return el.n
+	return len(el.n.Data)
 }
 
 func (el *Element) Animate(opts ...any) *Animation {
@@ -2201,50 +2134,7 @@ func (el *Element) Animate(opts ...any) *Animation {
 }
 
 func (el *Element) Style() *js.Object {
-	return vm.NewDynamicObject(&Style{
-		n: el.n,
-	})
-}
-
-func (el *Element) OffsetHeight() int {
-	x1, _, x2, _ := el.geom()
-	return x2 - x1
-}
-
-func (el *Element) OffsetWidth() int {
-	_, y1, _, y2 := el.geom()
-	return y2 - y1
-}
-
-func (el *Element) geom() (x1, y1, x2, y2 int) {
-	if Geom == nil {
-		log.Errorf("Geom is nil")
-		return
-	}
-	p, ok := path(el)
-	if !ok {
-		log.Errorf("path lookup failed")
-		return
-	}
-	geom, err := Geom(p)
-	if err != nil {
-		log.Errorf("geom %v: %v", p, err)
-		return
-	}
-	items := strings.Split(geom, ",")
-	x1, _ = strconv.Atoi(items[0])
-	y1, _ = strconv.Atoi(items[1])
-	x2, _ = strconv.Atoi(items[2])
-	y2, _ = strconv.Atoi(items[3])
-	return
-}
-
-func (el *Element) GetClientRects() *js.Object {
-	return vm.NewDynamicObject(&DOMRect{})
-}
-
-func (el *Element) GetBoundingClientRect() *DOMRect {
-	return &DOMRect{}
+	return (&Style{n: el.n}).Obj()
 }
 
 func Init(r *js.Runtime, url, htm, script string) (d *Document, err error) {
@@ -2263,14 +2153,7 @@ func Init(r *js.Runtime, url, htm, script string) (d *Document, err error) {
 		      .catch(e => setTimeout(() => { throw e; })); // report exceptions
 		};
 
-		function CDATASection() {}
-		function CharacterData() {}
-		function HTMLIFrameElement() {}
-		function HTMLSlotElement() {}
-		function HTMLTemplateElement() {}
-
 		function NodeFilter() {}
-		function ProcessingInstruction() {}
 		function Window() {}
 
 		function error(msg) {
@@ -2298,46 +2181,48 @@ func Init(r *js.Runtime, url, htm, script string) (d *Document, err error) {
 	if err != nil {
 		return nil, fmt.Errorf("define misc entities: %v", err)
 	}
-	NodePrototype, err = vm.RunString(`
-		function Node() {};
-		Node.ELEMENT_NODE = 1;
-		Node.ATTRIBUTE_NODE = 2;
-		Node.TEXT_NODE = 3;
-		Node.CDATA_SECTION_NODE = 4;
-		Node.PROCESSING_INSTRUCTION_NODE = 7;
-		Node.COMMENT_NODE = 8;
-		Node.DOCUMENT_NODE = 9;
-		Node.DOCUMENT_TYPE_NODE = 10;
-		Node.DOCUMENT_FRAGMENT_NODE = 11;
+	_, err = vm.RunString(`
 		// https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/Symbol/hasInstance
-		Object.defineProperty(Node, Symbol.hasInstance, {
-			value: function(instance) { return instance.nodeType === 1; }
-		});
 		Object.defineProperty(Window, Symbol.hasInstance, {
 			value: function(instance) { return instance === window; }
-		});
-		Element = Node;
-		Node;`)
+		});`)
 	if err != nil {
-		return nil, fmt.Errorf("define NodePrototype: %v", err)
+		return nil, fmt.Errorf("define Window: %v", err)
 	}
-	TextPrototype, err = vm.RunString(`function Text() {}; Text;`)
-	if err != nil {
-		return nil, fmt.Errorf("define Text: %v", err)
+	if err = initDOMException(); err != nil {
+		return nil, fmt.Errorf("define DOMException: %v", err)
 	}
-	HTMLElementPrototype, err = vm.RunString(`function HTMLElement() {}; HTMLElement;`)
-	if err != nil {
-		return nil, fmt.Errorf("define HTMLElementPrototype: %v", err)
+	initEventCtors()
+	initFormData()
+	initDOMTokenList()
+	if err = initNodeList(); err != nil {
+		return nil, fmt.Errorf("define NodeList: %v", err)
 	}
-	HTMLInputElementPrototype, err = vm.RunString(`function HTMLInputElement() {}; HTMLInputElement;`)
-	if err != nil {
-		return nil, fmt.Errorf("define HTMLInputElementPrototype: %v", err)
+	if err = initHTMLCollection(); err != nil {
+		return nil, fmt.Errorf("define HTMLCollection: %v", err)
+	}
+	if err = initFormControls(); err != nil {
+		return nil, fmt.Errorf("define form control collections: %v", err)
+	}
+	if err = initFrames(); err != nil {
+		return nil, fmt.Errorf("define realms: %v", err)
 	}
 	d = NewDocument(doc)
+	d.url = url
+	// the host runs the scripts of the page
+	d.markStarted(doc)
+	initNodeCtors(d)
+	initRanges(d)
+	initTraversal()
+	initCSSOM(d)
+	initGeometry()
+	initObservers(d)
+	initMedia()
 	builtinThis := vm.GlobalObject()
 	w := NewWindow(url, builtinThis, d)
 	d.Window = w
 	vm.SetGlobalObject(w.Obj())
+	d.loadStyleSheets(d.doc)
 	_, err = vm.RunString(`
 		console.log("running...");
 	` + script)
@@ -2366,12 +2251,32 @@ func grep(n *html.Node, tag string) *html.Node {
 	return t
 }
 
-func grepAll(n *html.Node, tag string, skipRoot bool) (all []*html.Node) {
-	tag = strings.ToLower(tag)
-	if n.Type == html.ElementNode {
-		if (strings.ToLower(n.Data) == tag || tag == "*") && !skipRoot {
-			all = append(all, n)
+const xhtmlNS = "http://www.w3.org/1999/xhtml"
+
+// matchesTag is true if n has the qualified name tag. Names of HTML
+// elements are compared ASCII case-insensitively.
+func matchesTag(n *html.Node, tag string) bool {
+	if tag == "*" {
+		return true
+	}
+	if n.Namespace == "" || n.Namespace == xhtmlNS {
+		return n.Data == asciiLower(tag)
+	}
+	return n.Data == tag
+}
+
+func asciiLower(s string) string {
+	return strings.Map(func(r rune) rune {
+		if 'A' <= r && r <= 'Z' {
+			return r + 'a' - 'A'
 		}
+		return r
+	}, s)
+}
+
+func grepAll(n *html.Node, tag string, skipRoot bool) (all []*html.Node) {
+	if n.Type == html.ElementNode && !skipRoot && matchesTag(n, tag) {
+		all = append(all, n)
 	}
 
 	for c := n.FirstChild; c != nil; c = c.NextSibling {
@@ -2492,21 +2397,30 @@ func setAttr(n *html.Node, key, val string) {
 		Key: key,
 		Val: val,
 	}
+	found := false
 	for i, a := range n.Attr {
 		if a.Key == key {
 			n.Attr[i] = newAttr
-			addMutation(nil, ChAttr, n)
-			return
+			found = true
+			break
 		}
 	}
-	n.Attr = append(n.Attr, newAttr)
+	if !found {
+		n.Attr = append(n.Attr, newAttr)
+	}
+	// detached nodes are recorded when inserted
+	if connected(n) {
+		addMutation(nil, ChAttr, n)
+	}
 }
 
 func rmAttr(n *html.Node, key string) {
 	for i, a := range n.Attr {
 		if a.Key == key {
 			n.Attr = append(n.Attr[:i], n.Attr[i+1:]...)
-			addMutation(nil, RmAttr, n)
+			if connected(n) {
+				addMutation(nil, RmAttr, n)
+			}
 			return
 		}
 	}
@@ -2521,6 +2435,25 @@ func render(n *html.Node) string {
 	return buf.String()
 }
 
+// copyTree returns a deep copy of n without parent and siblings. f is
+// called (if not nil) on each original node and its copy.
+func copyTree(n *html.Node, f func(orig, c *html.Node)) *html.Node {
+	c := &html.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+		Attr:      append([]html.Attribute{}, n.Attr...),
+	}
+	for cc := n.FirstChild; cc != nil; cc = cc.NextSibling {
+		c.AppendChild(copyTree(cc, f))
+	}
+	if f != nil {
+		f(n, c)
+	}
+	return c
+}
+
 func renderInner(n *html.Node) string {
 	buf := bytes.NewBufferString("")
 	for c := n.FirstChild; c != nil; c = c.NextSibling {
@@ -2532,21 +2465,24 @@ func renderInner(n *html.Node) string {
 	return buf.String()
 }
 
-func normalize(n *html.Node) {
+func (d *Document) normalize(n *html.Node) {
 	for {
 		correction := false
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			normalize(c)
-			if c.Type != html.TextNode {
+			d.normalize(c)
+			if c.Type != html.TextNode || isCDATA(c) {
 				continue
 			}
 			if c.Data == "" {
-				n.RemoveChild(c)
+				d.removeChild(n, c)
 				correction = true
 				break
-			} else if nx := c.NextSibling; nx != nil && nx.Type == html.TextNode {
+			} else if nx := c.NextSibling; nx != nil && nx.Type == html.TextNode && !isCDATA(nx) {
+				if len(liveRanges) > 0 {
+					rangesMerge(d.getNode(c), d.getNode(nx))
+				}
 				c.Data += nx.Data
-				n.RemoveChild(nx)
+				d.removeChild(n, nx)
 				correction = true
 				break
 			}
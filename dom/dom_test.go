@@ -686,11 +686,13 @@ $( function() {
 		}
 		t.Logf("now do the click!!!!!")
 		ev := &MouseEvent{
-			Event: Event{
-				Type: "click",
+			UIEvent: UIEvent{
+				Event: Event{
+					Type: "click",
+				},
 			},
 		}
-		consumed := d.getEl(d.QuerySelector("#ui-id-7").n).DispatchEvent(ev)
+		consumed := d.getEl(d.QuerySelector("#ui-id-7").n).dispatch(ev)
 		if !consumed {
 			errCh <- fmt.Errorf("ev not consumed")
 			return
@@ -949,7 +951,7 @@ $( function() {
 		e := &Event{
 			Type: "focus",
 		}
-		consumed := d.QuerySelector("#datepicker").DispatchEvent(e)
+		consumed := d.QuerySelector("#datepicker").dispatch(e)
 		if !consumed {
 			errCh <- fmt.Errorf("expected click to be consumed")
 			return
@@ -2,12 +2,18 @@ package dom
 
 import (
 	"github.com/psilva261/sparkle/js"
+	"github.com/psilva261/sparklefs/logger"
+	"golang.org/x/net/html"
+	"strings"
 )
 
-type DOMParser struct{}
+// DOMParser parses documents which get the URL of the document d
+type DOMParser struct {
+	d *Document
+}
 
-func NewDOMParser() *DOMParser {
-	return &DOMParser{}
+func NewDOMParser(d *Document) *DOMParser {
+	return &DOMParser{d: d}
 }
 
 func (dp *DOMParser) Obj() *js.Object {
@@ -15,8 +21,7 @@ func (dp *DOMParser) Obj() *js.Object {
 }
 
 func (dp *DOMParser) Getters() map[string]bool {
-	return map[string]bool{
-	}
+	return map[string]bool{}
 }
 
 func (dp *DOMParser) Props() map[string]bool {
@@ -48,3 +53,87 @@ func (dp *DOMParser) Delete(k string) bool {
 func (dp *DOMParser) Keys() []string {
 	return []string{""}
 }
+
+// ParseFromString parses s as HTML or XML depending on the MIME type t.
+// Malformed XML results in a document with a <parsererror> element.
+func (dp *DOMParser) ParseFromString(s, t string) *Document {
+	var doc *html.Node
+	var err error
+	switch {
+	case t == "text/html":
+		doc, err = html.Parse(strings.NewReader(s))
+	case isXMLType(t):
+		if doc, err = parseXML(s); err != nil {
+			doc, err = parseErrorDoc(err), nil
+		}
+	default:
+		panic(vm.NewTypeError("parseFromString: unsupported type " + t))
+	}
+	if err != nil {
+		log.Errorf("parse from string: %v", err)
+		doc = &html.Node{Type: html.DocumentNode}
+	}
+	d := NewDocument(doc)
+	d.markStarted(doc)
+	d.contentType = t
+	if dp.d != nil {
+		d.url = dp.d.url
+	}
+	return d
+}
+
+// XMLSerializer serializes nodes as XML
+type XMLSerializer struct{}
+
+func (xs *XMLSerializer) Obj() *js.Object {
+	return vm.NewDynamicObject(xs)
+}
+
+func (xs *XMLSerializer) Getters() map[string]bool {
+	return map[string]bool{}
+}
+
+func (xs *XMLSerializer) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+func (xs *XMLSerializer) Get(k string) (v js.Value) {
+	if res, ok := GetCall(xs, k); ok {
+		return res
+	}
+	return vm.ToValue(nil)
+}
+
+func (xs *XMLSerializer) Set(k string, desc js.PropertyDescriptor) bool {
+	return true
+}
+
+func (xs *XMLSerializer) Has(k string) bool {
+	return HasCall(xs, k)
+}
+
+func (xs *XMLSerializer) Delete(k string) bool {
+	return false
+}
+
+func (xs *XMLSerializer) Keys() []string {
+	return []string{""}
+}
+
+func (xs *XMLSerializer) SerializeToString(v any) string {
+	var b strings.Builder
+	switch x := v.(type) {
+	case *Document:
+		x.serializeXML(&b, x.doc, "", nil)
+	case *DocumentFragment:
+		for _, c := range x.children {
+			x.d.serializeXML(&b, c, "", nil)
+		}
+	case Node:
+		x.base().d.serializeXML(&b, x.base().n, "", nil)
+	case *Attr:
+	default:
+		panic(vm.NewTypeError("serializeToString: parameter 1 is not of type 'Node'"))
+	}
+	return b.String()
+}
@@ -0,0 +1,69 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"testing"
+)
+
+func TestDOMParser(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><body></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var p = new DOMParser();
+		var doc = p.parseFromString('<?xml version="1.0"?><root xmlns:a="urn:a"><a:b x="1"/><c>t</c></root>', 'application/xml');
+		var root = doc.documentElement;
+		var log = [doc.contentType, doc instanceof XMLDocument, root.tagName, root.namespaceURI, root.firstChild.prefix, root.firstChild.localName, root.firstChild.namespaceURI];
+		log.push(new XMLSerializer().serializeToString(doc));
+		var bad = p.parseFromString('<a><b></a>', 'text/xml');
+		log.push(bad.documentElement.localName, bad.documentElement.namespaceURI);
+		log.push(p.parseFromString('<p>x', 'text/html').body.firstChild.tagName);
+		try { p.parseFromString('', 'text/plain') } catch (e) { log.push(e.name) }
+		var x = document.implementation.createDocument('http://www.w3.org/2000/svg', 'svg:svg', null);
+		log.push(x.contentType, x.documentElement.prefix, x.documentElement.tagName, x.createElement('DIV').localName);
+		try { document.implementation.createDocument(null, 'a:b') } catch (e) { log.push(e.name) }
+		try { document.createElementNS(null, '1a') } catch (e) { log.push(e.name) }
+		var cd = p.parseFromString('<r>a<![CDATA[<b>&]]>c</r>', 'text/xml').documentElement;
+		log.push(cd.childNodes.length, cd.childNodes[1].nodeType, cd.childNodes[1].nodeName, cd.childNodes[1] instanceof CDATASection, cd.childNodes[1] instanceof Text, cd.childNodes[1].data, cd.textContent);
+		cd.appendChild(x.createCDATASection('d'));
+		log.push(new XMLSerializer().serializeToString(cd));
+		try { document.createCDATASection('x') } catch (e) { log.push(e.name) }
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := `application/xml,true,root,,a,b,urn:a,<root xmlns:a="urn:a"><a:b x="1"/><c>t</c></root>,parsererror,http://www.mozilla.org/newlayout/xml/parsererror.xml,P,TypeError,image/svg+xml,svg,svg:svg,DIV,NamespaceError,InvalidCharacterError,3,4,#cdata-section,true,true,<b>&,a<b>&c,<r>a<![CDATA[<b>&]]>c<![CDATA[d]]></r>,NotSupportedError`
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestMoveBetweenHTMLAndXML(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><body><p id="p">x</p></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var doc = new DOMParser().parseFromString('<rss><item><title>t</title></item></rss>', 'text/xml');
+		var r = document.importNode(doc.documentElement, true);
+		var log = [r.nodeName, r.namespaceURI, r.firstChild.nodeName];
+		var a = document.adoptNode(doc.documentElement.firstChild);
+		log.push(a.nodeName, a.namespaceURI);
+		var p = doc.adoptNode(document.getElementById('p'));
+		log.push(p.nodeName, p.namespaceURI);
+		document.body.appendChild(p);
+		log.push(p.nodeName, p.namespaceURI);
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := `rss,,item,item,,p,http://www.w3.org/1999/xhtml,P,http://www.w3.org/1999/xhtml`
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/psilva261/sparkle/js"
 	"github.com/psilva261/sparklefs/logger"
 	"strings"
+	"time"
 )
 
 type Event struct {
@@ -14,25 +15,491 @@ type Event struct {
 	Bubbles            bool
 	CancelBubble       bool
 	Cancelable         bool
+	Composed           bool
 	IsTrusted          bool
+	TimeStamp          float64
 	propagationStopped bool
-	CurrentTarget      *Element
-	Target             *Element
-	SrcElement         *Element
+	immediateStopped   bool
+	dispatching        bool
+	CurrentTarget      EventTarget
+	Target             EventTarget
+	SrcElement         EventTarget
+
+	// path lists the targets of the ongoing dispatch, beginning with
+	// the target
+	path []EventTarget
+
+	// wrapper is the most derived event struct (e.g. *MouseEvent)
+	// embedding this Event
+	wrapper eventer
+
+	// activation is the innermost element with activation behavior
+	// in the path. The behavior runs on it after the dispatch.
+	activation *Element
+	wasChecked bool
 }
 
 const (
 	EvPhNone = iota
+	EvPhCapturing
+	EvPhAtTarget
+	EvPhBubbling
 )
 
+// eventer is implemented by Event and all structs embedding it
+type eventer interface {
+	Gettable
+	js.DynamicObject
+	event() *Event
+}
+
+// EventTarget is a node, document or window events are dispatched to
+type EventTarget interface {
+	Obj() *js.Object
+	listeners() listeners
+}
+
+// listener is registered with addEventListener
+type listener struct {
+	fn      js.Value
+	capture bool
+	once    bool
+	removed bool
+
+	// handler is set for the listener of an event handler property
+	// like onchange. It keeps its position when the property is
+	// reassigned and can't be removed with removeEventListener.
+	handler bool
+}
+
+// listeners maps event types to their listeners
+type listeners map[string][]*listener
+
+// listenerOpts returns capture and once from the options argument which
+// is a boolean or a dictionary
+func listenerOpts(opts []any) (capture, once bool) {
+	if len(opts) == 0 {
+		return
+	}
+	switch v := opts[0].(type) {
+	case bool:
+		capture = v
+	case map[string]any:
+		capture, _ = v["capture"].(bool)
+		once, _ = v["once"].(bool)
+	}
+	return
+}
+
+func (ls listeners) add(t string, fn js.Value, opts ...any) {
+	if fn == nil || js.IsUndefined(fn) || js.IsNull(fn) {
+		return
+	}
+	capture, once := listenerOpts(opts)
+	for _, l := range ls[t] {
+		if !l.handler && l.fn.StrictEquals(fn) && l.capture == capture {
+			return
+		}
+	}
+	ls[t] = append(ls[t], &listener{fn: fn, capture: capture, once: once})
+}
+
+func (ls listeners) remove(t string, fn js.Value, opts ...any) {
+	capture, _ := listenerOpts(opts)
+	for i, l := range ls[t] {
+		if !l.handler && fn != nil && l.fn.StrictEquals(fn) && l.capture == capture {
+			l.removed = true
+			ls[t] = append(ls[t][:i:i], ls[t][i+1:]...)
+			return
+		}
+	}
+}
+
+// handler returns the listener of the event handler property for t
+func (ls listeners) handler(t string) *listener {
+	for _, l := range ls[t] {
+		if l.handler {
+			return l
+		}
+	}
+	return nil
+}
+
+// setHandler sets the event handler property for t to fn. Values other
+// than functions remove the handler.
+func (ls listeners) setHandler(t string, fn js.Value) {
+	_, isFunc := js.AssertFunction(fn)
+	l := ls.handler(t)
+	switch {
+	case isFunc && l != nil:
+		l.fn = fn
+	case isFunc:
+		ls[t] = append(ls[t], &listener{fn: fn, handler: true})
+	case l != nil:
+		l.removed = true
+		for i, o := range ls[t] {
+			if o == l {
+				ls[t] = append(ls[t][:i:i], ls[t][i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// eventInterfaces lists the event constructors exposed on window
+// together with their parent interface and the Go type whose members
+// are defined on the prototype.
+var eventInterfaces = []struct {
+	name   string
+	parent string
+	recv   eventer
+}{
+	{"Event", "", &Event{}},
+	{"CustomEvent", "Event", &CustomEvent{}},
+	{"UIEvent", "Event", &UIEvent{}},
+	{"FocusEvent", "UIEvent", &FocusEvent{}},
+	{"InputEvent", "UIEvent", &InputEvent{}},
+	{"KeyboardEvent", "UIEvent", &KeyboardEvent{}},
+	{"MouseEvent", "UIEvent", &MouseEvent{}},
+	{"PointerEvent", "MouseEvent", &PointerEvent{}},
+	{"WheelEvent", "MouseEvent", &WheelEvent{}},
+	{"SubmitEvent", "Event", &SubmitEvent{}},
+	{"MediaQueryListEvent", "Event", &MediaQueryListEvent{}},
+}
+
+// eventCtors holds the event constructors of the current runtime
+var eventCtors = make(map[string]*js.Object)
+
+func initEventCtors() {
+	eventCtors = make(map[string]*js.Object)
+	for _, ei := range eventInterfaces {
+		name := ei.name
+		c := newCtor(name, func(call js.ConstructorCall) *js.Object {
+			var opts map[string]any
+			if len(call.Arguments) >= 2 {
+				opts, _ = call.Argument(1).Export().(map[string]any)
+			}
+			e := newEvent(name, call.Argument(0).String(), opts)
+			o := e.event().Obj()
+			o.SetPrototype(call.This.Prototype())
+			return o
+		})
+		proto := c.Get("prototype").(*js.Object)
+		proto.DefineDataPropertySymbol(js.SymToStringTag, vm.ToValue(name), js.FLAG_FALSE, js.FLAG_TRUE, js.FLAG_FALSE)
+		var parent Gettable
+		if p, ok := eventCtors[ei.parent]; ok {
+			proto.SetPrototype(p.Get("prototype").(*js.Object))
+			c.SetPrototype(p)
+			for _, pi := range eventInterfaces {
+				if pi.name == ei.parent {
+					parent = pi.recv
+				}
+			}
+		}
+		protoMembers(proto, ei.recv, parent)
+		eventCtors[name] = c
+	}
+	ev := eventCtors["Event"]
+	// the targets and phase aren't fields with the same name
+	for _, k := range []string{"target", "srcElement", "currentTarget", "eventPhase"} {
+		k := k
+		getter := vm.ToValue(func(call js.FunctionCall) js.Value {
+			e, ok := protoRecv(call.This).(eventer)
+			if !ok {
+				panic(vm.NewTypeError("Illegal invocation"))
+			}
+			return e.event().Get(k)
+		})
+		ev.Get("prototype").(*js.Object).DefineAccessorProperty(k, getter, nil, js.FLAG_TRUE, js.FLAG_TRUE)
+	}
+	for k, v := range map[string]int{
+		"NONE":            EvPhNone,
+		"CAPTURING_PHASE": EvPhCapturing,
+		"AT_TARGET":       EvPhAtTarget,
+		"BUBBLING_PHASE":  EvPhBubbling,
+	} {
+		ev.Set(k, v)
+		ev.Get("prototype").(*js.Object).Set(k, v)
+	}
+	consts := map[string]map[string]int{
+		"KeyboardEvent": {
+			"DOM_KEY_LOCATION_STANDARD": 0,
+			"DOM_KEY_LOCATION_LEFT":     1,
+			"DOM_KEY_LOCATION_RIGHT":    2,
+			"DOM_KEY_LOCATION_NUMPAD":   3,
+		},
+		"WheelEvent": {
+			"DOM_DELTA_PIXEL": 0,
+			"DOM_DELTA_LINE":  1,
+			"DOM_DELTA_PAGE":  2,
+		},
+	}
+	for name, cs := range consts {
+		c := eventCtors[name]
+		for k, v := range cs {
+			c.Set(k, v)
+			c.Get("prototype").(*js.Object).Set(k, v)
+		}
+	}
+}
+
+// newEvent creates an event of interface iface (e.g. "MouseEvent")
+// initialized from an init dictionary.
+func newEvent(iface, t string, opts map[string]any) (e eventer) {
+	switch iface {
+	case "CustomEvent":
+		ce := &CustomEvent{}
+		ce.detail = js.Null()
+		if v, ok := opts["detail"]; ok {
+			ce.detail = vm.ToValue(v)
+		}
+		e = ce
+	case "UIEvent":
+		ue := &UIEvent{}
+		ue.initUI(opts)
+		e = ue
+	case "FocusEvent":
+		fe := &FocusEvent{}
+		fe.initUI(opts)
+		fe.relatedTarget = optTarget(opts, "relatedTarget")
+		e = fe
+	case "InputEvent":
+		ie := &InputEvent{}
+		ie.initUI(opts)
+		ie.Data = optString(opts, "data")
+		ie.InputType = optString(opts, "inputType")
+		ie.IsComposing = optBool(opts, "isComposing")
+		e = ie
+	case "KeyboardEvent":
+		ke := &KeyboardEvent{}
+		ke.initUI(opts)
+		ke.initModifiers(opts)
+		ke.Key = optString(opts, "key")
+		ke.Code = optString(opts, "code")
+		ke.Location = int(optNum(opts, "location"))
+		ke.Repeat = optBool(opts, "repeat")
+		ke.IsComposing = optBool(opts, "isComposing")
+		ke.CharCode = int(optNum(opts, "charCode"))
+		ke.KeyCode = int(optNum(opts, "keyCode"))
+		e = ke
+	case "MouseEvent":
+		me := &MouseEvent{}
+		me.initMouse(opts)
+		e = me
+	case "PointerEvent":
+		pe := &PointerEvent{}
+		pe.initMouse(opts)
+		pe.PointerId = int(optNum(opts, "pointerId"))
+		pe.Width = 1
+		if _, ok := opts["width"]; ok {
+			pe.Width = optNum(opts, "width")
+		}
+		pe.Height = 1
+		if _, ok := opts["height"]; ok {
+			pe.Height = optNum(opts, "height")
+		}
+		pe.Pressure = optNum(opts, "pressure")
+		pe.TangentialPressure = optNum(opts, "tangentialPressure")
+		pe.TiltX = int(optNum(opts, "tiltX"))
+		pe.TiltY = int(optNum(opts, "tiltY"))
+		pe.Twist = int(optNum(opts, "twist"))
+		pe.PointerType = optString(opts, "pointerType")
+		pe.IsPrimary = optBool(opts, "isPrimary")
+		e = pe
+	case "WheelEvent":
+		we := &WheelEvent{}
+		we.initMouse(opts)
+		we.DeltaX = optNum(opts, "deltaX")
+		we.DeltaY = optNum(opts, "deltaY")
+		we.DeltaZ = optNum(opts, "deltaZ")
+		we.DeltaMode = int(optNum(opts, "deltaMode"))
+		e = we
+	case "SubmitEvent":
+		se := &SubmitEvent{}
+		se.submitter = optElement(opts, "submitter")
+		e = se
+	case "MediaQueryListEvent":
+		me := &MediaQueryListEvent{}
+		me.Media = optString(opts, "media")
+		me.Matches = optBool(opts, "matches")
+		e = me
+	default:
+		e = &Event{}
+	}
+	ev := e.event()
+	ev.wrapper = e
+	ev.Type = t
+	ev.Bubbles = optBool(opts, "bubbles")
+	ev.Cancelable = optBool(opts, "cancelable")
+	ev.Composed = optBool(opts, "composed")
+	ev.TimeStamp = float64(time.Now().UnixMilli())
+	return
+}
+
 func NewEvent(t string, opts map[string]any) (o *js.Object) {
-	e := &Event{
-		Type: t,
+	return newEvent("Event", t, opts).event().Obj()
+}
+
+func (e *Event) event() *Event {
+	return e
+}
+
+// recv returns the receiver used for reflected property access
+func (e *Event) recv() eventer {
+	if e.wrapper != nil {
+		return e.wrapper
+	}
+	return e
+}
+
+// wrap registers ei as the most derived struct of its Event. Needed for
+// events instantiated as Go literals.
+func wrap(ei any) *Event {
+	ev, ok := ei.(eventer)
+	if !ok {
+		return nil
+	}
+	e := ev.event()
+	if e.wrapper == nil {
+		e.wrapper = ev
+	}
+	return e
+}
+
+// dispatch runs the capture, target and bubble phases of the event
+// along path which begins with the target
+func (e *Event) dispatch(path []EventTarget) (consumed bool) {
+	if e.dispatching {
+		throwDOMException("InvalidStateError", "the event is already being dispatched")
+	}
+	e.Target = path[0]
+	e.SrcElement = path[0]
+	e.path = path
+	e.dispatching = true
+	defer func() {
+		consumed = e.finishDispatch() || consumed
+	}()
+	if _, ok := e.recv().(interface{ mouse() *MouseEvent }); ok && e.Type == "click" {
+		// parents are only considered when the event bubbles
+		for i, t := range path {
+			if i > 0 && !e.Bubbles {
+				break
+			}
+			if el, ok := t.(*Element); ok && hasActivation(el.n) {
+				e.activation = el
+				e.wasChecked = el.activate()
+				break
+			}
+		}
 	}
-	if yes, ok := opts["bubbles"]; ok {
-		e.Bubbles, _ = yes.(bool)
+	for i := len(path) - 1; i >= 0; i-- {
+		e.Phase = EvPhCapturing
+		if i == 0 {
+			e.Phase = EvPhAtTarget
+		}
+		e.invoke(path[i], true)
+	}
+	for i, t := range path {
+		if i == 0 {
+			e.Phase = EvPhAtTarget
+		} else if !e.Bubbles {
+			break
+		} else {
+			e.Phase = EvPhBubbling
+		}
+		e.invoke(t, false)
+	}
+	return e.Consumed
+}
+
+// invoke calls the handlers and listeners of t for the capture or
+// non-capture phase
+func (e *Event) invoke(t EventTarget, capture bool) {
+	if e.propagationStopped {
+		return
+	}
+	e.CurrentTarget = t
+	if h, ok := t.(interface{ runHandlers(*Event) }); ok && !capture {
+		h.runHandlers(e)
+	}
+	ls := t.listeners()
+	for _, l := range append([]*listener(nil), ls[e.Type]...) {
+		if e.immediateStopped {
+			return
+		}
+		if l.removed || l.capture != capture {
+			continue
+		}
+		if l.once {
+			ls.remove(e.Type, l.fn, l.capture)
+		}
+		e.Consumed = true
+		this := t.Obj()
+		fn, ok := js.AssertFunction(l.fn)
+		if !ok {
+			o, isObj := l.fn.(*js.Object)
+			if !isObj {
+				continue
+			}
+			if fn, ok = js.AssertFunction(o.Get("handleEvent")); !ok {
+				log.Errorf("event listener: handleEvent is not a function")
+				continue
+			}
+			this = o
+		}
+		if _, err := fn(this, e.Obj()); err != nil {
+			log.Errorf("event listener: %v", err)
+		}
+	}
+}
+
+// finishDispatch runs the activation behavior (or its canceled
+// variant) once the dispatch of the event returns
+func (e *Event) finishDispatch() (consumed bool) {
+	e.dispatching = false
+	e.propagationStopped = false
+	e.immediateStopped = false
+	e.CancelBubble = false
+	e.path = nil
+	e.CurrentTarget = nil
+	e.Phase = 0
+	a := e.activation
+	if a == nil {
+		return
 	}
-	return e.Obj()
+	e.activation = nil
+	if e.DefaultPrevented {
+		a.deactivate(e.wasChecked)
+		return
+	}
+	return a.postActivate(e.wasChecked)
+}
+
+// iface is the name of the event's JS interface
+func (e *Event) iface() string {
+	switch e.recv().(type) {
+	case *CustomEvent:
+		return "CustomEvent"
+	case *UIEvent:
+		return "UIEvent"
+	case *FocusEvent:
+		return "FocusEvent"
+	case *InputEvent:
+		return "InputEvent"
+	case *KeyboardEvent:
+		return "KeyboardEvent"
+	case *MouseEvent:
+		return "MouseEvent"
+	case *PointerEvent:
+		return "PointerEvent"
+	case *WheelEvent:
+		return "WheelEvent"
+	case *SubmitEvent:
+		return "SubmitEvent"
+	case *MediaQueryListEvent:
+		return "MediaQueryListEvent"
+	}
+	return "Event"
 }
 
 func (e *Event) InitEvent(t string, opts ...any) {
@@ -43,6 +510,9 @@ func (e *Event) InitEvent(t string, opts ...any) {
 	if len(opts) >= 2 {
 		cancelable, _ = opts[1].(bool)
 	}
+	if e.dispatching {
+		return
+	}
 	if e.Consumed {
 		log.Errorf("init on consumed event")
 		return
@@ -52,6 +522,8 @@ func (e *Event) InitEvent(t string, opts ...any) {
 	e.Cancelable = cancelable
 	e.DefaultPrevented = false
 	e.propagationStopped = false
+	e.immediateStopped = false
+	e.CancelBubble = false
 }
 
 func (e *Event) PreventDefault() {
@@ -66,10 +538,20 @@ func (e *Event) ReturnValue() bool {
 
 func (e *Event) StopPropagation() {
 	e.propagationStopped = true
+	e.CancelBubble = true
 }
 
 func (e *Event) StopImmediatePropagation() {
-	e.propagationStopped = true
+	e.StopPropagation()
+	e.immediateStopped = true
+}
+
+func (e *Event) ComposedPath() js.Value {
+	objs := make([]*js.Object, 0, len(e.path))
+	for _, t := range e.path {
+		objs = append(objs, t.Obj())
+	}
+	return vm.ToValue(objs)
 }
 
 func (e *Event) Getters() map[string]bool {
@@ -84,13 +566,15 @@ func (e *Event) Props() map[string]bool {
 		"bubbles":          true,
 		"cancelBubble":     true,
 		"cancelable":       true,
+		"composed":         true,
 		"eventPhase":       true,
 		"isTrusted":        true,
 		"defaultPrevented": true,
+		"timeStamp":        true,
 		"type":             true,
 		"currentTarget":    true,
-		//"target": true,
-		"srcElement": true,
+		"target":           true,
+		"srcElement":       true,
 	}
 }
 
@@ -99,13 +583,16 @@ func (e *Event) Obj() (o *js.Object) {
 	if ok {
 		return
 	}
-	o = vm.NewDynamicObject(e)
+	o = vm.NewDynamicObject(e.recv())
+	if c, ok := eventCtors[e.iface()]; ok {
+		o.SetPrototype(c.Get("prototype").(*js.Object))
+	}
 	evObjRefs[e] = o
 	return
 }
 
 func (e *Event) Get(key string) js.Value {
-	if key == "target" { // TODO: reflect dyn obj. also fails here
+	if key == "target" || key == "srcElement" { // TODO: reflect dyn obj. also fails here
 		if e.Target == nil {
 			return js.Null()
 		}
@@ -117,22 +604,28 @@ func (e *Event) Get(key string) js.Value {
 		}
 		return e.CurrentTarget.Obj()
 	}
+	if key == "eventPhase" {
+		return vm.ToValue(e.Phase)
+	}
 	if vs, ok := evVars[e]; ok {
 		if v, ok := vs[key]; ok {
 			return v
 		}
 	}
-	if res, ok := GetCall(e, key); ok {
+	if res, ok := GetCall(e.recv(), key); ok {
 		return res
 	}
-	return js.Undefined()
+	// inherited from the prototype, e.g. constructor
+	return nil
 }
 
 func (e *Event) Set(key string, desc js.PropertyDescriptor) bool {
 	val := desc.Value
 	switch key {
 	case "cancelBubble":
-		e.CancelBubble = val.ToBoolean()
+		if val.ToBoolean() {
+			e.StopPropagation()
+		}
 	case "returnValue":
 		if e.Cancelable {
 			e.DefaultPrevented = !val.ToBoolean()
@@ -156,7 +649,7 @@ func (e *Event) Has(key string) bool {
 			return true
 		}
 	}
-	return HasCall(e, key)
+	return HasCall(e.recv(), key)
 }
 
 func (e *Event) Delete(key string) (ok bool) {
@@ -170,26 +663,496 @@ func (e *Event) Delete(key string) (ok bool) {
 }
 
 func (e *Event) Keys() []string {
-	ks := Calls(e)
+	ks := Calls(e.recv())
 	for k := range evVars[e] {
 		ks = append(ks, k)
 	}
 	return ks
 }
 
-type MouseEvent struct {
+type CustomEvent struct {
+	Event
+
+	detail js.Value
+}
+
+func (ce *CustomEvent) Getters() map[string]bool {
+	return merge(ce.Event.Getters(), map[string]bool{
+		"detail": true,
+	})
+}
+
+func (ce *CustomEvent) Detail() js.Value {
+	if ce.detail == nil {
+		return js.Null()
+	}
+	return ce.detail
+}
+
+func (ce *CustomEvent) InitCustomEvent(t string, opts ...any) {
+	ce.InitEvent(t, opts...)
+	if ce.dispatching {
+		return
+	}
+	if len(opts) >= 3 {
+		ce.detail = vm.ToValue(opts[2])
+	}
+}
+
+type UIEvent struct {
+	Event
+
+	Detail int
+	view   *Window
+}
+
+func (ue *UIEvent) initUI(opts map[string]any) {
+	ue.Detail = int(optNum(opts, "detail"))
+	ue.view, _ = opts["view"].(*Window)
+}
+
+func (ue *UIEvent) Getters() map[string]bool {
+	return merge(ue.Event.Getters(), map[string]bool{
+		"view":  true,
+		"which": true,
+	})
+}
+
+func (ue *UIEvent) Props() map[string]bool {
+	return merge(ue.Event.Props(), map[string]bool{
+		"detail": true,
+	})
+}
+
+func (ue *UIEvent) View() js.Value {
+	if ue.view == nil {
+		return js.Null()
+	}
+	return ue.view.Obj()
+}
+
+func (ue *UIEvent) Which() int {
+	return 0
+}
+
+// InitUIEvent(type, bubbles, cancelable, view, detail)
+func (ue *UIEvent) InitUIEvent(t string, opts ...any) {
+	ue.InitEvent(t, opts...)
+	if ue.dispatching {
+		return
+	}
+	if len(opts) >= 3 {
+		ue.view, _ = opts[2].(*Window)
+	}
+	if len(opts) >= 4 {
+		ue.Detail = toInt(opts[3])
+	}
+}
+
+// modifiers of the EventModifierInit dictionary
+type modifiers struct {
+	CtrlKey  bool
+	ShiftKey bool
+	AltKey   bool
+	MetaKey  bool
+}
+
+func (m *modifiers) initModifiers(opts map[string]any) {
+	m.CtrlKey = optBool(opts, "ctrlKey")
+	m.ShiftKey = optBool(opts, "shiftKey")
+	m.AltKey = optBool(opts, "altKey")
+	m.MetaKey = optBool(opts, "metaKey")
+}
+
+func (m *modifiers) GetModifierState(k string) bool {
+	switch k {
+	case "Control":
+		return m.CtrlKey
+	case "Shift":
+		return m.ShiftKey
+	case "Alt":
+		return m.AltKey
+	case "Meta":
+		return m.MetaKey
+	}
+	return false
+}
+
+var modifierProps = map[string]bool{
+	"ctrlKey":  true,
+	"shiftKey": true,
+	"altKey":   true,
+	"metaKey":  true,
+}
+
+type FocusEvent struct {
+	UIEvent
+
+	relatedTarget EventTarget
+}
+
+func (fe *FocusEvent) Getters() map[string]bool {
+	return merge(fe.UIEvent.Getters(), map[string]bool{
+		"relatedTarget": true,
+	})
+}
+
+func (fe *FocusEvent) RelatedTarget() js.Value {
+	if fe.relatedTarget == nil {
+		return js.Null()
+	}
+	return fe.relatedTarget.Obj()
+}
+
+type SubmitEvent struct {
 	Event
+
+	submitter *Element
+}
+
+func (se *SubmitEvent) Getters() map[string]bool {
+	return merge(se.Event.Getters(), map[string]bool{
+		"submitter": true,
+	})
+}
+
+func (se *SubmitEvent) Submitter() js.Value {
+	if se.submitter == nil {
+		return js.Null()
+	}
+	return se.submitter.Obj()
+}
+
+type MediaQueryListEvent struct {
+	Event
+
+	Media   string
+	Matches bool
+}
+
+func (me *MediaQueryListEvent) Props() map[string]bool {
+	return merge(me.Event.Props(), map[string]bool{
+		"media":   true,
+		"matches": true,
+	})
+}
+
+type InputEvent struct {
+	UIEvent
+
+	Data        string
+	InputType   string
+	IsComposing bool
+}
+
+func (ie *InputEvent) Props() map[string]bool {
+	return merge(ie.UIEvent.Props(), map[string]bool{
+		"data":        true,
+		"inputType":   true,
+		"isComposing": true,
+	})
+}
+
+type KeyboardEvent struct {
+	UIEvent
+	modifiers
+
+	Key         string
+	Code        string
+	Location    int
+	Repeat      bool
+	IsComposing bool
+	CharCode    int
+	KeyCode     int
+}
+
+func (ke *KeyboardEvent) Props() map[string]bool {
+	return merge(ke.UIEvent.Props(), modifierProps, map[string]bool{
+		"key":         true,
+		"code":        true,
+		"location":    true,
+		"repeat":      true,
+		"isComposing": true,
+		"charCode":    true,
+		"keyCode":     true,
+	})
+}
+
+func (ke *KeyboardEvent) Which() int {
+	if ke.KeyCode != 0 {
+		return ke.KeyCode
+	}
+	return ke.CharCode
+}
+
+// InitKeyboardEvent(type, bubbles, cancelable, view, key, location,
+// ctrlKey, altKey, shiftKey, metaKey)
+func (ke *KeyboardEvent) InitKeyboardEvent(t string, opts ...any) {
+	ke.InitUIEvent(t, opts...)
+	if ke.dispatching {
+		return
+	}
+	ke.Detail = 0
+	if len(opts) >= 4 {
+		ke.Key, _ = opts[3].(string)
+	}
+	if len(opts) >= 5 {
+		ke.Location = toInt(opts[4])
+	}
+	bs := []*bool{&ke.CtrlKey, &ke.AltKey, &ke.ShiftKey, &ke.MetaKey}
+	for i, b := range bs {
+		if len(opts) >= 6+i {
+			*b, _ = opts[5+i].(bool)
+		}
+	}
+}
+
+type MouseEvent struct {
+	UIEvent
+	modifiers
+
+	ScreenX       int
+	ScreenY       int
+	ClientX       int
+	ClientY       int
+	Button        int
+	Buttons       int
+	relatedTarget EventTarget
 }
 
 func NewMouseEvent(t string, opts map[string]any) (o *js.Object) {
-	e := Event{
-		Type: t,
+	return newEvent("MouseEvent", t, opts).event().Obj()
+}
+
+func (me *MouseEvent) mouse() *MouseEvent {
+	return me
+}
+
+func (me *MouseEvent) initMouse(opts map[string]any) {
+	me.initUI(opts)
+	me.initModifiers(opts)
+	me.ScreenX = int(optNum(opts, "screenX"))
+	me.ScreenY = int(optNum(opts, "screenY"))
+	me.ClientX = int(optNum(opts, "clientX"))
+	me.ClientY = int(optNum(opts, "clientY"))
+	me.Button = int(optNum(opts, "button"))
+	me.Buttons = int(optNum(opts, "buttons"))
+	me.relatedTarget = optTarget(opts, "relatedTarget")
+}
+
+func (me *MouseEvent) Getters() map[string]bool {
+	return merge(me.UIEvent.Getters(), map[string]bool{
+		"relatedTarget": true,
+		"x":             true,
+		"y":             true,
+		"pageX":         true,
+		"pageY":         true,
+		"offsetX":       true,
+		"offsetY":       true,
+	})
+}
+
+func (me *MouseEvent) Props() map[string]bool {
+	return merge(me.UIEvent.Props(), modifierProps, map[string]bool{
+		"screenX": true,
+		"screenY": true,
+		"clientX": true,
+		"clientY": true,
+		"button":  true,
+		"buttons": true,
+	})
+}
+
+func (me *MouseEvent) RelatedTarget() js.Value {
+	if me.relatedTarget == nil {
+		return js.Null()
+	}
+	return me.relatedTarget.Obj()
+}
+
+func (me *MouseEvent) X() int {
+	return me.ClientX
+}
+
+func (me *MouseEvent) Y() int {
+	return me.ClientY
+}
+
+func (me *MouseEvent) PageX() int {
+	x, _ := me.scrollOffset()
+	return me.ClientX + int(x)
+}
+
+func (me *MouseEvent) PageY() int {
+	_, y := me.scrollOffset()
+	return me.ClientY + int(y)
+}
+
+// scrollOffset of the view or else the window of the target
+func (me *MouseEvent) scrollOffset() (x, y float64) {
+	w := me.view
+	if n, ok := me.Target.(Node); ok && w == nil {
+		w = n.base().d.Window
+	}
+	if w == nil || w.Document == nil {
+		return
+	}
+	return w.scrollOffset()
+}
+
+func (me *MouseEvent) OffsetX() int {
+	return me.ClientX
+}
+
+func (me *MouseEvent) OffsetY() int {
+	return me.ClientY
+}
+
+func (me *MouseEvent) Which() int {
+	return me.Button + 1
+}
+
+// InitMouseEvent(type, bubbles, cancelable, view, detail, screenX,
+// screenY, clientX, clientY, ctrlKey, altKey, shiftKey, metaKey,
+// button, relatedTarget)
+func (me *MouseEvent) InitMouseEvent(t string, opts ...any) {
+	me.InitUIEvent(t, opts...)
+	if me.dispatching {
+		return
+	}
+	is := []*int{&me.ScreenX, &me.ScreenY, &me.ClientX, &me.ClientY}
+	for i, p := range is {
+		if len(opts) >= 5+i {
+			*p = toInt(opts[4+i])
+		}
+	}
+	bs := []*bool{&me.CtrlKey, &me.AltKey, &me.ShiftKey, &me.MetaKey}
+	for i, b := range bs {
+		if len(opts) >= 9+i {
+			*b, _ = opts[8+i].(bool)
+		}
+	}
+	if len(opts) >= 13 {
+		me.Button = toInt(opts[12])
 	}
-	if v, ok := opts["cancelable"]; ok {
-		e.Cancelable = v.(bool)
+	if len(opts) >= 14 {
+		me.relatedTarget, _ = opts[13].(EventTarget)
 	}
-	o = vm.NewDynamicObject(&MouseEvent{
-		Event: e,
+}
+
+type PointerEvent struct {
+	MouseEvent
+
+	PointerId          int
+	Width              float64
+	Height             float64
+	Pressure           float64
+	TangentialPressure float64
+	TiltX              int
+	TiltY              int
+	Twist              int
+	PointerType        string
+	IsPrimary          bool
+}
+
+func (pe *PointerEvent) Props() map[string]bool {
+	return merge(pe.MouseEvent.Props(), map[string]bool{
+		"pointerId":          true,
+		"width":              true,
+		"height":             true,
+		"pressure":           true,
+		"tangentialPressure": true,
+		"tiltX":              true,
+		"tiltY":              true,
+		"twist":              true,
+		"pointerType":        true,
+		"isPrimary":          true,
 	})
-	return
+}
+
+type WheelEvent struct {
+	MouseEvent
+
+	DeltaX    float64
+	DeltaY    float64
+	DeltaZ    float64
+	DeltaMode int
+}
+
+func (we *WheelEvent) Props() map[string]bool {
+	return merge(we.MouseEvent.Props(), map[string]bool{
+		"deltaX":    true,
+		"deltaY":    true,
+		"deltaZ":    true,
+		"deltaMode": true,
+	})
+}
+
+// createEventInterfaces maps the (lowercase) arguments accepted by
+// document.createEvent to event interfaces.
+var createEventInterfaces = map[string]string{
+	"customevent":   "CustomEvent",
+	"event":         "Event",
+	"events":        "Event",
+	"focusevent":    "FocusEvent",
+	"htmlevents":    "Event",
+	"keyboardevent": "KeyboardEvent",
+	"mouseevent":    "MouseEvent",
+	"mouseevents":   "MouseEvent",
+	"svgevents":     "Event",
+	"uievent":       "UIEvent",
+	"uievents":      "UIEvent",
+}
+
+func merge(ms ...map[string]bool) map[string]bool {
+	res := make(map[string]bool)
+	for _, m := range ms {
+		for k, v := range m {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+func optBool(opts map[string]any, k string) bool {
+	v, _ := opts[k].(bool)
+	return v
+}
+
+func optString(opts map[string]any, k string) string {
+	v, _ := opts[k].(string)
+	return v
+}
+
+func optNum(opts map[string]any, k string) float64 {
+	switch v := opts[k].(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+func optElement(opts map[string]any, k string) *Element {
+	el, _ := opts[k].(*Element)
+	return el
+}
+
+// optTarget returns the node, document or window of the option k
+func optTarget(opts map[string]any, k string) EventTarget {
+	t, _ := opts[k].(EventTarget)
+	return t
+}
+
+func toInt(x any) int {
+	switch v := x.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
 }
@@ -22,3 +22,131 @@ func TestNewEvent(t *testing.T) {
 		t.Fatalf("%v", e)
 	}
 }
+
+func TestUIEvents(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", "<body></body>", "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var e = new MouseEvent('click', {clientX: 10, clientY: 20, button: 2, shiftKey: true, bubbles: true});
+		[e instanceof MouseEvent, e instanceof UIEvent, e instanceof Event, e instanceof KeyboardEvent,
+		 e.clientX, e.clientY, e.button, e.shiftKey, e.ctrlKey, e.bubbles].join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != "true,true,true,false,10,20,2,true,false,true" {
+		t.Fatalf("%v", v)
+	}
+	res, err = vm.RunString(`
+		var k = new KeyboardEvent('keydown', {key: 'Enter', code: 'Enter', ctrlKey: true});
+		var i = new InputEvent('input', {inputType: 'insertText', data: 'a'});
+		var w = new WheelEvent('wheel', {deltaY: 1.5, deltaMode: WheelEvent.DOM_DELTA_LINE});
+		var p = new PointerEvent('pointerdown', {pointerId: 3});
+		[k.key, k.code, k.getModifierState('Control'), i.inputType, i.data,
+		 w.deltaY, w instanceof MouseEvent, p.pointerId, p instanceof PointerEvent,
+		 w.deltaMode, WheelEvent.DOM_DELTA_PIXEL, WheelEvent.DOM_DELTA_LINE, WheelEvent.DOM_DELTA_PAGE,
+		 w.DOM_DELTA_PAGE, KeyboardEvent.DOM_KEY_LOCATION_NUMPAD, k.DOM_KEY_LOCATION_RIGHT].join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != "Enter,Enter,true,insertText,a,1.5,true,3,true,1,0,1,2,2,3,2" {
+		t.Fatalf("%v", v)
+	}
+	res, err = vm.RunString(`
+		var tx = document.createTextNode('t');
+		var m = document.createEvent('MouseEvent');
+		m.initMouseEvent('click', true, true, window, 0, 0, 0, 0, 0, false, false, false, false, 0, tx);
+		[new FocusEvent('focus', {relatedTarget: document}).relatedTarget === document,
+		 new MouseEvent('mouseover', {relatedTarget: window}).relatedTarget === window,
+		 new FocusEvent('focus', {relatedTarget: tx}).relatedTarget === tx,
+		 m.relatedTarget === tx, new FocusEvent('focus').relatedTarget].join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != "true,true,true,true," {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestCreateEvent(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", "<body></body>", "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var e = document.createEvent('MouseEvents');
+		e.initMouseEvent('click', true, true, window, 1, 5, 6, 7, 8, false, true, false, false, 1, null);
+		var k = document.createEvent('KeyboardEvent');
+		k.initKeyboardEvent('keyup', true, false, window, 'a', 0, true);
+		var thrown;
+		try {
+			document.createEvent('foo');
+		} catch (ex) {
+			thrown = ex.name;
+		}
+		[e instanceof MouseEvent, e.type, e.clientX, e.clientY, e.altKey, e.button,
+		 k instanceof KeyboardEvent, k.key, k.ctrlKey, thrown].join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != "true,click,7,8,true,1,true,a,true,NotSupportedError" {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestDispatchEventResult(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", "<body><p id=p></p></body>", "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var p = document.getElementById('p');
+		var log = [];
+		for (var t of [p, document, window]) {
+			log.push(t.dispatchEvent(new Event('y', {cancelable: true})));
+			t.addEventListener('y', function(e) { e.preventDefault(); });
+			log.push(t.dispatchEvent(new Event('y', {cancelable: true})), t.dispatchEvent(new Event('y')));
+		}
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != "true,false,true,true,false,true,true,false,true" {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestEventPrototypes(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", "<body></body>", "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var log = [];
+		log.push(typeof Event.prototype.preventDefault, typeof MouseEvent.prototype.initMouseEvent,
+			'clientX' in MouseEvent.prototype, 'clientX' in UIEvent.prototype, 'key' in KeyboardEvent.prototype,
+			Object.prototype.toString.call(new MouseEvent('x')), Object.prototype.toString.call(new Event('x')),
+			Event.prototype.AT_TARGET, MouseEvent.BUBBLING_PHASE);
+		document.body.addEventListener('x', function(e) {
+			log.push(Object.getOwnPropertyDescriptor(Event.prototype, 'target').get.call(e) === document.body);
+		});
+		document.body.dispatchEvent(new Event('x'));
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != "function,function,true,false,true,[object MouseEvent],[object Event],2,3,true" {
+		t.Fatalf("%v", v)
+	}
+}
@@ -0,0 +1,101 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"github.com/psilva261/sparklefs/logger"
+)
+
+// domExceptionCodes are the legacy codes of DOMException names
+var domExceptionCodes = map[string]int{
+	"IndexSizeError":             1,
+	"HierarchyRequestError":      3,
+	"WrongDocumentError":         4,
+	"InvalidCharacterError":      5,
+	"NoModificationAllowedError": 7,
+	"NotFoundError":              8,
+	"NotSupportedError":          9,
+	"InUseAttributeError":        10,
+	"InvalidStateError":          11,
+	"SyntaxError":                12,
+	"InvalidModificationError":   13,
+	"NamespaceError":             14,
+	"InvalidAccessError":         15,
+	"TypeMismatchError":          17,
+	"SecurityError":              18,
+	"NetworkError":               19,
+	"AbortError":                 20,
+	"URLMismatchError":           21,
+	"QuotaExceededError":         22,
+	"TimeoutError":               23,
+	"InvalidNodeTypeError":       24,
+	"DataCloneError":             25,
+}
+
+var domExceptionCtor js.Value
+
+func initDOMException() (err error) {
+	domExceptionCtor, err = vm.RunString(`
+		function DOMException(message, name) {
+			this.message = message === undefined ? '' : String(message);
+			this.name = name === undefined ? 'Error' : String(name);
+			this.code = DOMException.__codes[this.name] || 0;
+		}
+		DOMException.prototype = Object.create(Error.prototype);
+		DOMException.prototype.constructor = DOMException;
+		DOMException.__codes = {};
+		DOMException;
+	`)
+	if err != nil {
+		return
+	}
+	c := domExceptionCtor.(*js.Object)
+	codes := c.Get("__codes").(*js.Object)
+	for name, code := range domExceptionCodes {
+		codes.Set(name, code)
+	}
+	consts := map[string]int{
+		"INDEX_SIZE_ERR":              1,
+		"DOMSTRING_SIZE_ERR":          2,
+		"HIERARCHY_REQUEST_ERR":       3,
+		"WRONG_DOCUMENT_ERR":          4,
+		"INVALID_CHARACTER_ERR":       5,
+		"NO_DATA_ALLOWED_ERR":         6,
+		"NO_MODIFICATION_ALLOWED_ERR": 7,
+		"NOT_FOUND_ERR":               8,
+		"NOT_SUPPORTED_ERR":           9,
+		"INUSE_ATTRIBUTE_ERR":         10,
+		"INVALID_STATE_ERR":           11,
+		"SYNTAX_ERR":                  12,
+		"INVALID_MODIFICATION_ERR":    13,
+		"NAMESPACE_ERR":               14,
+		"INVALID_ACCESS_ERR":          15,
+		"VALIDATION_ERR":              16,
+		"TYPE_MISMATCH_ERR":           17,
+		"SECURITY_ERR":                18,
+		"NETWORK_ERR":                 19,
+		"ABORT_ERR":                   20,
+		"URL_MISMATCH_ERR":            21,
+		"QUOTA_EXCEEDED_ERR":          22,
+		"TIMEOUT_ERR":                 23,
+		"INVALID_NODE_TYPE_ERR":       24,
+		"DATA_CLONE_ERR":              25,
+	}
+	proto := c.Get("prototype").(*js.Object)
+	for k, v := range consts {
+		c.Set(k, v)
+		proto.Set(k, v)
+	}
+	return
+}
+
+// throwDOMException throws a DOMException into the currently running
+// script
+func throwDOMException(name, msg string) {
+	log.Printf("throw %v: %v", name, msg)
+	o, err := vm.New(domExceptionCtor, vm.ToValue(msg), vm.ToValue(name))
+	if err != nil {
+		log.Errorf("new DOMException: %v", err)
+		panic(vm.NewTypeError(msg))
+	}
+	panic(o)
+}
@@ -0,0 +1,265 @@
+package dom
+
+import (
+	"github.com/psilva261/sparklefs/dom/sel"
+	"golang.org/x/net/html"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	sel.Focused = func(n *html.Node) bool {
+		return n != nil && focusedNode() == n
+	}
+	sel.FocusWithin = func(n *html.Node) bool {
+		for p := focusedNode(); p != nil; p = p.Parent {
+			if p == n {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// focusedNode returns the focused element of the window's document.
+// Documents without a window have no focus to match.
+func focusedNode() *html.Node {
+	if vm == nil {
+		return nil
+	}
+	w, ok := vm.GlobalObject().Export().(*Window)
+	if !ok || w.Document == nil {
+		return nil
+	}
+	if f := w.Document.focused; f != nil && connected(f.n) {
+		return f.n
+	}
+	return nil
+}
+
+// focusableByDefault is true for elements which are focusable without
+// a tabindex attribute
+func focusableByDefault(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	switch n.Data {
+	case "a", "area":
+		return hasAttr(*n, "href")
+	case "input":
+		return attr(*n, "type") != "hidden"
+	case "button", "select", "textarea", "iframe":
+		return true
+	case "summary":
+		return n.Parent != nil && n.Parent.Data == "details"
+	}
+	if ce, ok := attrOk(*n, "contenteditable"); ok && ce != "false" {
+		return true
+	}
+	return false
+}
+
+// tabIndex returns the parsed tabindex attribute or the default
+func tabIndex(n *html.Node) int {
+	if v, ok := attrOk(*n, "tabindex"); ok {
+		if i, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return i
+		}
+	}
+	if focusableByDefault(n) {
+		return 0
+	}
+	return -1
+}
+
+// focusable elements can be focused by script or click
+func focusable(n *html.Node) bool {
+	if n == nil || n.Type != html.ElementNode {
+		return false
+	}
+	if hasAttr(*n, "disabled") {
+		switch n.Data {
+		case "button", "input", "select", "textarea", "optgroup", "option", "fieldset":
+			return false
+		}
+	}
+	if !connected(n) {
+		return false
+	}
+	_, ok := attrOk(*n, "tabindex")
+	return ok || focusableByDefault(n)
+}
+
+// connected is true if n is part of a document tree
+func connected(n *html.Node) bool {
+	for ; n != nil; n = n.Parent {
+		if n.Type == html.DocumentNode {
+			return true
+		}
+	}
+	return false
+}
+
+func attrOk(n html.Node, key string) (val string, ok bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return
+}
+
+// activeElement is the focused element, falling back to <body>
+func (d *Document) activeElement() *Element {
+	if d.focused != nil && !connected(d.focused.n) {
+		d.focused = nil
+	}
+	if d.focused != nil {
+		return d.focused
+	}
+	return d.getEl(grep(d.doc, "body"))
+}
+
+func (d *Document) HasFocus() bool {
+	return true
+}
+
+// setFocus moves the focus to el (or clears it for nil) and runs the
+// focus update steps: blur, focusout, focus, focusin.
+func (d *Document) setFocus(el *Element) (consumed bool) {
+	old := d.focused
+	if old != nil && !connected(old.n) {
+		old = nil
+	}
+	if old == el {
+		return
+	}
+	if old != nil {
+		d.focused = nil
+		consumed = fireFocusEvent(old, "blur", el) || consumed
+		consumed = fireFocusEvent(old, "focusout", el) || consumed
+	}
+	if el != nil {
+		d.focused = el
+		d.focusSelection(el)
+		consumed = fireFocusEvent(el, "focus", old) || consumed
+		consumed = fireFocusEvent(el, "focusin", old) || consumed
+	}
+	return
+}
+
+func fireFocusEvent(el *Element, t string, related *Element) bool {
+	e := newEvent("FocusEvent", t, map[string]any{
+		"bubbles": t == "focusin" || t == "focusout",
+	}).(*FocusEvent)
+	if related != nil {
+		e.relatedTarget = related
+	}
+	e.view = el.d.Window
+	return el.dispatch(e)
+}
+
+// Focus the element if it is focusable
+func (el *Element) Focus(opts ...any) {
+	if !focusable(el.n) {
+		return
+	}
+	el.d.setFocus(el)
+}
+
+// Blur removes the focus if the element has it
+func (el *Element) Blur() {
+	if el.d.focused != el {
+		return
+	}
+	el.d.setFocus(nil)
+}
+
+func (el *Element) TabIndex() int {
+	return tabIndex(el.n)
+}
+
+// ClickFocus runs the focusing steps of a user click on el, focusing
+// the nearest focusable inclusive ancestor or clearing the focus.
+func (el *Element) ClickFocus() (consumed bool) {
+	for n := el.n; n != nil; n = n.Parent {
+		if focusable(n) {
+			return el.d.setFocus(el.d.getEl(n))
+		}
+	}
+	return el.d.setFocus(nil)
+}
+
+// tabOrder returns the sequential focus navigation order: positive
+// tabindex values ascending, then tabindex 0 in tree order.
+func (d *Document) tabOrder() (ns []*html.Node) {
+	var pos, zero []*html.Node
+	var f func(n *html.Node)
+	f = func(n *html.Node) {
+		if n.Type == html.ElementNode && focusable(n) {
+			if i := tabIndex(n); i > 0 {
+				pos = append(pos, n)
+			} else if i == 0 {
+				zero = append(zero, n)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+	}
+	f(d.doc)
+	sort.SliceStable(pos, func(i, j int) bool {
+		return tabIndex(pos[i]) < tabIndex(pos[j])
+	})
+	return append(pos, zero...)
+}
+
+// Tab moves the focus to the next (or previous) element in sequential
+// focus navigation order like pressing the tab key. A keydown listener
+// can cancel the navigation.
+func (d *Document) Tab(backward bool) (consumed bool) {
+	cur := d.activeElement()
+	ke := newEvent("KeyboardEvent", "keydown", map[string]any{
+		"bubbles":    true,
+		"cancelable": true,
+		"key":        "Tab",
+		"code":       "Tab",
+		"shiftKey":   backward,
+	}).(*KeyboardEvent)
+	ke.KeyCode = 9
+	ke.IsTrusted = true
+	if cur != nil {
+		consumed = cur.dispatch(ke)
+	}
+	if ke.DefaultPrevented {
+		return
+	}
+	order := d.tabOrder()
+	if len(order) == 0 {
+		return
+	}
+	i := -1
+	for j, n := range order {
+		if d.focused != nil && n == d.focused.n {
+			i = j
+			break
+		}
+	}
+	var next *html.Node
+	switch {
+	case i < 0 && backward:
+		next = order[len(order)-1]
+	case i < 0:
+		next = order[0]
+	case backward && i > 0:
+		next = order[i-1]
+	case !backward && i+1 < len(order):
+		next = order[i+1]
+	}
+	if next == nil {
+		// leaving the document: focus wraps to the viewport
+		return d.setFocus(nil) || consumed
+	}
+	return d.setFocus(d.getEl(next)) || consumed
+}
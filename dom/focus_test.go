@@ -0,0 +1,122 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"testing"
+)
+
+const focusHTML = `
+<html>
+<body>
+<form id="f">
+<input id="a">
+<span id="s">text</span>
+<button id="b" tabindex="2">b</button>
+<input id="c" tabindex="1">
+<input id="h" type="hidden">
+<button id="d" disabled>d</button>
+</form>
+</body>
+</html>
+`
+
+func TestFocusBlur(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", focusHTML, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var log = [];
+		var a = document.getElementById('a');
+		var b = document.getElementById('b');
+		['focus', 'blur', 'focusin', 'focusout'].forEach(function(t) {
+			a.addEventListener(t, function(e) { log.push('a:' + e.type); });
+			b.addEventListener(t, function(e) { log.push('b:' + e.type); });
+		});
+		document.getElementById('f').addEventListener('focusin', function(e) {
+			log.push('f:focusin:' + e.target.id);
+		});
+		a.focus();
+		b.focus();
+		log.push(document.activeElement.id);
+		b.blur();
+		log.push(document.activeElement.tagName);
+		document.getElementById('s').focus();
+		document.getElementById('d').focus();
+		log.push(document.activeElement.tagName);
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := "a:focus,a:focusin,f:focusin:a,a:blur,a:focusout,b:focus,b:focusin,f:focusin:b,b,b:blur,b:focusout,BODY,BODY"
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestFocusSelectors(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", focusHTML, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		document.getElementById('c').focus();
+		[document.querySelector(':focus').id,
+		 document.getElementById('f').matches(':focus-within'),
+		 document.getElementById('a').matches(':focus')].join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != "c,true,false" {
+		t.Fatalf("%v", v)
+	}
+	vm = js.New()
+	if _, err = Init(vm, "https://example.com", focusHTML, ""); err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err = vm.RunString(`
+		[document.querySelector(':focus'), document.body.matches(':focus-within')].join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != ",false" {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestTabOrder(t *testing.T) {
+	vm := js.New()
+	d, err := Init(vm, "https://example.com", focusHTML, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	ids := make([]string, 0, 4)
+	for i := 0; i < 4; i++ {
+		d.Tab(false)
+		ids = append(ids, d.activeElement().Id())
+	}
+	if v := ids; v[0] != "c" || v[1] != "b" || v[2] != "a" || v[3] != "" {
+		t.Fatalf("%v", v)
+	}
+	d.Tab(true)
+	if id := d.activeElement().Id(); id != "a" {
+		t.Fatalf("%v", id)
+	}
+	_, err = vm.RunString(`
+		document.addEventListener('keydown', function(e) {
+			if (e.key === 'Tab') e.preventDefault();
+		});
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	d.Tab(false)
+	if id := d.activeElement().Id(); id != "a" {
+		t.Fatalf("%v", id)
+	}
+}
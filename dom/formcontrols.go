@@ -0,0 +1,181 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"golang.org/x/net/html"
+	"strconv"
+)
+
+var (
+	formControlsCtor   *js.Object
+	formControlsProto  *js.Object
+	radioNodeListCtor  *js.Object
+	radioNodeListProto *js.Object
+	validityStateCtor  *js.Object
+)
+
+// initFormControls defines HTMLFormControlsCollection and RadioNodeList
+// which inherit from HTMLCollection and NodeList and ValidityState
+func initFormControls() (err error) {
+	c, err := vm.RunString(`(function(HTMLCollection, NodeList) {
+		function HTMLFormControlsCollection() {
+			throw new TypeError('Illegal constructor');
+		}
+		function RadioNodeList() {
+			throw new TypeError('Illegal constructor');
+		}
+		Object.setPrototypeOf(HTMLFormControlsCollection, HTMLCollection);
+		Object.setPrototypeOf(HTMLFormControlsCollection.prototype, HTMLCollection.prototype);
+		Object.defineProperty(HTMLFormControlsCollection.prototype, Symbol.toStringTag, { value: 'HTMLFormControlsCollection', configurable: true });
+		Object.setPrototypeOf(RadioNodeList, NodeList);
+		Object.setPrototypeOf(RadioNodeList.prototype, NodeList.prototype);
+		Object.defineProperty(RadioNodeList.prototype, Symbol.toStringTag, { value: 'RadioNodeList', configurable: true });
+		return [HTMLFormControlsCollection, RadioNodeList];
+	})`)
+	if err != nil {
+		return
+	}
+	f, _ := js.AssertFunction(c)
+	res, err := f(js.Undefined(), htmlCollectionCtor, nodeListCtor)
+	if err != nil {
+		return
+	}
+	ctors := res.(*js.Object)
+	formControlsCtor = ctors.Get("0").(*js.Object)
+	formControlsProto = formControlsCtor.Get("prototype").(*js.Object)
+	radioNodeListCtor = ctors.Get("1").(*js.Object)
+	radioNodeListProto = radioNodeListCtor.Get("prototype").(*js.Object)
+	protoMethods(formControlsProto, "namedItem")
+	protoGetters(radioNodeListProto, "value")
+	validityStateCtor = newCtor("ValidityState", func(call js.ConstructorCall) *js.Object {
+		panic(vm.NewTypeError("Illegal constructor"))
+	})
+	proto := validityStateCtor.Get("prototype").(*js.Object)
+	proto.DefineDataPropertySymbol(js.SymToStringTag, vm.ToValue("ValidityState"), js.FLAG_FALSE, js.FLAG_TRUE, js.FLAG_FALSE)
+	protoMembers(proto, &ValidityState{}, nil)
+	return
+}
+
+// HTMLFormControlsCollection is the collection of form.elements. Its
+// named items are RadioNodeLists when several controls share the name.
+type HTMLFormControlsCollection struct {
+	*HTMLCollection
+}
+
+func (fc *HTMLFormControlsCollection) Obj() *js.Object {
+	return fc.obj(fc, formControlsProto)
+}
+
+// Get returns indexed elements, expandos and named items
+func (fc *HTMLFormControlsCollection) Get(k string) js.Value {
+	v := fc.HTMLCollection.Get(k)
+	if _, err := strconv.Atoi(k); err == nil || v == nil {
+		return v
+	}
+	if _, ok := fc.vars[k]; ok {
+		return v
+	}
+	return fc.NamedItem(k)
+}
+
+// NamedItem returns null, the only element with the id or name k or
+// else a RadioNodeList of all of them
+func (fc *HTMLFormControlsCollection) NamedItem(k string) js.Value {
+	ns := fc.named(k)
+	switch len(ns) {
+	case 0:
+		return js.Null()
+	case 1:
+		return fc.d.getEl(ns[0]).Obj()
+	}
+	rl := &RadioNodeList{
+		NodeList: &NodeList{
+			d: fc.d,
+			f: func() []*html.Node {
+				return fc.named(k)
+			},
+		},
+	}
+	return rl.Obj()
+}
+
+// named returns the elements with the id or name k
+func (fc *HTMLFormControlsCollection) named(k string) (ns []*html.Node) {
+	if k == "" {
+		return
+	}
+	for _, n := range fc.elements() {
+		if attr(*n, "id") == k || attr(*n, "name") == k {
+			ns = append(ns, n)
+		}
+	}
+	return
+}
+
+func (fc *HTMLFormControlsCollection) ToString() string {
+	return "[object HTMLFormControlsCollection]"
+}
+
+// RadioNodeList is a live list of the form controls with the same name
+type RadioNodeList struct {
+	*NodeList
+}
+
+func (rl *RadioNodeList) Obj() *js.Object {
+	obj, ok := nlObjRefs[rl.NodeList]
+	if ok {
+		return obj
+	}
+	obj = vm.NewDynamicObject(rl)
+	if radioNodeListProto != nil {
+		obj.SetPrototype(radioNodeListProto)
+	}
+	nlObjRefs[rl.NodeList] = obj
+	return obj
+}
+
+func (rl *RadioNodeList) Getters() map[string]bool {
+	return map[string]bool{
+		"length": true,
+		"value":  true,
+	}
+}
+
+// radios returns the radio buttons of the list
+func (rl *RadioNodeList) radios() (ns []*html.Node) {
+	for _, n := range rl.f() {
+		if n.Data == "input" && inputType(n) == "radio" {
+			ns = append(ns, n)
+		}
+	}
+	return
+}
+
+// Value of the first checked radio button
+func (rl *RadioNodeList) Value() string {
+	for _, n := range rl.radios() {
+		if rl.d.checkedness(n) {
+			return rl.d.controlValue(n)
+		}
+	}
+	return ""
+}
+
+// Set checks the first radio button with the assigned value
+func (rl *RadioNodeList) Set(k string, desc js.PropertyDescriptor) bool {
+	if k != "value" {
+		return rl.NodeList.Set(k, desc)
+	}
+	v := desc.Value.String()
+	for _, n := range rl.radios() {
+		if hasAttr(*n, "value") && attr(*n, "value") == v || !hasAttr(*n, "value") && v == "on" {
+			rl.d.setChecked(n, true)
+			break
+		}
+	}
+	return true
+}
+
+func (rl *RadioNodeList) ToString() string {
+	return "[object RadioNodeList]"
+}
@@ -0,0 +1,526 @@
+package dom
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/psilva261/sparkle/js"
+	"github.com/psilva261/sparklefs/logger"
+	"golang.org/x/net/html"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// formEntry is an entry of a form's entry list
+type formEntry struct {
+	name     string
+	value    string
+	filename string
+	file     bool
+}
+
+// FormData is an entry list as constructed from a form or by script
+type FormData struct {
+	entries []formEntry
+}
+
+var formDataRefs = make(map[*js.Object]*FormData)
+
+var formDataCtor *js.Object
+
+func initFormData() {
+	formDataRefs = make(map[*js.Object]*FormData)
+	formDataCtor = newCtor("FormData", func(call js.ConstructorCall) *js.Object {
+		fd := &FormData{}
+		if a := call.Argument(0); !js.IsUndefined(a) {
+			f, ok := a.Export().(*Element)
+			if !ok || f.n.Data != "form" {
+				panic(vm.NewTypeError("FormData: argument is not a form"))
+			}
+			var sub *html.Node
+			if s, ok := call.Argument(1).Export().(*Element); ok {
+				if !submitButton(s.n) || formOwner(s.n) != f.n {
+					panic(vm.NewTypeError("FormData: invalid submitter"))
+				}
+				sub = s.n
+			}
+			fd.entries = f.d.constructEntryList(f.n, sub)
+		}
+		formDataRefs[call.This] = fd
+		return nil
+	})
+	proto := formDataCtor.Get("prototype").(*js.Object)
+	method := func(name string, f func(fd *FormData, args []js.Value) js.Value) {
+		proto.Set(name, func(call js.FunctionCall) js.Value {
+			o, _ := call.This.(*js.Object)
+			fd, ok := formDataRefs[o]
+			if !ok {
+				panic(vm.NewTypeError("FormData." + name + ": illegal invocation"))
+			}
+			return f(fd, call.Arguments)
+		})
+	}
+	str := func(args []js.Value, i int) string {
+		if i < len(args) {
+			return args[i].String()
+		}
+		return "undefined"
+	}
+	method("append", func(fd *FormData, args []js.Value) js.Value {
+		fd.Append(str(args, 0), str(args, 1), fileArg(args)...)
+		return js.Undefined()
+	})
+	method("set", func(fd *FormData, args []js.Value) js.Value {
+		fd.Set(str(args, 0), str(args, 1), fileArg(args)...)
+		return js.Undefined()
+	})
+	method("delete", func(fd *FormData, args []js.Value) js.Value {
+		fd.Delete(str(args, 0))
+		return js.Undefined()
+	})
+	method("get", func(fd *FormData, args []js.Value) js.Value {
+		if v, ok := fd.Get(str(args, 0)); ok {
+			return vm.ToValue(v)
+		}
+		return js.Null()
+	})
+	method("getAll", func(fd *FormData, args []js.Value) js.Value {
+		vs := fd.GetAll(str(args, 0))
+		items := make([]any, 0, len(vs))
+		for _, v := range vs {
+			items = append(items, v)
+		}
+		return vm.NewArray(items...)
+	})
+	method("has", func(fd *FormData, args []js.Value) js.Value {
+		_, ok := fd.Get(str(args, 0))
+		return vm.ToValue(ok)
+	})
+	iterator := func(f func(e formEntry) any) func(fd *FormData, args []js.Value) js.Value {
+		return func(fd *FormData, args []js.Value) js.Value {
+			items := make([]any, 0, len(fd.entries))
+			for _, e := range fd.entries {
+				items = append(items, f(e))
+			}
+			arr := vm.NewArray(items...)
+			values, ok := js.AssertFunction(arr.Get("values"))
+			if !ok {
+				log.Errorf("FormData: array values is not a function")
+				return js.Undefined()
+			}
+			it, err := values(arr)
+			if err != nil {
+				log.Errorf("FormData: values: %v", err)
+				return js.Undefined()
+			}
+			return it
+		}
+	}
+	entries := iterator(func(e formEntry) any {
+		return vm.NewArray(e.name, e.value)
+	})
+	method("entries", entries)
+	method("keys", iterator(func(e formEntry) any {
+		return e.name
+	}))
+	method("values", iterator(func(e formEntry) any {
+		return e.value
+	}))
+	method("forEach", func(fd *FormData, args []js.Value) js.Value {
+		var cb js.Value = js.Undefined()
+		if len(args) > 0 {
+			cb = args[0]
+		}
+		fn, ok := js.AssertFunction(cb)
+		if !ok {
+			panic(vm.NewTypeError("FormData.forEach: callback is not a function"))
+		}
+		for _, e := range append([]formEntry{}, fd.entries...) {
+			if _, err := fn(js.Undefined(), vm.ToValue(e.value), vm.ToValue(e.name)); err != nil {
+				panic(err)
+			}
+		}
+		return js.Undefined()
+	})
+	proto.SetSymbol(js.SymIterator, proto.Get("entries"))
+}
+
+// fileArg returns the optional filename argument of append and set
+func fileArg(args []js.Value) []string {
+	if len(args) >= 3 && !js.IsUndefined(args[2]) {
+		return []string{args[2].String()}
+	}
+	return nil
+}
+
+func (fd *FormData) Append(name, value string, filename ...string) {
+	e := formEntry{name: name, value: value}
+	if len(filename) > 0 {
+		e.file = true
+		e.filename = filename[0]
+	}
+	fd.entries = append(fd.entries, e)
+}
+
+// Set replaces the first entry with name and removes all others
+func (fd *FormData) Set(name, value string, filename ...string) {
+	e := formEntry{name: name, value: value}
+	if len(filename) > 0 {
+		e.file = true
+		e.filename = filename[0]
+	}
+	es := fd.entries[:0]
+	replaced := false
+	for _, ee := range fd.entries {
+		if ee.name != name {
+			es = append(es, ee)
+		} else if !replaced {
+			es = append(es, e)
+			replaced = true
+		}
+	}
+	if !replaced {
+		es = append(es, e)
+	}
+	fd.entries = es
+}
+
+func (fd *FormData) Delete(name string) {
+	es := fd.entries[:0]
+	for _, e := range fd.entries {
+		if e.name != name {
+			es = append(es, e)
+		}
+	}
+	fd.entries = es
+}
+
+func (fd *FormData) Get(name string) (string, bool) {
+	for _, e := range fd.entries {
+		if e.name == name {
+			return e.value, true
+		}
+	}
+	return "", false
+}
+
+func (fd *FormData) GetAll(name string) (vs []string) {
+	for _, e := range fd.entries {
+		if e.name == name {
+			vs = append(vs, e.value)
+		}
+	}
+	return
+}
+
+// submitButton is true for elements which can submit a form
+func submitButton(n *html.Node) bool {
+	switch n.Data {
+	case "button":
+		t := strings.ToLower(attr(*n, "type"))
+		return t != "reset" && t != "button"
+	case "input":
+		t := inputType(n)
+		return t == "submit" || t == "image"
+	}
+	return false
+}
+
+// disabledControl is true for disabled form controls or controls
+// inside a disabled fieldset (but not in its first legend)
+func disabledControl(n *html.Node) bool {
+	if hasAttr(*n, "disabled") {
+		return true
+	}
+	for c, p := n, n.Parent; p != nil; c, p = p, p.Parent {
+		if p.Type != html.ElementNode || p.Data != "fieldset" || !hasAttr(*p, "disabled") {
+			continue
+		}
+		if c.Data == "legend" && firstLegend(p) == c {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func firstLegend(fs *html.Node) *html.Node {
+	for c := fs.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "legend" {
+			return c
+		}
+	}
+	return nil
+}
+
+// constructEntryList of form with the optional submitter
+func (d *Document) constructEntryList(form, submitter *html.Node) (es []formEntry) {
+	for _, n := range formElements(form) {
+		if disabledControl(n) {
+			continue
+		}
+		inDatalist := false
+		for p := n.Parent; p != nil; p = p.Parent {
+			if p.Data == "datalist" {
+				inDatalist = true
+			}
+		}
+		if inDatalist {
+			continue
+		}
+		if submitButton(n) && n != submitter {
+			continue
+		}
+		if n.Data == "object" || n.Data == "output" || n.Data == "fieldset" {
+			continue
+		}
+		if n.Data == "button" && !submitButton(n) {
+			continue
+		}
+		t := ""
+		if n.Data == "input" {
+			t = inputType(n)
+			if (t == "checkbox" || t == "radio") && !d.checkedness(n) {
+				continue
+			}
+			if t == "reset" || t == "button" {
+				continue
+			}
+		}
+		name := attr(*n, "name")
+		if t == "image" {
+			if name != "" {
+				name += "."
+			}
+			es = append(es, formEntry{name: name + "x", value: "0"})
+			es = append(es, formEntry{name: name + "y", value: "0"})
+			continue
+		}
+		if name == "" {
+			continue
+		}
+		switch {
+		case n.Data == "select":
+			for _, o := range d.selectedOptions(n) {
+				if !hasAttr(*o, "disabled") {
+					es = append(es, formEntry{name: name, value: optionValue(o)})
+				}
+			}
+		case t == "checkbox" || t == "radio":
+			es = append(es, formEntry{name: name, value: d.controlValue(n)})
+		case t == "file":
+			es = append(es, formEntry{name: name, file: true})
+		case t == "hidden" && strings.EqualFold(name, "_charset_"):
+			es = append(es, formEntry{name: name, value: "UTF-8"})
+		default:
+			es = append(es, formEntry{name: name, value: d.controlValue(n)})
+		}
+		if dn := attr(*n, "dirname"); dn != "" && (n.Data == "textarea" || t == "text" || t == "search") {
+			es = append(es, formEntry{name: dn, value: "ltr"})
+		}
+	}
+	return
+}
+
+// normalizeNewlines converts lone CR and LF into CRLF
+func normalizeNewlines(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
+
+// urlEncode serializes the entries as application/x-www-form-urlencoded
+func urlEncode(es []formEntry) string {
+	parts := make([]string, 0, len(es))
+	for _, e := range es {
+		v := e.value
+		if e.file {
+			v = e.filename
+		}
+		parts = append(parts, url.QueryEscape(normalizeNewlines(e.name))+"="+url.QueryEscape(normalizeNewlines(v)))
+	}
+	return strings.Join(parts, "&")
+}
+
+// multipartEncode serializes the entries as multipart/form-data and
+// returns the body and content type
+func multipartEncode(es []formEntry) (body []byte, contentType string, err error) {
+	buf := bytes.NewBuffer(nil)
+	mw := multipart.NewWriter(buf)
+	for _, e := range es {
+		name := normalizeNewlines(e.name)
+		if e.file {
+			fw, err := mw.CreateFormFile(name, e.filename)
+			if err != nil {
+				return nil, "", err
+			}
+			if _, err = fw.Write([]byte(e.value)); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+		if err = mw.WriteField(name, normalizeNewlines(e.value)); err != nil {
+			return nil, "", err
+		}
+	}
+	if err = mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), mw.FormDataContentType(), nil
+}
+
+// plainEncode serializes the entries as text/plain
+func plainEncode(es []formEntry) string {
+	var b strings.Builder
+	for _, e := range es {
+		v := e.value
+		if e.file {
+			v = e.filename
+		}
+		b.WriteString(e.name + "=" + v + "\r\n")
+	}
+	return b.String()
+}
+
+// submitAttr returns the submitter's override (e.g. formmethod) or the
+// form's attribute
+func submitAttr(form, submitter *html.Node, key string) string {
+	if submitter != nil {
+		if v, ok := attrOk(*submitter, "form"+key); ok {
+			return v
+		}
+	}
+	return attr(*form, key)
+}
+
+// submitForm runs the form submission algorithm. submit() calls it
+// with fromMethod set which skips validation and the submit event.
+func (d *Document) submitForm(form, submitter *html.Node, fromMethod bool) {
+	if !connected(form) {
+		return
+	}
+	if submitter == nil {
+		submitter = form
+	}
+	sub := submitter
+	if sub == form {
+		sub = nil
+	}
+	if !fromMethod {
+		noValidate := hasAttr(*form, "novalidate") || (sub != nil && hasAttr(*sub, "formnovalidate"))
+		if !noValidate && !d.getEl(form).CheckValidity() {
+			return
+		}
+		e := newEvent("SubmitEvent", "submit", map[string]any{
+			"bubbles":    true,
+			"cancelable": true,
+		}).(*SubmitEvent)
+		if sub != nil {
+			e.submitter = d.getEl(sub)
+		}
+		e.IsTrusted = true
+		d.getEl(form).DispatchEvent(e)
+		if e.DefaultPrevented || !connected(form) {
+			return
+		}
+	}
+	es := d.constructEntryList(form, sub)
+	req, err := d.submissionRequest(form, sub, es)
+	if err != nil {
+		log.Errorf("form submission: %v", err)
+		return
+	}
+	if req != nil {
+		d.submission = req
+		addMutation(d, Value, form)
+	}
+}
+
+// submissionRequest creates the request to navigate to for the entry
+// list es
+func (d *Document) submissionRequest(form, sub *html.Node, es []formEntry) (req *http.Request, err error) {
+	action := strings.TrimSpace(submitAttr(form, sub, "action"))
+	base, err := url.Parse(d.url)
+	if err != nil {
+		return nil, fmt.Errorf("parse document url %v: %w", d.url, err)
+	}
+	u, err := base.Parse(action)
+	if err != nil {
+		return nil, fmt.Errorf("parse action %v: %w", action, err)
+	}
+	method := strings.ToLower(submitAttr(form, sub, "method"))
+	switch method {
+	case "post", "dialog":
+	default:
+		method = "get"
+	}
+	enctype := strings.ToLower(submitAttr(form, sub, "enctype"))
+	switch enctype {
+	case "multipart/form-data", "text/plain":
+	default:
+		enctype = "application/x-www-form-urlencoded"
+	}
+	switch {
+	case method == "dialog":
+		log.Printf("form submission: dialog method not supported")
+		return nil, nil
+	case u.Scheme != "http" && u.Scheme != "https":
+		log.Printf("form submission: unsupported scheme %v", u.Scheme)
+		return nil, nil
+	case method == "get":
+		u.RawQuery = urlEncode(es)
+		return http.NewRequest(http.MethodGet, u.String(), nil)
+	}
+	var body []byte
+	switch enctype {
+	case "multipart/form-data":
+		body, enctype, err = multipartEncode(es)
+		if err != nil {
+			return nil, fmt.Errorf("multipart: %w", err)
+		}
+	case "text/plain":
+		body = []byte(plainEncode(es))
+	default:
+		body = []byte(urlEncode(es))
+	}
+	req, err = http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", enctype)
+	return
+}
+
+// Submission returns and clears the request of the last form
+// submission that wasn't prevented
+func Submission(d *Document) (req *http.Request) {
+	req, d.submission = d.submission, nil
+	return
+}
+
+// Submit the form without validation or submit event
+func (el *Element) Submit() {
+	if el.n.Data != "form" {
+		return
+	}
+	el.d.submitForm(el.n, nil, true)
+}
+
+// RequestSubmit submits the form like a click on submitter would
+func (el *Element) RequestSubmit(submitter ...*Element) {
+	if el.n.Data != "form" {
+		return
+	}
+	var sub *html.Node
+	if len(submitter) > 0 && submitter[0] != nil {
+		sub = submitter[0].n
+		if !submitButton(sub) {
+			panic(vm.NewTypeError("requestSubmit: submitter is not a submit button"))
+		}
+		if formOwner(sub) != el.n {
+			throwDOMException("NotFoundError", "requestSubmit: submitter is not owned by the form")
+		}
+	}
+	el.d.submitForm(el.n, sub, false)
+}
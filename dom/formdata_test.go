@@ -0,0 +1,154 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"io"
+	"strings"
+	"testing"
+)
+
+const submitHTML = `
+<html>
+<body>
+<form id="f" action="/search" method="post">
+<input name="q" value="a b">
+<input name="c" type="checkbox" value="yes" checked>
+<input name="u" type="checkbox">
+<input name="dis" value="x" disabled>
+<select name="s" multiple>
+<option selected>1</option>
+<option value="2" selected>two</option>
+</select>
+<textarea name="t">line1
+line2</textarea>
+<button id="b" name="btn" value="go">Go</button>
+<button id="g" formmethod="get" formaction="/get">Get</button>
+</form>
+</body>
+</html>
+`
+
+func TestFormData(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com/page", submitHTML, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var fd = new FormData(document.getElementById('f'), document.getElementById('b'));
+		fd.append('extra', 'e');
+		fd.set('q', 'replaced');
+		var log = [fd.get('q'), fd.getAll('s').join('+'), fd.has('u'), fd.get('u')];
+		for (var e of fd) {
+			log.push(e[0]);
+		}
+		fd.delete('s');
+		log.push(Array.from(fd.keys()).length);
+		[{}, null, document.body, 'f'].forEach(function(a) {
+			try {
+				new FormData(a);
+				log.push('ok');
+			} catch (e) {
+				log.push(e instanceof TypeError);
+			}
+		});
+		log.push(Array.from(new FormData(undefined).keys()).length);
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := "replaced,1+2,false,,q,c,s,s,t,btn,extra,5,true,true,true,true,0"
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	vm := js.New()
+	d, err := Init(vm, "https://example.com/page", submitHTML, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	_, err = vm.RunString(`
+		var submitter = '';
+		var f = document.getElementById('f');
+		f.addEventListener('submit', function(e) {
+			submitter = e.submitter.id;
+		});
+		document.getElementById('b').click();
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	req := Submission(d)
+	if req == nil {
+		t.Fatalf("no submission")
+	}
+	if req.Method != "POST" || req.URL.String() != "https://example.com/search" {
+		t.Fatalf("%v %v", req.Method, req.URL)
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "application/x-www-form-urlencoded" {
+		t.Fatalf("%v", ct)
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if exp := "q=a+b&c=yes&s=1&s=2&t=line1%0D%0Aline2&btn=go"; string(body) != exp {
+		t.Fatalf("%s", body)
+	}
+
+	_, err = vm.RunString(`
+		f.addEventListener('submit', function(e) {
+			e.preventDefault();
+		});
+		document.getElementById('b').click();
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if req := Submission(d); req != nil {
+		t.Fatalf("%v", req)
+	}
+
+	_, err = vm.RunString(`
+		f.setAttribute('enctype', 'multipart/form-data');
+		f.submit();
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	req = Submission(d)
+	if req == nil || !strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data; boundary=") {
+		t.Fatalf("%v", req)
+	}
+	if err := req.ParseMultipartForm(1024); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := req.MultipartForm.Value["t"]; len(v) != 1 || v[0] != "line1\r\nline2" {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestSubmitGet(t *testing.T) {
+	vm := js.New()
+	d, err := Init(vm, "https://example.com/page", submitHTML, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	_, err = vm.RunString(`
+		var f = document.getElementById('f');
+		f.requestSubmit(document.getElementById('g'));
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	req := Submission(d)
+	if req == nil || req.Method != "GET" {
+		t.Fatalf("%v", req)
+	}
+	if u := req.URL.String(); u != "https://example.com/get?q=a+b&c=yes&s=1&s=2&t=line1%0D%0Aline2" {
+		t.Fatalf("%v", u)
+	}
+}
@@ -0,0 +1,1052 @@
+package dom
+
+import (
+	"fmt"
+	"github.com/psilva261/sparkle/js"
+	"github.com/psilva261/sparklefs/logger"
+	"golang.org/x/net/html"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// controlState is the state of a form control (or <option>) which is
+// kept separate from its content attributes
+type controlState struct {
+	value      string
+	dirtyValue bool
+
+	// userEdited is set when the value was last changed by the user
+	userEdited bool
+
+	checked      bool
+	dirtyChecked bool
+	selected     bool
+	dirtySelect  bool
+
+	// noneSelected is set on a <select> whose selection was
+	// explicitly cleared
+	noneSelected bool
+
+	customError string
+}
+
+// state returns the control state of n for changing it
+func (d *Document) state(n *html.Node) *controlState {
+	s, ok := d.controls[n]
+	if !ok {
+		s = &controlState{}
+		d.controls[n] = s
+	}
+	return s
+}
+
+// stateOf returns the control state of n without adding it. Controls
+// which were never changed have the zero state.
+func (d *Document) stateOf(n *html.Node) controlState {
+	if s, ok := d.controls[n]; ok {
+		return *s
+	}
+	return controlState{}
+}
+
+// inputType returns the normalized type of an <input>
+func inputType(n *html.Node) string {
+	t := strings.ToLower(strings.TrimSpace(attr(*n, "type")))
+	switch t {
+	case "hidden", "search", "tel", "url", "email", "password", "date",
+		"month", "week", "time", "datetime-local", "number", "range",
+		"color", "checkbox", "radio", "file", "submit", "image", "reset",
+		"button":
+		return t
+	}
+	return "text"
+}
+
+// valueMode of an <input> according to its type
+func valueMode(n *html.Node) string {
+	switch inputType(n) {
+	case "hidden", "submit", "image", "reset", "button":
+		return "default"
+	case "checkbox", "radio":
+		return "default/on"
+	case "file":
+		return "filename"
+	}
+	return "value"
+}
+
+// listed elements are associated with forms
+func listed(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	switch n.Data {
+	case "button", "fieldset", "object", "output", "select", "textarea":
+		return true
+	case "input":
+		return inputType(n) != "image"
+	}
+	return false
+}
+
+// formOwner of a listed element
+func formOwner(n *html.Node) *html.Node {
+	if id, ok := attrOk(*n, "form"); ok {
+		root := n
+		for root.Parent != nil {
+			root = root.Parent
+		}
+		f := grepById(root, id)
+		if f != nil && f.Data == "form" {
+			return f
+		}
+		return nil
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == "form" {
+			return p
+		}
+	}
+	return nil
+}
+
+// formElements returns the listed elements of form f in tree order
+func formElements(f *html.Node) (ns []*html.Node) {
+	root := f
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if listed(n) && formOwner(n) == f {
+			ns = append(ns, n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return
+}
+
+// selectOptions returns the list of options of a <select>
+func selectOptions(n *html.Node) (opts []*html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		if c.Data == "option" {
+			opts = append(opts, c)
+		} else if c.Data == "optgroup" {
+			for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+				if cc.Type == html.ElementNode && cc.Data == "option" {
+					opts = append(opts, cc)
+				}
+			}
+		}
+	}
+	return
+}
+
+// ownerSelect of an <option>
+func ownerSelect(n *html.Node) *html.Node {
+	p := n.Parent
+	if p != nil && p.Data == "optgroup" {
+		p = p.Parent
+	}
+	if p != nil && p.Type == html.ElementNode && p.Data == "select" {
+		return p
+	}
+	return nil
+}
+
+func singleSelect(n *html.Node) bool {
+	if hasAttr(*n, "multiple") {
+		return false
+	}
+	size, err := strconv.Atoi(attr(*n, "size"))
+	return err != nil || size <= 1
+}
+
+// selectedness of an option without the select's selectedness
+// setting algorithm
+func (d *Document) rawSelected(n *html.Node) bool {
+	if s := d.stateOf(n); s.dirtySelect {
+		return s.selected
+	}
+	return hasAttr(*n, "selected")
+}
+
+// selectedOptions computes the selected options of a <select>
+func (d *Document) selectedOptions(n *html.Node) (sel []*html.Node) {
+	opts := selectOptions(n)
+	for _, o := range opts {
+		if d.rawSelected(o) {
+			sel = append(sel, o)
+		}
+	}
+	if !hasAttr(*n, "multiple") {
+		if len(sel) > 1 {
+			sel = sel[len(sel)-1:]
+		}
+		if len(sel) == 0 && singleSelect(n) && !d.stateOf(n).noneSelected {
+			for _, o := range opts {
+				if !hasAttr(*o, "disabled") {
+					return []*html.Node{o}
+				}
+			}
+		}
+	}
+	return
+}
+
+func (d *Document) optionSelected(n *html.Node) bool {
+	s := ownerSelect(n)
+	if s == nil {
+		return d.rawSelected(n)
+	}
+	for _, o := range d.selectedOptions(s) {
+		if o == n {
+			return true
+		}
+	}
+	return false
+}
+
+// setSelected sets the selectedness of option o, deselecting the other
+// options of single selects
+func (d *Document) setSelected(o *html.Node, yes bool) {
+	s := ownerSelect(o)
+	if s != nil && !hasAttr(*s, "multiple") && yes {
+		for _, oo := range selectOptions(s) {
+			st := d.state(oo)
+			st.selected = false
+			st.dirtySelect = true
+		}
+	}
+	if s != nil {
+		d.state(s).noneSelected = false
+	}
+	st := d.state(o)
+	st.selected = yes
+	st.dirtySelect = true
+}
+
+func (d *Document) selectIndex(n *html.Node, i int) {
+	opts := selectOptions(n)
+	for j, o := range opts {
+		st := d.state(o)
+		st.selected = i == j
+		st.dirtySelect = true
+	}
+	d.state(n).noneSelected = i < 0 || i >= len(opts)
+}
+
+func optionValue(n *html.Node) string {
+	if v, ok := attrOk(*n, "value"); ok {
+		return v
+	}
+	return optionText(n)
+}
+
+func optionText(n *html.Node) string {
+	return strings.Join(strings.Fields(textContent(n)), " ")
+}
+
+func textContent(n *html.Node) (t string) {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		t += textContent(c)
+	}
+	return
+}
+
+// controlValue returns the value IDL attribute
+func (d *Document) controlValue(n *html.Node) string {
+	switch n.Data {
+	case "input":
+		switch valueMode(n) {
+		case "value":
+			if s := d.stateOf(n); s.dirtyValue {
+				return s.value
+			}
+			return sanitizeValue(n, attr(*n, "value"))
+		case "default/on":
+			if v, ok := attrOk(*n, "value"); ok {
+				return v
+			}
+			return "on"
+		case "filename":
+			return ""
+		}
+		return attr(*n, "value")
+	case "textarea":
+		if s := d.stateOf(n); s.dirtyValue {
+			return s.value
+		}
+		return textContent(n)
+	case "select":
+		sel := d.selectedOptions(n)
+		if len(sel) == 0 {
+			return ""
+		}
+		return optionValue(sel[0])
+	case "option":
+		return optionValue(n)
+	}
+	return attr(*n, "value")
+}
+
+// sanitizeValue runs the value sanitization algorithm of some input
+// types
+func sanitizeValue(n *html.Node, v string) string {
+	switch inputType(n) {
+	case "text", "search", "tel", "password":
+		v = strings.ReplaceAll(v, "\r", "")
+		v = strings.ReplaceAll(v, "\n", "")
+	case "email", "url":
+		v = strings.TrimSpace(strings.ReplaceAll(v, "\n", ""))
+	case "number":
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			v = ""
+		}
+	}
+	return v
+}
+
+// setControlValue sets the value IDL attribute
+func (d *Document) setControlValue(n *html.Node, v string) {
+	switch n.Data {
+	case "input":
+		switch valueMode(n) {
+		case "value":
+			s := d.state(n)
+			s.value = sanitizeValue(n, v)
+			s.dirtyValue = true
+			s.userEdited = false
+		case "filename":
+			// only the empty string is allowed
+		default:
+			setAttr(n, "value", v)
+		}
+	case "textarea":
+		s := d.state(n)
+		s.value = v
+		s.dirtyValue = true
+		s.userEdited = false
+	case "select":
+		found := false
+		for _, o := range selectOptions(n) {
+			st := d.state(o)
+			st.selected = !found && optionValue(o) == v
+			st.dirtySelect = true
+			if st.selected {
+				found = true
+			}
+		}
+		d.state(n).noneSelected = !found
+	default:
+		setAttr(n, "value", v)
+	}
+}
+
+func (d *Document) checkedness(n *html.Node) bool {
+	if s := d.stateOf(n); s.dirtyChecked {
+		return s.checked
+	}
+	return hasAttr(*n, "checked")
+}
+
+// setChecked sets the checkedness of an input and unchecks the other
+// radio buttons of its group
+func (d *Document) setChecked(n *html.Node, yes bool) {
+	s := d.state(n)
+	s.checked = yes
+	s.dirtyChecked = true
+	if yes && inputType(n) == "radio" {
+		for _, o := range radioGroup(n) {
+			if o != n {
+				st := d.state(o)
+				st.checked = false
+				st.dirtyChecked = true
+			}
+		}
+	}
+}
+
+// radioGroup returns the radio buttons in the same group as n
+func radioGroup(n *html.Node) (g []*html.Node) {
+	name := attr(*n, "name")
+	if name == "" {
+		return []*html.Node{n}
+	}
+	f := formOwner(n)
+	var root *html.Node
+	if f != nil {
+		root = f
+		for root.Parent != nil {
+			root = root.Parent
+		}
+	} else {
+		root = n
+		for root.Parent != nil {
+			root = root.Parent
+		}
+	}
+	var walk func(c *html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.ElementNode && c.Data == "input" && inputType(c) == "radio" && attr(*c, "name") == name && formOwner(c) == f {
+			g = append(g, c)
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc)
+		}
+	}
+	walk(root)
+	return
+}
+
+// resetControl runs the reset algorithm of a listed element
+func (d *Document) resetControl(n *html.Node) {
+	delete(d.controls, n)
+	if n.Data == "select" {
+		for _, o := range selectOptions(n) {
+			delete(d.controls, o)
+		}
+	}
+}
+
+func (el *Element) DefaultValue() string {
+	if el.n.Data == "textarea" {
+		return textContent(el.n)
+	}
+	return attr(*el.n, "value")
+}
+
+func (el *Element) DefaultChecked() bool {
+	return hasAttr(*el.n, "checked")
+}
+
+func (el *Element) DefaultSelected() bool {
+	return hasAttr(*el.n, "selected")
+}
+
+func (el *Element) SelectedIndex() int {
+	if el.n.Data != "select" {
+		return -1
+	}
+	sel := el.d.selectedOptions(el.n)
+	if len(sel) == 0 {
+		return -1
+	}
+	for i, o := range selectOptions(el.n) {
+		if o == sel[0] {
+			return i
+		}
+	}
+	return -1
+}
+
+func (el *Element) SelectedOptions() js.Value {
+	if el.n.Data != "select" {
+		return js.Undefined()
+	}
+	return el.collection("selectedOptions", func() []*html.Node {
+		return el.d.selectedOptions(el.n)
+	}).Obj()
+}
+
+// Index of an <option> in its select's list of options
+func (el *Element) Index() int {
+	if s := ownerSelect(el.n); s != nil {
+		for i, o := range selectOptions(s) {
+			if o == el.n {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// Text of an <option> (or textContent of other elements)
+func (el *Element) Text() string {
+	if el.n.Data == "option" {
+		return optionText(el.n)
+	}
+	return el.text()
+}
+
+// Form returns the form owner of listed elements
+func (el *Element) Form() js.Value {
+	n := el.n
+	if n.Data == "option" {
+		if s := ownerSelect(n); s != nil {
+			n = s
+		}
+	}
+	if !listed(n) {
+		return js.Undefined()
+	}
+	if f := formOwner(n); f != nil {
+		return el.d.getEl(f).Obj()
+	}
+	return js.Null()
+}
+
+// Elements of a <form>
+func (el *Element) Elements() js.Value {
+	if el.n.Data != "form" {
+		return js.Undefined()
+	}
+	hc := el.collection("elements", func() []*html.Node {
+		return formElements(el.n)
+	})
+	fc := &HTMLFormControlsCollection{HTMLCollection: hc}
+	return fc.Obj()
+}
+
+// Reset a <form>
+func (el *Element) Reset() {
+	if el.n.Data != "form" {
+		return
+	}
+	e := newEvent("Event", "reset", map[string]any{
+		"bubbles":    true,
+		"cancelable": true,
+	})
+	el.DispatchEvent(e)
+	if e.event().DefaultPrevented {
+		return
+	}
+	for _, n := range formElements(el.n) {
+		el.d.resetControl(n)
+	}
+	addMutation(el.d, Value, el.n)
+}
+
+// UserInput sets the value of a control as if typed in by the user and
+// fires the input and change events.
+func (el *Element) UserInput(v string) (consumed bool) {
+	var e eventer
+	switch el.n.Data {
+	case "input", "textarea":
+		el.d.setControlValue(el.n, v)
+		el.d.state(el.n).userEdited = true
+		e = newEvent("InputEvent", "input", map[string]any{
+			"bubbles":   true,
+			"composed":  true,
+			"inputType": "insertReplacementText",
+			"data":      v,
+		})
+	case "select":
+		el.d.setControlValue(el.n, v)
+		e = newEvent("Event", "input", map[string]any{
+			"bubbles":  true,
+			"composed": true,
+		})
+	default:
+		log.Errorf("user input on %v", el.n.Data)
+		return false
+	}
+	e.event().IsTrusted = true
+	consumed = el.dispatch(e)
+	return el.fireChange() || consumed
+}
+
+func (el *Element) fireChange() bool {
+	e := newEvent("Event", "change", map[string]any{
+		"bubbles": true,
+	})
+	e.event().IsTrusted = true
+	return el.dispatch(e)
+}
+
+// willValidate is false for elements barred from constraint validation
+func willValidate(n *html.Node) bool {
+	if !listed(n) {
+		return false
+	}
+	switch n.Data {
+	case "fieldset", "object", "output":
+		return false
+	case "input":
+		switch inputType(n) {
+		case "hidden", "reset", "button":
+			return false
+		}
+		if hasAttr(*n, "readonly") {
+			return false
+		}
+	case "button":
+		if t := strings.ToLower(attr(*n, "type")); t == "reset" || t == "button" {
+			return false
+		}
+	case "textarea":
+		if hasAttr(*n, "readonly") {
+			return false
+		}
+	}
+	for p := n; p != nil; p = p.Parent {
+		if p.Type != html.ElementNode {
+			continue
+		}
+		if p.Data == "datalist" {
+			return false
+		}
+		if hasAttr(*p, "disabled") && (p == n || p.Data == "fieldset") {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidityState of a form control
+type ValidityState struct {
+	ValueMissing    bool
+	TypeMismatch    bool
+	PatternMismatch bool
+	TooLong         bool
+	TooShort        bool
+	RangeUnderflow  bool
+	RangeOverflow   bool
+	StepMismatch    bool
+	BadInput        bool
+	CustomError     bool
+	Valid           bool
+}
+
+func (d *Document) validity(n *html.Node) (v *ValidityState) {
+	v = &ValidityState{}
+	if s := d.stateOf(n); s.customError != "" {
+		v.CustomError = true
+	}
+	val := d.controlValue(n)
+	required := hasAttr(*n, "required")
+	switch n.Data {
+	case "input":
+		t := inputType(n)
+		switch t {
+		case "checkbox":
+			v.ValueMissing = required && !d.checkedness(n)
+		case "radio":
+			missing := true
+			for _, r := range radioGroup(n) {
+				if d.checkedness(r) {
+					missing = false
+				}
+				if hasAttr(*r, "required") {
+					required = true
+				}
+			}
+			v.ValueMissing = required && missing
+		default:
+			v.ValueMissing = required && val == ""
+		}
+		if val != "" {
+			switch t {
+			case "email":
+				for _, a := range strings.Split(val, ",") {
+					if !hasAttr(*n, "multiple") && strings.Contains(val, ",") {
+						v.TypeMismatch = true
+					}
+					if _, err := mail.ParseAddress(strings.TrimSpace(a)); err != nil || !strings.Contains(a, "@") {
+						v.TypeMismatch = true
+					}
+				}
+			case "url":
+				if u, err := url.Parse(val); err != nil || u.Scheme == "" {
+					v.TypeMismatch = true
+				}
+			case "number", "range":
+				f, err := strconv.ParseFloat(val, 64)
+				if err != nil {
+					v.BadInput = true
+					break
+				}
+				if min, err := strconv.ParseFloat(attr(*n, "min"), 64); err == nil && f < min {
+					v.RangeUnderflow = true
+				}
+				if max, err := strconv.ParseFloat(attr(*n, "max"), 64); err == nil && f > max {
+					v.RangeOverflow = true
+				}
+			}
+			if p, ok := attrOk(*n, "pattern"); ok && p != "" && !patternMatches(p, val) {
+				v.PatternMismatch = true
+			}
+		}
+		v.tooLongShort(d, n, val)
+	case "textarea":
+		v.ValueMissing = required && val == ""
+		v.tooLongShort(d, n, val)
+	case "select":
+		if required {
+			sel := d.selectedOptions(n)
+			v.ValueMissing = len(sel) == 0 || (len(sel) == 1 && optionValue(sel[0]) == "" && isPlaceholder(n, sel[0]))
+		}
+	}
+	v.Valid = !(v.ValueMissing || v.TypeMismatch || v.PatternMismatch ||
+		v.TooLong || v.TooShort || v.RangeUnderflow || v.RangeOverflow ||
+		v.StepMismatch || v.BadInput || v.CustomError)
+	return
+}
+
+// tooLongShort checks maxlength/minlength which only apply to values
+// last changed by the user
+func (v *ValidityState) tooLongShort(d *Document, n *html.Node, val string) {
+	if !d.stateOf(n).userEdited || val == "" {
+		return
+	}
+	l := utf16Len(val)
+	if max, err := strconv.Atoi(attr(*n, "maxlength")); err == nil && l > max {
+		v.TooLong = true
+	}
+	if min, err := strconv.Atoi(attr(*n, "minlength")); err == nil && l < min {
+		v.TooShort = true
+	}
+}
+
+// patternMatches is true if val matches the whole pattern p which is
+// compiled as JS RegExp with the v flag. The VM doesn't support that
+// flag yet, so the u flag is used instead. Invalid patterns match
+// anything.
+func patternMatches(p, val string) bool {
+	ctor := vm.Get("RegExp")
+	src := vm.ToValue("^(?:" + p + ")$")
+	re, err := vm.New(ctor, src, vm.ToValue("v"))
+	if err != nil {
+		re, err = vm.New(ctor, src, vm.ToValue("u"))
+	}
+	if err != nil {
+		log.Errorf("pattern %v: %v", p, err)
+		return true
+	}
+	test, ok := js.AssertFunction(re.Get("test"))
+	if !ok {
+		return true
+	}
+	res, err := test(re, vm.ToValue(val))
+	if err != nil {
+		log.Errorf("pattern %v: %v", p, err)
+		return true
+	}
+	return res.ToBoolean()
+}
+
+// isPlaceholder is true for the placeholder label option of a select
+func isPlaceholder(s, o *html.Node) bool {
+	if hasAttr(*s, "multiple") || !singleSelect(s) {
+		return false
+	}
+	opts := selectOptions(s)
+	return len(opts) > 0 && opts[0] == o && o.Parent == s
+}
+
+func (v *ValidityState) Obj() *js.Object {
+	o := vm.NewDynamicObject(v)
+	o.SetPrototype(validityStateCtor.Get("prototype").(*js.Object))
+	return o
+}
+
+func (v *ValidityState) Getters() map[string]bool {
+	return map[string]bool{}
+}
+
+func (v *ValidityState) Props() map[string]bool {
+	return map[string]bool{
+		"valueMissing":    true,
+		"typeMismatch":    true,
+		"patternMismatch": true,
+		"tooLong":         true,
+		"tooShort":        true,
+		"rangeUnderflow":  true,
+		"rangeOverflow":   true,
+		"stepMismatch":    true,
+		"badInput":        true,
+		"customError":     true,
+		"valid":           true,
+	}
+}
+
+func (v *ValidityState) Get(k string) js.Value {
+	if res, ok := GetCall(v, k); ok {
+		return res
+	}
+	return nil
+}
+
+func (v *ValidityState) Set(k string, desc js.PropertyDescriptor) bool {
+	return false
+}
+
+func (v *ValidityState) Has(k string) bool {
+	return HasCall(v, k)
+}
+
+func (v *ValidityState) Delete(k string) bool {
+	return false
+}
+
+func (v *ValidityState) Keys() []string {
+	return Calls(v)
+}
+
+func (v *ValidityState) ToString() string {
+	return "[object ValidityState]"
+}
+
+func (el *Element) Validity() js.Value {
+	if !listed(el.n) {
+		return js.Undefined()
+	}
+	return el.d.validity(el.n).Obj()
+}
+
+func (el *Element) WillValidate() bool {
+	return willValidate(el.n)
+}
+
+func (el *Element) ValidationMessage() string {
+	if !willValidate(el.n) {
+		return ""
+	}
+	v := el.d.validity(el.n)
+	switch {
+	case v.CustomError:
+		return el.d.stateOf(el.n).customError
+	case v.ValueMissing:
+		return "Please fill out this field."
+	case v.TypeMismatch:
+		return fmt.Sprintf("Please enter a valid %v.", inputType(el.n))
+	case v.PatternMismatch:
+		return "Please match the requested format."
+	case v.TooLong, v.TooShort:
+		return "Please adjust the length of this text."
+	case v.RangeUnderflow, v.RangeOverflow:
+		return "Please select a value within the allowed range."
+	case v.BadInput:
+		return "Please enter a number."
+	}
+	return ""
+}
+
+func (el *Element) SetCustomValidity(msg string) {
+	el.d.state(el.n).customError = msg
+}
+
+// CheckValidity of a control or all controls of a form, firing invalid
+// events on invalid controls
+func (el *Element) CheckValidity() bool {
+	if el.n.Data == "form" {
+		valid := true
+		for _, n := range formElements(el.n) {
+			if !el.d.getEl(n).CheckValidity() {
+				valid = false
+			}
+		}
+		return valid
+	}
+	if !willValidate(el.n) || el.d.validity(el.n).Valid {
+		return true
+	}
+	e := newEvent("Event", "invalid", map[string]any{
+		"cancelable": true,
+	})
+	el.DispatchEvent(e)
+	return false
+}
+
+func (el *Element) ReportValidity() bool {
+	return el.CheckValidity()
+}
+
+// hasActivation is true for elements with activation behavior for
+// click events
+func hasActivation(n *html.Node) bool {
+	switch n.Data {
+	case "button":
+		return true
+	case "input":
+		switch inputType(n) {
+		case "checkbox", "radio", "submit", "image", "reset", "button":
+			return true
+		}
+	}
+	return false
+}
+
+// activate runs the legacy-pre-activation behavior of checkboxes and
+// radio buttons. It returns the previous checkedness.
+func (el *Element) activate() (wasChecked bool) {
+	if el.n.Data != "input" {
+		return
+	}
+	switch inputType(el.n) {
+	case "checkbox":
+		wasChecked = el.d.checkedness(el.n)
+		el.d.setChecked(el.n, !wasChecked)
+	case "radio":
+		wasChecked = el.d.checkedness(el.n)
+		el.d.setChecked(el.n, true)
+	}
+	return
+}
+
+// deactivate runs the legacy-canceled-activation behavior
+func (el *Element) deactivate(wasChecked bool) {
+	if el.n.Data != "input" {
+		return
+	}
+	switch inputType(el.n) {
+	case "checkbox", "radio":
+		if inputType(el.n) == "checkbox" || !wasChecked {
+			s := el.d.state(el.n)
+			s.checked = wasChecked
+			s.dirtyChecked = true
+		}
+	}
+}
+
+// postActivate runs the activation behavior after the dispatch of a
+// click: checkboxes and radio buttons fire input and change events,
+// submit and reset buttons act on their form owner.
+func (el *Element) postActivate(wasChecked bool) (consumed bool) {
+	t := el.Type()
+	switch {
+	case el.n.Data == "input" && (t == "checkbox" || t == "radio"):
+		if el.d.checkedness(el.n) == wasChecked {
+			return false
+		}
+		if !connected(el.n) {
+			return true
+		}
+		e := newEvent("Event", "input", map[string]any{
+			"bubbles":  true,
+			"composed": true,
+		})
+		el.DispatchEvent(e)
+		el.fireChange()
+		addMutation(el.d, Value, el.n)
+		return true
+	case submitButton(el.n), t == "reset":
+		if disabledControl(el.n) {
+			return false
+		}
+		f := formOwner(el.n)
+		if f == nil {
+			return false
+		}
+		if t == "reset" {
+			el.d.getEl(f).Reset()
+		} else {
+			el.d.submitForm(f, el.n, false)
+		}
+		return true
+	}
+	return false
+}
+
+// RenderState renders el like OuterHTML but with the form control
+// state (values, checkedness, selectedness) written to the attributes.
+func RenderState(el *Element) string {
+	return render(copyTree(el.n, el.d.applyState))
+}
+
+// cloneState copies the value and checkedness of the <input> or
+// <textarea> n and the selectedness of the <option> n to its clone c in
+// the document to
+func (d *Document) cloneState(n, c *html.Node, to *Document) {
+	s, ok := d.controls[n]
+	if !ok {
+		return
+	}
+	switch n.Data {
+	case "input", "textarea":
+		to.controls[c] = &controlState{
+			value:        s.value,
+			dirtyValue:   s.dirtyValue,
+			userEdited:   s.userEdited,
+			checked:      s.checked,
+			dirtyChecked: s.dirtyChecked,
+		}
+	case "option":
+		to.controls[c] = &controlState{
+			selected:    s.selected,
+			dirtySelect: s.dirtySelect,
+		}
+	}
+}
+
+// applyState reflects the state of the original node n to its copy c
+func (d *Document) applyState(n, c *html.Node) {
+	if n.Type != html.ElementNode {
+		return
+	}
+	switch n.Data {
+	case "input":
+		if _, ok := d.controls[n]; !ok {
+			return
+		}
+		switch valueMode(n) {
+		case "value":
+			setAttrQuiet(c, "value", d.controlValue(n))
+		case "default/on":
+			if d.checkedness(n) {
+				setAttrQuiet(c, "checked", "")
+			} else {
+				rmAttrQuiet(c, "checked")
+			}
+		}
+	case "textarea":
+		if s := d.stateOf(n); s.dirtyValue {
+			for c.FirstChild != nil {
+				c.RemoveChild(c.FirstChild)
+			}
+			c.AppendChild(&html.Node{
+				Type: html.TextNode,
+				Data: s.value,
+			})
+		}
+	case "option":
+		s := ownerSelect(n)
+		if s == nil {
+			return
+		}
+		changed := false
+		for _, o := range selectOptions(s) {
+			if _, ok := d.controls[o]; ok {
+				changed = true
+			}
+		}
+		if !changed {
+			return
+		}
+		if d.optionSelected(n) {
+			setAttrQuiet(c, "selected", "")
+		} else {
+			rmAttrQuiet(c, "selected")
+		}
+	}
+}
+
+func setAttrQuiet(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+func rmAttrQuiet(n *html.Node, key string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr = append(n.Attr[:i:i], n.Attr[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"strings"
+	"testing"
+)
+
+const formHTML = `
+<html>
+<body>
+<form id="f">
+<input id="t" name="t" value="initial">
+<input id="c" type="checkbox" checked>
+<input id="r1" type="radio" name="r" checked>
+<input id="r2" type="radio" name="r">
+<select id="s">
+<option value="x">X</option>
+<option id="o2" selected>Y</option>
+</select>
+<textarea id="ta">text</textarea>
+<input id="e" type="email" required>
+</form>
+<input id="outside" form="f">
+<input id="r3" type="radio" name="r" checked>
+</body>
+</html>
+`
+
+func TestFormState(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", formHTML, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var log = [];
+		var t = document.getElementById('t');
+		t.value = 'typed';
+		log.push(t.value, t.defaultValue, t.getAttribute('value'));
+		var c = document.getElementById('c');
+		c.checked = false;
+		log.push(c.checked, c.defaultChecked);
+		document.getElementById('r2').checked = true;
+		log.push(document.getElementById('r1').checked, document.getElementById('r3').checked);
+		var s = document.getElementById('s');
+		log.push(s.value, s.selectedIndex, s.type);
+		s.value = 'x';
+		log.push(s.selectedIndex, document.getElementById('o2').selected);
+		var ta = document.getElementById('ta');
+		ta.value = 'new';
+		log.push(ta.value, ta.defaultValue);
+		var f = document.getElementById('f');
+		log.push(f.elements.length, f.length, document.getElementById('outside').form.id);
+		f.reset();
+		log.push(t.value, c.checked, s.value, ta.value);
+		t.value = null;
+		ta.value = null;
+		log.push(t.value === '', ta.value === '');
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := "typed,initial,initial,false,true,false,true,Y,1,select-one,0,false,new,text,8,8,f,initial,true,Y,text,true,true"
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestFormCollections(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", formHTML, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var log = [];
+		var s = document.getElementById('s');
+		s.selectedIndex = 1;
+		log.push(s.options.length, s.options[1].id, s.options.item(0).value, s.options instanceof HTMLCollection, s.options === s.options);
+		log.push(s.selectedOptions.length, s.selectedOptions[0].id, s.selectedOptions instanceof HTMLCollection);
+		s.options[0].selected = true;
+		log.push(s.selectedOptions[0].value, s.selectedOptions[1]);
+		var f = document.getElementById('f');
+		var els = f.elements;
+		log.push(els instanceof HTMLFormControlsCollection, els instanceof HTMLCollection, els === f.elements, els.namedItem('t').id, els.namedItem('nope'));
+		var r = els.namedItem('r');
+		document.getElementById('r2').setAttribute('value', 'b');
+		log.push(String(r), r instanceof RadioNodeList, r instanceof NodeList, r.length, r[1].id, r.value, els.r.length);
+		r.value = 'b';
+		log.push(document.getElementById('r2').checked, document.getElementById('r1').checked, r.value);
+		r.value = 'on';
+		log.push(document.getElementById('r1').checked, r.value);
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := "2,o2,x,true,true,1,o2,true,x,,true,true,true,t,,[object RadioNodeList],true,true,2,r2,on,2,true,false,b,true,on"
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestCheckboxClick(t *testing.T) {
+	vm := js.New()
+	d, err := Init(vm, "https://example.com", formHTML, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var log = [];
+		var c = document.getElementById('c');
+		c.addEventListener('click', function(e) {
+			log.push('click:' + c.checked);
+		});
+		c.addEventListener('change', function(e) {
+			log.push('change:' + c.checked);
+		});
+		c.click();
+		var cancel = function(e) { e.preventDefault(); };
+		c.addEventListener('click', cancel);
+		c.click();
+		log.push(c.checked);
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != "click:false,change:false,click:true,false" {
+		t.Fatalf("%v", v)
+	}
+	h := RenderState(d.Element())
+	if !strings.Contains(h, `<input id="c" type="checkbox"/>`) {
+		t.Fatalf("%v", h)
+	}
+}
+
+func TestValidity(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", formHTML, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var log = [];
+		var e = document.getElementById('e');
+		e.addEventListener('invalid', function() { log.push('invalid'); });
+		log.push(e.validity.valueMissing, e.checkValidity());
+		e.value = 'no-at';
+		log.push(e.validity.typeMismatch);
+		e.value = 'a@example.com';
+		log.push(e.validity.valid);
+		e.setCustomValidity('custom');
+		log.push(e.validity.customError, e.validationMessage);
+		log.push(e.validity instanceof ValidityState, Object.prototype.toString.call(e.validity), 'tooLong' in ValidityState.prototype);
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != "invalid,true,false,true,true,true,custom,true,[object ValidityState],true" {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestPatternAndLength(t *testing.T) {
+	vm := js.New()
+	d, err := Init(vm, "https://example.com", `<html><body><input id="p" pattern="(?=.*\d)[a-z\d]{3,}"><input id="l" maxlength="2" minlength="2"></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var log = [];
+		var p = document.getElementById('p');
+		var l = document.getElementById('l');
+		log.push(p.validity.valid, l.value, l.checkValidity());
+		p.value = 'abc';
+		log.push(p.validity.patternMismatch);
+		p.value = 'ab1';
+		log.push(p.validity.patternMismatch);
+		l.value = 'abc';
+		log.push(l.validity.tooLong, l.validity.tooShort, l.checkValidity());
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != "true,,true,true,false,false,false,true" {
+		t.Fatalf("%v", v)
+	}
+	l := d.Element().QuerySelector("#l")
+	l.UserInput("\U0001F600")
+	res, err = vm.RunString(`l.validity.tooLong + ',' + l.validity.tooShort`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != "false,false" {
+		t.Fatalf("%v", v)
+	}
+	l.UserInput("a\U0001F600")
+	res, err = vm.RunString(`l.validity.tooLong + ',' + l.checkValidity()`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != "true,false" {
+		t.Fatalf("%v", v)
+	}
+	if len(d.controls) != 2 {
+		t.Fatalf("%v", d.controls)
+	}
+}
+
+func TestUserInput(t *testing.T) {
+	vm := js.New()
+	d, err := Init(vm, "https://example.com", formHTML, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	_, err = vm.RunString(`
+		var log = [];
+		var t = document.getElementById('t');
+		t.addEventListener('input', function(e) { log.push(e.inputType + ':' + t.value); });
+		document.addEventListener('change', function(e) { log.push('change:' + e.target.id); });
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	d.Element().QuerySelector("#t").UserInput("hello")
+	res, err := vm.RunString(`log.join(',')`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != "insertReplacementText:hello,change:t" {
+		t.Fatalf("%v", v)
+	}
+	if h := RenderState(d.Element()); !strings.Contains(h, `value="hello"`) {
+		t.Fatalf("%v", h)
+	}
+}
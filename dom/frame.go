@@ -0,0 +1,174 @@
+package dom
+
+import (
+	"fmt"
+	"github.com/psilva261/sparkle/js"
+	"github.com/psilva261/sparklefs/logger"
+	"golang.org/x/net/html"
+)
+
+// frame is the browsing context of a connected iframe. Documents of
+// frames aren't loaded, the global object only emulates a realm of its
+// own with distinct Object, TypeError and Proxy constructors so that
+// e.g. callbacks created in the frame throw errors of the frame.
+type frame struct {
+	global      *js.Object
+	objectProto *js.Object
+	typeError   *js.Object
+	// proxies is a WeakSet of the proxies created in the frame
+	proxies *js.Object
+}
+
+var newRealm js.Callable
+
+func initFrames() (err error) {
+	v, err := vm.RunString(`
+		(function() {
+			const BaseObject = Object, BaseTypeError = TypeError, BaseProxy = Proxy;
+
+			return function(parent) {
+				const proxies = new WeakSet();
+				const RealmObject = { Object: function(value) {
+					if (new.target === undefined && value !== null && value !== undefined) {
+						return BaseObject(value);
+					}
+				}}.Object;
+				for (const k of BaseObject.getOwnPropertyNames(BaseObject)) {
+					if (k !== 'length' && k !== 'name' && k !== 'prototype') {
+						RealmObject[k] = BaseObject[k];
+					}
+				}
+				const RealmTypeError = { TypeError: function(message) {
+					const e = new BaseTypeError(message);
+					BaseObject.setPrototypeOf(e, (new.target || RealmTypeError).prototype);
+					return e;
+				}}.TypeError;
+				BaseObject.setPrototypeOf(RealmTypeError, BaseTypeError);
+				RealmTypeError.prototype = BaseObject.create(BaseTypeError.prototype, {
+					constructor: { value: RealmTypeError, writable: true, configurable: true }
+				});
+				const RealmProxy = { Proxy: function(target, handler) {
+					const p = new BaseProxy(target, handler);
+					proxies.add(p);
+					return p;
+				}}.Proxy;
+				RealmProxy.revocable = function(target, handler) {
+					const r = BaseProxy.revocable(target, handler);
+					proxies.add(r.proxy);
+					return r;
+				};
+				const g = {
+					Object: RealmObject,
+					TypeError: RealmTypeError,
+					Proxy: RealmProxy,
+					parent: parent,
+					top: parent
+				};
+				g.window = g.self = g.frames = g;
+				return { global: g, proxies: proxies };
+			};
+		})();
+	`)
+	if err != nil {
+		return
+	}
+	fn, ok := js.AssertFunction(v)
+	if !ok {
+		return fmt.Errorf("realm factory is not a function")
+	}
+	newRealm = fn
+	return
+}
+
+// frame returns the browsing context of the iframe n. Only connected
+// iframes of the window's document have one.
+func (d *Document) frame(n *html.Node) *frame {
+	if d.Window == nil || !connected(n) {
+		return nil
+	}
+	if f, ok := d.frames[n]; ok {
+		return f
+	}
+	v, err := newRealm(js.Undefined(), d.Window.Obj())
+	if err != nil {
+		log.Errorf("new realm: %v", err)
+		return nil
+	}
+	r := v.(*js.Object)
+	g := r.Get("global").(*js.Object)
+	f := &frame{
+		global:      g,
+		objectProto: g.Get("Object").(*js.Object).Get("prototype").(*js.Object),
+		typeError:   g.Get("TypeError").(*js.Object),
+		proxies:     r.Get("proxies").(*js.Object),
+	}
+	d.frames[n] = f
+	return f
+}
+
+// discardFrames discards the browsing contexts of iframes which aren't
+// connected anymore
+func (d *Document) discardFrames() {
+	for n := range d.frames {
+		if !connected(n) {
+			delete(d.frames, n)
+		}
+	}
+}
+
+// owns is true if the object o was created in the realm of f
+func (f *frame) owns(o *js.Object) bool {
+	if o.ExportType() == proxyType {
+		has, ok := js.AssertFunction(f.proxies.Get("has"))
+		if !ok {
+			return false
+		}
+		res, err := has(f.proxies, o)
+		return err == nil && res.ToBoolean()
+	}
+	for p := o; p != nil; p = p.Prototype() {
+		if p.SameAs(f.objectProto) {
+			return true
+		}
+	}
+	return false
+}
+
+// frameOf returns the frame of the window's document whose realm the
+// object v was created in or nil for the realm of the window
+func frameOf(v js.Value) *frame {
+	o, ok := v.(*js.Object)
+	if !ok || vm == nil {
+		return nil
+	}
+	w, ok := vm.GlobalObject().Export().(*Window)
+	if !ok || w.Document == nil {
+		return nil
+	}
+	for _, f := range w.Document.frames {
+		if f.owns(o) {
+			return f
+		}
+	}
+	return nil
+}
+
+// throwTypeError throws a TypeError of the realm the object v was
+// created in
+func throwTypeError(v js.Value, msg string) {
+	if f := frameOf(v); f != nil {
+		if e, err := vm.New(f.typeError, vm.ToValue(msg)); err == nil {
+			panic(e)
+		}
+	}
+	panic(vm.NewTypeError(msg))
+}
+
+// revoked is true if v is a revoked proxy
+func revoked(v js.Value) bool {
+	o, ok := v.(*js.Object)
+	if !ok || o.ExportType() != proxyType {
+		return false
+	}
+	return o.Export().(js.Proxy).Handler() == nil
+}
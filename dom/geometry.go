@@ -0,0 +1,446 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"github.com/psilva261/sparklefs/logger"
+	"golang.org/x/net/html"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	domRectReadOnlyCtor *js.Object
+	domRectCtor         *js.Object
+	domRectListCtor     *js.Object
+)
+
+// frameDuration is how long geometry from the host is cached unless
+// the tree changes
+const frameDuration = 16 * time.Millisecond
+
+var (
+	geomCache = make(map[*html.Node]box)
+	geomFrame time.Time
+)
+
+// box is the border box of an element in document coordinates. ok is
+// false if the host has no geometry for it.
+type box struct {
+	x1, y1, x2, y2 float64
+	ok             bool
+}
+
+// resetGeom invalidates the cached geometry, e.g. after a mutation
+func resetGeom() {
+	geomCache = make(map[*html.Node]box)
+	laidOut = make(map[*Document]bool)
+	geomFrame = time.Now()
+}
+
+// initGeometry defines DOMRectReadOnly, DOMRect and DOMRectList
+func initGeometry() {
+	ctor := func(readOnly bool) func(call js.ConstructorCall) *js.Object {
+		return func(call js.ConstructorCall) *js.Object {
+			var xs [4]float64
+			for i := range xs {
+				if a := call.Argument(i); !js.IsUndefined(a) {
+					xs[i] = a.ToFloat()
+				}
+			}
+			r := &DOMRect{x: xs[0], y: xs[1], w: xs[2], h: xs[3], readOnly: readOnly}
+			o := r.Obj()
+			o.SetPrototype(call.This.Prototype())
+			return o
+		}
+	}
+	domRectReadOnlyCtor = newCtor("DOMRectReadOnly", ctor(true))
+	domRectCtor = newCtor("DOMRect", ctor(false))
+	domRectListCtor = newCtor("DOMRectList", func(call js.ConstructorCall) *js.Object {
+		panic(vm.NewTypeError("Illegal constructor"))
+	})
+	for name, c := range map[string]*js.Object{"DOMRectReadOnly": domRectReadOnlyCtor, "DOMRect": domRectCtor, "DOMRectList": domRectListCtor} {
+		proto := c.Get("prototype").(*js.Object)
+		proto.DefineDataPropertySymbol(js.SymToStringTag, vm.ToValue(name), js.FLAG_FALSE, js.FLAG_TRUE, js.FLAG_FALSE)
+	}
+	domRectCtor.Get("prototype").(*js.Object).SetPrototype(domRectReadOnlyCtor.Get("prototype").(*js.Object))
+	domRectCtor.SetPrototype(domRectReadOnlyCtor)
+	protoMembers(domRectReadOnlyCtor.Get("prototype").(*js.Object), &DOMRect{}, nil)
+	protoMembers(domRectListCtor.Get("prototype").(*js.Object), &DOMRectList{}, nil)
+	iter := vm.Get("Array").(*js.Object).Get("prototype").(*js.Object).Get("values")
+	domRectListCtor.Get("prototype").(*js.Object).DefineDataPropertySymbol(js.SymIterator, iter, js.FLAG_TRUE, js.FLAG_TRUE, js.FLAG_FALSE)
+}
+
+// box asks the host for the border box of el unless it is cached for
+// the current frame
+func (el *Element) box() (b box) {
+	if time.Since(geomFrame) > frameDuration {
+		resetGeom()
+	}
+	if b, ok := geomCache[el.n]; ok {
+		return b
+	}
+	defer func() { geomCache[el.n] = b }()
+	if Geom == nil {
+		el.d.layout()
+		return geomCache[el.n]
+	}
+	p, ok := path(el)
+	if !ok {
+		log.Printf("path lookup failed")
+		return
+	}
+	geom, err := Geom(p)
+	if err != nil {
+		log.Errorf("geom %v: %v", p, err)
+		return
+	}
+	items := strings.Split(strings.TrimSpace(geom), ",")
+	if len(items) != 4 {
+		log.Errorf("geom %v: malformed %v", p, geom)
+		return
+	}
+	xs := make([]float64, 4)
+	for i, it := range items {
+		if xs[i], err = strconv.ParseFloat(strings.TrimSpace(it), 64); err != nil {
+			log.Errorf("geom %v: %v", p, err)
+			return
+		}
+	}
+	return box{x1: xs[0], y1: xs[1], x2: xs[2], y2: xs[3], ok: true}
+}
+
+// computed value of prop for el in pixels
+func (el *Element) computedPx(prop string) float64 {
+	v := (&ComputedStyle{el: el}).GetPropertyValue(prop)
+	x, _ := strconv.ParseFloat(strings.TrimSuffix(v, "px"), 64)
+	return x
+}
+
+// isViewportElement is true for the document element whose client
+// area is the viewport
+func (el *Element) isViewportElement() bool {
+	return el.n.Parent != nil && el.n.Parent.Type == html.DocumentNode
+}
+
+func (el *Element) GetBoundingClientRect() *DOMRect {
+	b := el.clientBox()
+	return &DOMRect{x: b.x1, y: b.y1, w: b.x2 - b.x1, h: b.y2 - b.y1}
+}
+
+func (el *Element) GetClientRects() *DOMRectList {
+	rl := &DOMRectList{}
+	if b := el.box(); b.ok {
+		rl.rs = append(rl.rs, el.GetBoundingClientRect())
+	}
+	return rl
+}
+
+// offsetParent is the nearest positioned ancestor, table cell or table,
+// or the body
+func (el *Element) offsetParent() *Element {
+	if el.isViewportElement() || el.n.Data == "body" || !connected(el.n) {
+		return nil
+	}
+	cs := &ComputedStyle{el: el}
+	if cs.GetPropertyValue("position") == "fixed" || cs.GetPropertyValue("display") == "none" {
+		return nil
+	}
+	static := cs.GetPropertyValue("position") == "static"
+	for p := el.n.Parent; p != nil && p.Type == html.ElementNode; p = p.Parent {
+		pe := el.d.getEl(p)
+		if pe.n.Data == "body" || (&ComputedStyle{el: pe}).GetPropertyValue("position") != "static" {
+			return pe
+		}
+		if static && (p.Data == "td" || p.Data == "th" || p.Data == "table") {
+			return pe
+		}
+	}
+	return nil
+}
+
+func (el *Element) OffsetParent() js.Value {
+	if p := el.offsetParent(); p != nil {
+		return p.Obj()
+	}
+	return js.Null()
+}
+
+// offset of el relative to the padding edge of its offset parent
+func (el *Element) offset() (x, y float64) {
+	b := el.box()
+	x, y = b.x1, b.y1
+	if p := el.offsetParent(); p != nil && p.n.Data != "body" {
+		pb := p.box()
+		x -= pb.x1 + p.computedPx("border-left-width")
+		y -= pb.y1 + p.computedPx("border-top-width")
+	}
+	return
+}
+
+func (el *Element) OffsetTop() int {
+	_, y := el.offset()
+	return int(math.Round(y))
+}
+
+func (el *Element) OffsetLeft() int {
+	x, _ := el.offset()
+	return int(math.Round(x))
+}
+
+func (el *Element) OffsetWidth() int {
+	b := el.box()
+	return int(math.Round(b.x2 - b.x1))
+}
+
+func (el *Element) OffsetHeight() int {
+	b := el.box()
+	return int(math.Round(b.y2 - b.y1))
+}
+
+func (el *Element) ClientTop() int {
+	return int(math.Round(el.computedPx("border-top-width")))
+}
+
+func (el *Element) ClientLeft() int {
+	return int(math.Round(el.computedPx("border-left-width")))
+}
+
+// clientSize is the padding box size of el, or the viewport size for
+// the document element
+func (el *Element) clientSize() (w, h float64) {
+	if el.isViewportElement() {
+		return float64(ViewportWidth), float64(ViewportHeight)
+	}
+	if (&ComputedStyle{el: el}).GetPropertyValue("display") == "inline" {
+		return 0, 0
+	}
+	b := el.box()
+	w = b.x2 - b.x1 - el.computedPx("border-left-width") - el.computedPx("border-right-width")
+	h = b.y2 - b.y1 - el.computedPx("border-top-width") - el.computedPx("border-bottom-width")
+	return math.Max(w, 0), math.Max(h, 0)
+}
+
+func (el *Element) ClientWidth() int {
+	w, _ := el.clientSize()
+	return int(math.Round(w))
+}
+
+func (el *Element) ClientHeight() int {
+	_, h := el.clientSize()
+	return int(math.Round(h))
+}
+
+// scrollSize is the client size of el extended to the boxes of its
+// child elements
+func (el *Element) scrollSize() (w, h float64) {
+	w, h = el.clientSize()
+	b := el.box()
+	x0, y0 := b.x1+el.computedPx("border-left-width"), b.y1+el.computedPx("border-top-width")
+	if el.isViewportElement() {
+		x0, y0 = 0, 0
+	}
+	for c := el.n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		if cb := el.d.getEl(c).box(); cb.ok {
+			w = math.Max(w, cb.x2-x0)
+			h = math.Max(h, cb.y2-y0)
+		}
+	}
+	return
+}
+
+func (el *Element) ScrollWidth() int {
+	w, _ := el.scrollSize()
+	return int(math.Round(w))
+}
+
+func (el *Element) ScrollHeight() int {
+	_, h := el.scrollSize()
+	return int(math.Round(h))
+}
+
+// DOMRect is a rectangle like the border box of an element
+type DOMRect struct {
+	x, y, w, h float64
+	readOnly   bool
+	obj        *js.Object
+}
+
+func (dr *DOMRect) Obj() *js.Object {
+	if dr.obj == nil {
+		dr.obj = vm.NewDynamicObject(dr)
+		if dr.readOnly {
+			dr.obj.SetPrototype(domRectReadOnlyCtor.Get("prototype").(*js.Object))
+		} else {
+			dr.obj.SetPrototype(domRectCtor.Get("prototype").(*js.Object))
+		}
+	}
+	return dr.obj
+}
+
+func (dr *DOMRect) Getters() map[string]bool {
+	return map[string]bool{
+		"x":      true,
+		"y":      true,
+		"width":  true,
+		"height": true,
+		"top":    true,
+		"right":  true,
+		"bottom": true,
+		"left":   true,
+	}
+}
+
+func (dr *DOMRect) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+func (dr *DOMRect) X() float64 {
+	return dr.x
+}
+
+func (dr *DOMRect) Y() float64 {
+	return dr.y
+}
+
+func (dr *DOMRect) Width() float64 {
+	return dr.w
+}
+
+func (dr *DOMRect) Height() float64 {
+	return dr.h
+}
+
+func (dr *DOMRect) Top() float64 {
+	return math.Min(dr.y, dr.y+dr.h)
+}
+
+func (dr *DOMRect) Right() float64 {
+	return math.Max(dr.x, dr.x+dr.w)
+}
+
+func (dr *DOMRect) Bottom() float64 {
+	return math.Max(dr.y, dr.y+dr.h)
+}
+
+func (dr *DOMRect) Left() float64 {
+	return math.Min(dr.x, dr.x+dr.w)
+}
+
+func (dr *DOMRect) ToJSON() *js.Object {
+	o := vm.NewObject()
+	for _, k := range []string{"x", "y", "width", "height", "top", "right", "bottom", "left"} {
+		o.Set(k, dr.Get(k))
+	}
+	return o
+}
+
+func (dr *DOMRect) Get(k string) (v js.Value) {
+	if res, ok := GetCall(dr, k); ok {
+		return res
+	}
+	return nil
+}
+
+func (dr *DOMRect) Set(k string, desc js.PropertyDescriptor) bool {
+	if dr.readOnly {
+		return false
+	}
+	v := desc.Value.ToFloat()
+	switch k {
+	case "x":
+		dr.x = v
+	case "y":
+		dr.y = v
+	case "width":
+		dr.w = v
+	case "height":
+		dr.h = v
+	default:
+		return false
+	}
+	return true
+}
+
+func (dr *DOMRect) Has(k string) bool {
+	return HasCall(dr, k)
+}
+
+func (dr *DOMRect) Delete(k string) bool {
+	return false
+}
+
+func (dr *DOMRect) Keys() []string {
+	return []string{}
+}
+
+// DOMRectList is the list of rectangles returned by getClientRects
+type DOMRectList struct {
+	rs  []*DOMRect
+	obj *js.Object
+}
+
+func (rl *DOMRectList) Obj() *js.Object {
+	if rl.obj == nil {
+		rl.obj = vm.NewDynamicObject(rl)
+		rl.obj.SetPrototype(domRectListCtor.Get("prototype").(*js.Object))
+	}
+	return rl.obj
+}
+
+func (rl *DOMRectList) Getters() map[string]bool {
+	return map[string]bool{
+		"length": true,
+	}
+}
+
+func (rl *DOMRectList) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+func (rl *DOMRectList) Length() int {
+	return len(rl.rs)
+}
+
+func (rl *DOMRectList) Item(j any) js.Value {
+	if i, ok := index(j); ok && i >= 0 && i < len(rl.rs) {
+		return rl.rs[i].Obj()
+	}
+	return js.Null()
+}
+
+func (rl *DOMRectList) Get(k string) js.Value {
+	if i, err := strconv.Atoi(k); err == nil {
+		if i >= 0 && i < len(rl.rs) {
+			return rl.rs[i].Obj()
+		}
+		return js.Undefined()
+	}
+	return nil
+}
+
+func (rl *DOMRectList) Set(k string, desc js.PropertyDescriptor) bool {
+	return false
+}
+
+func (rl *DOMRectList) Has(k string) bool {
+	if i, err := strconv.Atoi(k); err == nil {
+		return i >= 0 && i < len(rl.rs)
+	}
+	return false
+}
+
+func (rl *DOMRectList) Delete(k string) bool {
+	return false
+}
+
+func (rl *DOMRectList) Keys() (ks []string) {
+	for i := range rl.rs {
+		ks = append(ks, strconv.Itoa(i))
+	}
+	return
+}
@@ -0,0 +1,59 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"testing"
+)
+
+func TestGeometry(t *testing.T) {
+	calls := 0
+	Geom = func(p string) (string, error) {
+		calls++
+		switch p {
+		case "/0":
+			return "0,0,1280,300", nil
+		case "/0/0":
+			return "10,20,410,220", nil
+		case "/0/0/0":
+			return "15,25,65,1025", nil
+		}
+		return "", nil
+	}
+	defer func() { Geom = nil }()
+	vm := js.New()
+	d, err := Init(vm, "https://example.com", `<html><body><div id="a" style="position: relative; border: 5px solid"><p id="b">x</p></div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var a = document.getElementById('a');
+		var b = document.getElementById('b');
+		var r = a.getBoundingClientRect();
+		var log = [r instanceof DOMRect, r instanceof DOMRectReadOnly, r.x, r.y, r.width, r.height, r.right, r.bottom];
+		log.push(a.offsetWidth, a.offsetHeight, a.offsetTop, a.offsetLeft, a.offsetParent.tagName);
+		log.push(b.offsetParent === a, b.offsetTop, b.offsetLeft, a.clientWidth, a.clientHeight, a.clientTop);
+		log.push(a.scrollHeight, a.scrollWidth, document.documentElement.clientWidth, innerWidth, innerHeight);
+		var rs = b.getClientRects();
+		log.push(rs.length, rs[0].height, JSON.stringify(new DOMRect(1, 2, 3, 4)));
+		log.join('|');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != `true|true|10|20|400|200|410|220|400|200|20|10|BODY|true|0|0|390|190|5|1000|390|1280|1280|720|1|1000|{"x":1,"y":2,"width":3,"height":4,"top":2,"right":4,"bottom":6,"left":1}` {
+		t.Fatalf("%v", v)
+	}
+	resetGeom()
+	calls = 0
+	el := d.getEl(d.QuerySelector("#b").n)
+	el.box()
+	el.box()
+	if calls != 1 {
+		t.Fatalf("%v calls", calls)
+	}
+	addMutation(d, ChAttr, el.n)
+	el.box()
+	if calls != 2 {
+		t.Fatalf("%v calls after mutation", calls)
+	}
+}
@@ -7,21 +7,100 @@ import (
 	"strconv"
 )
 
+var (
+	htmlCollectionCtor  *js.Object
+	htmlCollectionProto *js.Object
+)
+
+// initHTMLCollection defines the HTMLCollection interface object. Unlike
+// NodeList it isn't declared iterable<Node> in the IDL: it only gets
+// @@iterator from its indexed getter and length, and has no forEach,
+// entries, keys or values (which HTMLCollection-iterator.html checks).
+func initHTMLCollection() (err error) {
+	c, err := vm.RunString(`(function() {
+		function HTMLCollection() {
+			throw new TypeError('Illegal constructor');
+		}
+		Object.defineProperties(HTMLCollection.prototype, {
+			[Symbol.iterator]: { value: Array.prototype.values, writable: true, configurable: true },
+			[Symbol.toStringTag]: { value: 'HTMLCollection', configurable: true }
+		});
+		return HTMLCollection;
+	})()`)
+	if err != nil {
+		return
+	}
+	htmlCollectionCtor = c.(*js.Object)
+	htmlCollectionProto = htmlCollectionCtor.Get("prototype").(*js.Object)
+	protoMethods(htmlCollectionProto, "item", "namedItem")
+	protoGetters(htmlCollectionProto, "length")
+	return
+}
+
+// HTMLCollection is a live list of elements
 type HTMLCollection struct {
 	d *Document
 	f func() []*html.Node
+
+	// expandos set from JS
+	vars map[string]js.Value
 }
 
 func (hc *HTMLCollection) Obj() *js.Object {
+	return hc.obj(hc, htmlCollectionProto)
+}
+
+// obj returns the object of the collection do which is hc or embeds it
+func (hc *HTMLCollection) obj(do js.DynamicObject, proto *js.Object) *js.Object {
 	obj, ok := hcObjRefs[hc]
 	if ok {
 		return obj
 	}
-	obj = vm.NewDynamicObject(hc)
+	obj = vm.NewDynamicObject(do)
+	if proto != nil {
+		obj.SetPrototype(proto)
+	}
+	// the proxy makes the named properties unenumerable which dynamic
+	// objects can't do
+	p := vm.NewProxy(obj, &js.ProxyTrapConfig{
+		GetOwnPropertyDescriptor: func(target *js.Object, k string) js.PropertyDescriptor {
+			return hc.ownProperty(k, do.Get(k))
+		},
+		OwnKeys: func(target *js.Object) *js.Object {
+			ks := hc.Keys()
+			vs := make([]any, len(ks))
+			for i, k := range ks {
+				vs[i] = k
+			}
+			return vm.NewArray(vs...)
+		},
+	})
+	obj = vm.ToValue(p).(*js.Object)
 	hcObjRefs[hc] = obj
 	return obj
 }
 
+// ownProperty returns the property descriptor of k with the value v.
+// Indices and named elements are read-only and named elements aren't
+// enumerable.
+func (hc *HTMLCollection) ownProperty(k string, v js.Value) (desc js.PropertyDescriptor) {
+	if v == nil {
+		return
+	}
+	desc = js.PropertyDescriptor{
+		Value:        v,
+		Writable:     js.FLAG_FALSE,
+		Enumerable:   js.FLAG_TRUE,
+		Configurable: js.FLAG_TRUE,
+	}
+	if _, ok := hc.vars[k]; ok {
+		desc.Writable = js.FLAG_TRUE
+	} else if _, err := strconv.Atoi(k); err != nil {
+		desc.Enumerable = js.FLAG_FALSE
+	}
+	return
+}
+
 func (hc *HTMLCollection) Getters() map[string]bool {
 	return map[string]bool{
 		"length": true,
@@ -32,61 +111,95 @@ func (hc *HTMLCollection) Props() map[string]bool {
 	return map[string]bool{}
 }
 
-func (hc *HTMLCollection) Get(k string) (v js.Value) {
-	if res, ok := GetCall(hc, k); ok {
-		return res
-	}
-	switch k {
-	default:
-		i, err := strconv.Atoi(k)
-		if err == nil {
-			c := hc.f()
-			if i >= len(c) {
-				return nil
-			}
-			return hc.d.getEl(c[i]).Obj()
+// elements filters non-element nodes
+func (hc *HTMLCollection) elements() (es []*html.Node) {
+	for _, n := range hc.f() {
+		if n.Type == html.ElementNode {
+			es = append(es, n)
 		}
-		c := hc.f()
-		for _, n := range c {
-			if attr(*n, "id") == k || attr(*n, "name") == k {
-				return hc.d.getEl(n).Obj()
-			}
+	}
+	return
+}
+
+// Get returns indexed and named elements and expandos. Methods and
+// getters are inherited from the prototype.
+func (hc *HTMLCollection) Get(k string) (v js.Value) {
+	if i, err := strconv.Atoi(k); err == nil {
+		c := hc.elements()
+		if i < 0 || i >= len(c) {
+			return nil
 		}
-		log.Printf("html collection get unknown %v", k)
+		return hc.d.getEl(c[i]).Obj()
+	}
+	if v, ok := hc.vars[k]; ok {
+		return v
+	}
+	if HasCall(hc, k) {
+		// prototype members take precedence over named elements
+		return nil
 	}
-	return vm.ToValue(nil)
+	if el := hc.NamedItem(k); el != nil {
+		return el.Obj()
+	}
+	return nil
 }
 
 func (hc *HTMLCollection) Set(k string, desc js.PropertyDescriptor) bool {
-	return false
+	if _, err := strconv.Atoi(k); err == nil || k == "length" || hc.NamedItem(k) != nil {
+		log.Printf("HTMLCollection: ignore set %v", k)
+		return false
+	}
+	if hc.vars == nil {
+		hc.vars = make(map[string]js.Value)
+	}
+	hc.vars[k] = desc.Value
+	return true
 }
 
 func (hc *HTMLCollection) Has(k string) bool {
-	if yes := HasCall(hc, k); yes {
-		return true
-	}
 	if i, err := strconv.Atoi(k); err == nil {
-		c := hc.f()
-		return 0 <= i && i < len(c)
+		return 0 <= i && i < hc.Length()
+	}
+	if _, ok := hc.vars[k]; ok {
+		return true
 	}
-	return false
+	return hc.NamedItem(k) != nil
 }
 
 func (hc *HTMLCollection) Delete(k string) bool {
-	return false
+	if _, ok := hc.vars[k]; ok {
+		delete(hc.vars, k)
+		return true
+	}
+	return !hc.Has(k)
 }
 
-func (hc *HTMLCollection) Keys() []string {
-	ks := Calls(hc)
-	c := hc.f()
+// Keys returns the indices followed by the supported property names
+func (hc *HTMLCollection) Keys() (ks []string) {
+	c := hc.elements()
 	for i := range c {
 		ks = append(ks, strconv.Itoa(i))
 	}
+	seen := make(map[string]bool)
+	for _, n := range c {
+		for _, a := range []string{"id", "name"} {
+			if a == "name" && hc.d.namespace(n) != xhtmlNS {
+				continue
+			}
+			if v := attr(*n, a); v != "" && !seen[v] {
+				seen[v] = true
+				ks = append(ks, v)
+			}
+		}
+	}
+	for k := range hc.vars {
+		ks = append(ks, k)
+	}
 	return ks
 }
 
 func (hc *HTMLCollection) ChildNodes() (es []*Element) {
-	c := hc.f()
+	c := hc.elements()
 	es = make([]*Element, 0, len(c))
 	for _, n := range c {
 		es = append(es, hc.d.getEl(n))
@@ -95,23 +208,35 @@ func (hc *HTMLCollection) ChildNodes() (es []*Element) {
 }
 
 func (hc *HTMLCollection) Length() int {
-	c := hc.f()
-	return len(c)
+	return len(hc.elements())
 }
 
 func (hc *HTMLCollection) Item(j any) *Element {
-	i, ok := j.(int)
+	i, ok := index(j)
 	if !ok {
-		log.Errorf("html collection item: %T %v", j, j)
 		return nil
 	}
-	c := hc.f()
-	if i >= len(c) {
+	c := hc.elements()
+	if i < 0 || i >= len(c) {
 		return nil
 	}
 	return hc.d.getEl(c[i])
 }
 
+// NamedItem returns the first element with the id k or HTML element with
+// the name k
+func (hc *HTMLCollection) NamedItem(k string) *Element {
+	if k == "" {
+		return nil
+	}
+	for _, n := range hc.elements() {
+		if attr(*n, "id") == k || hc.d.namespace(n) == xhtmlNS && attr(*n, "name") == k {
+			return hc.d.getEl(n)
+		}
+	}
+	return nil
+}
+
 func (hc *HTMLCollection) ToString() string {
 	return "[object HTMLCollection]"
 }
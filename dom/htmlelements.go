@@ -0,0 +1,544 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"golang.org/x/net/html"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// reflectKind is the IDL type of a reflected content attribute
+type reflectKind int
+
+const (
+	reflString reflectKind = iota
+	reflURL
+	reflBool
+	reflLong
+	reflUnsigned
+	reflEnum
+)
+
+// reflected describes an IDL attribute reflecting a content attribute
+// as specified in https://html.spec.whatwg.org/#reflecting-content-attributes-in-idl-attributes
+type reflected struct {
+	attr string
+	kind reflectKind
+
+	// def is the default of long and unsigned long attributes
+	def int
+
+	// limited attributes throw an IndexSizeError when set to a
+	// negative (long) or zero (unsigned long) value
+	limited bool
+
+	// min and max clamp unsigned long attributes if max > 0
+	min, max int
+
+	// keywords of enumerated attributes with the values returned when
+	// the attribute is missing or has an invalid value
+	keywords []string
+	missing  string
+	invalid  string
+
+	// nullable enumerated attributes return null when missing
+	nullable bool
+
+	// docURL attributes return the document URL when missing or empty
+	docURL bool
+}
+
+var (
+	enctypes    = []string{"application/x-www-form-urlencoded", "multipart/form-data", "text/plain"}
+	formMethods = []string{"get", "post", "dialog"}
+)
+
+var referrerPolicies = []string{"", "no-referrer", "no-referrer-when-downgrade", "same-origin", "origin", "strict-origin", "origin-when-cross-origin", "strict-origin-when-cross-origin", "unsafe-url"}
+
+var (
+	crossOrigin    = reflected{attr: "crossorigin", kind: reflEnum, keywords: []string{"anonymous", "use-credentials"}, invalid: "anonymous", nullable: true}
+	referrerPolicy = reflected{attr: "referrerpolicy", kind: reflEnum, keywords: referrerPolicies}
+	loading        = reflected{attr: "loading", kind: reflEnum, keywords: []string{"lazy", "eager"}, missing: "eager", invalid: "eager"}
+	maxLength      = reflected{attr: "maxlength", kind: reflLong, def: -1, limited: true}
+	minLength      = reflected{attr: "minlength", kind: reflLong, def: -1, limited: true}
+	enctype        = reflected{attr: "enctype", kind: reflEnum, keywords: enctypes, missing: "application/x-www-form-urlencoded", invalid: "application/x-www-form-urlencoded"}
+	formAction     = reflected{attr: "formaction", kind: reflURL, docURL: true}
+	formEnctype    = reflected{attr: "formenctype", kind: reflEnum, keywords: enctypes, invalid: "application/x-www-form-urlencoded"}
+	formMethod     = reflected{attr: "formmethod", kind: reflEnum, keywords: formMethods, invalid: "get"}
+)
+
+// reflectedAttrs lists the reflected IDL attributes of the element
+// interfaces. Attributes with behaviour beyond reflection (value, type
+// of form controls, checked...) are implemented by Element.
+var reflectedAttrs = map[string]map[string]reflected{
+	"Element": {
+		"id":   {attr: "id"},
+		"slot": {attr: "slot"},
+	},
+	"HTMLElement": {
+		"title":     {attr: "title"},
+		"lang":      {attr: "lang"},
+		"dir":       {attr: "dir", kind: reflEnum, keywords: []string{"ltr", "rtl", "auto"}},
+		"hidden":    {attr: "hidden", kind: reflBool},
+		"inert":     {attr: "inert", kind: reflBool},
+		"accessKey": {attr: "accesskey"},
+		"autofocus": {attr: "autofocus", kind: reflBool},
+		"nonce":     {attr: "nonce"},
+	},
+	"HTMLAnchorElement": {
+		"href":           {attr: "href", kind: reflURL},
+		"target":         {attr: "target"},
+		"download":       {attr: "download"},
+		"ping":           {attr: "ping"},
+		"rel":            {attr: "rel"},
+		"hreflang":       {attr: "hreflang"},
+		"type":           {attr: "type"},
+		"name":           {attr: "name"},
+		"referrerPolicy": referrerPolicy,
+	},
+	"HTMLAreaElement": {
+		"alt":            {attr: "alt"},
+		"coords":         {attr: "coords"},
+		"shape":          {attr: "shape"},
+		"href":           {attr: "href", kind: reflURL},
+		"target":         {attr: "target"},
+		"download":       {attr: "download"},
+		"ping":           {attr: "ping"},
+		"rel":            {attr: "rel"},
+		"noHref":         {attr: "nohref", kind: reflBool},
+		"referrerPolicy": referrerPolicy,
+	},
+	"HTMLBaseElement": {
+		"href":   {attr: "href", kind: reflURL},
+		"target": {attr: "target"},
+	},
+	"HTMLImageElement": {
+		"alt":            {attr: "alt"},
+		"src":            {attr: "src", kind: reflURL},
+		"srcset":         {attr: "srcset"},
+		"sizes":          {attr: "sizes"},
+		"crossOrigin":    crossOrigin,
+		"useMap":         {attr: "usemap"},
+		"isMap":          {attr: "ismap", kind: reflBool},
+		"width":          {attr: "width", kind: reflUnsigned},
+		"height":         {attr: "height", kind: reflUnsigned},
+		"name":           {attr: "name"},
+		"referrerPolicy": referrerPolicy,
+		"decoding":       {attr: "decoding", kind: reflEnum, keywords: []string{"sync", "async", "auto"}, missing: "auto", invalid: "auto"},
+		"loading":        loading,
+	},
+	"HTMLInputElement": {
+		"accept":         {attr: "accept"},
+		"alt":            {attr: "alt"},
+		"dirName":        {attr: "dirname"},
+		"disabled":       {attr: "disabled", kind: reflBool},
+		"formAction":     formAction,
+		"formEnctype":    formEnctype,
+		"formMethod":     formMethod,
+		"formNoValidate": {attr: "formnovalidate", kind: reflBool},
+		"formTarget":     {attr: "formtarget"},
+		"height":         {attr: "height", kind: reflUnsigned},
+		"max":            {attr: "max"},
+		"maxLength":      maxLength,
+		"min":            {attr: "min"},
+		"minLength":      minLength,
+		"multiple":       {attr: "multiple", kind: reflBool},
+		"name":           {attr: "name"},
+		"pattern":        {attr: "pattern"},
+		"placeholder":    {attr: "placeholder"},
+		"readOnly":       {attr: "readonly", kind: reflBool},
+		"required":       {attr: "required", kind: reflBool},
+		"size":           {attr: "size", kind: reflUnsigned, def: 20, limited: true},
+		"src":            {attr: "src", kind: reflURL},
+		"step":           {attr: "step"},
+		"width":          {attr: "width", kind: reflUnsigned},
+	},
+	"HTMLSelectElement": {
+		"disabled": {attr: "disabled", kind: reflBool},
+		"multiple": {attr: "multiple", kind: reflBool},
+		"name":     {attr: "name"},
+		"required": {attr: "required", kind: reflBool},
+		"size":     {attr: "size", kind: reflUnsigned},
+	},
+	"HTMLOptGroupElement": {
+		"disabled": {attr: "disabled", kind: reflBool},
+		"label":    {attr: "label"},
+	},
+	"HTMLOptionElement": {
+		"disabled": {attr: "disabled", kind: reflBool},
+	},
+	"HTMLTextAreaElement": {
+		"cols":        {attr: "cols", kind: reflUnsigned, def: 20, limited: true},
+		"dirName":     {attr: "dirname"},
+		"disabled":    {attr: "disabled", kind: reflBool},
+		"maxLength":   maxLength,
+		"minLength":   minLength,
+		"name":        {attr: "name"},
+		"placeholder": {attr: "placeholder"},
+		"readOnly":    {attr: "readonly", kind: reflBool},
+		"required":    {attr: "required", kind: reflBool},
+		"rows":        {attr: "rows", kind: reflUnsigned, def: 2, limited: true},
+		"wrap":        {attr: "wrap"},
+	},
+	"HTMLButtonElement": {
+		"disabled":       {attr: "disabled", kind: reflBool},
+		"formAction":     formAction,
+		"formEnctype":    formEnctype,
+		"formMethod":     formMethod,
+		"formNoValidate": {attr: "formnovalidate", kind: reflBool},
+		"formTarget":     {attr: "formtarget"},
+		"name":           {attr: "name"},
+	},
+	"HTMLFieldSetElement": {
+		"disabled": {attr: "disabled", kind: reflBool},
+		"name":     {attr: "name"},
+	},
+	"HTMLFormElement": {
+		"acceptCharset": {attr: "accept-charset"},
+		"action":        {attr: "action", kind: reflURL, docURL: true},
+		"autocomplete":  {attr: "autocomplete", kind: reflEnum, keywords: []string{"on", "off"}, missing: "on", invalid: "on"},
+		"enctype":       enctype,
+		"encoding":      enctype,
+		"method":        {attr: "method", kind: reflEnum, keywords: formMethods, missing: "get", invalid: "get"},
+		"name":          {attr: "name"},
+		"noValidate":    {attr: "novalidate", kind: reflBool},
+		"rel":           {attr: "rel"},
+		"target":        {attr: "target"},
+	},
+	"HTMLTableElement": {
+		"align":       {attr: "align"},
+		"border":      {attr: "border"},
+		"frame":       {attr: "frame"},
+		"rules":       {attr: "rules"},
+		"summary":     {attr: "summary"},
+		"width":       {attr: "width"},
+		"bgColor":     {attr: "bgcolor"},
+		"cellPadding": {attr: "cellpadding"},
+		"cellSpacing": {attr: "cellspacing"},
+	},
+	"HTMLTableCaptionElement": {
+		"align": {attr: "align"},
+	},
+	"HTMLTableColElement": {
+		"span":   {attr: "span", kind: reflUnsigned, def: 1, min: 1, max: 1000},
+		"align":  {attr: "align"},
+		"ch":     {attr: "char"},
+		"chOff":  {attr: "charoff"},
+		"vAlign": {attr: "valign"},
+		"width":  {attr: "width"},
+	},
+	"HTMLTableSectionElement": {
+		"align":  {attr: "align"},
+		"ch":     {attr: "char"},
+		"chOff":  {attr: "charoff"},
+		"vAlign": {attr: "valign"},
+	},
+	"HTMLTableRowElement": {
+		"align":   {attr: "align"},
+		"ch":      {attr: "char"},
+		"chOff":   {attr: "charoff"},
+		"vAlign":  {attr: "valign"},
+		"bgColor": {attr: "bgcolor"},
+	},
+	"HTMLTableCellElement": {
+		"colSpan": {attr: "colspan", kind: reflUnsigned, def: 1, min: 1, max: 1000},
+		"rowSpan": {attr: "rowspan", kind: reflUnsigned, def: 1, min: 0, max: 65534},
+		"headers": {attr: "headers"},
+		"scope":   {attr: "scope", kind: reflEnum, keywords: []string{"row", "col", "rowgroup", "colgroup"}},
+		"abbr":    {attr: "abbr"},
+		"align":   {attr: "align"},
+		"axis":    {attr: "axis"},
+		"height":  {attr: "height"},
+		"width":   {attr: "width"},
+		"ch":      {attr: "char"},
+		"chOff":   {attr: "charoff"},
+		"noWrap":  {attr: "nowrap", kind: reflBool},
+		"vAlign":  {attr: "valign"},
+		"bgColor": {attr: "bgcolor"},
+	},
+	"HTMLLabelElement": {
+		"htmlFor": {attr: "for"},
+	},
+	"HTMLIFrameElement": {
+		"src":             {attr: "src", kind: reflURL},
+		"srcdoc":          {attr: "srcdoc"},
+		"name":            {attr: "name"},
+		"allow":           {attr: "allow"},
+		"allowFullscreen": {attr: "allowfullscreen", kind: reflBool},
+		"width":           {attr: "width"},
+		"height":          {attr: "height"},
+		"referrerPolicy":  referrerPolicy,
+		"loading":         loading,
+	},
+	"HTMLAudioElement": {
+		"src":          {attr: "src", kind: reflURL},
+		"crossOrigin":  crossOrigin,
+		"autoplay":     {attr: "autoplay", kind: reflBool},
+		"loop":         {attr: "loop", kind: reflBool},
+		"controls":     {attr: "controls", kind: reflBool},
+		"defaultMuted": {attr: "muted", kind: reflBool},
+	},
+	"HTMLVideoElement": {
+		"src":          {attr: "src", kind: reflURL},
+		"crossOrigin":  crossOrigin,
+		"autoplay":     {attr: "autoplay", kind: reflBool},
+		"loop":         {attr: "loop", kind: reflBool},
+		"controls":     {attr: "controls", kind: reflBool},
+		"defaultMuted": {attr: "muted", kind: reflBool},
+		"width":        {attr: "width", kind: reflUnsigned},
+		"height":       {attr: "height", kind: reflUnsigned},
+		"poster":       {attr: "poster", kind: reflURL},
+		"playsInline":  {attr: "playsinline", kind: reflBool},
+	},
+	"HTMLSourceElement": {
+		"src":    {attr: "src", kind: reflURL},
+		"type":   {attr: "type"},
+		"srcset": {attr: "srcset"},
+		"sizes":  {attr: "sizes"},
+		"media":  {attr: "media"},
+		"width":  {attr: "width", kind: reflUnsigned},
+		"height": {attr: "height", kind: reflUnsigned},
+	},
+	"HTMLEmbedElement": {
+		"src":    {attr: "src", kind: reflURL},
+		"type":   {attr: "type"},
+		"width":  {attr: "width"},
+		"height": {attr: "height"},
+	},
+	"HTMLTrackElement": {
+		"kind":    {attr: "kind", kind: reflEnum, keywords: []string{"subtitles", "captions", "descriptions", "chapters", "metadata"}, missing: "subtitles", invalid: "metadata"},
+		"src":     {attr: "src", kind: reflURL},
+		"srclang": {attr: "srclang"},
+		"label":   {attr: "label"},
+		"default": {attr: "default", kind: reflBool},
+	},
+	"HTMLScriptElement": {
+		"src":            {attr: "src", kind: reflURL},
+		"type":           {attr: "type"},
+		"noModule":       {attr: "nomodule", kind: reflBool},
+		"async":          {attr: "async", kind: reflBool},
+		"defer":          {attr: "defer", kind: reflBool},
+		"crossOrigin":    crossOrigin,
+		"integrity":      {attr: "integrity"},
+		"referrerPolicy": referrerPolicy,
+		"charset":        {attr: "charset"},
+		"event":          {attr: "event"},
+		"htmlFor":        {attr: "for"},
+	},
+	"HTMLLinkElement": {
+		"href":           {attr: "href", kind: reflURL},
+		"crossOrigin":    crossOrigin,
+		"rel":            {attr: "rel"},
+		"media":          {attr: "media"},
+		"integrity":      {attr: "integrity"},
+		"hreflang":       {attr: "hreflang"},
+		"type":           {attr: "type"},
+		"referrerPolicy": referrerPolicy,
+		"disabled":       {attr: "disabled", kind: reflBool},
+		"imageSrcset":    {attr: "imagesrcset"},
+		"imageSizes":     {attr: "imagesizes"},
+		"charset":        {attr: "charset"},
+		"rev":            {attr: "rev"},
+		"target":         {attr: "target"},
+	},
+	"HTMLMetaElement": {
+		"name":      {attr: "name"},
+		"httpEquiv": {attr: "http-equiv"},
+		"content":   {attr: "content"},
+		"media":     {attr: "media"},
+		"scheme":    {attr: "scheme"},
+	},
+}
+
+// reflected returns the reflected IDL attribute key of el's interface
+// or one of its ancestors
+func (el *Element) reflected(key string) (r reflected, ok bool) {
+	if el.n.Type != html.ElementNode {
+		return
+	}
+	i := el.iface()
+	if r, ok = reflectedAttrs[i][key]; ok {
+		return
+	}
+	if strings.HasPrefix(i, "HTML") {
+		if r, ok = reflectedAttrs["HTMLElement"][key]; ok {
+			return
+		}
+	}
+	r, ok = reflectedAttrs["Element"][key]
+	return
+}
+
+func (r reflected) get(el *Element) js.Value {
+	v, ok := attrOk(*el.n, r.attr)
+	switch r.kind {
+	case reflURL:
+		if r.docURL && v == "" {
+			return vm.ToValue(el.d.url)
+		}
+		if !ok {
+			return vm.ToValue("")
+		}
+		if el.n.Data == "base" {
+			// the base URL itself is resolved against the document URL
+			return vm.ToValue(resolveURLFrom(el.d.url, v))
+		}
+		return vm.ToValue(el.d.resolveURL(v))
+	case reflBool:
+		return vm.ToValue(ok)
+	case reflLong:
+		if i, ok := parseInteger(v); ok && i >= math.MinInt32 && i <= math.MaxInt32 && (!r.limited || i >= 0) {
+			return vm.ToValue(i)
+		}
+		return vm.ToValue(r.def)
+	case reflUnsigned:
+		i, ok := parseInteger(v)
+		if !ok || i < 0 || i > math.MaxInt32 || (r.limited && i == 0) {
+			return vm.ToValue(r.def)
+		}
+		if r.max > 0 {
+			i = clamp(i, r.min, r.max)
+		}
+		return vm.ToValue(i)
+	case reflEnum:
+		if !ok {
+			if r.nullable {
+				return js.Null()
+			}
+			return vm.ToValue(r.missing)
+		}
+		for _, kw := range r.keywords {
+			if strings.EqualFold(v, kw) {
+				return vm.ToValue(kw)
+			}
+		}
+		return vm.ToValue(r.invalid)
+	}
+	return vm.ToValue(v)
+}
+
+func (r reflected) set(el *Element, val js.Value) {
+	switch r.kind {
+	case reflBool:
+		if val.ToBoolean() {
+			setAttr(el.n, r.attr, "")
+		} else {
+			rmAttr(el.n, r.attr)
+		}
+	case reflLong:
+		i := int(int32(val.ToInteger()))
+		if r.limited && i < 0 {
+			throwDOMException("IndexSizeError", "negative value for "+r.attr)
+		}
+		setAttr(el.n, r.attr, strconv.Itoa(i))
+	case reflUnsigned:
+		i := int(uint32(val.ToInteger()))
+		if r.limited && i == 0 {
+			throwDOMException("IndexSizeError", "zero value for "+r.attr)
+		}
+		if i > math.MaxInt32 {
+			i = r.def
+		}
+		setAttr(el.n, r.attr, strconv.Itoa(i))
+	case reflEnum:
+		if r.nullable && js.IsNull(val) {
+			rmAttr(el.n, r.attr)
+			return
+		}
+		setAttr(el.n, r.attr, val.String())
+	default:
+		setAttr(el.n, r.attr, val.String())
+	}
+}
+
+// parseInteger implements the rules for parsing integers: leading
+// whitespace is skipped and trailing garbage ignored
+func parseInteger(s string) (i int, ok bool) {
+	s = strings.TrimLeft(s, " \t\n\f\r")
+	neg := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	j := 0
+	for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+		j++
+	}
+	if j == 0 {
+		return
+	}
+	i, err := strconv.Atoi(s[:j])
+	if err != nil {
+		// out of range
+		i = math.MaxInt64
+	}
+	if neg {
+		i = -i
+	}
+	return i, true
+}
+
+func clamp(i, min, max int) int {
+	if i < min {
+		return min
+	}
+	if i > max {
+		return max
+	}
+	return i
+}
+
+// resolveURL parses u relative to the document base URL. u is returned
+// unchanged if that fails.
+func (d *Document) resolveURL(u string) string {
+	base := d.url
+	if b := grep(d.doc, "base"); b != nil && hasAttr(*b, "href") {
+		base = resolveURLFrom(d.url, attr(*b, "href"))
+	}
+	return resolveURLFrom(base, u)
+}
+
+// resolveURLFrom parses u relative to base
+func resolveURLFrom(base, u string) string {
+	bu, err := url.Parse(base)
+	if err != nil {
+		return u
+	}
+	res, err := bu.Parse(strings.TrimSpace(u))
+	if err != nil || !res.IsAbs() {
+		return u
+	}
+	return res.String()
+}
+
+// protoReflected defines the reflected IDL attributes of the
+// interface name as accessors on its prototype
+func protoReflected(proto *js.Object, name string) {
+	keys := make([]string, 0, len(reflectedAttrs[name]))
+	for k := range reflectedAttrs[name] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		k := k
+		getter := vm.ToValue(func(call js.FunctionCall) js.Value {
+			el, ok := protoRecv(call.This).(*Element)
+			if !ok {
+				panic(vm.NewTypeError("Illegal invocation"))
+			}
+			return el.Get(k)
+		})
+		setter := vm.ToValue(func(call js.FunctionCall) js.Value {
+			el, ok := protoRecv(call.This).(*Element)
+			if !ok {
+				panic(vm.NewTypeError("Illegal invocation"))
+			}
+			el.Set(k, js.PropertyDescriptor{Value: call.Argument(0)})
+			return js.Undefined()
+		})
+		proto.DefineAccessorProperty(k, getter, setter, js.FLAG_TRUE, js.FLAG_TRUE)
+	}
+}
@@ -0,0 +1,89 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"testing"
+)
+
+func TestReflectedAttrs(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com/dir/page.html", `<html><body><a id="a" href="x.html" target="_blank">a</a><img id="i" src="/img.png" alt="pic" width="12px"><input id="in" maxlength="5" size="0"><td id="td" colspan="2000"></td><form id="f" method="POST"></form></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var a = document.getElementById('a');
+		var img = document.getElementById('i');
+		var inp = document.getElementById('in');
+		var td = document.createElement('td');
+		var f = document.getElementById('f');
+		var log = [a.href, a.target, a.rel, img.src, img.alt, img.width, img.crossOrigin, img.decoding, a instanceof HTMLAnchorElement, 'alt' in HTMLImageElement.prototype, 'alt' in HTMLAnchorElement.prototype];
+		log.push(inp.maxLength, inp.size, inp.readOnly, inp.minLength);
+		inp.readOnly = true;
+		inp.maxLength = 3;
+		log.push(inp.getAttribute('readonly'), inp.getAttribute('maxlength'));
+		try {
+			inp.maxLength = -1;
+		} catch (e) {
+			log.push(e.name);
+		}
+		td.setAttribute('colspan', '2000');
+		log.push(td.colSpan, td.rowSpan, f.method, f.enctype);
+		f.method = 'dialog';
+		a.title = 't';
+		a.hidden = true;
+		log.push(f.getAttribute('method'), a.getAttribute('title'), a.hasAttribute('hidden'), a.dir, document.body.lang);
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := "https://example.com/dir/x.html,_blank,,https://example.com/img.png,pic,12,,auto,true,true,false,5,20,false,-1,,3,IndexSizeError,1000,1,post,application/x-www-form-urlencoded,dialog,t,true,,"
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestReflectedURLs(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com/dir/page.html", `<html><head><base href="sub/"></head><body><audio src="a.mp4"></audio><video src="b.webm" poster="p.png"></video><source src="c.ogg"><embed src="c.html"><track src="t.vtt" kind="bogus"><area href="x.html"></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var q = function(s) { return document.querySelector(s); };
+		[q('audio').src, q('video').src, q('video').poster, q('source').src, q('embed').src, q('track').src, q('track').kind, q('area').href, q('base').href].join('|');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := "https://example.com/dir/sub/a.mp4|https://example.com/dir/sub/b.webm|https://example.com/dir/sub/p.png|https://example.com/dir/sub/c.ogg|https://example.com/dir/sub/c.html|https://example.com/dir/sub/t.vtt|metadata|https://example.com/dir/sub/x.html|https://example.com/dir/sub/"
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestReflectedFormAttrs(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com/dir/page.html", `<html><body><form id="f"><button id="b">x</button><input id="i" type="submit" formaction="x.php" formmethod="POST"></form><form id="g" action="send"></form></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var q = function(s) { return document.querySelector(s); };
+		var b = q('#b');
+		var res = [q('#f').action, q('#g').action, b.formAction, b.formMethod, b.formEnctype, q('#i').formAction, q('#i').formMethod];
+		b.formAction = 'y';
+		b.formMethod = 'bogus';
+		b.formEnctype = 'text/plain';
+		res.push(b.getAttribute('formaction'), b.formAction, b.formMethod, b.formEnctype);
+		res.join('|');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := "https://example.com/dir/page.html|https://example.com/dir/send|https://example.com/dir/page.html|||https://example.com/dir/x.php|post|y|https://example.com/dir/y|get|text/plain"
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"strings"
 )
 
-type Implementation struct{}
+type Implementation struct {
+	d *Document
+}
 
 func (impl *Implementation) Obj() *js.Object {
 	return vm.NewDynamicObject(impl)
@@ -47,7 +49,11 @@ func (impl *Implementation) Keys() []string {
 }
 
 func (impl *Implementation) CreateHTMLDocument(title ...string) (d *Document) {
-	h := fmt.Sprintf("<html><head><title></title><head><body></body></html>")
+	var t string
+	if len(title) > 0 {
+		t = "<title>" + html.EscapeString(title[0]) + "</title>"
+	}
+	h := fmt.Sprintf("<!DOCTYPE html><html><head>%v</head><body></body></html>", t)
 	doc, err := html.Parse(strings.NewReader(h))
 	if err != nil {
 		log.Printf("parse error")
@@ -56,10 +62,54 @@ func (impl *Implementation) CreateHTMLDocument(title ...string) (d *Document) {
 	return
 }
 
-func (impl *Implementation) HasFeature(args ...any) bool {
-	var feature string
-	if len(args) > 0 {
-		feature, _ = args[0].(string)
+// CreateDocument returns an XML document with the document element qn
+// in the namespace ns (if qn isn't empty) and the doctype dt (if given)
+func (impl *Implementation) CreateDocument(ns, qn js.Value, dt ...any) *Document {
+	var uri, name string
+	if ns == nil || qn == nil {
+		panic(vm.NewTypeError("createDocument: 2 arguments required"))
+	}
+	if !js.IsUndefined(ns) && !js.IsNull(ns) {
+		uri = ns.String()
+	}
+	if !js.IsNull(qn) {
+		name = qn.String()
+	}
+	if name != "" {
+		validateQName(uri, name)
+	}
+	d := NewDocument(&html.Node{Type: html.DocumentNode})
+	switch uri {
+	case xhtmlNS:
+		d.contentType = xhtmlMimeType
+	case svgNS:
+		d.contentType = "image/svg+xml"
+	default:
+		d.contentType = "application/xml"
+	}
+	d.url = "about:blank"
+	if len(dt) > 0 {
+		switch t := dt[0].(type) {
+		case nil:
+		case *DocumentType:
+			t.d.detach(t.n)
+			d.adopt(t)
+			d.doc.AppendChild(t.n)
+		default:
+			panic(vm.NewTypeError("createDocument: parameter 3 is not of type 'DocumentType'"))
+		}
 	}
-	return feature != "XML"
+	if name != "" {
+		d.doc.AppendChild(CreateElementNS(d, uri, name).n)
+	}
+	return d
+}
+
+// HasFeature always returns true as required by the DOM standard
+func (impl *Implementation) HasFeature(args ...any) bool {
+	return true
+}
+
+func (impl *Implementation) CreateDocumentType(qualifiedName, publicId, systemId string) *DocumentType {
+	return newDocumentType(impl.d, qualifiedName, publicId, systemId)
 }
@@ -0,0 +1,306 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"golang.org/x/net/html"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// nodeInterfaces lists the node interface objects exposed on window
+// together with their parent interface. The HTML element interfaces
+// from htmlInterfaces are added below HTMLElement.
+var nodeInterfaces = []struct {
+	name   string
+	parent string
+}{
+	{"EventTarget", ""},
+	{"Node", "EventTarget"},
+	{"Attr", "Node"},
+	{"CharacterData", "Node"},
+	{"Text", "CharacterData"},
+	{"CDATASection", "Text"},
+	{"Comment", "CharacterData"},
+	{"ProcessingInstruction", "CharacterData"},
+	{"DocumentType", "Node"},
+	{"Document", "Node"},
+	{"HTMLDocument", "Document"},
+	{"XMLDocument", "Document"},
+	{"DocumentFragment", "Node"},
+	{"Element", "Node"},
+	{"HTMLElement", "Element"},
+	{"HTMLUnknownElement", "HTMLElement"},
+}
+
+// htmlInterfaces maps tag names to their HTMLElement interface. Tags
+// which aren't listed use HTMLElement if they are in plainElements or
+// valid custom element names and HTMLUnknownElement otherwise.
+var htmlInterfaces = map[string]string{
+	"a":          "HTMLAnchorElement",
+	"area":       "HTMLAreaElement",
+	"audio":      "HTMLAudioElement",
+	"base":       "HTMLBaseElement",
+	"blockquote": "HTMLQuoteElement",
+	"body":       "HTMLBodyElement",
+	"br":         "HTMLBRElement",
+	"button":     "HTMLButtonElement",
+	"canvas":     "HTMLCanvasElement",
+	"caption":    "HTMLTableCaptionElement",
+	"col":        "HTMLTableColElement",
+	"colgroup":   "HTMLTableColElement",
+	"data":       "HTMLDataElement",
+	"datalist":   "HTMLDataListElement",
+	"del":        "HTMLModElement",
+	"details":    "HTMLDetailsElement",
+	"dialog":     "HTMLDialogElement",
+	"dir":        "HTMLDirectoryElement",
+	"div":        "HTMLDivElement",
+	"dl":         "HTMLDListElement",
+	"embed":      "HTMLEmbedElement",
+	"fieldset":   "HTMLFieldSetElement",
+	"font":       "HTMLFontElement",
+	"form":       "HTMLFormElement",
+	"frame":      "HTMLFrameElement",
+	"frameset":   "HTMLFrameSetElement",
+	"h1":         "HTMLHeadingElement",
+	"h2":         "HTMLHeadingElement",
+	"h3":         "HTMLHeadingElement",
+	"h4":         "HTMLHeadingElement",
+	"h5":         "HTMLHeadingElement",
+	"h6":         "HTMLHeadingElement",
+	"head":       "HTMLHeadElement",
+	"hr":         "HTMLHRElement",
+	"html":       "HTMLHtmlElement",
+	"iframe":     "HTMLIFrameElement",
+	"img":        "HTMLImageElement",
+	"input":      "HTMLInputElement",
+	"ins":        "HTMLModElement",
+	"label":      "HTMLLabelElement",
+	"legend":     "HTMLLegendElement",
+	"li":         "HTMLLIElement",
+	"link":       "HTMLLinkElement",
+	"listing":    "HTMLPreElement",
+	"map":        "HTMLMapElement",
+	"marquee":    "HTMLMarqueeElement",
+	"menu":       "HTMLMenuElement",
+	"meta":       "HTMLMetaElement",
+	"meter":      "HTMLMeterElement",
+	"object":     "HTMLObjectElement",
+	"ol":         "HTMLOListElement",
+	"optgroup":   "HTMLOptGroupElement",
+	"option":     "HTMLOptionElement",
+	"output":     "HTMLOutputElement",
+	"p":          "HTMLParagraphElement",
+	"param":      "HTMLParamElement",
+	"picture":    "HTMLPictureElement",
+	"pre":        "HTMLPreElement",
+	"progress":   "HTMLProgressElement",
+	"q":          "HTMLQuoteElement",
+	"script":     "HTMLScriptElement",
+	"select":     "HTMLSelectElement",
+	"slot":       "HTMLSlotElement",
+	"source":     "HTMLSourceElement",
+	"span":       "HTMLSpanElement",
+	"style":      "HTMLStyleElement",
+	"table":      "HTMLTableElement",
+	"tbody":      "HTMLTableSectionElement",
+	"td":         "HTMLTableCellElement",
+	"template":   "HTMLTemplateElement",
+	"textarea":   "HTMLTextAreaElement",
+	"tfoot":      "HTMLTableSectionElement",
+	"th":         "HTMLTableCellElement",
+	"thead":      "HTMLTableSectionElement",
+	"time":       "HTMLTimeElement",
+	"title":      "HTMLTitleElement",
+	"tr":         "HTMLTableRowElement",
+	"track":      "HTMLTrackElement",
+	"ul":         "HTMLUListElement",
+	"video":      "HTMLVideoElement",
+	"xmp":        "HTMLPreElement",
+}
+
+// plainElements are the HTML tags using the HTMLElement interface
+var plainElements = map[string]bool{
+	"abbr": true, "acronym": true, "address": true, "article": true,
+	"aside": true, "b": true, "basefont": true, "bdi": true, "bdo": true,
+	"big": true, "center": true, "cite": true, "code": true, "dd": true,
+	"dfn": true, "dt": true, "em": true, "figcaption": true, "figure": true,
+	"footer": true, "header": true, "hgroup": true, "i": true, "kbd": true,
+	"main": true, "mark": true, "nav": true, "nobr": true, "noembed": true,
+	"noframes": true, "noscript": true, "plaintext": true, "rb": true,
+	"rp": true, "rt": true, "rtc": true, "ruby": true, "s": true,
+	"samp": true, "search": true, "section": true, "small": true,
+	"strike": true, "strong": true, "sub": true, "summary": true,
+	"sup": true, "tt": true, "u": true, "var": true, "wbr": true,
+}
+
+// nodeCtors holds the node constructors of the current runtime
+var nodeCtors = make(map[string]*js.Object)
+
+// nodeConstructors create the nodes of the interfaces which can be
+// constructed from JS. The others throw a TypeError.
+var nodeConstructors = map[string]func(d *Document, call js.ConstructorCall) *js.Object{
+	"Text": func(d *Document, call js.ConstructorCall) *js.Object {
+		var data string
+		if a := call.Argument(0); !js.IsUndefined(a) {
+			data = a.String()
+		}
+		return d.CreateTextNode(data).Obj()
+	},
+	"Comment": func(d *Document, call js.ConstructorCall) *js.Object {
+		var data string
+		if a := call.Argument(0); !js.IsUndefined(a) {
+			data = a.String()
+		}
+		return d.CreateComment(data).Obj()
+	},
+	"DocumentFragment": func(d *Document, call js.ConstructorCall) *js.Object {
+		return d.CreateDocumentFragment().Obj()
+	},
+	"Document": func(d *Document, call js.ConstructorCall) *js.Object {
+		doc := NewDocument(&html.Node{Type: html.DocumentNode})
+		doc.contentType = "application/xml"
+		doc.url = d.url
+		return doc.Obj()
+	},
+}
+
+// protoRecvs are the Go types whose members are defined on the
+// prototype of an interface. Members inherited from the parent's type
+// are skipped.
+var protoRecvs = map[string]Gettable{
+	"Node":                  &node{},
+	"Attr":                  &Attr{},
+	"CharacterData":         &CharacterData{},
+	"Text":                  &Text{},
+	"ProcessingInstruction": &ProcessingInstruction{},
+	"DocumentType":          &DocumentType{},
+	"Element":               &Element{},
+}
+
+// initNodeCtors defines the node interface objects for the document d.
+// Prototypes form a chain like EventTarget → Node → Element →
+// HTMLElement → HTMLDivElement so instanceof works for all nodes.
+func initNodeCtors(d *Document) {
+	nodeCtors = make(map[string]*js.Object)
+	ifaces := append([]struct {
+		name   string
+		parent string
+	}{}, nodeInterfaces...)
+	names := make([]string, 0, len(htmlInterfaces))
+	seen := make(map[string]bool)
+	for _, name := range htmlInterfaces {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ifaces = append(ifaces, struct {
+			name   string
+			parent string
+		}{name, "HTMLElement"})
+	}
+	for _, ni := range ifaces {
+		name := ni.name
+		c := newCtor(name, func(call js.ConstructorCall) *js.Object {
+			f, ok := nodeConstructors[name]
+			if !ok {
+				panic(vm.NewTypeError("Illegal constructor"))
+			}
+			o := f(d, call)
+			o.SetPrototype(call.This.Prototype())
+			return o
+		})
+		proto := c.Get("prototype").(*js.Object)
+		if p, ok := nodeCtors[ni.parent]; ok {
+			proto.SetPrototype(p.Get("prototype").(*js.Object))
+			c.SetPrototype(p)
+		}
+		proto.DefineDataPropertySymbol(js.SymToStringTag, vm.ToValue(name), js.FLAG_FALSE, js.FLAG_TRUE, js.FLAG_FALSE)
+		if recv, ok := protoRecvs[name]; ok {
+			protoMembers(proto, recv, protoRecvs[ni.parent])
+		}
+		protoReflected(proto, name)
+		nodeCtors[name] = c
+	}
+	consts := map[string]int{
+		"ELEMENT_NODE":                1,
+		"ATTRIBUTE_NODE":              2,
+		"TEXT_NODE":                   3,
+		"CDATA_SECTION_NODE":          4,
+		"PROCESSING_INSTRUCTION_NODE": 7,
+		"COMMENT_NODE":                8,
+		"DOCUMENT_NODE":               9,
+		"DOCUMENT_TYPE_NODE":          10,
+		"DOCUMENT_FRAGMENT_NODE":      11,
+
+		"DOCUMENT_POSITION_DISCONNECTED":            positionDisconnected,
+		"DOCUMENT_POSITION_PRECEDING":               positionPreceding,
+		"DOCUMENT_POSITION_FOLLOWING":               positionFollowing,
+		"DOCUMENT_POSITION_CONTAINS":                positionContains,
+		"DOCUMENT_POSITION_CONTAINED_BY":            positionContainedBy,
+		"DOCUMENT_POSITION_IMPLEMENTATION_SPECIFIC": positionImplementationSpecific,
+	}
+	n := nodeCtors["Node"]
+	for k, v := range consts {
+		n.Set(k, v)
+		n.Get("prototype").(*js.Object).Set(k, v)
+	}
+}
+
+// newCtor returns the interface object name which constructs objects
+// with f. Its name is set since Go funcs are named after the Go symbol.
+func newCtor(name string, f func(call js.ConstructorCall) *js.Object) *js.Object {
+	c := vm.ToValue(f).(*js.Object)
+	c.DefineDataProperty("name", vm.ToValue(name), js.FLAG_FALSE, js.FLAG_FALSE, js.FLAG_TRUE)
+	return c
+}
+
+// protoMembers defines the methods, getters and props of recv on proto
+// which aren't inherited from the Go type of parent (nil for none).
+// Props are defined as getters.
+func protoMembers(proto *js.Object, recv, parent Gettable) {
+	var pt reflect.Type
+	if parent != nil {
+		pt = reflect.TypeOf(parent)
+	}
+	var ms, gs []string
+	for _, k := range Calls(recv) {
+		switch k {
+		case "obj", "getters", "props", "toString", "node":
+			continue
+		}
+		if pt != nil {
+			if _, ok := pt.MethodByName(strings.Title(k)); ok || parent.Props()[k] {
+				continue
+			}
+		}
+		if recv.Getters()[k] || recv.Props()[k] {
+			gs = append(gs, k)
+		} else {
+			ms = append(ms, k)
+		}
+	}
+	protoMethods(proto, ms...)
+	protoGetters(proto, gs...)
+}
+
+func (el *Element) iface() string {
+	switch {
+	case el.n.Type != html.ElementNode:
+		return "Node"
+	case el.d.namespace(el.n) != xhtmlNS:
+		return "Element"
+	}
+	l := el.LocalName()
+	if i, ok := htmlInterfaces[l]; ok {
+		return i
+	}
+	if plainElements[l] || strings.Contains(l, "-") {
+		return "HTMLElement"
+	}
+	return "HTMLUnknownElement"
+}
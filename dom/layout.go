@@ -0,0 +1,829 @@
+package dom
+
+import (
+	"fmt"
+	"golang.org/x/net/html"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Font metrics of the built-in layout relative to the font size
+const (
+	charWidth  = 0.5
+	lineFactor = 1.2
+)
+
+// laidOut documents in the current frame
+var laidOut = make(map[*Document]bool)
+
+// layout computes the boxes of the elements of a document without a
+// host. It supports block and inline flow, basic flex, replaced
+// elements and positioning with a fixed viewport and font metrics.
+type layout struct {
+	c    *cascade
+	vals map[*html.Node]map[string]string
+	fs   map[*html.Node]float64
+
+	// abs are the absolutely positioned elements at their static
+	// position
+	abs []static
+}
+
+type static struct {
+	n    *html.Node
+	x, y float64
+}
+
+// sizing overrides the used size of a box. fw and fh are the margin
+// box size if not negative.
+type sizing struct {
+	shrink bool
+	fw, fh float64
+}
+
+var flowSizing = sizing{fw: -1, fh: -1}
+
+// layout the document d into geomCache unless already done in this
+// frame
+func (d *Document) layout() {
+	if laidOut[d] {
+		return
+	}
+	laidOut[d] = true
+	root := d.doc.FirstChild
+	for root != nil && root.Type != html.ElementNode {
+		root = root.NextSibling
+	}
+	if root == nil {
+		return
+	}
+	l := &layout{
+		c:    newCascade(d),
+		vals: make(map[*html.Node]map[string]string),
+		fs:   make(map[*html.Node]float64),
+	}
+	vw, vh := float64(ViewportWidth), float64(ViewportHeight)
+	if l.get(root, "display") != "none" {
+		l.block(root, 0, 0, vw, vh, flowSizing)
+	}
+	for i := 0; i < len(l.abs); i++ {
+		l.position(l.abs[i])
+	}
+}
+
+// LayoutGeom returns the border box of the element at path p like Geom
+// but computed by the built-in layout
+func (d *Document) LayoutGeom(p string) (string, error) {
+	n := d.nodeAt(p)
+	if n == nil {
+		return "", fmt.Errorf("no element at %v", p)
+	}
+	d.layout()
+	b, ok := geomCache[n]
+	if !ok || !b.ok {
+		return "", fmt.Errorf("no box for %v", p)
+	}
+	f := func(x float64) string {
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	}
+	return f(b.x1) + "," + f(b.y1) + "," + f(b.x2) + "," + f(b.y2), nil
+}
+
+// nodeAt resolves a path as returned by path
+func (d *Document) nodeAt(p string) (n *html.Node) {
+	n = grep(d.doc, "body")
+	ps := strings.Split(strings.Trim(p, "/"), "/")
+	if n == nil || len(ps) == 0 || ps[0] != "0" {
+		return nil
+	}
+	for _, s := range ps[1:] {
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return nil
+		}
+		c := n.FirstChild
+		for ; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode || (c.Type == html.TextNode && strings.TrimSpace(c.Data) != "") {
+				if i == 0 {
+					break
+				}
+				i--
+			}
+		}
+		if c == nil {
+			return nil
+		}
+		n = c
+	}
+	return
+}
+
+func (l *layout) get(n *html.Node, prop string) string {
+	m, ok := l.vals[n]
+	if !ok {
+		m = make(map[string]string)
+		l.vals[n] = m
+	}
+	v, ok := m[prop]
+	if !ok {
+		v = l.c.value(n, prop)
+		m[prop] = v
+	}
+	return v
+}
+
+// fontSize of n in pixels
+func (l *layout) fontSize(n *html.Node) float64 {
+	if n == nil || n.Type != html.ElementNode {
+		return 16
+	}
+	if fs, ok := l.fs[n]; ok {
+		return fs
+	}
+	pfs := l.fontSize(n.Parent)
+	fs := pfs
+	if cd, ok := l.c.declared(n, "font-size"); ok {
+		switch v := strings.ToLower(cd.dl.val); v {
+		case "xx-small":
+			fs = 9
+		case "x-small":
+			fs = 10
+		case "small":
+			fs = 13
+		case "medium", "initial":
+			fs = 16
+		case "large":
+			fs = 18
+		case "x-large":
+			fs = 24
+		case "xx-large":
+			fs = 32
+		case "smaller":
+			fs = pfs / 1.2
+		case "larger":
+			fs = pfs * 1.2
+		default:
+			if strings.HasSuffix(v, "em") && !strings.HasSuffix(v, "rem") {
+				x, _ := strconv.ParseFloat(strings.TrimSuffix(v, "em"), 64)
+				fs = x * pfs
+			} else if x, ok := l.length(n, v, pfs); ok {
+				fs = x
+			}
+		}
+	}
+	l.fs[n] = fs
+	return fs
+}
+
+// lineHeight of the lines of n in pixels
+func (l *layout) lineHeight(n *html.Node) float64 {
+	fs := l.fontSize(n)
+	for a := n; a != nil && a.Type == html.ElementNode; a = a.Parent {
+		cd, ok := l.c.declared(a, "line-height")
+		if !ok || cd.dl.val == "inherit" {
+			continue
+		}
+		v := strings.ToLower(cd.dl.val)
+		if x, err := strconv.ParseFloat(v, 64); err == nil {
+			return x * fs
+		}
+		if x, ok := l.length(a, v, l.fontSize(a)); ok {
+			return x
+		}
+		break
+	}
+	return lineFactor * fs
+}
+
+// length in pixels of the value v where percentages refer to base. ok
+// is false for auto, none and unsupported values.
+func (l *layout) length(n *html.Node, v string, base float64) (x float64, ok bool) {
+	units := []struct {
+		suffix string
+		f      func() float64
+	}{
+		{"px", func() float64 { return 1 }},
+		{"rem", func() float64 { return 16 }},
+		{"em", func() float64 { return l.fontSize(n) }},
+		{"ex", func() float64 { return l.fontSize(n) * charWidth }},
+		{"ch", func() float64 { return l.fontSize(n) * charWidth }},
+		{"vw", func() float64 { return float64(ViewportWidth) / 100 }},
+		{"vh", func() float64 { return float64(ViewportHeight) / 100 }},
+		{"vmin", func() float64 { return math.Min(float64(ViewportWidth), float64(ViewportHeight)) / 100 }},
+		{"vmax", func() float64 { return math.Max(float64(ViewportWidth), float64(ViewportHeight)) / 100 }},
+		{"pt", func() float64 { return 4.0 / 3 }},
+		{"%", func() float64 { return base / 100 }},
+	}
+	v = strings.TrimSpace(v)
+	for _, u := range units {
+		if !strings.HasSuffix(v, u.suffix) {
+			continue
+		}
+		if u.suffix == "%" && base < 0 {
+			return 0, false
+		}
+		x, err := strconv.ParseFloat(strings.TrimSuffix(v, u.suffix), 64)
+		if err != nil {
+			return 0, false
+		}
+		return x * u.f(), true
+	}
+	if x, err := strconv.ParseFloat(v, 64); err == nil && x == 0 {
+		return 0, true
+	}
+	return 0, false
+}
+
+// edges are the used margin, border and padding widths in the order
+// top, right, bottom, left
+type edges struct {
+	m, b, p [4]float64
+	autoM   [4]bool
+}
+
+func (e edges) h() float64 {
+	return e.m[1] + e.m[3] + e.b[1] + e.b[3] + e.p[1] + e.p[3]
+}
+
+func (e edges) v() float64 {
+	return e.m[0] + e.m[2] + e.b[0] + e.b[2] + e.p[0] + e.p[2]
+}
+
+func (l *layout) edges(n *html.Node, cbw float64) (e edges) {
+	for i, s := range []string{"top", "right", "bottom", "left"} {
+		mv := l.get(n, "margin-"+s)
+		e.autoM[i] = mv == "auto"
+		e.m[i], _ = l.length(n, mv, cbw)
+		e.b[i], _ = l.length(n, l.get(n, "border-"+s+"-width"), -1)
+		e.p[i], _ = l.length(n, l.get(n, "padding-"+s), cbw)
+	}
+	return
+}
+
+// intrinsic size of replaced elements
+func (l *layout) intrinsic(n *html.Node) (w, h float64, ok bool) {
+	fs := l.fontSize(n)
+	switch n.Data {
+	case "img", "svg":
+	case "canvas", "iframe", "video", "embed", "object":
+		w, h = 300, 150
+	case "textarea":
+		w, h = 20*charWidth*fs, 2*lineFactor*fs
+	case "input":
+		switch strings.ToLower(attr(*n, "type")) {
+		case "hidden":
+		case "checkbox", "radio":
+			w, h = 13, 13
+		case "submit", "reset", "button":
+			w, h = float64(utf8.RuneCountInString(attr(*n, "value")))*charWidth*fs+12, lineFactor*fs+6
+		default:
+			w, h = 20*charWidth*fs, lineFactor*fs+4
+		}
+	default:
+		return 0, 0, false
+	}
+	if x, err := strconv.ParseFloat(attr(*n, "width"), 64); err == nil {
+		w = x
+	}
+	if x, err := strconv.ParseFloat(attr(*n, "height"), 64); err == nil {
+		h = x
+	}
+	return w, h, true
+}
+
+// outerDisplay is "none", "block", "inline" or "atomic" for
+// inline-level boxes laid out like blocks
+func (l *layout) outerDisplay(n *html.Node) string {
+	d := l.get(n, "display")
+	switch d {
+	case "none":
+		return "none"
+	case "inline", "contents", "ruby", "ruby-text":
+		if _, _, ok := l.intrinsic(n); ok {
+			return "atomic"
+		}
+		return "inline"
+	case "inline-block", "inline-flex", "inline-grid", "inline-table":
+		return "atomic"
+	}
+	return "block"
+}
+
+func isFlexRow(l *layout, n *html.Node) bool {
+	switch l.get(n, "display") {
+	case "flex", "inline-flex":
+		return !strings.HasPrefix(l.get(n, "flex-direction"), "column")
+	case "table-row":
+		return true
+	}
+	return false
+}
+
+func isFlexColumn(l *layout, n *html.Node) bool {
+	switch l.get(n, "display") {
+	case "flex", "inline-flex":
+		return strings.HasPrefix(l.get(n, "flex-direction"), "column")
+	}
+	return false
+}
+
+func (l *layout) outOfFlow(n *html.Node) bool {
+	p := l.get(n, "position")
+	return p == "absolute" || p == "fixed"
+}
+
+// block lays out the element n with the top left corner of its margin
+// box at x, y in a containing block of width cbw and height cbh (or -1
+// if unknown). It returns the size of the margin box.
+func (l *layout) block(n *html.Node, x, y, cbw, cbh float64, sz sizing) (ow, oh float64) {
+	e := l.edges(n, cbw)
+	borderBox := l.get(n, "box-sizing") == "border-box"
+	iw, ih, replaced := l.intrinsic(n)
+	w, wok := l.length(n, l.get(n, "width"), cbw)
+	if wok && borderBox {
+		w -= e.b[1] + e.b[3] + e.p[1] + e.p[3]
+	}
+	h, hok := l.length(n, l.get(n, "height"), cbh)
+	if hok && borderBox {
+		h -= e.b[0] + e.b[2] + e.p[0] + e.p[2]
+	}
+	switch {
+	case sz.fw >= 0:
+		w = sz.fw - e.h()
+	case wok:
+	case replaced && hok && ih > 0:
+		w = iw * h / ih
+	case replaced:
+		w = iw
+	case sz.shrink:
+		w = math.Min(l.maxContent(n), cbw) - e.h()
+	default:
+		w = cbw - e.h()
+	}
+	if mw, ok := l.length(n, l.get(n, "max-width"), cbw); ok && w > mw {
+		w = mw
+	}
+	if mw, ok := l.length(n, l.get(n, "min-width"), cbw); ok && w < mw {
+		w = mw
+	}
+	w = math.Max(w, 0)
+	if free := cbw - w - e.h(); free > 0 && sz.fw < 0 && !sz.shrink {
+		switch {
+		case e.autoM[1] && e.autoM[3]:
+			e.m[3] += free / 2
+			e.m[1] += free / 2
+		case e.autoM[3]:
+			e.m[3] += free
+		}
+	}
+	cx := x + e.m[3] + e.b[3] + e.p[3]
+	cy := y + e.m[0] + e.b[0] + e.p[0]
+	ch := -1.0
+	switch {
+	case sz.fh >= 0:
+		ch = sz.fh - e.v()
+	case hok:
+		ch = h
+	case replaced && wok && iw > 0:
+		ch = ih * w / iw
+	case replaced:
+		ch = ih
+	}
+	contentH := 0.0
+	if !replaced {
+		switch {
+		case isFlexRow(l, n):
+			contentH = l.flexRow(n, cx, cy, w, ch)
+		case isFlexColumn(l, n):
+			contentH = l.flexColumn(n, cx, cy, w, ch)
+		default:
+			contentH = l.flow(n, cx, cy, w, ch)
+		}
+	}
+	if ch < 0 {
+		ch = contentH
+	}
+	if mh, ok := l.length(n, l.get(n, "max-height"), cbh); ok && ch > mh {
+		ch = mh
+	}
+	if mh, ok := l.length(n, l.get(n, "min-height"), cbh); ok && ch < mh {
+		ch = mh
+	}
+	ch = math.Max(ch, 0)
+	b := box{
+		x1: x + e.m[3],
+		y1: y + e.m[0],
+		ok: true,
+	}
+	b.x2 = b.x1 + e.b[3] + e.p[3] + w + e.p[1] + e.b[1]
+	b.y2 = b.y1 + e.b[0] + e.p[0] + ch + e.p[2] + e.b[2]
+	geomCache[n] = b
+	if l.get(n, "position") == "relative" {
+		dx, dy := l.offsets(n, cbw, cbh)
+		l.shift(n, dx, dy)
+	}
+	return b.x2 - b.x1 + e.m[1] + e.m[3], b.y2 - b.y1 + e.m[0] + e.m[2]
+}
+
+// offsets of a relatively positioned element
+func (l *layout) offsets(n *html.Node, cbw, cbh float64) (dx, dy float64) {
+	if x, ok := l.length(n, l.get(n, "left"), cbw); ok {
+		dx = x
+	} else if x, ok := l.length(n, l.get(n, "right"), cbw); ok {
+		dx = -x
+	}
+	if y, ok := l.length(n, l.get(n, "top"), cbh); ok {
+		dy = y
+	} else if y, ok := l.length(n, l.get(n, "bottom"), cbh); ok {
+		dy = -y
+	}
+	return
+}
+
+// shift the boxes of the subtree of n
+func (l *layout) shift(n *html.Node, dx, dy float64) {
+	if dx == 0 && dy == 0 {
+		return
+	}
+	if b, ok := geomCache[n]; ok && b.ok {
+		b.x1 += dx
+		b.x2 += dx
+		b.y1 += dy
+		b.y2 += dy
+		geomCache[n] = b
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			l.shift(c, dx, dy)
+		}
+	}
+}
+
+// position an absolutely positioned element
+func (l *layout) position(s static) {
+	n := s.n
+	cb := box{x2: float64(ViewportWidth), y2: float64(ViewportHeight)}
+	if l.get(n, "position") == "absolute" {
+		for a := n.Parent; a != nil && a.Type == html.ElementNode; a = a.Parent {
+			if l.get(a, "position") == "static" {
+				continue
+			}
+			if b, ok := geomCache[a]; ok && b.ok {
+				e := l.edges(a, 0)
+				cb = box{x1: b.x1 + e.b[3], y1: b.y1 + e.b[0], x2: b.x2 - e.b[1], y2: b.y2 - e.b[2]}
+			}
+			break
+		}
+	}
+	cbw, cbh := cb.x2-cb.x1, cb.y2-cb.y1
+	left, lok := l.length(n, l.get(n, "left"), cbw)
+	right, rok := l.length(n, l.get(n, "right"), cbw)
+	top, tok := l.length(n, l.get(n, "top"), cbh)
+	bottom, bok := l.length(n, l.get(n, "bottom"), cbh)
+	sz := sizing{shrink: true, fw: -1, fh: -1}
+	if _, ok := l.length(n, l.get(n, "width"), cbw); !ok && lok && rok {
+		sz.fw = math.Max(cbw-left-right, 0)
+	}
+	if _, ok := l.length(n, l.get(n, "height"), cbh); !ok && tok && bok {
+		sz.fh = math.Max(cbh-top-bottom, 0)
+	}
+	ow, oh := l.block(n, s.x, s.y, cbw, cbh, sz)
+	x, y := s.x, s.y
+	switch {
+	case lok:
+		x = cb.x1 + left
+	case rok:
+		x = cb.x2 - right - ow
+	}
+	switch {
+	case tok:
+		y = cb.y1 + top
+	case bok:
+		y = cb.y2 - bottom - oh
+	}
+	l.shift(n, x-s.x, y-s.y)
+}
+
+// maxContent is the width of the margin box of n without line breaks
+func (l *layout) maxContent(n *html.Node) float64 {
+	if n.Type == html.TextNode {
+		words := strings.Fields(n.Data)
+		if len(words) == 0 {
+			return 0
+		}
+		cw := charWidth * l.fontSize(n.Parent)
+		return float64(utf8.RuneCountInString(strings.Join(words, " "))) * cw
+	}
+	if n.Type != html.ElementNode || l.outerDisplay(n) == "none" || l.outOfFlow(n) {
+		return 0
+	}
+	e := l.edges(n, 0)
+	if w, ok := l.length(n, l.get(n, "width"), -1); ok {
+		if l.get(n, "box-sizing") == "border-box" {
+			return w + e.m[1] + e.m[3]
+		}
+		return w + e.h()
+	}
+	if w, _, ok := l.intrinsic(n); ok {
+		return w + e.h()
+	}
+	w, line := 0.0, 0.0
+	row := isFlexRow(l, n)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		cw := l.maxContent(c)
+		if row || c.Type == html.TextNode || l.outerDisplay(c) != "block" {
+			line += cw
+			continue
+		}
+		w = math.Max(w, math.Max(line, cw))
+		line = 0
+	}
+	return math.Max(w, line) + e.h()
+}
+
+// ifc is an inline formatting context that places inline content
+// into lines and stacks blocks between them
+type ifc struct {
+	x0, w, cbh float64
+
+	// y is the top of the current line, cx the current position
+	// and lh the height of the current line
+	y, cx, lh float64
+
+	space bool
+
+	// open inline boxes that grow with their content
+	open []*html.Node
+}
+
+func (f *ifc) breakLine() {
+	if f.cx > f.x0 || f.lh > 0 {
+		f.y += f.lh
+	}
+	f.cx, f.lh, f.space = f.x0, 0, false
+}
+
+// fragment places content of width w and height h on the current line
+func (f *ifc) fragment(w, h float64) (x float64) {
+	if f.cx+w > f.x0+f.w && f.cx > f.x0 {
+		f.breakLine()
+	}
+	x = f.cx
+	f.cx += w
+	f.lh = math.Max(f.lh, h)
+	for _, n := range f.open {
+		b := geomCache[n]
+		b.x1, b.y1 = math.Min(b.x1, x), math.Min(b.y1, f.y)
+		b.x2, b.y2 = math.Max(b.x2, x+w), math.Max(b.y2, f.y+h)
+		geomCache[n] = b
+	}
+	return
+}
+
+// flow lays out the children of n in the content box at x, y of width
+// w and returns the height of the content
+func (l *layout) flow(n *html.Node, x, y, w, h float64) float64 {
+	f := &ifc{x0: x, w: w, cbh: h, y: y, cx: x}
+	l.flowChildren(f, n)
+	f.breakLine()
+	return f.y - y
+}
+
+func (l *layout) flowChildren(f *ifc, n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.TextNode:
+			l.text(f, c)
+		case html.ElementNode:
+			l.flowChild(f, c)
+		}
+	}
+}
+
+func (l *layout) flowChild(f *ifc, c *html.Node) {
+	d := l.outerDisplay(c)
+	switch {
+	case d == "none":
+	case l.outOfFlow(c):
+		l.abs = append(l.abs, static{n: c, x: f.cx, y: f.y})
+	case c.Data == "br":
+		lh := l.lineHeight(c)
+		geomCache[c] = box{x1: f.cx, y1: f.y, x2: f.cx, y2: f.y + lh, ok: true}
+		f.lh = math.Max(f.lh, lh)
+		f.breakLine()
+	case d == "block":
+		f.breakLine()
+		_, oh := l.block(c, f.x0, f.y, f.w, f.cbh, flowSizing)
+		f.y += oh
+	case d == "atomic":
+		if f.cx+math.Min(l.maxContent(c), f.w) > f.x0+f.w && f.cx > f.x0 {
+			f.breakLine()
+		}
+		ow, oh := l.block(c, f.cx, f.y, f.w, f.cbh, sizing{shrink: true, fw: -1, fh: -1})
+		f.fragment(ow, oh)
+		f.space = false
+	default:
+		e := l.edges(c, f.w)
+		lh := l.lineHeight(c)
+		if f.space && f.cx > f.x0 {
+			f.fragment(charWidth*l.fontSize(c), 0)
+			f.space = false
+		}
+		f.cx += e.m[3]
+		geomCache[c] = box{x1: f.cx, y1: f.y, x2: f.cx, y2: f.y + lh, ok: true}
+		f.open = append(f.open, c)
+		f.fragment(e.b[3]+e.p[3], lh)
+		l.flowChildren(f, c)
+		f.fragment(e.b[1]+e.p[1], lh)
+		f.open = f.open[:len(f.open)-1]
+		f.cx += e.m[1]
+		if l.get(c, "position") == "relative" {
+			dx, dy := l.offsets(c, f.w, f.cbh)
+			l.shift(c, dx, dy)
+		}
+	}
+}
+
+// text places the words of the text node n
+func (l *layout) text(f *ifc, n *html.Node) {
+	p := n.Parent
+	if p == nil || p.Type != html.ElementNode {
+		return
+	}
+	fs := l.fontSize(p)
+	cw := charWidth * fs
+	lh := l.lineHeight(p)
+	if ws := l.get(p, "white-space"); ws == "pre" || ws == "pre-wrap" || ws == "break-spaces" {
+		for i, line := range strings.Split(n.Data, "\n") {
+			if i > 0 {
+				f.lh = math.Max(f.lh, lh)
+				f.breakLine()
+			}
+			if line != "" {
+				f.fragment(float64(utf8.RuneCountInString(line))*cw, lh)
+			}
+		}
+		return
+	}
+	if strings.TrimSpace(n.Data) == "" {
+		f.space = f.space || n.Data != ""
+		return
+	}
+	if strings.TrimLeft(n.Data, " \t\n\r\f") != n.Data {
+		f.space = true
+	}
+	for _, w := range strings.Fields(n.Data) {
+		ww := float64(utf8.RuneCountInString(w)) * cw
+		if f.space && f.cx > f.x0 {
+			if f.cx+cw+ww > f.x0+f.w {
+				f.breakLine()
+			} else {
+				f.fragment(cw, lh)
+			}
+		}
+		f.fragment(ww, lh)
+		f.space = true
+	}
+	f.space = strings.TrimRight(n.Data, " \t\n\r\f") != n.Data
+}
+
+// items of a flex container in flow whose content box starts at x, y
+func (l *layout) items(n *html.Node, x, y float64) (is []*html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || l.outerDisplay(c) == "none" {
+			continue
+		}
+		if l.outOfFlow(c) {
+			l.abs = append(l.abs, static{n: c, x: x, y: y})
+			continue
+		}
+		is = append(is, c)
+	}
+	return
+}
+
+func (l *layout) gap(n *html.Node, prop string, base float64) float64 {
+	g, _ := l.length(n, l.get(n, prop), base)
+	return g
+}
+
+// flexRow lays out the items of n in a single row
+func (l *layout) flexRow(n *html.Node, x, y, w, h float64) float64 {
+	is := l.items(n, x, y)
+	if len(is) == 0 {
+		return 0
+	}
+	gap := l.gap(n, "column-gap", w)
+	sizes := make([]float64, len(is))
+	free := w - gap*float64(len(is)-1)
+	grow, shrink := 0.0, 0.0
+	for i, c := range is {
+		e := l.edges(c, w)
+		if b, ok := l.length(c, l.get(c, "flex-basis"), w); ok {
+			sizes[i] = b + e.h()
+		} else {
+			sizes[i] = l.maxContent(c)
+		}
+		free -= sizes[i]
+		g, _ := strconv.ParseFloat(l.get(c, "flex-grow"), 64)
+		s, _ := strconv.ParseFloat(l.get(c, "flex-shrink"), 64)
+		grow += g
+		shrink += s * sizes[i]
+	}
+	if n.Data == "tr" && grow == 0 {
+		// table cells share the row
+		for i := range sizes {
+			sizes[i] += free / float64(len(sizes))
+		}
+		free = 0
+	}
+	for i, c := range is {
+		switch {
+		case free > 0 && grow > 0:
+			g, _ := strconv.ParseFloat(l.get(c, "flex-grow"), 64)
+			sizes[i] += free * g / grow
+		case free < 0 && shrink > 0:
+			s, _ := strconv.ParseFloat(l.get(c, "flex-shrink"), 64)
+			sizes[i] = math.Max(sizes[i]+free*s*sizes[i]/shrink, 0)
+		}
+	}
+	if grow > 0 || free < 0 {
+		free = 0
+	}
+	cx, between := x, gap
+	switch l.get(n, "justify-content") {
+	case "flex-end", "end", "right":
+		cx += free
+	case "center":
+		cx += free / 2
+	case "space-between":
+		if len(is) > 1 {
+			between += free / float64(len(is)-1)
+		}
+	case "space-around":
+		cx += free / float64(2*len(is))
+		between += free / float64(len(is))
+	case "space-evenly":
+		cx += free / float64(len(is)+1)
+		between += free / float64(len(is)+1)
+	}
+	xs := make([]float64, len(is))
+	lineH := h
+	for i, c := range is {
+		xs[i] = cx
+		_, oh := l.block(c, cx, y, sizes[i], h, sizing{fw: sizes[i], fh: -1})
+		if h < 0 {
+			lineH = math.Max(lineH, oh)
+		}
+		cx += sizes[i] + between
+	}
+	align := l.get(n, "align-items")
+	for i, c := range is {
+		a := l.get(c, "align-self")
+		if a == "auto" {
+			a = align
+		}
+		b := geomCache[c]
+		e := l.edges(c, w)
+		oh := b.y2 - b.y1 + e.m[0] + e.m[2]
+		_, fixed := l.length(c, l.get(c, "height"), h)
+		switch a {
+		case "normal", "stretch":
+			if !fixed && oh != lineH {
+				l.block(c, xs[i], y, sizes[i], h, sizing{fw: sizes[i], fh: lineH})
+			}
+		case "center":
+			l.shift(c, 0, (lineH-oh)/2)
+		case "flex-end", "end":
+			l.shift(c, 0, lineH-oh)
+		}
+	}
+	return lineH
+}
+
+// flexColumn stacks the items of n
+func (l *layout) flexColumn(n *html.Node, x, y, w, h float64) float64 {
+	gap := l.gap(n, "row-gap", h)
+	cy := y
+	for i, c := range l.items(n, x, y) {
+		if i > 0 {
+			cy += gap
+		}
+		sz := flowSizing
+		switch l.get(n, "align-items") {
+		case "center", "flex-start", "flex-end", "start", "end":
+			sz.shrink = true
+		}
+		ow, oh := l.block(c, x, cy, w, h, sz)
+		switch l.get(n, "align-items") {
+		case "center":
+			l.shift(c, (w-ow)/2, 0)
+		case "flex-end", "end":
+			l.shift(c, w-ow, 0)
+		}
+		cy += oh
+	}
+	return cy - y
+}
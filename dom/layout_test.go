@@ -0,0 +1,39 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"testing"
+)
+
+func TestLayout(t *testing.T) {
+	vm := js.New()
+	d, err := Init(vm, "https://example.com", `<html><head><style>
+		#c { width: 200px; padding: 10px; border: 5px solid; margin: 0 auto }
+		.f { display: flex; height: 50px }
+		.f div { flex-grow: 1 }
+		#r { position: relative }
+		#abs { position: absolute; top: 5px; right: 10px; width: 20px; height: 20px }
+	</style></head><body><p id="a">hello world</p><div id="c"><span id="s">ab cd</span></div><div hidden id="h">x</div><div class="f"><div id="f1" style="flex-grow: 0; width: 100px"></div><div id="f2"></div></div><div id="r"><img id="i" width="40" height="30"><div id="abs"></div></div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var log = [];
+		['a', 'c', 's', 'h', 'f1', 'f2', 'i', 'abs'].forEach(function(id) {
+			var r = document.getElementById(id).getBoundingClientRect();
+			log.push(id + ':' + [r.x, r.y, r.width, r.height].map(function(x) { return Math.round(x * 10) / 10 }).join(','));
+		});
+		log.push(document.getElementById('c').clientWidth, document.getElementById('h').getClientRects().length);
+		log.join(' ');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != `a:8,24,1264,19.2 c:525,59.2,230,49.2 s:540,74.2,40,19.2 h:0,0,0,0 f1:8,108.4,100,50 f2:108,108.4,1164,50 i:8,158.4,40,30 abs:1242,163.4,20,20 220 0` {
+		t.Fatalf("%v", v)
+	}
+	g, err := d.LayoutGeom("/0/1")
+	if err != nil || g != "525,59.2,755,108.4" {
+		t.Fatalf("%v %v", g, err)
+	}
+}
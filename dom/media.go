@@ -0,0 +1,459 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var mediaQueryListCtor *js.Object
+
+// initMedia defines MediaQueryList which can't be constructed
+func initMedia() {
+	mediaQueryListCtor = newCtor("MediaQueryList", func(call js.ConstructorCall) *js.Object {
+		panic(vm.NewTypeError("Illegal constructor"))
+	})
+	proto := mediaQueryListCtor.Get("prototype").(*js.Object)
+	if et, ok := nodeCtors["EventTarget"]; ok {
+		proto.SetPrototype(et.Get("prototype").(*js.Object))
+		mediaQueryListCtor.SetPrototype(et)
+	}
+	proto.DefineDataPropertySymbol(js.SymToStringTag, vm.ToValue("MediaQueryList"), js.FLAG_FALSE, js.FLAG_TRUE, js.FLAG_FALSE)
+	protoMembers(proto, &MediaQueryList{}, nil)
+}
+
+// mediaMatches is true if the media query list q matches the viewport.
+// An empty list matches. It is used for matchMedia, @media rules and
+// the media attribute of <style> and <link>.
+func mediaMatches(q string) bool {
+	q = strings.TrimSpace(strings.ToLower(q))
+	if q == "" {
+		return true
+	}
+	for _, mq := range splitTop(q, ",") {
+		if mediaQueryMatches(strings.TrimSpace(mq)) {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaQueryMatches evaluates a query like "not print and (hover)".
+// Invalid queries don't match.
+func mediaQueryMatches(q string) bool {
+	q = strings.Join(strings.Fields(q), " ")
+	if strings.HasPrefix(q, "(") || strings.HasPrefix(q, "not (") {
+		ok, valid := mediaCondition(q)
+		return ok && valid
+	}
+	not := false
+	fs := strings.SplitN(q, " ", 2)
+	if (fs[0] == "not" || fs[0] == "only") && len(fs) == 2 {
+		not = fs[0] == "not"
+		fs = strings.SplitN(fs[1], " ", 2)
+	}
+	if fs[0] == "" || strings.ContainsAny(fs[0], "()") {
+		return false
+	}
+	ok := fs[0] == "all" || fs[0] == "screen"
+	if len(fs) == 2 {
+		rest, found := strings.CutPrefix(fs[1], "and ")
+		if !found {
+			return false
+		}
+		cond, valid := mediaCondition(rest)
+		if !valid || strings.HasPrefix(strings.TrimSpace(rest), "not ") || len(splitTop(rest, " or ")) > 1 {
+			return false
+		}
+		ok = ok && cond
+	}
+	return ok != not
+}
+
+// mediaCondition evaluates a condition of media features combined
+// with and, or and not. valid is false if it can't be parsed.
+func mediaCondition(c string) (ok, valid bool) {
+	c = strings.TrimSpace(c)
+	if rest, found := strings.CutPrefix(c, "not "); found {
+		ok, valid = mediaInParens(rest)
+		return !ok, valid
+	}
+	if ands := splitTop(c, " and "); len(ands) > 1 {
+		ok = true
+		for _, a := range ands {
+			x, v := mediaInParens(a)
+			if !v {
+				return false, false
+			}
+			ok = ok && x
+		}
+		return ok, true
+	}
+	if ors := splitTop(c, " or "); len(ors) > 1 {
+		for _, o := range ors {
+			x, v := mediaInParens(o)
+			if !v {
+				return false, false
+			}
+			ok = ok || x
+		}
+		return ok, true
+	}
+	return mediaInParens(c)
+}
+
+// mediaInParens evaluates (feature) or a nested (condition)
+func mediaInParens(s string) (ok, valid bool) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") || len(splitTop(s, " ")) > 1 {
+		return false, false
+	}
+	inner := strings.TrimSpace(s[1 : len(s)-1])
+	if strings.HasPrefix(inner, "(") || strings.HasPrefix(inner, "not ") {
+		return mediaCondition(inner)
+	}
+	return mediaFeatureMatches(inner), true
+}
+
+// splitTop splits s at sep outside of parentheses
+func splitTop(s, sep string) (parts []string) {
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		default:
+			if depth == 0 && strings.HasPrefix(s[i:], sep) {
+				parts = append(parts, s[start:i])
+				start = i + len(sep)
+				i += len(sep) - 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+var reMediaRange = regexp.MustCompile(`^([^<>=]+?)\s*(<=|>=|<|>|=)\s*([^<>=]+?)(?:\s*(<=|>=|<|>|=)\s*([^<>=]+?))?$`)
+
+// mediaFeatureMatches evaluates a media feature like min-width: 600px,
+// width >= 600px or hover
+func mediaFeatureMatches(f string) bool {
+	if name, val, ok := strings.Cut(f, ":"); ok {
+		name, val = strings.TrimSpace(name), strings.TrimSpace(val)
+		op := "="
+		if n, found := strings.CutPrefix(name, "min-"); found {
+			name, op = n, ">="
+		} else if n, found := strings.CutPrefix(name, "max-"); found {
+			name, op = n, "<="
+		}
+		if _, _, isNum := mediaValue(name); isNum {
+			return compareMedia(name, op, val)
+		}
+		return op == "=" && discreteMediaValue(name) == val
+	}
+	if m := reMediaRange.FindStringSubmatch(f); m != nil {
+		if m[4] != "" {
+			// a < name < b
+			return compareMedia(m[3], flip(m[2]), m[1]) && compareMedia(m[3], m[4], m[5])
+		}
+		if _, _, isNum := mediaValue(m[1]); isNum {
+			return compareMedia(m[1], m[2], m[3])
+		}
+		return compareMedia(m[3], flip(m[2]), m[1])
+	}
+	if v, _, isNum := mediaValue(f); isNum {
+		return v != 0
+	}
+	switch v := discreteMediaValue(f); v {
+	case "", "none", "no-preference":
+		return false
+	}
+	return true
+}
+
+// flip the comparison a op b into b op a
+func flip(op string) string {
+	return strings.NewReplacer("<", ">", ">", "<").Replace(op)
+}
+
+// compareMedia is true if the feature name compares by op to the value
+// val
+func compareMedia(name, op, val string) bool {
+	x, kind, ok := mediaValue(strings.TrimSpace(name))
+	if !ok {
+		return false
+	}
+	y, ok := parseMediaValue(strings.TrimSpace(val), kind)
+	if !ok {
+		return false
+	}
+	switch op {
+	case "=":
+		return x == y
+	case "<":
+		return x < y
+	case "<=":
+		return x <= y
+	case ">":
+		return x > y
+	case ">=":
+		return x >= y
+	}
+	return false
+}
+
+// mediaValue returns the value of a numeric media feature and its kind
+// of value: length, ratio, resolution or integer
+func mediaValue(name string) (v float64, kind string, ok bool) {
+	w, h := float64(ViewportWidth), float64(ViewportHeight)
+	switch name {
+	case "width", "device-width":
+		return w, "length", true
+	case "height", "device-height":
+		return h, "length", true
+	case "aspect-ratio", "device-aspect-ratio":
+		return w / h, "ratio", true
+	case "resolution":
+		return DevicePixelRatio, "resolution", true
+	case "color":
+		return 8, "integer", true
+	case "color-index", "monochrome", "grid":
+		return 0, "integer", true
+	}
+	return 0, "", false
+}
+
+// discreteMediaValue returns the keyword of a discrete media feature or
+// "" if it is unknown
+func discreteMediaValue(name string) string {
+	switch name {
+	case "orientation":
+		if ViewportHeight >= ViewportWidth {
+			return "portrait"
+		}
+		return "landscape"
+	case "prefers-color-scheme":
+		return PrefersColorScheme
+	case "prefers-reduced-motion":
+		if PrefersReducedMotion {
+			return "reduce"
+		}
+		return "no-preference"
+	case "prefers-contrast", "prefers-reduced-transparency", "prefers-reduced-data":
+		return "no-preference"
+	case "hover", "any-hover":
+		if Hover {
+			return "hover"
+		}
+		return "none"
+	case "pointer", "any-pointer":
+		return Pointer
+	case "color-gamut":
+		return "srgb"
+	case "display-mode":
+		return "browser"
+	case "scripting":
+		return "enabled"
+	case "update":
+		return "fast"
+	case "overflow-block", "overflow-inline":
+		return "scroll"
+	case "forced-colors", "inverted-colors":
+		return "none"
+	case "dynamic-range", "video-dynamic-range":
+		return "standard"
+	}
+	return ""
+}
+
+// parseMediaValue parses a value of the given kind in px, x or as a
+// plain number
+func parseMediaValue(s, kind string) (float64, bool) {
+	num := func(s string) (float64, bool) {
+		x, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		return x, err == nil
+	}
+	switch kind {
+	case "length":
+		for _, u := range []struct {
+			suffix string
+			f      float64
+		}{{"rem", 16}, {"em", 16}, {"px", 1}, {"pt", 4.0 / 3}, {"cm", 96 / 2.54}, {"mm", 96 / 25.4}, {"in", 96}} {
+			if v, found := strings.CutSuffix(s, u.suffix); found {
+				x, ok := num(v)
+				return x * u.f, ok
+			}
+		}
+		if s == "0" {
+			return 0, true
+		}
+	case "ratio":
+		a, b, found := strings.Cut(s, "/")
+		x, ok := num(a)
+		if !found || !ok {
+			return x, ok
+		}
+		y, ok := num(b)
+		return x / y, ok && y != 0
+	case "resolution":
+		for _, u := range []struct {
+			suffix string
+			f      float64
+		}{{"dppx", 1}, {"dpcm", 2.54 / 96}, {"dpi", 1.0 / 96}, {"x", 1}} {
+			if v, found := strings.CutSuffix(s, u.suffix); found {
+				x, ok := num(v)
+				return x * u.f, ok
+			}
+		}
+	case "integer":
+		return num(s)
+	}
+	return 0, false
+}
+
+// MediaQueryList is the result of matchMedia
+type MediaQueryList struct {
+	d     *Document
+	media string
+	obj   *js.Object
+
+	// matches is the state last reported by a change event
+	matches        bool
+	eventListeners listeners
+}
+
+func (mql *MediaQueryList) Obj() *js.Object {
+	if mql.obj == nil {
+		mql.matches = mediaMatches(mql.media)
+		mql.eventListeners = make(listeners)
+		mql.obj = vm.NewDynamicObject(mql)
+		mql.obj.SetPrototype(mediaQueryListCtor.Get("prototype").(*js.Object))
+	}
+	return mql.obj
+}
+
+func (mql *MediaQueryList) Getters() map[string]bool {
+	return map[string]bool{
+		"media":    true,
+		"matches":  true,
+		"onchange": true,
+	}
+}
+
+func (mql *MediaQueryList) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+// Media is the serialized media query list
+func (mql *MediaQueryList) Media() string {
+	var ms []string
+	for _, m := range splitTop(mql.media, ",") {
+		if m = strings.Join(strings.Fields(strings.ToLower(m)), " "); m != "" {
+			ms = append(ms, m)
+		}
+	}
+	return strings.Join(ms, ", ")
+}
+
+func (mql *MediaQueryList) Matches() bool {
+	return mediaMatches(mql.media)
+}
+
+func (mql *MediaQueryList) Onchange() js.Value {
+	if l := mql.eventListeners.handler("change"); l != nil {
+		return l.fn
+	}
+	return js.Null()
+}
+
+// watch registers mql for change events while it has listeners
+func (mql *MediaQueryList) watch() {
+	for _, o := range mql.d.mediaQueryLists {
+		if o == mql {
+			return
+		}
+	}
+	mql.d.mediaQueryLists = append(mql.d.mediaQueryLists, mql)
+}
+
+func (mql *MediaQueryList) listeners() listeners {
+	return mql.eventListeners
+}
+
+func (mql *MediaQueryList) AddEventListener(t string, fn js.Value, opts ...any) {
+	mql.eventListeners.add(t, fn, opts...)
+	if t == "change" {
+		mql.watch()
+	}
+}
+
+func (mql *MediaQueryList) RemoveEventListener(t string, fn js.Value, opts ...any) {
+	mql.eventListeners.remove(t, fn, opts...)
+}
+
+// AddListener is the legacy alias of addEventListener('change', fn)
+func (mql *MediaQueryList) AddListener(fn js.Value) {
+	mql.AddEventListener("change", fn)
+}
+
+func (mql *MediaQueryList) RemoveListener(fn js.Value) {
+	mql.RemoveEventListener("change", fn)
+}
+
+func (mql *MediaQueryList) DispatchEvent(ei any) bool {
+	e := wrap(ei)
+	if e == nil {
+		panic(vm.NewTypeError("dispatchEvent: parameter 1 is not of type 'Event'"))
+	}
+	e.dispatch([]EventTarget{mql})
+	return !e.DefaultPrevented
+}
+
+func (mql *MediaQueryList) Get(k string) js.Value {
+	if res, ok := GetCall(mql, k); ok {
+		return res
+	}
+	return nil
+}
+
+func (mql *MediaQueryList) Set(k string, desc js.PropertyDescriptor) bool {
+	if k != "onchange" {
+		return false
+	}
+	mql.eventListeners.setHandler("change", desc.Value)
+	mql.watch()
+	return true
+}
+
+func (mql *MediaQueryList) Has(k string) bool {
+	return HasCall(mql, k)
+}
+
+func (mql *MediaQueryList) Delete(k string) bool {
+	return false
+}
+
+func (mql *MediaQueryList) Keys() []string {
+	return []string{}
+}
+
+// evaluateMediaQueries fires change events at the media query lists
+// whose state changed since the last animation frame
+func (d *Document) evaluateMediaQueries() (ok bool) {
+	for _, mql := range append([]*MediaQueryList{}, d.mediaQueryLists...) {
+		m := mql.Matches()
+		if m == mql.matches {
+			continue
+		}
+		mql.matches = m
+		mql.DispatchEvent(newEvent("MediaQueryListEvent", "change", map[string]any{
+			"media":   mql.Media(),
+			"matches": m,
+		}))
+		ok = true
+	}
+	return
+}
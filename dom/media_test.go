@@ -0,0 +1,99 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"testing"
+)
+
+func TestMediaMatches(t *testing.T) {
+	for q, exp := range map[string]bool{
+		"":                                        true,
+		"screen":                                  true,
+		"print":                                   false,
+		"not print":                               true,
+		"only screen and (min-width: 800px)":      true,
+		"screen and (max-width: 800px)":           false,
+		"print, (orientation: landscape)":         true,
+		"(width >= 1280px)":                       true,
+		"(400px < width < 1000px)":                false,
+		"(100px <= height <= 50em)":               true,
+		"(min-aspect-ratio: 16/9)":                true,
+		"(min-resolution: 2dppx)":                 false,
+		"(resolution: 96dpi)":                     true,
+		"(prefers-color-scheme: dark)":            false,
+		"(prefers-reduced-motion)":                false,
+		"(prefers-reduced-motion: no-preference)": true,
+		"(hover: hover) and (pointer: fine)":      true,
+		"(any-pointer: coarse)":                   false,
+		"not (hover: none)":                       true,
+		"((color) or (monochrome)) and (grid: 0)": true,
+		"(unknown)":                               false,
+		"screen and":                              false,
+		"(min-width: 100)":                        false,
+	} {
+		if v := mediaMatches(q); v != exp {
+			t.Errorf("%v: %v", q, v)
+		}
+	}
+}
+
+func TestMatchMedia(t *testing.T) {
+	vm := js.New()
+	d, err := Init(vm, "https://example.com", `<html><head><style>
+		p { color: red }
+		@media (max-width: 1000px) { p { color: green } }
+	</style><style media="(prefers-color-scheme: dark)">p { font-size: 20px }</style></head><body><p id="p">x</p></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer d.Window.SetViewport(1280, 720, 1)
+	defer func() { PrefersColorScheme = "light" }()
+	_, err = vm.RunString(`
+		var log = [];
+		var p = document.getElementById('p');
+		var m = matchMedia('(MAX-width:  1000px)');
+		var dark = matchMedia('(prefers-color-scheme: dark)');
+		log.push(m instanceof MediaQueryList, m.media, m.matches, dark.matches, getComputedStyle(p).color);
+		m.addListener(function(e) { log.push('listener:' + e.matches + ':' + (e instanceof MediaQueryListEvent)) });
+		m.onchange = function(e) { log.push('onchange:' + e.media + ':' + (e.target === m) + ':' + (e.currentTarget === m)) };
+		m.addEventListener('change', function(e) { log.push('once') }, {once: true});
+		m.addEventListener('other', function(e) { log.push('other:' + e.type) });
+		m.onchange = m.onchange;
+		var handler = m.onchange;
+		m.removeEventListener('change', handler);
+		log.push(m instanceof EventTarget, typeof m.onchange);
+		dark.addEventListener('change', function(e) { log.push('dark:' + e.matches) });
+		try { new MediaQueryList() } catch (e) { log.push(e.name) }
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	frame := func() string {
+		d.Window.RenderAnimationFrame()
+		res, err := vm.RunString(`var s = log.join('|'); log = []; s`)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		return res.String()
+	}
+	if v := frame(); v != `true|(max-width: 1000px)|false|false|rgb(255, 0, 0)|true|function|TypeError` {
+		t.Fatalf("%v", v)
+	}
+	d.Window.SetViewport(800, 600, 1)
+	PrefersColorScheme = "dark"
+	if v := frame(); v != `listener:true:true|onchange:(max-width: 1000px):true:true|once|dark:true` {
+		t.Fatalf("%v", v)
+	}
+	d.Window.SetViewport(1280, 720, 1)
+	if _, err := vm.RunString(`m.dispatchEvent(new Event('other')); m.onchange = null`); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := frame(); v != `other:other|listener:false:true` {
+		t.Fatalf("%v", v)
+	}
+	d.Window.SetViewport(800, 600, 1)
+	res, err := vm.RunString(`[m.matches, getComputedStyle(p).color, getComputedStyle(p).fontSize].join('|')`)
+	if err != nil || res.String() != `true|rgb(0, 128, 0)|20px` {
+		t.Fatalf("%v %v", res, err)
+	}
+}
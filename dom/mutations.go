@@ -41,16 +41,23 @@ type Mutation struct {
 	Path string
 	Tag  string
 	Node map[string]string
+
+	// Frame is true for mutations while rendering an animation frame
+	Frame bool
 }
 
 // addMutation can be called after changing the node tree
 func addMutation(d *Document, t MutationType, n *html.Node) {
+	resetGeom()
 	m := Mutation{
 		Time: time.Now(),
 		Type: t,
 		Path: "",
 		Node: map[string]string{},
 	}
+	if d != nil && d.Window != nil {
+		m.Frame = d.Window.rendering
+	}
 	if n != nil {
 		if n.Type == html.ElementNode {
 			m.Tag = n.Data
@@ -59,7 +66,7 @@ func addMutation(d *Document, t MutationType, n *html.Node) {
 			m.Node[a.Key] = a.Val
 		}
 		if d != nil {
-			m.Node["innerHTML"] = d.getEl(n).InnerHTML()
+			m.Node["innerHTML"] = renderInner(n)
 		}
 	}
 	select {
@@ -114,5 +121,5 @@ func (m *MutObserver) Keys() []string {
 	return []string{""}
 }
 
-func (m *MutObserver) Observe(target *Element, opts map[string]any) {
+func (m *MutObserver) Observe(target any, opts map[string]any) {
 }
@@ -4,9 +4,11 @@ import (
 	"github.com/psilva261/sparkle/js"
 	"github.com/psilva261/sparklefs/logger"
 	"golang.org/x/net/html"
+	"strconv"
 )
 
 type NamedNodeMap struct {
+	d *Document
 	n *html.Node
 }
 
@@ -14,27 +16,163 @@ func (nm *NamedNodeMap) Obj() *js.Object {
 	return vm.NewDynamicObject(nm)
 }
 
+func (nm *NamedNodeMap) Getters() map[string]bool {
+	return map[string]bool{
+		"length": true,
+	}
+}
+
+func (nm *NamedNodeMap) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+func (nm *NamedNodeMap) Length() int {
+	return len(nm.n.Attr)
+}
+
+func (nm *NamedNodeMap) Item(j any) js.Value {
+	i, ok := index(j)
+	if !ok || i < 0 || i >= len(nm.n.Attr) {
+		return js.Null()
+	}
+	return newAttr(nm.d, nm.n, nm.n.Attr[i]).Obj()
+}
+
+// attrName is the qualified name of a
+func attrName(a html.Attribute) string {
+	if a.Namespace != "" {
+		return a.Namespace + ":" + a.Key
+	}
+	return a.Key
+}
+
+// namedAttr returns the index of the attribute with the qualified name
+// k or -1
+func (nm *NamedNodeMap) namedAttr(k string) int {
+	for i, a := range nm.n.Attr {
+		if attrName(a) == k {
+			return i
+		}
+	}
+	return -1
+}
+
+// nsAttr returns the index of the attribute with the namespace ns and
+// local name k or -1
+func (nm *NamedNodeMap) nsAttr(ns js.Value, k string) int {
+	var uri string
+	if ns != nil && !js.IsNull(ns) && !js.IsUndefined(ns) {
+		uri = ns.String()
+	}
+	for i, a := range nm.n.Attr {
+		if a.Namespace == uri && a.Key == k {
+			return i
+		}
+	}
+	return -1
+}
+
+func (nm *NamedNodeMap) GetNamedItem(k string) js.Value {
+	return nm.Item(nm.namedAttr(k))
+}
+
+func (nm *NamedNodeMap) GetNamedItemNS(ns js.Value, k string) js.Value {
+	return nm.Item(nm.nsAttr(ns, k))
+}
+
+// SetNamedItem attaches the Attr to the element. It returns the
+// replaced attribute or null.
+func (nm *NamedNodeMap) SetNamedItem(v js.Value) js.Value {
+	a := asAttr(v)
+	if a.owner != nil && a.owner != nm.n {
+		throwDOMException("InUseAttributeError", "the attribute is in use by another element")
+	}
+	if a.owner == nm.n {
+		return a.Obj()
+	}
+	var old js.Value = js.Null()
+	i := nm.nsAttr(vm.ToValue(a.ns), a.key)
+	if i >= 0 {
+		old = nm.detach(i)
+	}
+	val := a.Value()
+	obj := a.Obj()
+	delete(attrObjRefs, *a)
+	a.d, a.owner, a.own, a.prefix = nm.d, nm.n, nil, ""
+	attrObjRefs[*a] = obj
+	at := html.Attribute{Namespace: a.ns, Key: a.key, Val: val}
+	if i >= 0 && i <= len(nm.n.Attr) {
+		nm.n.Attr = append(nm.n.Attr[:i:i], append([]html.Attribute{at}, nm.n.Attr[i:]...)...)
+	} else {
+		nm.n.Attr = append(nm.n.Attr, at)
+	}
+	addMutation(nm.d, ChAttr, nm.n)
+	return old
+}
+
+func (nm *NamedNodeMap) SetNamedItemNS(v js.Value) js.Value {
+	return nm.SetNamedItem(v)
+}
+
+// RemoveNamedItem removes the attribute with the qualified name k and
+// returns it
+func (nm *NamedNodeMap) RemoveNamedItem(k string) js.Value {
+	i := nm.namedAttr(k)
+	if i < 0 {
+		throwDOMException("NotFoundError", "no attribute "+k)
+	}
+	return nm.detach(i)
+}
+
+func (nm *NamedNodeMap) RemoveNamedItemNS(ns js.Value, k string) js.Value {
+	i := nm.nsAttr(ns, k)
+	if i < 0 {
+		throwDOMException("NotFoundError", "no attribute "+k)
+	}
+	return nm.detach(i)
+}
+
+// detach removes the i-th attribute and returns its Attr which keeps
+// the value without owner element
+func (nm *NamedNodeMap) detach(i int) js.Value {
+	at := nm.n.Attr[i]
+	a := newAttr(nm.d, nm.n, at)
+	obj := a.Obj()
+	a = obj.Export().(*Attr)
+	delete(attrObjRefs, *a)
+	a.owner, a.own = nil, &html.Attribute{Namespace: at.Namespace, Key: at.Key, Val: at.Val}
+	attrObjRefs[*a] = obj
+	nm.n.Attr = append(nm.n.Attr[:i:i], nm.n.Attr[i+1:]...)
+	if connected(nm.n) {
+		addMutation(nm.d, RmAttr, nm.n)
+	}
+	return obj
+}
+
+// asAttr returns the Attr of v or throws a TypeError
+func asAttr(v js.Value) *Attr {
+	if v != nil {
+		if a, ok := v.Export().(*Attr); ok {
+			return a
+		}
+	}
+	panic(vm.NewTypeError("parameter 1 is not of type 'Attr'"))
+}
+
 func (nm *NamedNodeMap) Get(k string) (v js.Value) {
-	switch k {
-	case "item":
-		return vm.ToValue(func(x interface{}) (o *js.Object) {
-			i, ok := x.(int64)
-			if ok {
-				if int(i) >= len(nm.n.Attr) {
-					return nil
-				}
-				a := &Attr{&nm.n.Attr[i]}
-				return a.Obj()
-			}
-			return nil
-		})
-	case "length":
-		return vm.ToValue(len(nm.n.Attr))
+	if i, err := strconv.Atoi(k); err == nil {
+		if i >= 0 && i < len(nm.n.Attr) {
+			return newAttr(nm.d, nm.n, nm.n.Attr[i]).Obj()
+		}
+		return nil
 	}
-	if a := attr(*nm.n, k); a != "" {
-		return vm.ToValue(a)
+	if res, ok := GetCall(nm, k); ok {
+		return res
 	}
-	return vm.ToValue(nil)
+	if i := nm.namedAttr(k); i >= 0 {
+		return newAttr(nm.d, nm.n, nm.n.Attr[i]).Obj()
+	}
+	return nil
 }
 
 func (nm *NamedNodeMap) Set(k string, desc js.PropertyDescriptor) bool {
@@ -44,54 +182,202 @@ func (nm *NamedNodeMap) Set(k string, desc js.PropertyDescriptor) bool {
 }
 
 func (nm *NamedNodeMap) Has(k string) bool {
-	log.Printf("named node has? %v", k)
-	return true
+	if i, err := strconv.Atoi(k); err == nil {
+		return i >= 0 && i < len(nm.n.Attr)
+	}
+	return HasCall(nm, k) || nm.namedAttr(k) >= 0
 }
 
 func (nm *NamedNodeMap) Delete(k string) bool {
 	return false
 }
 
-func (nm *NamedNodeMap) Keys() []string {
-	return []string{""}
+func (nm *NamedNodeMap) Keys() (ks []string) {
+	for i := range nm.n.Attr {
+		ks = append(ks, strconv.Itoa(i))
+	}
+	return
 }
 
+// Attr is an attribute of an element. It refers to the attribute by
+// namespace and name so it stays valid when the attribute list of the
+// owner changes.
 type Attr struct {
-	a *html.Attribute
+	d      *Document
+	owner  *html.Node
+	ns     string
+	key    string
+	prefix string
+
+	// own holds the attribute of an Attr without owner element
+	own *html.Attribute
+}
+
+var attrObjRefs = make(map[Attr]*js.Object)
+
+func newAttr(d *Document, owner *html.Node, a html.Attribute) *Attr {
+	return &Attr{d: d, owner: owner, ns: a.Namespace, key: a.Key}
+}
+
+// newOwnAttr returns an Attr without owner element
+func newOwnAttr(d *Document, ns, prefix, key, val string) *Attr {
+	own := &html.Attribute{Namespace: ns, Key: key, Val: val}
+	return &Attr{d: d, ns: ns, key: key, prefix: prefix, own: own}
+}
+
+func (a *Attr) attr() *html.Attribute {
+	if a.owner == nil {
+		return a.own
+	}
+	for i, aa := range a.owner.Attr {
+		if aa.Namespace == a.ns && aa.Key == a.key {
+			return &a.owner.Attr[i]
+		}
+	}
+	return nil
 }
 
 func (a *Attr) Obj() *js.Object {
-	return vm.NewDynamicObject(a)
+	obj, ok := attrObjRefs[*a]
+	if ok {
+		return obj
+	}
+	obj = vm.NewDynamicObject(a)
+	if c, ok := nodeCtors["Attr"]; ok {
+		obj.SetPrototype(c.Get("prototype").(*js.Object))
+	}
+	attrObjRefs[*a] = obj
+	return obj
+}
+
+func (a *Attr) Getters() map[string]bool {
+	return map[string]bool{
+		"name":          true,
+		"localName":     true,
+		"namespaceURI":  true,
+		"prefix":        true,
+		"value":         true,
+		"ownerElement":  true,
+		"specified":     true,
+		"nodeType":      true,
+		"nodeName":      true,
+		"nodeValue":     true,
+		"textContent":   true,
+		"ownerDocument": true,
+		"baseURI":       true,
+	}
+}
+
+func (a *Attr) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+func (a *Attr) Name() string {
+	if a.prefix != "" {
+		return a.prefix + ":" + a.key
+	}
+	if a.ns != "" && a.owner != nil {
+		return a.ns + ":" + a.key
+	}
+	return a.key
+}
+
+func (a *Attr) LocalName() string {
+	return a.key
+}
+
+func (a *Attr) NamespaceURI() js.Value {
+	if a.ns == "" {
+		return js.Null()
+	}
+	return vm.ToValue(a.ns)
+}
+
+func (a *Attr) Prefix() js.Value {
+	if a.prefix == "" {
+		return js.Null()
+	}
+	return vm.ToValue(a.prefix)
+}
+
+func (a *Attr) Value() string {
+	if aa := a.attr(); aa != nil {
+		return aa.Val
+	}
+	return ""
+}
+
+func (a *Attr) OwnerElement() js.Value {
+	if a.owner == nil || a.attr() == nil {
+		return js.Null()
+	}
+	return a.d.getNode(a.owner).Obj()
+}
+
+func (a *Attr) OwnerDocument() js.Value {
+	return a.d.Obj()
+}
+
+func (a *Attr) BaseURI() string {
+	return a.d.BaseURI()
+}
+
+func (a *Attr) CompareDocumentPosition(o any) int {
+	return compareDocumentPosition(a, o)
+}
+
+func (a *Attr) Specified() bool {
+	return true
+}
+
+func (a *Attr) NodeType() int {
+	return 2
+}
+
+func (a *Attr) NodeName() string {
+	return a.Name()
+}
+
+func (a *Attr) NodeValue() string {
+	return a.Value()
+}
+
+func (a *Attr) TextContent() string {
+	return a.Value()
+}
+
+func (a *Attr) CloneNode(deep ...bool) js.Value {
+	return newOwnAttr(a.d, a.ns, a.prefix, a.key, a.Value()).Obj()
+}
+
+func (a *Attr) ToString() string {
+	return "[object Attr]"
 }
 
 func (a *Attr) Get(k string) (v js.Value) {
-	log.Printf("attr get %v", k)
-	switch k {
-	case "name":
-		return vm.ToValue(a.a.Key)
-	case "value":
-		return vm.ToValue(a.a.Val)
-	case "toString":
-		return vm.ToValue(func() string {
-			return "[object Attr]"
-		})
-	case "valueOf":
-		return vm.ToValue(func() string {
-			return a.a.Key + `="` + a.a.Val + `"`
-		})
-	}
-	return vm.ToValue(nil)
+	if res, ok := GetCall(a, k); ok {
+		return res
+	}
+	return nil
 }
 
 func (a *Attr) Set(k string, desc js.PropertyDescriptor) bool {
-	v := desc.Value
-	log.Printf("attr set %v => %v", k, v)
-	return true
+	switch k {
+	case "value", "nodeValue", "textContent":
+		if aa := a.attr(); aa != nil {
+			aa.Val = desc.Value.String()
+			if a.owner != nil {
+				addMutation(a.d, ChAttr, a.owner)
+			}
+		}
+		return true
+	}
+	log.Printf("attr set %v => %v", k, desc.Value)
+	return false
 }
 
 func (a *Attr) Has(k string) bool {
-	log.Printf("attr has? %v", k)
-	return true
+	return HasCall(a, k)
 }
 
 func (a *Attr) Delete(k string) bool {
@@ -99,5 +385,5 @@ func (a *Attr) Delete(k string) bool {
 }
 
 func (a *Attr) Keys() []string {
-	return []string{""}
+	return Calls(a)
 }
@@ -0,0 +1,1103 @@
+package dom
+
+import (
+	"fmt"
+	"github.com/psilva261/sparkle/js"
+	"github.com/psilva261/sparklefs/logger"
+	"golang.org/x/net/html"
+	"reflect"
+	"strings"
+)
+
+// Node is implemented by the wrappers of the node types in the tree
+// (Element, Text, Comment, ProcessingInstruction and DocumentType).
+// They all embed node which implements the Node interface of the DOM
+// together with the ChildNode mixin.
+type Node interface {
+	Gettable
+	js.DynamicObject
+	base() *node
+	iface() string
+	listeners() listeners
+}
+
+// node is the state shared by all node wrappers
+type node struct {
+	d  *Document
+	df *DocumentFragment
+	n  *html.Node
+}
+
+var (
+	ndObjRefs = make(map[Node]*js.Object)
+)
+
+// getNode returns the wrapper of n which depends on its type. The
+// document node is wrapped as Element for internal use.
+func (d *Document) getNode(n *html.Node) Node {
+	if n == nil {
+		return nil
+	}
+	if nd, ok := d.ndRefs[n]; ok {
+		return nd
+	}
+	b := node{d: d, n: n}
+	var nd Node
+	switch n.Type {
+	case html.TextNode:
+		if isCDATA(n) {
+			nd = &CDATASection{Text{CharacterData{b}}}
+		} else {
+			nd = &Text{CharacterData{b}}
+		}
+	case html.CommentNode:
+		nd = &Comment{CharacterData{b}}
+	case html.RawNode:
+		nd = &ProcessingInstruction{CharacterData{b}}
+	case html.DoctypeNode:
+		nd = &DocumentType{b}
+	default:
+		nd = &Element{b}
+	}
+	d.ndRefs[n] = nd
+	return nd
+}
+
+// getEl returns the wrapper of n if it's an element (or the document
+// node) and nil otherwise
+func (d *Document) getEl(n *html.Node) *Element {
+	el, _ := d.getNode(n).(*Element)
+	return el
+}
+
+// nodeObj returns the JS object of n. The document node is represented
+// by the document.
+func (d *Document) nodeObj(n *html.Node) js.Value {
+	if n == nil {
+		return js.Null()
+	}
+	if n.Type == html.DocumentNode && n == d.doc {
+		return d.Obj()
+	}
+	return d.getNode(n).Obj()
+}
+
+// asNode returns the node wrapper behind a JS argument or nil
+func asNode(v any) Node {
+	switch nd := v.(type) {
+	case Node:
+		return nd
+	case *js.Object:
+		if nd == nil {
+			return nil
+		}
+		return asNode(nd.Export())
+	}
+	return nil
+}
+
+func (nd *node) base() *node {
+	return nd
+}
+
+// self returns the most derived wrapper embedding nd
+func (nd *node) self() Node {
+	return nd.d.getNode(nd.n)
+}
+
+func (nd *node) Obj() *js.Object {
+	self := nd.self()
+	obj, ok := ndObjRefs[self]
+	if ok {
+		return obj
+	}
+	obj = vm.NewDynamicObject(self)
+	if c, ok := nodeCtors[self.iface()]; ok {
+		obj.SetPrototype(c.Get("prototype").(*js.Object))
+	}
+	ndObjRefs[self] = obj
+	return obj
+}
+
+func (nd *node) Getters() map[string]bool {
+	return map[string]bool{
+		"nodeType":        true,
+		"nodeName":        true,
+		"nodeValue":       true,
+		"textContent":     true,
+		"ownerDocument":   true,
+		"baseURI":         true,
+		"parentNode":      true,
+		"parentElement":   true,
+		"firstChild":      true,
+		"lastChild":       true,
+		"previousSibling": true,
+		"nextSibling":     true,
+		"childNodes":      true,
+	}
+}
+
+func (nd *node) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+func (nd *node) Get(key string) js.Value {
+	if nd.n == nil {
+		log.Errorf("node wrapper with nil node")
+		return js.Undefined()
+	}
+	if vs, ok := elVars[nd.n]; ok {
+		if v, ok := vs[key]; ok {
+			return v
+		}
+	}
+	if key == "addEventListener" || key == "attachEvent" {
+		// dispatch here because 2nd parameter can be a function or an object
+		c := &Call{
+			recv:  fmt.Sprintf("%T", nd.self()),
+			k:     key,
+			found: true,
+		}
+		calls = append(calls, c)
+		return vm.ToValue(func(e string, fn js.Value, opts ...any) {
+			nd.AddEventListener(e, fn, opts...)
+		})
+	}
+	self := nd.self()
+	if !self.Getters()[key] && nd.protoHas(key) {
+		// methods are inherited so they can be called with another this
+		return nil
+	}
+	if res, ok := GetCall(self, key); ok {
+		return res
+	}
+	return js.Undefined()
+}
+
+// protoHas is true if key is defined on the interface prototypes
+func (nd *node) protoHas(key string) bool {
+	c, ok := nodeCtors[nd.self().iface()]
+	if !ok {
+		return false
+	}
+	return c.Get("prototype").(*js.Object).Get(key) != nil
+}
+
+// Set handles the setters of Node and stores everything else as
+// expando
+func (nd *node) Set(key string, desc js.PropertyDescriptor) bool {
+	val := desc.Value
+	switch key {
+	case "nodeValue", "textContent", "data":
+		switch nd.n.Type {
+		case html.CommentNode, html.TextNode, html.RawNode:
+			nd.replaceData(0, utf16Len(nd.n.Data), val.String())
+			return true
+		case html.DoctypeNode:
+			// no effect
+			return true
+		}
+		if key == "textContent" {
+			nd.setText(val.String())
+			return true
+		}
+		if key == "nodeValue" {
+			// no effect
+			return true
+		}
+	}
+	if _, ok := elVars[nd.n]; !ok {
+		elVars[nd.n] = make(map[string]js.Value)
+	}
+	elVars[nd.n][key] = val
+	return true
+}
+
+func (nd *node) Has(key string) bool {
+	if vs, ok := elVars[nd.n]; ok {
+		if _, ok := vs[key]; ok {
+			return true
+		}
+	}
+	return HasCall(nd.self(), key)
+}
+
+func (nd *node) Delete(key string) bool {
+	if vs, ok := elVars[nd.n]; ok {
+		if _, ok := vs[key]; ok {
+			delete(vs, key)
+			return true
+		}
+	}
+	return false
+}
+
+func (nd *node) Keys() []string {
+	ks := Calls(nd.self())
+	for k := range elVars[nd.n] {
+		ks = append(ks, k)
+	}
+	return ks
+}
+
+func (nd *node) NodeType() (i int) {
+	switch nd.n.Type {
+	case html.ElementNode:
+		i = 1
+	case html.TextNode:
+		i = 3
+		if isCDATA(nd.n) {
+			i = 4
+		}
+	case html.RawNode:
+		i = 7
+	case html.CommentNode:
+		i = 8
+	case html.DocumentNode:
+		i = 9
+	case html.DoctypeNode:
+		i = 10
+	}
+	return
+}
+
+func (nd *node) NodeName() string {
+	switch nd.n.Type {
+	case html.CommentNode:
+		return "#comment"
+	case html.TextNode:
+		if isCDATA(nd.n) {
+			return "#cdata-section"
+		}
+		return "#text"
+	case html.DocumentNode:
+		return "HTML"
+	case html.RawNode:
+		return attr(*nd.n, "target")
+	case html.DoctypeNode:
+		return nd.n.Data
+	}
+	return strings.ToUpper(nd.n.Data)
+}
+
+func (nd *node) NodeValue() js.Value {
+	switch nd.n.Type {
+	case html.CommentNode, html.TextNode, html.RawNode:
+		return vm.ToValue(nd.n.Data)
+	}
+	return js.Null()
+}
+
+func (nd *node) TextContent() js.Value {
+	switch nd.n.Type {
+	case html.CommentNode, html.TextNode, html.RawNode:
+		return vm.ToValue(nd.n.Data)
+	case html.DoctypeNode, html.DocumentNode:
+		return js.Null()
+	}
+	return vm.ToValue(nd.text())
+}
+
+func (nd *node) text() string {
+	var f func(*html.Node, int) string
+	f = func(n *html.Node, r int) (t string) {
+		if r > 30 {
+			log.Errorf("element text: recursion limit exceeded")
+			return
+		}
+		if n.Type == html.TextNode {
+			t += n.Data
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			t += f(c, r+1)
+		}
+		return
+	}
+	return f(nd.n, 0)
+}
+
+func (nd *node) setText(t string) {
+	for nd.n.FirstChild != nil {
+		nd.d.removeChild(nd.n, nd.n.FirstChild)
+	}
+	if t != "" {
+		tn := &html.Node{
+			Type: html.TextNode,
+			Data: t,
+		}
+		nd.n.AppendChild(tn)
+	}
+	addMutation(nd.d, Value, nd.n)
+}
+
+func (nd *node) setData(s string) {
+	nd.n.Data = s
+	addMutation(nd.d, Value, nd.n)
+}
+
+// replaceData replaces n code units of the data at offset i with s
+// and updates the live ranges
+func (nd *node) replaceData(i, n int, s string) {
+	l := utf16Len(nd.n.Data)
+	if i < 0 || i > l {
+		throwDOMException("IndexSizeError", "offset is out of range")
+	}
+	if n < 0 || i+n > l {
+		n = l - i
+	}
+	a, b := utf16Index(nd.n.Data, i), utf16Index(nd.n.Data, i+n)
+	nd.setData(nd.n.Data[:a] + s + nd.n.Data[b:])
+	rangesReplaceData(nd.self(), i, n, utf16Len(s))
+}
+
+// utf16Len returns the length of s in UTF-16 code units like the
+// length of JS strings
+func utf16Len(s string) (n int) {
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return
+}
+
+// utf16Index returns the byte index of the UTF-16 offset i in s. An
+// offset within a surrogate pair is rounded up to the end of the rune.
+func utf16Index(s string, i int) int {
+	n := 0
+	for j, r := range s {
+		if n >= i {
+			return j
+		}
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return len(s)
+}
+
+func (nd *node) ToString() string {
+	return "[object " + nd.self().iface() + "]"
+}
+
+func (nd *node) OwnerDocument() js.Value {
+	return nd.d.Obj()
+}
+
+func (nd *node) BaseURI() string {
+	return nd.d.BaseURI()
+}
+
+// Node returns the underlying node of the wrapper
+func (nd *node) Node() *html.Node {
+	return nd.n
+}
+
+func (nd *node) GetRootNode(opts ...any) js.Value {
+	if nd.df != nil {
+		return nd.df.Obj()
+	}
+	r := nd.n
+	for r.Parent != nil {
+		r = r.Parent
+	}
+	return nd.d.nodeObj(r)
+}
+
+func (nd *node) ParentNode() js.Value {
+	if nd.df != nil {
+		return nd.df.Obj()
+	}
+	return nd.d.nodeObj(nd.n.Parent)
+}
+
+func (nd *node) ParentElement() js.Value {
+	if p := nd.n.Parent; p != nil && p.Type == html.ElementNode {
+		return nd.d.getNode(p).Obj()
+	}
+	return js.Null()
+}
+
+func (nd *node) FirstChild() js.Value {
+	return nd.d.nodeObj(nd.n.FirstChild)
+}
+
+func (nd *node) LastChild() js.Value {
+	return nd.d.nodeObj(nd.n.LastChild)
+}
+
+func (nd *node) PreviousSibling() js.Value {
+	return nd.d.nodeObj(nd.n.PrevSibling)
+}
+
+func (nd *node) NextSibling() js.Value {
+	return nd.d.nodeObj(nd.n.NextSibling)
+}
+
+func (nd *node) HasChildNodes() bool {
+	return nd.n.FirstChild != nil
+}
+
+var elChildNodes = make(map[*html.Node]*NodeList)
+
+func (nd *node) ChildNodes() *js.Object {
+	nl, ok := elChildNodes[nd.n]
+	if !ok {
+		nl = &NodeList{
+			d: nd.d,
+			f: func() []*html.Node {
+				nodes := make([]*html.Node, 0, 2)
+				for c := nd.n.FirstChild; c != nil; c = c.NextSibling {
+					nodes = append(nodes, c)
+				}
+				return nodes
+			},
+		}
+		elChildNodes[nd.n] = nl
+	}
+	return nl.Obj()
+}
+
+func (nd *node) Normalize() {
+	nd.d.normalize(nd.n)
+}
+
+// Contains is true if o is an inclusive descendant
+func (nd *node) Contains(o any) bool {
+	on := asNode(o)
+	if on == nil {
+		return false
+	}
+	for n := on.base().n; n != nil; n = n.Parent {
+		if n == nd.n {
+			return true
+		}
+	}
+	return false
+}
+
+func (nd *node) IsSameNode(n ...any) bool {
+	if len(n) == 0 {
+		return false
+	}
+	o := asNode(n[0])
+	return o != nil && o.base().n == nd.n
+}
+
+// Bits of the bitmask returned by compareDocumentPosition
+const (
+	positionDisconnected = 1 << iota
+	positionPreceding
+	positionFollowing
+	positionContains
+	positionContainedBy
+	positionImplementationSpecific
+)
+
+func (nd *node) CompareDocumentPosition(o any) int {
+	return compareDocumentPosition(nd.self(), o)
+}
+
+// treePos is the position of a node or attribute in its tree
+type treePos struct {
+	// path are the inclusive ancestors, root first. Nodes in a document
+	// fragment have the fragment as root.
+	path []any
+	attr *Attr
+}
+
+func (p *treePos) node() any {
+	return p.path[len(p.path)-1]
+}
+
+func (p *treePos) root() any {
+	return p.path[0]
+}
+
+// position returns the position of a node, document, document fragment
+// or attribute
+func position(v any) *treePos {
+	switch x := v.(type) {
+	case *js.Object:
+		if x != nil {
+			return position(x.Export())
+		}
+	case Node:
+		b := x.base()
+		return &treePos{path: b.d.treePath(b.n)}
+	case *Document:
+		return &treePos{path: []any{x.doc}}
+	case *DocumentFragment:
+		return &treePos{path: []any{x}}
+	case *Attr:
+		if x.owner == nil || x.attr() == nil {
+			return &treePos{path: []any{x}}
+		}
+		return &treePos{path: x.d.treePath(x.owner), attr: x}
+	}
+	return nil
+}
+
+// treePath returns the inclusive ancestors of n root first
+func (d *Document) treePath(n *html.Node) (path []any) {
+	for ; n != nil; n = n.Parent {
+		path = append([]any{n}, path...)
+		if n.Parent == nil {
+			if w, ok := d.ndRefs[n]; ok && w.base().df != nil {
+				path = append([]any{w.base().df}, path...)
+			}
+		}
+	}
+	return
+}
+
+// childIndex returns the index of c among the children of p
+func childIndex(p any, c any) int {
+	switch x := p.(type) {
+	case *html.Node:
+		i := 0
+		for cc := x.FirstChild; cc != nil; cc = cc.NextSibling {
+			if cc == c {
+				return i
+			}
+			i++
+		}
+	case *DocumentFragment:
+		for i, cc := range x.children {
+			if cc == c {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func sameAttr(a, b *Attr) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.owner == b.owner && a.own == b.own && a.ns == b.ns && a.key == b.key
+}
+
+// compareDocumentPosition returns the position of other relative to
+// this as a bitmask
+func compareDocumentPosition(this, other any) int {
+	p1, p2 := position(other), position(this)
+	if p1 == nil || p2 == nil {
+		panic(vm.NewTypeError("compareDocumentPosition: parameter 1 is not of type 'Node'"))
+	}
+	n1, n2 := p1.node(), p2.node()
+	if n1 == n2 && sameAttr(p1.attr, p2.attr) {
+		return 0
+	}
+	if p1.attr != nil && p2.attr != nil && n1 == n2 {
+		for _, a := range p1.attr.owner.Attr {
+			if a.Namespace == p1.attr.ns && a.Key == p1.attr.key {
+				return positionImplementationSpecific | positionPreceding
+			}
+			if a.Namespace == p2.attr.ns && a.Key == p2.attr.key {
+				return positionImplementationSpecific | positionFollowing
+			}
+		}
+	}
+	if p1.root() != p2.root() {
+		// consistent ordering of the disconnected trees
+		if reflect.ValueOf(p1.root()).Pointer() < reflect.ValueOf(p2.root()).Pointer() {
+			return positionDisconnected | positionImplementationSpecific | positionPreceding
+		}
+		return positionDisconnected | positionImplementationSpecific | positionFollowing
+	}
+	l1, l2 := len(p1.path), len(p2.path)
+	switch {
+	case l1 < l2 && p2.path[l1-1] == n1 && p1.attr == nil, n1 == n2 && p2.attr != nil:
+		return positionContains | positionPreceding
+	case l2 < l1 && p1.path[l2-1] == n2 && p2.attr == nil, n1 == n2 && p1.attr != nil:
+		return positionContainedBy | positionFollowing
+	}
+	i := 0
+	for i < l1 && i < l2 && p1.path[i] == p2.path[i] {
+		i++
+	}
+	switch {
+	case i == l1:
+		return positionPreceding
+	case i == l2:
+		return positionFollowing
+	case childIndex(p1.path[i-1], p1.path[i]) < childIndex(p2.path[i-1], p2.path[i]):
+		return positionPreceding
+	}
+	return positionFollowing
+}
+
+func (nd *node) IsEqualNode(o any) bool {
+	on := asNode(o)
+	if on == nil {
+		return false
+	}
+	return equalNodes(nd.d, nd.n, on.base().d, on.base().n)
+}
+
+// equalNodes compares the node a of document da with the node b of
+// document db. Element namespaces are compared after resolving them
+// in their documents.
+func equalNodes(da *Document, a *html.Node, db *Document, b *html.Node) bool {
+	if a.Data != b.Data || a.Type != b.Type {
+		return false
+	}
+	if a.Type == html.ElementNode && da.namespace(a) != db.namespace(b) {
+		return false
+	}
+	if len(a.Attr) != len(b.Attr) {
+		return false
+	}
+	for _, x := range a.Attr {
+		found := false
+		for _, y := range b.Attr {
+			if x.Namespace == y.Namespace && x.Key == y.Key && x.Val == y.Val {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	c, d := a.FirstChild, b.FirstChild
+	for ; c != nil && d != nil; c, d = c.NextSibling, d.NextSibling {
+		if !equalNodes(da, c, db, d) {
+			return false
+		}
+	}
+	return c == nil && d == nil
+}
+
+func (nd *node) CloneNode(deep ...bool) Node {
+	return nd.d.getNode(nd.d.cloneTree(nd.n, len(deep) > 0 && deep[0], nd.d))
+}
+
+// cloneTree returns a copy of n, including its descendants if deep, and
+// runs the cloning steps on each copied node for the document to
+func (d *Document) cloneTree(n *html.Node, deep bool, to *Document) *html.Node {
+	cloneSteps := func(n, c *html.Node) {
+		if n.Type != html.ElementNode {
+			return
+		}
+		if s, ok := d.scripts[n]; ok && s.alreadyStarted {
+			to.script(c).alreadyStarted = true
+		}
+		d.cloneState(n, c, to)
+	}
+	if deep {
+		return copyTree(n, cloneSteps)
+	}
+	c := &html.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+		Attr:      append([]html.Attribute{}, n.Attr...),
+	}
+	cloneSteps(n, c)
+	return c
+}
+
+func (nd *node) InsertBefore(nu, old any) any {
+	switch v := nu.(type) {
+	case *html.Node:
+		return nd.insertNode(nd.d.getNode(v), old)
+	case Node:
+		return nd.insertNode(v, old)
+	case *DocumentFragment:
+		nd.checkInsert(v.children, nd.refChild(old), nil)
+		for _, cc := range v.children {
+			c := v.d.getNode(cc)
+			c.base().df = nil // cache
+			nd.InsertBefore(c, old)
+		}
+		v.children = nil
+		return v
+	}
+	panic(vm.NewTypeError(fmt.Sprintf("insertBefore: parameter 1 is not of type 'Node' (%T)", nu)))
+}
+
+// checkHierarchy throws a HierarchyRequestError if inserting n into
+// parent would create a cycle
+func checkHierarchy(parent, n *html.Node) {
+	for p := parent; p != nil; p = p.Parent {
+		if p == n {
+			throwDOMException("HierarchyRequestError", "the new child is an ancestor of the parent")
+		}
+	}
+}
+
+// refChild returns the node of the reference child old and throws a
+// NotFoundError if it isn't a child of nd
+func (nd *node) refChild(old any) *html.Node {
+	oe := asNode(old)
+	if oe == nil {
+		return nil
+	}
+	if oe.base().n.Parent != nd.n {
+		throwDOMException("NotFoundError", "the reference node is not a child of this node")
+	}
+	return oe.base().n
+}
+
+func (nd *node) insertNode(nue Node, old any) Node {
+	nn := nue.base().n
+	checkHierarchy(nd.n, nn)
+	ref := nd.refChild(old)
+	nd.checkInsert([]*html.Node{nn}, ref, nil)
+	if ref == nn {
+		ref = nn.NextSibling
+	}
+	if nn.Parent != nil {
+		nue.base().d.removeChild(nn.Parent, nn)
+	}
+	nd.d.adopt(nue)
+	nd.d.insertBefore(nd.n, nn, ref)
+	addMutation(nd.d, Insert, nn)
+	return nue
+}
+
+func (nd *node) ReplaceChild(nu, ol any) Node {
+	nue, ole := asNode(nu), asNode(ol)
+	if nue == nil || ole == nil {
+		panic(vm.NewTypeError("replaceChild: parameter is not of type 'Node'"))
+	}
+	nn, on := nue.base().n, ole.base().n
+	checkHierarchy(nd.n, nn)
+	if on.Parent != nd.n {
+		throwDOMException("NotFoundError", "the node to be replaced is not a child of this node")
+	}
+	nd.checkInsert([]*html.Node{nn}, on, on)
+	nx := on.NextSibling
+	if nx == nn {
+		nx = nn.NextSibling
+	}
+	if nn.Parent != nil {
+		nue.base().d.removeChild(nn.Parent, nn)
+	}
+	nd.d.adopt(nue)
+	if on.Parent == nd.n {
+		nd.d.removeChild(nd.n, on)
+	}
+	nd.d.insertBefore(nd.n, nn, nx)
+	addMutation(nd.d, Rm, nd.n)
+	addMutation(nd.d, Insert, nn)
+	return ole
+}
+
+func (nd *node) AppendChild(c any) any {
+	switch v := c.(type) {
+	case *html.Node:
+		return nd.appendNode(nd.d.getNode(v))
+	case Node:
+		return nd.appendNode(v)
+	case *DocumentFragment:
+		nd.checkInsert(v.children, nil, nil)
+		for _, cc := range v.children {
+			c := v.d.getNode(cc)
+			c.base().df = nil // cache
+			nd.AppendChild(c)
+		}
+		v.children = nil
+		return v
+	case map[string]any:
+		// TODO
+		log.Errorf("appendChild called with map[string]any")
+		return nil
+	}
+	panic(vm.NewTypeError(fmt.Sprintf("appendChild: parameter 1 is not of type 'Node' (%T)", c)))
+}
+
+func (nd *node) appendNode(c Node) Node {
+	cn := c.base().n
+	nd.checkInsert([]*html.Node{cn}, nil, nil)
+	if p := cn.Parent; p != nil {
+		c.base().d.removeChild(p, cn)
+	}
+	nd.d.adopt(c)
+	nd.d.insertBefore(nd.n, cn, nil)
+	addMutation(nd.d, Insert, cn)
+	return c
+}
+
+func (nd *node) RemoveChild(c any) Node {
+	ce := asNode(c)
+	if ce == nil || ce.base().n == nil {
+		panic(vm.NewTypeError("removeChild: parameter 1 is not of type 'Node'"))
+	}
+	if ce.base().n.Parent != nd.n {
+		throwDOMException("NotFoundError", "the node to be removed is not a child of this node")
+	}
+	nd.d.removeChild(nd.n, ce.base().n)
+	addMutation(nd.d, Rm, nd.n)
+	return ce
+}
+
+// Remove implements the ChildNode mixin shared by elements, character
+// data and doctypes
+func (nd *node) Remove() js.Value {
+	if p := nd.n.Parent; p != nil {
+		nd.d.removeChild(p, nd.n)
+		addMutation(nd.d, Rm, p)
+	}
+	return js.Undefined()
+}
+
+// CharacterData is embedded by Text, Comment and ProcessingInstruction
+type CharacterData struct {
+	node
+}
+
+func (cd *CharacterData) Getters() map[string]bool {
+	gs := cd.node.Getters()
+	gs["data"] = true
+	gs["length"] = true
+	gs["nextElementSibling"] = true
+	gs["previousElementSibling"] = true
+	return gs
+}
+
+func (cd *CharacterData) Data() string {
+	return cd.n.Data
+}
+
+func (cd *CharacterData) Length() int {
+	return utf16Len(cd.n.Data)
+}
+
+func (cd *CharacterData) SubstringData(i, n int) string {
+	l := utf16Len(cd.n.Data)
+	if i > l {
+		throwDOMException("IndexSizeError", "offset is out of range")
+	}
+	if i+n > l {
+		n = l - i
+	}
+	return cd.n.Data[utf16Index(cd.n.Data, i):utf16Index(cd.n.Data, i+n)]
+}
+
+func (cd *CharacterData) AppendData(s string) {
+	cd.replaceData(utf16Len(cd.n.Data), 0, s)
+}
+
+func (cd *CharacterData) DeleteData(i, n int) {
+	cd.replaceData(i, n, "")
+}
+
+func (cd *CharacterData) InsertData(i int, s string) {
+	cd.replaceData(i, 0, s)
+}
+
+func (cd *CharacterData) ReplaceData(i, n int, s string) {
+	cd.replaceData(i, n, s)
+}
+
+type Text struct {
+	CharacterData
+}
+
+func (t *Text) iface() string {
+	return "Text"
+}
+
+func (t *Text) Getters() map[string]bool {
+	gs := t.CharacterData.Getters()
+	gs["wholeText"] = true
+	return gs
+}
+
+func (t *Text) SplitText(i int) Node {
+	if i < 0 || i > utf16Len(t.n.Data) {
+		throwDOMException("IndexSizeError", "offset is out of range")
+	}
+	n := &html.Node{}
+	n.Data = t.n.Data[utf16Index(t.n.Data, i):]
+	n.Type = html.TextNode
+	n.Attr = append([]html.Attribute(nil), t.n.Attr...)
+	nt := t.d.getNode(n)
+	if p := t.n.Parent; p != nil {
+		t.d.insertBefore(p, n, t.n.NextSibling)
+		addMutation(t.d, Value, p)
+		rangesSplitText(t, nt, i)
+	}
+	t.replaceData(i, utf16Len(t.n.Data)-i, "")
+	return nt
+}
+
+// WholeText concatenates the contiguous text nodes
+func (t *Text) WholeText() string {
+	first := t.n
+	for first.PrevSibling != nil && first.PrevSibling.Type == html.TextNode {
+		first = first.PrevSibling
+	}
+	var b strings.Builder
+	for n := first; n != nil && n.Type == html.TextNode; n = n.NextSibling {
+		b.WriteString(n.Data)
+	}
+	return b.String()
+}
+
+type CDATASection struct {
+	Text
+}
+
+func (c *CDATASection) iface() string {
+	return "CDATASection"
+}
+
+type Comment struct {
+	CharacterData
+}
+
+func (c *Comment) iface() string {
+	return "Comment"
+}
+
+// ProcessingInstruction is stored as raw node with the target in an
+// attribute
+type ProcessingInstruction struct {
+	CharacterData
+}
+
+func (pi *ProcessingInstruction) iface() string {
+	return "ProcessingInstruction"
+}
+
+func (pi *ProcessingInstruction) Getters() map[string]bool {
+	gs := pi.CharacterData.Getters()
+	gs["target"] = true
+	return gs
+}
+
+func (pi *ProcessingInstruction) Target() string {
+	return attr(*pi.n, "target")
+}
+
+// DocumentType uses the representation of the html package with the
+// public and system id in attributes
+type DocumentType struct {
+	node
+}
+
+func (dt *DocumentType) iface() string {
+	return "DocumentType"
+}
+
+func (dt *DocumentType) Getters() map[string]bool {
+	gs := dt.node.Getters()
+	gs["name"] = true
+	gs["publicId"] = true
+	gs["systemId"] = true
+	return gs
+}
+
+func (dt *DocumentType) Name() string {
+	return dt.n.Data
+}
+
+func (dt *DocumentType) PublicId() string {
+	return attr(*dt.n, "public")
+}
+
+func (dt *DocumentType) SystemId() string {
+	return attr(*dt.n, "system")
+}
+
+func newDocumentType(d *Document, name, publicId, systemId string) *DocumentType {
+	n := &html.Node{
+		Type: html.DoctypeNode,
+		Data: name,
+	}
+	if publicId != "" {
+		n.Attr = append(n.Attr, html.Attribute{Key: "public", Val: publicId})
+	}
+	if systemId != "" {
+		n.Attr = append(n.Attr, html.Attribute{Key: "system", Val: systemId})
+	}
+	return d.getNode(n).(*DocumentType)
+}
+
+func (nd *node) listeners() listeners {
+	if _, ok := elEventListener[nd.n]; !ok {
+		elEventListener[nd.n] = make(listeners)
+	}
+	return elEventListener[nd.n]
+}
+
+func (nd *node) AddEventListener(e string, fn js.Value, opts ...any) {
+	nd.listeners().add(e, fn, opts...)
+}
+
+func (nd *node) RemoveEventListener(e string, fn js.Value, opts ...any) {
+	nd.listeners().remove(e, fn, opts...)
+}
+
+// TODO: https://datastation.multiprocess.io/blog/2022-04-26-event-handler-attributes.html
+func (nd *node) attachEvent(e string, fn js.Value) {
+	nd.AddEventListener(e, fn)
+}
+
+// DispatchEvent dispatches the event to the node and returns false if
+// it was canceled
+func (nd *node) DispatchEvent(ei any) bool {
+	e := wrap(ei)
+	if e == nil {
+		panic(vm.NewTypeError(fmt.Sprintf("dispatchEvent: parameter 1 is not of type 'Event' (%T)", ei)))
+	}
+	nd.dispatch(e)
+	return !e.DefaultPrevented
+}
+
+// dispatch dispatches the event to the node and returns whether a
+// handler or listener consumed it
+func (nd *node) dispatch(ei any) (consumed bool) {
+	e := wrap(ei)
+	path := []EventTarget{nd.self()}
+	p := nd.n.Parent
+	for ; p != nil && p != nd.d.doc; p = p.Parent {
+		path = append(path, nd.d.getNode(p))
+	}
+	if p != nil {
+		path = append(path, nd.d.path(e)...)
+	}
+	return e.dispatch(path)
+}
+
+// runHandlers runs the event handler attribute and property of the node
+func (nd *node) runHandlers(e *Event) {
+	if onclick := attr(*nd.n, "onclick"); onclick != "" && e.Type == "click" {
+		_, err := vm.RunString(onclick)
+		if err != nil {
+			log.Errorf("onclick '%v': %v", onclick, err)
+		}
+		e.Consumed = true
+	}
+	evs, ok := elVars[nd.n]
+	if !ok {
+		return
+	}
+	f, ok := evs["on"+e.Type]
+	if !ok {
+		return
+	}
+	fn, ok := js.AssertFunction(vm.ToValue(f))
+	if !ok {
+		log.Errorf("el assert function: %v", ok)
+		return
+	}
+	e.Consumed = true
+	res, err := fn(nd.self().Obj(), e.Obj())
+	if err != nil {
+		log.Errorf("el event handler fn: %v", err)
+	} else if res != nil && res.StrictEquals(vm.ToValue(false)) {
+		// returning false from an event handler cancels
+		e.PreventDefault()
+	}
+}
@@ -0,0 +1,307 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"testing"
+)
+
+func TestNodeInterfaces(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<!DOCTYPE html><html><body><div id="d">a<!--c--></div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var el = document.getElementById('d');
+		var txt = el.firstChild;
+		var b = document.body;
+		var log = [
+			txt instanceof Node, txt instanceof CharacterData, txt instanceof Text, txt instanceof Element,
+			el.lastChild instanceof Comment,
+			b instanceof HTMLBodyElement, b instanceof HTMLElement, b instanceof Element, b instanceof Node, b instanceof EventTarget,
+			el instanceof HTMLDivElement,
+			Object.prototype.toString.call(txt), Object.prototype.toString.call(b),
+			'remove' in Element.prototype, 'appendData' in Text.prototype, Node.COMMENT_NODE
+		];
+		var t = new Text('x');
+		var c = new Comment('y');
+		log.push(t.nodeType, t.data, c.nodeType, c.data);
+		log.push(document.doctype.nodeType, document.doctype.name);
+		var dt = document.implementation.createDocumentType('svg', 'pub', 'sys');
+		log.push(dt instanceof DocumentType, dt.publicId, dt.systemId);
+		var pi = document.createProcessingInstruction('xml', 'v=1');
+		log.push(pi.nodeType, pi.target, pi.data, pi instanceof ProcessingInstruction);
+		txt.appendData('bc');
+		log.push(txt.data, txt.length, el.firstChild.splitText(1).data, el.childNodes.length);
+		log.push(document.createElement('unknown') instanceof HTMLUnknownElement, document.createElement('x-y') instanceof HTMLUnknownElement, document.createElement('dir') instanceof HTMLDirectoryElement);
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := "true,true,true,false,true,true,true,true,true,true,true,[object Text],[object HTMLBodyElement],true,true,8,3,x,8,y,10,html,true,pub,sys,7,xml,v=1,true,abc,3,bc,3,true,false,true"
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestAttrNodes(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><body><div id="d" class="a"></div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var el = document.getElementById('d');
+		var a = el.attributes[1];
+		var log = [a instanceof Attr, a.nodeType, a.name, a.value, a.ownerElement === el];
+		a.value = 'b';
+		log.push(el.className, el.attributes.id.value);
+		el.setAttribute('x', '1');
+		var x = el.attributes.getNamedItem('x');
+		log.push(x.value, x === el.getAttributeNode('x'), el.attributes.getNamedItemNS(null, 'x') === x, el.attributes.getNamedItem('y'));
+		var y = document.createAttribute('y');
+		y.value = '2';
+		log.push(el.setAttributeNode(y), el.getAttribute('y'), y.ownerElement === el, el.attributes.getNamedItem('y') === y);
+		var y2 = document.createAttribute('y');
+		y2.value = '3';
+		log.push(el.attributes.setNamedItem(y2) === y, y.ownerElement, y.value, el.getAttribute('y'));
+		var e = document.createElement('p');
+		try { e.setAttributeNode(y2) } catch (err) { log.push(err.name) }
+		log.push(el.removeAttributeNode(y2) === y2, y2.ownerElement, y2.value, el.hasAttribute('y'));
+		try { el.removeAttributeNode(y2) } catch (err) { log.push(err.name) }
+		var rx = el.attributes.removeNamedItem('x');
+		log.push(rx === x, rx.value, el.hasAttribute('x'), el.attributes.length);
+		try { el.attributes.removeNamedItem('x') } catch (err) { log.push(err.name) }
+		log.push(e.setAttributeNode(x), e.getAttribute('x'), x.ownerElement === e);
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != "true,2,class,a,true,b,d,1,true,true,,,2,true,true,true,,2,3,InUseAttributeError,true,,3,false,NotFoundError,true,1,false,2,NotFoundError,,1,true" {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestCompareDocumentPosition(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><body><div id="d" class="c"><b id="x"></b><i id="y"></i></div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var d = document.getElementById('d');
+		var x = document.getElementById('x');
+		var y = document.getElementById('y');
+		var df = document.createDocumentFragment();
+		var u = document.createElement('u');
+		df.appendChild(u);
+		var log = [
+			d.compareDocumentPosition(d), x.compareDocumentPosition(y), y.compareDocumentPosition(x),
+			d.compareDocumentPosition(x), x.compareDocumentPosition(d), document.compareDocumentPosition(x),
+			df.compareDocumentPosition(u), u.compareDocumentPosition(x) & Node.DOCUMENT_POSITION_DISCONNECTED,
+			d.compareDocumentPosition(d.attributes[0]), d.attributes[0].compareDocumentPosition(d.attributes[1]),
+			x.compareDocumentPosition(document), Node.DOCUMENT_POSITION_CONTAINED_BY
+		];
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != "0,4,2,20,10,20,20,1,20,36,10,16" {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestAdoptNode(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><body><div id="d"><b id="x">b</b></div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var doc = document.implementation.createHTMLDocument('t');
+		var x = document.getElementById('x');
+		var clicks = 0;
+		x.addEventListener('click', function() { clicks++; });
+		x.expando = 1;
+		var a = doc.adoptNode(x);
+		var log = [a === x, x.ownerDocument === doc, x.firstChild.ownerDocument === doc, x.parentNode, document.getElementById('d').childNodes.length];
+		doc.body.appendChild(x);
+		x.click();
+		log.push(doc.body.firstChild === x, x.expando, clicks);
+		var i = document.importNode(x, true);
+		log.push(i.ownerDocument === document, i.id, i.firstChild.ownerDocument === document, i === x, x.ownerDocument === doc);
+		document.body.appendChild(x);
+		log.push(x.ownerDocument === document, x.firstChild.ownerDocument === document, document.getElementById('x') === x);
+		try {
+			document.adoptNode(doc);
+		} catch (e) {
+			log.push(e.name);
+		}
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != "true,true,true,,0,true,1,1,true,x,true,false,true,true,true,true,NotSupportedError" {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestCloneNode(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><body><table><tr id="tr"><td class="c">1</td></tr></table><svg id="s"><circle r="1"/></svg><template id="t">a<b>b</b></template><input id="i"><select id="sel"><option>a</option><option>b</option></select></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var tr = document.getElementById('tr');
+		var c = tr.cloneNode(true);
+		var log = [c.outerHTML, c.parentNode, c.firstChild.className, tr.cloneNode().childNodes.length];
+		var txt = document.createTextNode('t');
+		log.push(txt.cloneNode().data);
+		var s = document.getElementById('s').cloneNode(true);
+		log.push(s instanceof HTMLElement, s.firstChild instanceof HTMLElement, s.firstChild.getAttribute('r'));
+		var tpl = document.getElementById('t').cloneNode(true);
+		log.push(tpl.content.childNodes.length, tpl.content.lastChild.textContent);
+		var i = document.getElementById('i');
+		i.value = 'v';
+		log.push(i.cloneNode().value, i.cloneNode().getAttribute('value'));
+		var sel = document.getElementById('sel');
+		sel.selectedIndex = 1;
+		log.push(sel.cloneNode(true).selectedIndex, sel.options[1].cloneNode().selected, sel.cloneNode().options.length);
+		var df = document.createDocumentFragment();
+		df.append('x', document.createElement('p'));
+		log.push(df.cloneNode().childNodes.length, df.cloneNode(true).childNodes.length, df.cloneNode(true).lastChild.parentNode);
+		var doc = document.cloneNode(true);
+		log.push(doc === document, doc.body.firstElementChild.tagName, doc.body.firstElementChild.ownerDocument === doc, document.cloneNode().childNodes.length);
+		var a = document.createAttribute('Title');
+		a.value = 'v';
+		var ac = a.cloneNode();
+		a.value = 'w';
+		var ans = document.createAttributeNS('urn:x', 'x:y');
+		log.push(a.name, ac.value, ac === a, ac instanceof Attr, ac.ownerElement, ans.name, ans.prefix, ans.localName, ans.namespaceURI);
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := `<tr id="tr"><td class="c">1</td></tr>,,c,0,t,false,false,1,2,b,v,,1,true,0,0,2,[object DocumentFragment],false,TABLE,true,0,title,v,false,true,,x:y,x,y,urn:x`
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestPreInsertValidity(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<!DOCTYPE html><html><body><div id="d"><b id="x"></b></div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var d = document.getElementById('d');
+		var x = document.getElementById('x');
+		var log = [];
+		function name(f) {
+			try {
+				f();
+				return 'ok';
+			} catch (e) {
+				return e.name;
+			}
+		}
+		var dt = document.implementation.createDocumentType('html', '', '');
+		log.push(name(function() { d.appendChild(dt); }));
+		log.push(name(function() { d.insertBefore(dt, x); }));
+		log.push(name(function() { document.appendChild(document.createTextNode('t')); }));
+		log.push(name(function() { document.appendChild(document.createElement('p')); }));
+		log.push(name(function() { document.insertBefore(dt, document.doctype); }));
+		log.push(name(function() { d.replaceChild(dt, x); }));
+		log.push(name(function() { document.createTextNode('t').appendChild(d); }));
+		log.push(name(function() { d.removeChild(document.body); }));
+		log.push(name(function() { d.replaceChild(x, x); }));
+		log.push(d.firstChild === x, d.childNodes.length);
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := "HierarchyRequestError,HierarchyRequestError,HierarchyRequestError,HierarchyRequestError,HierarchyRequestError,HierarchyRequestError,HierarchyRequestError,NotFoundError,ok,true,1"
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+	for len(mutations) > 0 {
+		<-mutations
+	}
+	if _, err := vm.RunString(`d.replaceChild(x, x)`); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(mutations) == 0 {
+		t.Fatalf("no mutation recorded")
+	}
+}
+
+func TestInsertMovesNode(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<!DOCTYPE html><html><body><div id="d"><b id="x"><i id="y"></i></b></div><p id="p"></p></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var d = document.getElementById('d');
+		var x = document.getElementById('x');
+		var y = document.getElementById('y');
+		var p = document.getElementById('p');
+		var log = [];
+		function name(f) {
+			try {
+				f();
+				return 'ok';
+			} catch (e) {
+				return e.name;
+			}
+		}
+		log.push(name(function() { x.appendChild(x); }));
+		log.push(name(function() { y.appendChild(d); }));
+		log.push(name(function() { y.insertBefore(x, null); }));
+		log.push(x.parentNode === d, y.parentNode === x);
+		p.appendChild(x);
+		log.push(x.parentNode === p, d.childNodes.length, p.firstChild === x, y.parentNode === x);
+		d.appendChild(y);
+		log.push(y.parentNode === d, x.childNodes.length, document.body.innerHTML);
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := `HierarchyRequestError,HierarchyRequestError,HierarchyRequestError,true,true,true,0,true,true,true,0,<div id="d"><i id="y"></i></div><p id="p"><b id="x"></b></p>`
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestInterfaceNames(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><body></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var names = ['Event', 'MouseEvent', 'KeyboardEvent', 'Node', 'Element', 'HTMLDivElement', 'Attr', 'CSSStyleSheet', 'StyleSheet',
+			'Range', 'StaticRange', 'Selection', 'IntersectionObserver', 'ResizeObserverEntry', 'MediaQueryList',
+			'DOMRect', 'DOMRectReadOnly', 'DOMRectList', 'FormData', 'NodeList', 'HTMLCollection', 'HTMLFormControlsCollection',
+			'DOMParser', 'XMLSerializer', 'MutationObserver', 'Image'];
+		names.filter(function(n) {
+			var c = window[n];
+			return c.name !== n || String(c) !== 'function ' + n + '() { [native code] }' && String(c).indexOf('function ' + n + '()') !== 0;
+		}).join(',') + '|' + new MouseEvent('click').constructor.name + '|' + Object.getOwnPropertyDescriptor(Event, 'name').writable;
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != `|MouseEvent|false` {
+		t.Fatalf("%v", v)
+	}
+}
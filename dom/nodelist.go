@@ -0,0 +1,183 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"github.com/psilva261/sparklefs/logger"
+	"golang.org/x/net/html"
+	"strconv"
+)
+
+var (
+	nodeListCtor  *js.Object
+	nodeListProto *js.Object
+)
+
+// initNodeList defines the NodeList interface object. Iteration is
+// inherited from Array.prototype like with DOMTokenList.
+func initNodeList() (err error) {
+	c, err := vm.RunString(`(function() {
+		function NodeList() {
+			throw new TypeError('Illegal constructor');
+		}
+		Object.defineProperties(NodeList.prototype, {
+			[Symbol.iterator]: { value: Array.prototype.values, writable: true, configurable: true },
+			[Symbol.toStringTag]: { value: 'NodeList', configurable: true },
+			entries: { value: Array.prototype.entries, writable: true, enumerable: true, configurable: true },
+			keys: { value: Array.prototype.keys, writable: true, enumerable: true, configurable: true },
+			values: { value: Array.prototype.values, writable: true, enumerable: true, configurable: true },
+			forEach: { value: Array.prototype.forEach, writable: true, enumerable: true, configurable: true }
+		});
+		return NodeList;
+	})()`)
+	if err != nil {
+		return
+	}
+	nodeListCtor = c.(*js.Object)
+	nodeListProto = nodeListCtor.Get("prototype").(*js.Object)
+	protoMethods(nodeListProto, "item")
+	protoGetters(nodeListProto, "length")
+	return
+}
+
+// NodeList is a list of nodes. It's live if f reflects the current
+// tree (e.g. childNodes) and static if f returns always the same nodes
+// (e.g. querySelectorAll).
+type NodeList struct {
+	d *Document
+	f func() []*html.Node
+
+	// expandos set from JS
+	vars map[string]js.Value
+}
+
+var nlObjRefs = make(map[*NodeList]*js.Object)
+
+// staticNodeList returns a NodeList which doesn't reflect later changes
+// to the tree
+func staticNodeList(d *Document, ns []*html.Node) *NodeList {
+	return &NodeList{
+		d: d,
+		f: func() []*html.Node {
+			return ns
+		},
+	}
+}
+
+func (nl *NodeList) Obj() *js.Object {
+	obj, ok := nlObjRefs[nl]
+	if ok {
+		return obj
+	}
+	obj = vm.NewDynamicObject(nl)
+	if nodeListProto != nil {
+		obj.SetPrototype(nodeListProto)
+	}
+	nlObjRefs[nl] = obj
+	return obj
+}
+
+func (nl *NodeList) Getters() map[string]bool {
+	return map[string]bool{
+		"length": true,
+	}
+}
+
+func (nl *NodeList) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+func (nl *NodeList) Length() int {
+	return len(nl.f())
+}
+
+func (nl *NodeList) Item(j any) Node {
+	i, ok := index(j)
+	if !ok {
+		return nil
+	}
+	ns := nl.f()
+	if i < 0 || i >= len(ns) {
+		return nil
+	}
+	return nl.d.getNode(ns[i])
+}
+
+func (nl *NodeList) ToString() string {
+	return "[object NodeList]"
+}
+
+// Get returns indexed nodes and expandos. Methods and getters are
+// inherited from the prototype.
+func (nl *NodeList) Get(k string) js.Value {
+	if i, err := strconv.Atoi(k); err == nil {
+		ns := nl.f()
+		if i >= 0 && i < len(ns) {
+			return nl.d.getNode(ns[i]).Obj()
+		}
+		return nil
+	}
+	if v, ok := nl.vars[k]; ok {
+		return v
+	}
+	return nil
+}
+
+func (nl *NodeList) Set(k string, desc js.PropertyDescriptor) bool {
+	if _, err := strconv.Atoi(k); err == nil || k == "length" {
+		log.Printf("NodeList: ignore set %v", k)
+		return false
+	}
+	if nl.vars == nil {
+		nl.vars = make(map[string]js.Value)
+	}
+	nl.vars[k] = desc.Value
+	return true
+}
+
+func (nl *NodeList) Has(k string) bool {
+	if i, err := strconv.Atoi(k); err == nil {
+		return i >= 0 && i < nl.Length()
+	}
+	_, ok := nl.vars[k]
+	return ok
+}
+
+func (nl *NodeList) Delete(k string) bool {
+	if i, err := strconv.Atoi(k); err == nil {
+		return i < 0 || i >= nl.Length()
+	}
+	delete(nl.vars, k)
+	return true
+}
+
+func (nl *NodeList) Keys() (ks []string) {
+	for i := range nl.f() {
+		ks = append(ks, strconv.Itoa(i))
+	}
+	for k := range nl.vars {
+		ks = append(ks, k)
+	}
+	return
+}
+
+// index converts the argument of item() into an index
+func index(j any) (i int, ok bool) {
+	switch v := j.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	case nil:
+		return 0, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, true
+		}
+		return int(f), true
+	}
+	log.Errorf("item: %T %v", j, j)
+	return 0, false
+}
@@ -0,0 +1,79 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"testing"
+)
+
+func TestNodeList(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><body><div id="d">a<b id="x"></b><!--c--><i name="y"></i></div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var el = document.getElementById('d');
+		var cn = el.childNodes;
+		var qsa = el.querySelectorAll('*');
+		var log = [cn === el.childNodes, cn instanceof NodeList, cn.length, cn[0].nodeType, cn.item(2).nodeType];
+		var ts = [];
+		cn.forEach(function(n, i) {
+			ts.push(i + ':' + n.nodeName);
+		});
+		log.push(ts.join('+'), Object.prototype.toString.call(cn));
+		el.appendChild(document.createElement('u'));
+		log.push(cn.length, qsa.length, qsa instanceof NodeList, Array.from(qsa.keys()).join('+'));
+		var names = [];
+		for (var n of document.getElementsByName('y')) {
+			names.push(n.tagName);
+		}
+		log.push(names.join('+'));
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := "true,true,4,3,8,0:#text+1:B+2:#comment+3:I,[object NodeList],5,2,true,0+1,I"
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestHTMLCollection(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><body><div id="d">a<b id="x"></b><!--c--><i name="y"></i></div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var el = document.getElementById('d');
+		var hc = el.children;
+		var log = [hc instanceof HTMLCollection, hc.length, hc[1].tagName, hc.namedItem('x').tagName, hc.y.tagName, hc.item(5)];
+		log.push('forEach' in hc || 'entries' in hc || 'keys' in hc || 'values' in hc, Symbol.iterator in hc, 'length' in hc, String(hc));
+		var ts = [];
+		for (var c of hc) {
+			ts.push(c.tagName);
+		}
+		log.push(ts.join('+'), Object.keys(hc).join('+'), Object.getOwnPropertyNames(hc).join('+'));
+		var z = document.createElementNS('', 'i');
+		z.setAttribute('name', 'z');
+		el.appendChild(z);
+		log.push('z' in hc, hc.namedItem('z'), z.namespaceURI);
+		el.removeChild(z);
+		hc.foo = 1;
+		log.push(hc.foo);
+		try {
+			new HTMLCollection();
+		} catch (e) {
+			log.push(e.name);
+		}
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := "true,2,I,B,I,,false,true,true,[object HTMLCollection],B+I,0+1,0+1+x+y,false,,,1,TypeError"
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
@@ -0,0 +1,597 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"github.com/psilva261/sparklefs/logger"
+	"golang.org/x/net/html"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	intersectionObserverCtor      *js.Object
+	intersectionObserverEntryCtor *js.Object
+	resizeObserverCtor            *js.Object
+	resizeObserverEntryCtor       *js.Object
+	resizeObserverSizeCtor        *js.Object
+)
+
+// initObservers defines IntersectionObserver and ResizeObserver whose
+// observations are updated by the animation frames of the document d
+func initObservers(d *Document) {
+	intersectionObserverCtor = newCtor("IntersectionObserver", func(call js.ConstructorCall) *js.Object {
+		io := newIntersectionObserver(d, call.Argument(0), call.Argument(1))
+		o := io.Obj()
+		o.SetPrototype(call.This.Prototype())
+		return o
+	})
+	resizeObserverCtor = newCtor("ResizeObserver", func(call js.ConstructorCall) *js.Object {
+		cb, ok := js.AssertFunction(call.Argument(0))
+		if !ok {
+			panic(vm.NewTypeError("ResizeObserver: callback is not a function"))
+		}
+		ro := &ResizeObserver{d: d, cb: cb}
+		o := ro.Obj()
+		o.SetPrototype(call.This.Prototype())
+		return o
+	})
+	illegal := func(call js.ConstructorCall) *js.Object {
+		panic(vm.NewTypeError("Illegal constructor"))
+	}
+	intersectionObserverEntryCtor = newCtor("IntersectionObserverEntry", illegal)
+	resizeObserverEntryCtor = newCtor("ResizeObserverEntry", illegal)
+	resizeObserverSizeCtor = newCtor("ResizeObserverSize", illegal)
+	ifaces := []struct {
+		name string
+		c    *js.Object
+		recv Gettable
+	}{
+		{"IntersectionObserver", intersectionObserverCtor, &IntersectionObserver{}},
+		{"IntersectionObserverEntry", intersectionObserverEntryCtor, nil},
+		{"ResizeObserver", resizeObserverCtor, &ResizeObserver{}},
+		{"ResizeObserverEntry", resizeObserverEntryCtor, nil},
+		{"ResizeObserverSize", resizeObserverSizeCtor, nil},
+	}
+	for _, i := range ifaces {
+		proto := i.c.Get("prototype").(*js.Object)
+		proto.DefineDataPropertySymbol(js.SymToStringTag, vm.ToValue(i.name), js.FLAG_FALSE, js.FLAG_TRUE, js.FLAG_FALSE)
+		if i.recv != nil {
+			protoMembers(proto, i.recv, nil)
+		}
+	}
+}
+
+// newEntry returns an object with the prototype of ctor and the
+// properties kvs
+func newEntry(ctor *js.Object, kvs ...any) *js.Object {
+	o := vm.NewObject()
+	o.SetPrototype(ctor.Get("prototype").(*js.Object))
+	for i := 0; i+1 < len(kvs); i += 2 {
+		o.Set(kvs[i].(string), kvs[i+1])
+	}
+	return o
+}
+
+// updateObservations runs the resize and intersection observations of
+// a frame at time t and returns true if a callback was invoked
+func (d *Document) updateObservations(t float64) (ok bool) {
+	for _, ro := range append([]*ResizeObserver{}, d.resizeObservers...) {
+		if ro.update() {
+			ok = true
+		}
+	}
+	for _, io := range append([]*IntersectionObserver{}, d.intersectionObservers...) {
+		if io.update(t) {
+			ok = true
+		}
+	}
+	return
+}
+
+// IntersectionObserver reports changes in the intersection of targets
+// with the viewport or a root element
+type IntersectionObserver struct {
+	d          *Document
+	cb         js.Callable
+	obj        *js.Object
+	root       js.Value
+	margin     [4]string
+	thresholds []float64
+
+	targets []*html.Node
+
+	// previous threshold index and intersection by target
+	prevIndex     map[*html.Node]int
+	prevIntersect map[*html.Node]bool
+
+	records []*js.Object
+}
+
+func newIntersectionObserver(d *Document, cb, opts js.Value) *IntersectionObserver {
+	f, ok := js.AssertFunction(cb)
+	if !ok {
+		panic(vm.NewTypeError("IntersectionObserver: callback is not a function"))
+	}
+	io := &IntersectionObserver{
+		d:             d,
+		cb:            f,
+		root:          js.Null(),
+		margin:        [4]string{"0px", "0px", "0px", "0px"},
+		thresholds:    []float64{0},
+		prevIndex:     make(map[*html.Node]int),
+		prevIntersect: make(map[*html.Node]bool),
+	}
+	o, ok := opts.(*js.Object)
+	if !ok {
+		return io
+	}
+	if r := o.Get("root"); r != nil && !js.IsUndefined(r) && !js.IsNull(r) {
+		switch r.Export().(type) {
+		case *Element, *Document:
+			io.root = r
+		default:
+			panic(vm.NewTypeError("IntersectionObserver: root is not an Element or Document"))
+		}
+	}
+	if m := o.Get("rootMargin"); m != nil && !js.IsUndefined(m) {
+		ms := strings.Fields(m.String())
+		if len(ms) == 0 || len(ms) > 4 {
+			throwDOMException("SyntaxError", "rootMargin must be specified in pixels or percent")
+		}
+		for _, v := range ms {
+			if !strings.HasSuffix(v, "px") && !strings.HasSuffix(v, "%") && v != "0" {
+				throwDOMException("SyntaxError", "rootMargin must be specified in pixels or percent")
+			}
+		}
+		idx := [][]int{{0, 0, 0, 0}, {0, 1, 0, 1}, {0, 1, 2, 1}, {0, 1, 2, 3}}[len(ms)-1]
+		for i := range io.margin {
+			if io.margin[i] = ms[idx[i]]; io.margin[i] == "0" {
+				io.margin[i] = "0px"
+			}
+		}
+	}
+	if t := o.Get("threshold"); t != nil && !js.IsUndefined(t) {
+		io.thresholds = nil
+		var ts []js.Value
+		if a, ok := t.(*js.Object); ok && a.ClassName() == "Array" {
+			for _, k := range a.Keys() {
+				ts = append(ts, a.Get(k))
+			}
+		} else {
+			ts = []js.Value{t}
+		}
+		for _, v := range ts {
+			x := v.ToFloat()
+			if math.IsNaN(x) || x < 0 || x > 1 {
+				re, _ := vm.New(vm.Get("RangeError"), vm.ToValue("Threshold values must be numbers between 0 and 1"))
+				panic(re)
+			}
+			io.thresholds = append(io.thresholds, x)
+		}
+		sort.Float64s(io.thresholds)
+		if len(io.thresholds) == 0 {
+			io.thresholds = []float64{0}
+		}
+	}
+	return io
+}
+
+func (io *IntersectionObserver) Obj() *js.Object {
+	if io.obj == nil {
+		io.obj = vm.NewDynamicObject(io)
+		io.obj.SetPrototype(intersectionObserverCtor.Get("prototype").(*js.Object))
+	}
+	return io.obj
+}
+
+func (io *IntersectionObserver) Getters() map[string]bool {
+	return map[string]bool{
+		"root":       true,
+		"rootMargin": true,
+		"thresholds": true,
+	}
+}
+
+func (io *IntersectionObserver) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+func (io *IntersectionObserver) Root() js.Value {
+	return io.root
+}
+
+func (io *IntersectionObserver) RootMargin() string {
+	return strings.Join(io.margin[:], " ")
+}
+
+func (io *IntersectionObserver) Thresholds() js.Value {
+	ts := make([]any, len(io.thresholds))
+	for i, t := range io.thresholds {
+		ts[i] = t
+	}
+	return vm.NewArray(ts...)
+}
+
+func (io *IntersectionObserver) Observe(el *Element) {
+	if el == nil {
+		panic(vm.NewTypeError("IntersectionObserver.observe: target is not an Element"))
+	}
+	for _, n := range io.targets {
+		if n == el.n {
+			return
+		}
+	}
+	if len(io.targets) == 0 {
+		io.d.intersectionObservers = append(io.d.intersectionObservers, io)
+	}
+	io.targets = append(io.targets, el.n)
+	io.prevIndex[el.n] = -1
+	io.prevIntersect[el.n] = false
+}
+
+func (io *IntersectionObserver) Unobserve(el *Element) {
+	if el == nil {
+		return
+	}
+	for i, n := range io.targets {
+		if n == el.n {
+			io.targets = append(io.targets[:i], io.targets[i+1:]...)
+			delete(io.prevIndex, n)
+			delete(io.prevIntersect, n)
+			break
+		}
+	}
+	if len(io.targets) == 0 {
+		io.Disconnect()
+	}
+}
+
+func (io *IntersectionObserver) Disconnect() {
+	io.targets = nil
+	io.prevIndex = make(map[*html.Node]int)
+	io.prevIntersect = make(map[*html.Node]bool)
+	for i, o := range io.d.intersectionObservers {
+		if o == io {
+			io.d.intersectionObservers = append(io.d.intersectionObservers[:i], io.d.intersectionObservers[i+1:]...)
+			break
+		}
+	}
+}
+
+func (io *IntersectionObserver) TakeRecords() js.Value {
+	rs := make([]any, len(io.records))
+	for i, r := range io.records {
+		rs[i] = r
+	}
+	io.records = nil
+	return vm.NewArray(rs...)
+}
+
+// rootBox is the root intersection rectangle including the margin
+func (io *IntersectionObserver) rootBox() (b box) {
+	b = box{x2: float64(ViewportWidth), y2: float64(ViewportHeight), ok: true}
+	if io.root != nil && !js.IsNull(io.root) {
+		if el, ok := io.root.Export().(*Element); ok {
+			b = el.clientBox()
+			if !b.ok {
+				return
+			}
+			cs := &ComputedStyle{el: el}
+			if cs.GetPropertyValue("overflow-x") != "visible" || cs.GetPropertyValue("overflow-y") != "visible" {
+				b = el.paddingBox()
+			}
+		}
+	}
+	w, h := b.x2-b.x1, b.y2-b.y1
+	m := make([]float64, 4)
+	for i, v := range io.margin {
+		base := h
+		if i%2 == 1 {
+			base = w
+		}
+		if strings.HasSuffix(v, "%") {
+			x, _ := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+			m[i] = x * base / 100
+		} else {
+			m[i], _ = strconv.ParseFloat(strings.TrimSuffix(v, "px"), 64)
+		}
+	}
+	b.y1 -= m[0]
+	b.x2 += m[1]
+	b.y2 += m[2]
+	b.x1 -= m[3]
+	return
+}
+
+func isDescendant(n, a *html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p == a {
+			return true
+		}
+	}
+	return false
+}
+
+// paddingBox of el
+func (el *Element) paddingBox() box {
+	b := el.clientBox()
+	b.x1 += el.computedPx("border-left-width")
+	b.y1 += el.computedPx("border-top-width")
+	b.x2 -= el.computedPx("border-right-width")
+	b.y2 -= el.computedPx("border-bottom-width")
+	return b
+}
+
+// intersect a and b, ok is false if they don't touch
+func intersect(a, b box) (c box) {
+	c = box{
+		x1: math.Max(a.x1, b.x1),
+		y1: math.Max(a.y1, b.y1),
+		x2: math.Min(a.x2, b.x2),
+		y2: math.Min(a.y2, b.y2),
+	}
+	c.ok = a.ok && b.ok && c.x1 <= c.x2 && c.y1 <= c.y2
+	if !c.ok {
+		return box{}
+	}
+	return
+}
+
+func rectOf(b box) *DOMRect {
+	return &DOMRect{x: b.x1, y: b.y1, w: b.x2 - b.x1, h: b.y2 - b.y1, readOnly: true}
+}
+
+// update the observations of the targets and invoke the callback with
+// the new entries
+func (io *IntersectionObserver) update(t float64) bool {
+	rb := io.rootBox()
+	var rootEl *Element
+	if io.root != nil && !js.IsNull(io.root) {
+		rootEl, _ = io.root.Export().(*Element)
+	}
+	for _, n := range append([]*html.Node{}, io.targets...) {
+		el := io.d.getEl(n)
+		tb := el.clientBox()
+		ib := box{}
+		observable := connected(n) && tb.ok && rb.ok && (rootEl == nil || isDescendant(n, rootEl.n))
+		if observable {
+			ib = intersect(tb, rb)
+			for a := n.Parent; ib.ok && a != nil && a.Type == html.ElementNode && (rootEl == nil || a != rootEl.n); a = a.Parent {
+				ae := io.d.getEl(a)
+				cs := &ComputedStyle{el: ae}
+				if cs.GetPropertyValue("overflow-x") != "visible" || cs.GetPropertyValue("overflow-y") != "visible" {
+					ib = intersect(ib, ae.paddingBox())
+				}
+			}
+		}
+		isIntersecting := ib.ok
+		ratio := 0.0
+		if isIntersecting {
+			if area := (tb.x2 - tb.x1) * (tb.y2 - tb.y1); area > 0 {
+				ratio = (ib.x2 - ib.x1) * (ib.y2 - ib.y1) / area
+			} else {
+				ratio = 1
+			}
+		}
+		index := 0
+		if isIntersecting {
+			index = len(io.thresholds)
+			for i, th := range io.thresholds {
+				if th > ratio {
+					index = i
+					break
+				}
+			}
+		}
+		if index == io.prevIndex[n] && isIntersecting == io.prevIntersect[n] {
+			continue
+		}
+		io.prevIndex[n] = index
+		io.prevIntersect[n] = isIntersecting
+		var rootBounds any = js.Null()
+		if observable {
+			rootBounds = rectOf(rb).Obj()
+		}
+		io.records = append(io.records, newEntry(intersectionObserverEntryCtor,
+			"time", t,
+			"rootBounds", rootBounds,
+			"boundingClientRect", rectOf(tb).Obj(),
+			"intersectionRect", rectOf(ib).Obj(),
+			"isIntersecting", isIntersecting,
+			"intersectionRatio", ratio,
+			"target", el.Obj(),
+		))
+	}
+	if len(io.records) == 0 {
+		return false
+	}
+	if _, err := io.cb(io.Obj(), io.TakeRecords(), io.Obj()); err != nil {
+		log.Errorf("intersection observer callback: %v", err)
+	}
+	return true
+}
+
+func (io *IntersectionObserver) Get(k string) js.Value {
+	if res, ok := GetCall(io, k); ok {
+		return res
+	}
+	return nil
+}
+
+func (io *IntersectionObserver) Set(k string, desc js.PropertyDescriptor) bool {
+	return false
+}
+
+func (io *IntersectionObserver) Has(k string) bool {
+	return HasCall(io, k)
+}
+
+func (io *IntersectionObserver) Delete(k string) bool {
+	return false
+}
+
+func (io *IntersectionObserver) Keys() []string {
+	return []string{}
+}
+
+// ResizeObserver reports changes in the size of the content or border
+// box of targets
+type ResizeObserver struct {
+	d       *Document
+	cb      js.Callable
+	obj     *js.Object
+	targets []*resizeTarget
+}
+
+type resizeTarget struct {
+	n *html.Node
+
+	// box is content-box, border-box or device-pixel-content-box
+	box string
+
+	// last reported inline and block size
+	w, h float64
+}
+
+func (ro *ResizeObserver) Obj() *js.Object {
+	if ro.obj == nil {
+		ro.obj = vm.NewDynamicObject(ro)
+		ro.obj.SetPrototype(resizeObserverCtor.Get("prototype").(*js.Object))
+	}
+	return ro.obj
+}
+
+func (ro *ResizeObserver) Getters() map[string]bool {
+	return map[string]bool{}
+}
+
+func (ro *ResizeObserver) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+func (ro *ResizeObserver) Observe(el *Element, opts map[string]any) {
+	if el == nil {
+		panic(vm.NewTypeError("ResizeObserver.observe: target is not an Element"))
+	}
+	b := "content-box"
+	if v, ok := opts["box"].(string); ok {
+		switch v {
+		case "content-box", "border-box", "device-pixel-content-box":
+			b = v
+		default:
+			panic(vm.NewTypeError("ResizeObserver.observe: invalid box " + v))
+		}
+	}
+	ro.Unobserve(el)
+	if len(ro.targets) == 0 {
+		ro.d.resizeObservers = append(ro.d.resizeObservers, ro)
+	}
+	ro.targets = append(ro.targets, &resizeTarget{n: el.n, box: b, w: -1, h: -1})
+}
+
+func (ro *ResizeObserver) Unobserve(el *Element) {
+	if el == nil {
+		return
+	}
+	for i, t := range ro.targets {
+		if t.n == el.n {
+			ro.targets = append(ro.targets[:i], ro.targets[i+1:]...)
+			break
+		}
+	}
+	if len(ro.targets) == 0 {
+		ro.Disconnect()
+	}
+}
+
+func (ro *ResizeObserver) Disconnect() {
+	ro.targets = nil
+	for i, o := range ro.d.resizeObservers {
+		if o == ro {
+			ro.d.resizeObservers = append(ro.d.resizeObservers[:i], ro.d.resizeObservers[i+1:]...)
+			break
+		}
+	}
+}
+
+// sizes of the border box and content box of el and the content rect
+func resizeSizes(el *Element) (border, content [2]float64, cr box) {
+	b := el.box()
+	if !b.ok || !connected(el.n) {
+		return
+	}
+	border = [2]float64{b.x2 - b.x1, b.y2 - b.y1}
+	var e [4]float64
+	for i, s := range []string{"top", "right", "bottom", "left"} {
+		e[i] = el.computedPx("border-"+s+"-width") + el.computedPx("padding-"+s)
+	}
+	content = [2]float64{math.Max(border[0]-e[1]-e[3], 0), math.Max(border[1]-e[0]-e[2], 0)}
+	cr = box{
+		x1: el.computedPx("padding-left"),
+		y1: el.computedPx("padding-top"),
+		ok: true,
+	}
+	cr.x2, cr.y2 = cr.x1+content[0], cr.y1+content[1]
+	return
+}
+
+func resizeObserverSizes(s [2]float64) *js.Object {
+	return vm.NewArray(newEntry(resizeObserverSizeCtor, "inlineSize", s[0], "blockSize", s[1]))
+}
+
+// update gathers the targets whose observed size changed and invokes
+// the callback with their entries
+func (ro *ResizeObserver) update() bool {
+	var entries []any
+	for _, t := range append([]*resizeTarget{}, ro.targets...) {
+		el := ro.d.getEl(t.n)
+		border, content, cr := resizeSizes(el)
+		s := content
+		if t.box == "border-box" {
+			s = border
+		}
+		if s[0] == t.w && s[1] == t.h {
+			continue
+		}
+		t.w, t.h = s[0], s[1]
+		entries = append(entries, newEntry(resizeObserverEntryCtor,
+			"target", el.Obj(),
+			"contentRect", rectOf(cr).Obj(),
+			"borderBoxSize", resizeObserverSizes(border),
+			"contentBoxSize", resizeObserverSizes(content),
+			"devicePixelContentBoxSize", resizeObserverSizes([2]float64{content[0] * DevicePixelRatio, content[1] * DevicePixelRatio}),
+		))
+	}
+	if len(entries) == 0 {
+		return false
+	}
+	if _, err := ro.cb(ro.Obj(), vm.NewArray(entries...), ro.Obj()); err != nil {
+		log.Errorf("resize observer callback: %v", err)
+	}
+	return true
+}
+
+func (ro *ResizeObserver) Get(k string) js.Value {
+	if res, ok := GetCall(ro, k); ok {
+		return res
+	}
+	return nil
+}
+
+func (ro *ResizeObserver) Set(k string, desc js.PropertyDescriptor) bool {
+	return false
+}
+
+func (ro *ResizeObserver) Has(k string) bool {
+	return HasCall(ro, k)
+}
+
+func (ro *ResizeObserver) Delete(k string) bool {
+	return false
+}
+
+func (ro *ResizeObserver) Keys() []string {
+	return []string{}
+}
@@ -0,0 +1,71 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"testing"
+)
+
+func TestObservers(t *testing.T) {
+	vm := js.New()
+	d, err := Init(vm, "https://example.com", `<html><head><style>
+		#a { width: 100px; height: 100px; padding: 5px; border: 2px solid }
+		#b { position: absolute; top: 700px; left: 0; width: 100px; height: 100px }
+	</style></head><body><div id="a"></div><div id="b"></div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	_, err = vm.RunString(`
+		var log = [];
+		var a = document.getElementById('a'), b = document.getElementById('b');
+		var io = new IntersectionObserver(function(es, o) {
+			es.forEach(function(e) {
+				log.push('io:' + e.target.id + ':' + e.isIntersecting + ':' + e.intersectionRatio + ':' + (e instanceof IntersectionObserverEntry));
+			});
+		}, { threshold: [0, 0.5, 1], rootMargin: '10px' });
+		io.observe(a);
+		io.observe(b);
+		var ro = new ResizeObserver(function(es) {
+			es.forEach(function(e) {
+				log.push('ro:' + e.target.id + ':' + e.contentRect.width + ':' + e.borderBoxSize[0].inlineSize + ':' + e.contentBoxSize[0].blockSize);
+			});
+		});
+		ro.observe(a);
+		requestAnimationFrame(function() { log.push('raf') });
+		cancelAnimationFrame(requestAnimationFrame(function() { log.push('cancelled') }));
+		log.push(io.rootMargin, io.thresholds.join(','));
+		try { new IntersectionObserver(function() {}, { threshold: 2 }) } catch (e) { log.push(e.name) }
+		try { new IntersectionObserver(function() {}, { rootMargin: '1em' }) } catch (e) { log.push(e.name) }
+		try { new ResizeObserverEntry() } catch (e) { log.push(e.name) }
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	frame := func() string {
+		d.Window.RenderAnimationFrame()
+		res, err := vm.RunString(`var s = log.join('|'); log = []; s`)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		return res.String()
+	}
+	if v := frame(); v != `10px 10px 10px 10px|0,0.5,1|RangeError|SyntaxError|TypeError|raf|ro:a:100:114:100|io:a:true:1:true|io:b:true:0.3:true` {
+		t.Fatalf("%v", v)
+	}
+	if v := frame(); v != `` {
+		t.Fatalf("%v", v)
+	}
+	_, err = vm.RunString(`a.style.width = '50px'; b.style.top = '900px'`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := frame(); v != `ro:a:50:64:100|io:b:false:0:true` {
+		t.Fatalf("%v", v)
+	}
+	_, err = vm.RunString(`ro.disconnect(); io.unobserve(b); a.style.height = '0'`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := frame(); v != `` {
+		t.Fatalf("%v", v)
+	}
+}
@@ -0,0 +1,676 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"github.com/psilva261/sparklefs/logger"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"strings"
+)
+
+// convertNodes converts the arguments of the ParentNode and ChildNode
+// methods into a list of nodes. Strings become text nodes and document
+// fragments are replaced by their children.
+func (d *Document) convertNodes(args []js.Value) (ns []*html.Node) {
+	for _, a := range args {
+		if o, ok := a.(*js.Object); ok {
+			switch v := o.Export().(type) {
+			case *DocumentFragment:
+				for _, c := range v.children {
+					d.adopt(v.d.getNode(c))
+				}
+				ns = append(ns, v.children...)
+				continue
+			case Node:
+				d.adopt(v)
+				ns = append(ns, v.base().n)
+				continue
+			case *Document:
+				throwDOMException("HierarchyRequestError", "a document cannot be inserted")
+			}
+		}
+		ns = append(ns, &html.Node{
+			Type: html.TextNode,
+			Data: a.String(),
+		})
+	}
+	return
+}
+
+// insertNodes inserts ns before the child ref (nil to append) and
+// records a mutation for each of them. If ref itself is inserted, the
+// nodes are inserted before its next sibling which isn't.
+func (nd *node) insertNodes(ns []*html.Node, ref *html.Node) {
+	for ref != nil && contains(ns, ref) {
+		ref = ref.NextSibling
+	}
+	nd.checkInsert(ns, ref, nil)
+	for _, n := range ns {
+		nd.d.detach(n)
+		nd.d.insertBefore(nd.n, n, ref)
+		addMutation(nd.d, Insert, n)
+	}
+}
+
+// checkInsert throws a HierarchyRequestError if ns can't be inserted
+// into nd before ref, replacing the child old unless it's nil. Only
+// documents and elements have children and a document can have at most
+// one doctype followed by at most one element and no text.
+func (nd *node) checkInsert(ns []*html.Node, ref, old *html.Node) {
+	if t := nd.n.Type; t != html.DocumentNode && t != html.ElementNode {
+		throwDOMException("HierarchyRequestError", "the parent cannot have children")
+	}
+	for _, n := range ns {
+		checkHierarchy(nd.n, n)
+	}
+	isDoc := nd.n.Type == html.DocumentNode
+	els, dts := 0, 0
+	for _, n := range ns {
+		switch n.Type {
+		case html.DoctypeNode:
+			if !isDoc {
+				throwDOMException("HierarchyRequestError", "a doctype can only be inserted into a document")
+			}
+			dts++
+		case html.TextNode:
+			if isDoc {
+				throwDOMException("HierarchyRequestError", "text cannot be inserted into a document")
+			}
+		case html.ElementNode:
+			els++
+		}
+	}
+	if !isDoc {
+		return
+	}
+	if els > 1 || dts > 1 {
+		throwDOMException("HierarchyRequestError", "a document can only have one element and doctype")
+	}
+	beforeRef := true
+	for c := nd.n.FirstChild; c != nil; c = c.NextSibling {
+		if c == ref {
+			beforeRef = false
+		}
+		if c == old || contains(ns, c) {
+			continue
+		}
+		switch {
+		case c.Type == html.ElementNode && (els > 0 || dts > 0 && beforeRef):
+			throwDOMException("HierarchyRequestError", "the document already has an element")
+		case c.Type == html.DoctypeNode && (dts > 0 || els > 0 && !beforeRef):
+			throwDOMException("HierarchyRequestError", "the document already has a doctype")
+		}
+	}
+}
+
+// detach removes n from its parent or document fragment
+func (d *Document) detach(n *html.Node) {
+	if p := n.Parent; p != nil {
+		d.removeChild(p, n)
+		addMutation(d, Rm, p)
+	} else if b := d.getNode(n).base(); b.df != nil {
+		b.df.RemoveChild(b.self())
+	}
+}
+
+// removeChild removes c from its parent p after running the removing
+// steps of the node iterators and live ranges. Removed iframes lose
+// their browsing context.
+func (d *Document) removeChild(p, c *html.Node) {
+	d.preRemove(c)
+	if len(liveRanges) > 0 {
+		rangesRemove(d.getNode(c))
+	}
+	p.RemoveChild(c)
+	if len(d.frames) > 0 {
+		d.discardFrames()
+	}
+}
+
+// insertBefore inserts c into p before ref (nil to append) and updates
+// the live ranges
+func (d *Document) insertBefore(p, c, ref *html.Node) {
+	if ref != nil && len(liveRanges) > 0 {
+		rangesInsert(d.getNode(ref), 1)
+	}
+	p.InsertBefore(c, ref)
+	d.loadStyleSheets(c)
+	d.prepareScripts(c)
+}
+
+// Before implements the ChildNode mixin
+func (nd *node) Before(args ...js.Value) {
+	p := nd.n.Parent
+	if p == nil {
+		return
+	}
+	ns := nd.d.convertNodes(args)
+	// viable previous sibling
+	prev := nd.n.PrevSibling
+	for prev != nil && contains(ns, prev) {
+		prev = prev.PrevSibling
+	}
+	ref := p.FirstChild
+	if prev != nil {
+		ref = prev.NextSibling
+	}
+	nd.d.getNode(p).base().insertNodes(ns, ref)
+}
+
+// After implements the ChildNode mixin
+func (nd *node) After(args ...js.Value) {
+	p := nd.n.Parent
+	if p == nil {
+		return
+	}
+	ns := nd.d.convertNodes(args)
+	next := nd.viableNext(ns)
+	nd.d.getNode(p).base().insertNodes(ns, next)
+}
+
+// ReplaceWith implements the ChildNode mixin
+func (nd *node) ReplaceWith(args ...js.Value) {
+	p := nd.n.Parent
+	if p == nil {
+		return
+	}
+	ns := nd.d.convertNodes(args)
+	next := nd.viableNext(ns)
+	pn := nd.d.getNode(p).base()
+	for _, n := range ns {
+		checkHierarchy(p, n)
+	}
+	if nd.n.Parent == p {
+		nd.d.removeChild(p, nd.n)
+		addMutation(nd.d, Rm, p)
+	}
+	pn.insertNodes(ns, next)
+}
+
+// viableNext returns the first following sibling not in ns
+func (nd *node) viableNext(ns []*html.Node) *html.Node {
+	next := nd.n.NextSibling
+	for next != nil && contains(ns, next) {
+		next = next.NextSibling
+	}
+	return next
+}
+
+func contains(ns []*html.Node, n *html.Node) bool {
+	for _, m := range ns {
+		if m == n {
+			return true
+		}
+	}
+	return false
+}
+
+// nextElement returns the next (or previous) element sibling of nd
+func (nd *node) nextElement(prev bool) js.Value {
+	for n := sibling(nd.n, prev); n != nil; n = sibling(n, prev) {
+		if n.Type == html.ElementNode {
+			return nd.d.nodeObj(n)
+		}
+	}
+	return js.Null()
+}
+
+func sibling(n *html.Node, prev bool) *html.Node {
+	if prev {
+		return n.PrevSibling
+	}
+	return n.NextSibling
+}
+
+// elementChild returns the first (or last) element child of n
+func elementChild(n *html.Node, last bool) *html.Node {
+	c := n.FirstChild
+	if last {
+		c = n.LastChild
+	}
+	for ; c != nil; c = sibling(c, last) {
+		if c.Type == html.ElementNode {
+			return c
+		}
+	}
+	return nil
+}
+
+func childElementCount(n *html.Node) (i int) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			i++
+		}
+	}
+	return
+}
+
+func (cd *CharacterData) NextElementSibling() js.Value {
+	return cd.nextElement(false)
+}
+
+func (cd *CharacterData) PreviousElementSibling() js.Value {
+	return cd.nextElement(true)
+}
+
+func (el *Element) NextElementSibling() js.Value {
+	return el.nextElement(false)
+}
+
+func (el *Element) PreviousElementSibling() js.Value {
+	return el.nextElement(true)
+}
+
+func (el *Element) FirstElementChild() js.Value {
+	return el.d.nodeObj(elementChild(el.n, false))
+}
+
+func (el *Element) LastElementChild() js.Value {
+	return el.d.nodeObj(elementChild(el.n, true))
+}
+
+func (el *Element) ChildElementCount() int {
+	return childElementCount(el.n)
+}
+
+// Append implements the ParentNode mixin
+func (el *Element) Append(args ...js.Value) {
+	el.insertNodes(el.d.convertNodes(args), nil)
+}
+
+// Prepend implements the ParentNode mixin
+func (el *Element) Prepend(args ...js.Value) {
+	el.insertNodes(el.d.convertNodes(args), el.n.FirstChild)
+}
+
+// ReplaceChildren implements the ParentNode mixin
+func (el *Element) ReplaceChildren(args ...js.Value) {
+	ns := el.d.convertNodes(args)
+	el.checkInsert(ns, nil, nil)
+	for el.n.FirstChild != nil {
+		el.d.removeChild(el.n, el.n.FirstChild)
+		addMutation(el.d, Rm, el.n)
+	}
+	el.insertNodes(ns, nil)
+}
+
+func (el *Element) Closest(s string) js.Value {
+	for n := el.n; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		if el.d.getEl(n).Matches(s) {
+			return el.d.nodeObj(n)
+		}
+	}
+	return js.Null()
+}
+
+func (el *Element) ToggleAttribute(k string, force ...bool) bool {
+	k = asciiLower(k)
+	has := hasAttr(*el.n, k)
+	switch {
+	case !has && (len(force) == 0 || force[0]):
+		setAttr(el.n, k, "")
+		addMutation(el.d, ChAttr, el.n)
+		return true
+	case has && (len(force) == 0 || !force[0]):
+		rmAttr(el.n, k)
+		return false
+	}
+	return has
+}
+
+func (el *Element) GetAttributeNames() js.Value {
+	names := make([]any, 0, len(el.n.Attr))
+	for _, a := range el.n.Attr {
+		if a.Namespace != "" {
+			names = append(names, a.Namespace+":"+a.Key)
+		} else {
+			names = append(names, a.Key)
+		}
+	}
+	return vm.NewArray(names...)
+}
+
+// adjacent returns the parent and reference child for the position of
+// insertAdjacent*
+func (el *Element) adjacent(where string) (p, ref *html.Node) {
+	switch strings.ToLower(where) {
+	case "beforebegin":
+		return el.n.Parent, el.n
+	case "afterbegin":
+		return el.n, el.n.FirstChild
+	case "beforeend":
+		return el.n, nil
+	case "afterend":
+		return el.n.Parent, el.n.NextSibling
+	}
+	throwDOMException("SyntaxError", "invalid position "+where)
+	return
+}
+
+func (el *Element) InsertAdjacentElement(where string, o any) js.Value {
+	nue := asNode(o)
+	if _, ok := nue.(*Element); !ok {
+		panic(vm.NewTypeError("insertAdjacentElement: parameter 2 is not of type 'Element'"))
+	}
+	p, ref := el.adjacent(where)
+	if p == nil {
+		return js.Null()
+	}
+	el.d.adopt(nue)
+	el.d.getNode(p).base().insertNodes([]*html.Node{nue.base().n}, ref)
+	return nue.Obj()
+}
+
+func (el *Element) InsertAdjacentText(where, data string) {
+	p, ref := el.adjacent(where)
+	if p == nil {
+		return
+	}
+	tn := &html.Node{
+		Type: html.TextNode,
+		Data: data,
+	}
+	el.d.getNode(p).base().insertNodes([]*html.Node{tn}, ref)
+}
+
+func (el *Element) InsertAdjacentHTML(where, h string) {
+	p, ref := el.adjacent(where)
+	if p == nil || p.Type == html.DocumentNode {
+		throwDOMException("NoModificationAllowedError", "the element has no parent element")
+	}
+	ctx := p
+	if ctx == el.d.doc || ctx.Data == "html" && ctx.Type == html.ElementNode && p != el.n {
+		ctx = &html.Node{Type: html.ElementNode, Data: "body"}
+	}
+	ns, err := html.ParseFragment(strings.NewReader(h), ctx)
+	if err != nil {
+		log.Errorf("insert adjacent html: %v", err)
+		return
+	}
+	for _, n := range ns {
+		el.d.markStarted(n)
+	}
+	el.d.getNode(p).base().insertNodes(ns, ref)
+}
+
+// InnerText approximates the rendered text of the element without
+// layout: elements which aren't displayed and invisible text are
+// skipped, <br> and block boundaries become line breaks, cells are
+// separated by tabs and whitespace is collapsed outside of <pre>.
+func (el *Element) InnerText() string {
+	if !connected(el.n) {
+		return el.text()
+	}
+	cs := newCascade(el.d)
+	for p := el.n; p != nil && p.Type == html.ElementNode; p = p.Parent {
+		if cs.value(p, "display") == "none" {
+			return el.text()
+		}
+	}
+	// items are strings, collapsible strings and required line break
+	// counts
+	var items []any
+	var f func(n *html.Node, pre bool)
+	f = func(n *html.Node, pre bool) {
+		switch n.Type {
+		case html.TextNode:
+			if cs.value(n.Parent, "visibility") != "visible" {
+				return
+			}
+			if pre {
+				items = append(items, n.Data)
+			} else {
+				items = append(items, collapsible(n.Data))
+			}
+			return
+		case html.ElementNode:
+		default:
+			return
+		}
+		display := cs.value(n, "display")
+		if display == "none" {
+			return
+		}
+		switch n.Data {
+		case "br":
+			items = append(items, "\n")
+			return
+		case "pre", "textarea", "listing":
+			pre = true
+		}
+		brk := 0
+		if n.Data == "p" {
+			brk = 2
+		} else if blockDisplay[display] {
+			brk = 1
+		}
+		items = append(items, brk)
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c, pre)
+		}
+		if (n.Data == "td" || n.Data == "th") && elementNext(n) != nil {
+			items = append(items, "\t")
+		}
+		items = append(items, brk)
+	}
+	for c := el.n.FirstChild; c != nil; c = c.NextSibling {
+		f(c, el.n.Data == "pre")
+	}
+	var out string
+	brk := 0
+	for _, it := range items {
+		switch v := it.(type) {
+		case int:
+			if v > brk {
+				brk = v
+			}
+			continue
+		case collapsible:
+			s := strings.Join(strings.Fields(string(v)), " ")
+			if s == "" && v != "" {
+				s = " "
+			} else if s != "" {
+				if strings.TrimLeft(string(v), " \t\n\f\r") != string(v) {
+					s = " " + s
+				}
+				if strings.TrimRight(string(v), " \t\n\f\r") != string(v) {
+					s += " "
+				}
+			}
+			if brk > 0 || out == "" || strings.HasSuffix(out, " ") || strings.HasSuffix(out, "\n") || strings.HasSuffix(out, "\t") {
+				s = strings.TrimLeft(s, " ")
+			}
+			if s == "" {
+				continue
+			}
+			out = flushBreaks(out, brk) + s
+		case string:
+			if v == "" {
+				continue
+			}
+			out = flushBreaks(out, brk) + v
+		}
+		brk = 0
+	}
+	return strings.TrimRight(out, " ")
+}
+
+// collapsible text has its whitespace collapsed by innerText
+type collapsible string
+
+// flushBreaks appends the required line breaks brk to s unless s is
+// empty
+func flushBreaks(s string, brk int) string {
+	if brk == 0 || s == "" {
+		return s
+	}
+	return strings.TrimRight(s, " ") + strings.Repeat("\n", brk)
+}
+
+func elementNext(n *html.Node) *html.Node {
+	for c := n.NextSibling; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			return c
+		}
+	}
+	return nil
+}
+
+// blockDisplay are the display values which begin and end lines in
+// innerText
+var blockDisplay = map[string]bool{
+	"block": true, "flow-root": true, "flex": true, "grid": true,
+	"list-item": true, "table": true, "table-caption": true,
+	"table-row": true,
+}
+
+// textNodes converts the value of innerText or outerText into text
+// nodes and <br> elements
+func textNodes(s string) (ns []*html.Node) {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	for i, l := range strings.Split(s, "\n") {
+		if i > 0 {
+			ns = append(ns, &html.Node{
+				Type:     html.ElementNode,
+				Data:     "br",
+				DataAtom: atom.Br,
+			})
+		}
+		if l != "" {
+			ns = append(ns, &html.Node{
+				Type: html.TextNode,
+				Data: l,
+			})
+		}
+	}
+	return
+}
+
+func (el *Element) setInnerText(s string) {
+	for el.n.FirstChild != nil {
+		el.d.removeChild(el.n, el.n.FirstChild)
+	}
+	for _, n := range textNodes(s) {
+		el.n.AppendChild(n)
+	}
+	addMutation(el.d, Value, el.n)
+}
+
+func (el *Element) OuterText() string {
+	return el.InnerText()
+}
+
+func (el *Element) setOuterText(s string) {
+	p := el.n.Parent
+	if p == nil {
+		throwDOMException("NoModificationAllowedError", "the element has no parent")
+	}
+	ns := textNodes(s)
+	if len(ns) == 0 {
+		ns = append(ns, &html.Node{Type: html.TextNode})
+	}
+	next := el.n.NextSibling
+	el.d.removeChild(p, el.n)
+	addMutation(el.d, Rm, p)
+	el.d.getNode(p).base().insertNodes(ns, next)
+	// merge adjacent text nodes at the boundaries
+	for _, n := range []*html.Node{ns[0], ns[len(ns)-1]} {
+		if n.Type != html.TextNode || n.Parent != p {
+			continue
+		}
+		if prev := n.PrevSibling; prev != nil && prev.Type == html.TextNode {
+			prev.Data += n.Data
+			el.d.removeChild(p, n)
+			n = prev
+		}
+		if nx := n.NextSibling; nx != nil && nx.Type == html.TextNode {
+			n.Data += nx.Data
+			el.d.removeChild(p, nx)
+		}
+	}
+}
+
+// Append implements the ParentNode mixin
+func (df *DocumentFragment) Append(args ...js.Value) {
+	for _, n := range df.d.convertNodes(args) {
+		df.InsertBefore(df.d.getNode(n), nil)
+	}
+}
+
+// Prepend implements the ParentNode mixin
+func (df *DocumentFragment) Prepend(args ...js.Value) {
+	var ref *html.Node
+	if len(df.children) > 0 {
+		ref = df.children[0]
+	}
+	for _, n := range df.d.convertNodes(args) {
+		df.InsertBefore(df.d.getNode(n), df.d.getNode(ref))
+	}
+}
+
+// ReplaceChildren implements the ParentNode mixin
+func (df *DocumentFragment) ReplaceChildren(args ...js.Value) {
+	ns := df.d.convertNodes(args)
+	for _, c := range df.children {
+		df.d.getNode(c).base().df = nil
+	}
+	df.children = nil
+	for _, n := range ns {
+		df.InsertBefore(df.d.getNode(n), nil)
+	}
+}
+
+func (df *DocumentFragment) FirstElementChild() js.Value {
+	for _, c := range df.children {
+		if c.Type == html.ElementNode {
+			return df.d.nodeObj(c)
+		}
+	}
+	return js.Null()
+}
+
+func (df *DocumentFragment) LastElementChild() js.Value {
+	for i := len(df.children) - 1; i >= 0; i-- {
+		if c := df.children[i]; c.Type == html.ElementNode {
+			return df.d.nodeObj(c)
+		}
+	}
+	return js.Null()
+}
+
+func (df *DocumentFragment) ChildElementCount() (i int) {
+	for _, c := range df.children {
+		if c.Type == html.ElementNode {
+			i++
+		}
+	}
+	return
+}
+
+// Append implements the ParentNode mixin
+func (d *Document) Append(args ...js.Value) {
+	d.Element().Append(args...)
+}
+
+// Prepend implements the ParentNode mixin
+func (d *Document) Prepend(args ...js.Value) {
+	d.Element().Prepend(args...)
+}
+
+// ReplaceChildren implements the ParentNode mixin
+func (d *Document) ReplaceChildren(args ...js.Value) {
+	d.Element().ReplaceChildren(args...)
+}
+
+func (d *Document) FirstElementChild() js.Value {
+	return d.nodeObj(elementChild(d.doc, false))
+}
+
+func (d *Document) LastElementChild() js.Value {
+	return d.nodeObj(elementChild(d.doc, true))
+}
+
+func (d *Document) ChildElementCount() int {
+	return childElementCount(d.doc)
+}
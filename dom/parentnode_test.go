@@ -0,0 +1,73 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"testing"
+)
+
+func TestParentNode(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><body><div id="d"><b id="x"></b>t<i id="y"></i></div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var d = document.getElementById('d');
+		var x = document.getElementById('x');
+		var y = document.getElementById('y');
+		var log = [d.firstElementChild.id, d.lastElementChild.id, d.childElementCount, x.nextElementSibling.id, y.previousElementSibling.id, x.previousElementSibling];
+		d.append('a', document.createElement('u'));
+		d.prepend('p');
+		log.push(d.innerHTML);
+		var df = document.createDocumentFragment();
+		df.append('1', document.createElement('s'));
+		x.before(df, 'b');
+		x.after('c');
+		log.push(d.innerHTML, df.childNodes.length);
+		y.replaceWith('r', x);
+		log.push(d.innerHTML);
+		d.replaceChildren('z');
+		log.push(d.innerHTML, d.childNodes.length);
+		d.insertAdjacentHTML('beforeend', '<em>e</em>');
+		d.insertAdjacentText('afterbegin', 'A');
+		d.lastChild.insertAdjacentElement('beforebegin', y);
+		log.push(d.innerHTML, d.querySelector('em').closest('div').id, d.closest('span'));
+		log.push(d.toggleAttribute('hidden'), d.hasAttribute('hidden'), d.toggleAttribute('hidden', true), d.toggleAttribute('hidden'), d.getAttributeNames().join('+'));
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := `x,y,2,y,x,,p<b id="x"></b>t<i id="y"></i>a<u></u>,p1<s></s>b<b id="x"></b>ct<i id="y"></i>a<u></u>,0,p1<s></s>bctr<b id="x"></b>a<u></u>,z,1,Az<i id="y"></i><em>e</em>,d,,true,true,true,false,id`
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestInnerText(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><body><div id="d">x <b>y</b>  z<p>para</p><table><tr><td>1</td><td>2</td></tr><tr><td>3</td></tr></table>a<br>b<script>var q;</script></div><style>.n { display: none }</style></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var d = document.getElementById('d');
+		var log = [JSON.stringify(d.innerText), JSON.stringify(d.textContent)];
+		d.innerText = 'l1\nl2';
+		log.push(d.innerHTML);
+		d.firstChild.remove();
+		d.insertAdjacentHTML('afterbegin', 'a<span>s</span>');
+		d.querySelector('span').outerText = 'o';
+		log.push(d.innerHTML, d.childNodes.length);
+		d.innerHTML = 'a<p style="display:none">h</p><p hidden>h</p><span class="n">n</span><div style="visibility:hidden">v<i style="visibility:visible">i</i></div>b';
+		log.push(JSON.stringify(d.innerText), d.querySelector('p').innerText);
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := `"x y z\n\npara\n\n1\t2\n3\na\nb","x y  zpara123abvar q;",l1<br/>l2,ao<br/>l2,3,"a\ni\nb",h`
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
@@ -0,0 +1,976 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"golang.org/x/net/html"
+	"strings"
+)
+
+// Range.compareBoundaryPoints modes
+const (
+	startToStart = iota
+	startToEnd
+	endToEnd
+	endToStart
+)
+
+// liveRanges are the ranges whose boundary points are updated on tree
+// mutations
+var liveRanges []*Range
+
+var (
+	abstractRangeCtor *js.Object
+	rangeCtor         *js.Object
+	staticRangeCtor   *js.Object
+	selectionCtor     *js.Object
+)
+
+// initRanges defines the range and selection interface objects. new
+// Range() creates a range in the document d.
+func initRanges(d *Document) {
+	liveRanges = nil
+	illegal := func(call js.ConstructorCall) *js.Object {
+		panic(vm.NewTypeError("Illegal constructor"))
+	}
+	abstractRangeCtor = newCtor("AbstractRange", illegal)
+	rangeCtor = newCtor("Range", func(call js.ConstructorCall) *js.Object {
+		o := newRange(d).Obj()
+		o.SetPrototype(call.This.Prototype())
+		return o
+	})
+	staticRangeCtor = newCtor("StaticRange", func(call js.ConstructorCall) *js.Object {
+		o := newStaticRange(call.Argument(0)).Obj()
+		o.SetPrototype(call.This.Prototype())
+		return o
+	})
+	selectionCtor = newCtor("Selection", illegal)
+	ifaces := []struct {
+		name string
+		c    *js.Object
+		recv Gettable
+	}{
+		{"AbstractRange", abstractRangeCtor, nil},
+		{"Range", rangeCtor, &Range{}},
+		{"StaticRange", staticRangeCtor, &StaticRange{}},
+		{"Selection", selectionCtor, &Selection{}},
+	}
+	abstractProto := abstractRangeCtor.Get("prototype").(*js.Object)
+	for _, i := range ifaces {
+		proto := i.c.Get("prototype").(*js.Object)
+		proto.DefineDataPropertySymbol(js.SymToStringTag, vm.ToValue(i.name), js.FLAG_FALSE, js.FLAG_TRUE, js.FLAG_FALSE)
+		if i.recv == nil {
+			protoGetters(proto, "startContainer", "startOffset", "endContainer", "endOffset", "collapsed")
+			continue
+		}
+		protoMembers(proto, i.recv, nil)
+		if i.name != "Selection" {
+			proto.SetPrototype(abstractProto)
+			i.c.SetPrototype(abstractRangeCtor)
+		}
+	}
+	consts := map[string]int{
+		"START_TO_START": startToStart,
+		"START_TO_END":   startToEnd,
+		"END_TO_END":     endToEnd,
+		"END_TO_START":   endToStart,
+	}
+	for k, v := range consts {
+		rangeCtor.Set(k, v)
+		rangeCtor.Get("prototype").(*js.Object).Set(k, v)
+	}
+}
+
+// boundary is a boundary point of a range: a tree node and an offset
+// into its children or its data
+type boundary struct {
+	node   any
+	offset int
+}
+
+// abstractRange holds the boundary points shared by Range and
+// StaticRange
+type abstractRange struct {
+	start boundary
+	end   boundary
+}
+
+func (ar *abstractRange) Getters() map[string]bool {
+	return map[string]bool{
+		"startContainer": true,
+		"startOffset":    true,
+		"endContainer":   true,
+		"endOffset":      true,
+		"collapsed":      true,
+	}
+}
+
+func (ar *abstractRange) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+func (ar *abstractRange) StartContainer() js.Value {
+	return treeNodeObj(ar.start.node)
+}
+
+func (ar *abstractRange) StartOffset() int {
+	return ar.start.offset
+}
+
+func (ar *abstractRange) EndContainer() js.Value {
+	return treeNodeObj(ar.end.node)
+}
+
+func (ar *abstractRange) EndOffset() int {
+	return ar.end.offset
+}
+
+func (ar *abstractRange) Collapsed() bool {
+	return ar.start == ar.end
+}
+
+// StaticRange implements the DOM StaticRange interface. Its boundary
+// points aren't validated nor updated on mutations.
+type StaticRange struct {
+	abstractRange
+	obj *js.Object
+}
+
+func newStaticRange(init js.Value) *StaticRange {
+	o, ok := init.(*js.Object)
+	if !ok {
+		panic(vm.NewTypeError("StaticRange: parameter 1 is not an object"))
+	}
+	get := func(k string) js.Value {
+		v := o.Get(k)
+		if v == nil || js.IsUndefined(v) {
+			panic(vm.NewTypeError("StaticRange: required member " + k + " is undefined"))
+		}
+		return v
+	}
+	sr := &StaticRange{}
+	for _, b := range []struct {
+		bp   *boundary
+		node string
+		off  string
+	}{
+		{&sr.start, "startContainer", "startOffset"},
+		{&sr.end, "endContainer", "endOffset"},
+	} {
+		b.bp.node = nodeArg(get(b.node))
+		b.bp.offset = offsetArg(get(b.off))
+		if isDoctype(b.bp.node) {
+			throwDOMException("InvalidNodeTypeError", "the container is a doctype")
+		}
+	}
+	return sr
+}
+
+func (sr *StaticRange) Obj() *js.Object {
+	if sr.obj == nil {
+		sr.obj = vm.NewDynamicObject(sr)
+		sr.obj.SetPrototype(staticRangeCtor.Get("prototype").(*js.Object))
+	}
+	return sr.obj
+}
+
+func (sr *StaticRange) Get(k string) (v js.Value) {
+	if res, ok := GetCall(sr, k); ok {
+		return res
+	}
+	return nil
+}
+
+func (sr *StaticRange) Set(k string, desc js.PropertyDescriptor) bool {
+	return true
+}
+
+func (sr *StaticRange) Has(k string) bool {
+	return HasCall(sr, k)
+}
+
+func (sr *StaticRange) Delete(k string) bool {
+	return false
+}
+
+func (sr *StaticRange) Keys() []string {
+	return []string{""}
+}
+
+func (sr *StaticRange) ToString() string {
+	return "[object StaticRange]"
+}
+
+// Range implements the DOM Range interface. Ranges are live: their
+// boundary points follow mutations of the tree.
+type Range struct {
+	abstractRange
+	obj *js.Object
+}
+
+// newRange returns a live range collapsed at the start of d
+func newRange(d *Document) *Range {
+	r := &Range{
+		abstractRange: abstractRange{
+			start: boundary{d, 0},
+			end:   boundary{d, 0},
+		},
+	}
+	liveRanges = append(liveRanges, r)
+	return r
+}
+
+func (d *Document) CreateRange() *Range {
+	return newRange(d)
+}
+
+func (r *Range) Obj() *js.Object {
+	if r.obj == nil {
+		r.obj = vm.NewDynamicObject(r)
+		r.obj.SetPrototype(rangeCtor.Get("prototype").(*js.Object))
+	}
+	return r.obj
+}
+
+func (r *Range) Getters() map[string]bool {
+	gs := r.abstractRange.Getters()
+	gs["commonAncestorContainer"] = true
+	return gs
+}
+
+func (r *Range) Get(k string) (v js.Value) {
+	if res, ok := GetCall(r, k); ok {
+		return res
+	}
+	return nil
+}
+
+func (r *Range) Set(k string, desc js.PropertyDescriptor) bool {
+	return true
+}
+
+func (r *Range) Has(k string) bool {
+	return HasCall(r, k)
+}
+
+func (r *Range) Delete(k string) bool {
+	return false
+}
+
+func (r *Range) Keys() []string {
+	return []string{""}
+}
+
+// nodeArg returns the tree node behind the argument v or throws a
+// TypeError
+func nodeArg(v js.Value) any {
+	var x any
+	if v != nil {
+		x = treeNode(v.Export())
+	}
+	if x == nil {
+		panic(vm.NewTypeError("parameter is not of type 'Node'"))
+	}
+	return x
+}
+
+// offsetArg converts the argument v to an unsigned long
+func offsetArg(v js.Value) int {
+	if v == nil {
+		panic(vm.NewTypeError("not enough arguments"))
+	}
+	return int(uint32(v.ToInteger()))
+}
+
+func rangeArg(v js.Value) *Range {
+	if v != nil {
+		if r, ok := v.Export().(*Range); ok {
+			return r
+		}
+	}
+	panic(vm.NewTypeError("parameter is not of type 'Range'"))
+}
+
+func isDoctype(x any) bool {
+	nd, ok := x.(Node)
+	return ok && nd.base().n.Type == html.DoctypeNode
+}
+
+// isCharData is true for text, comment and processing instruction
+// nodes
+func isCharData(x any) bool {
+	nd, ok := x.(Node)
+	if !ok {
+		return false
+	}
+	switch nd.base().n.Type {
+	case html.TextNode, html.CommentNode, html.RawNode:
+		return true
+	}
+	return false
+}
+
+func isText(x any) bool {
+	nd, ok := x.(Node)
+	return ok && nd.base().n.Type == html.TextNode
+}
+
+// nodeLength is the number of code units of character data, 0 for
+// doctypes and the number of children otherwise
+func nodeLength(x any) (l int) {
+	switch v := x.(type) {
+	case *Document:
+		for c := v.doc.FirstChild; c != nil; c = c.NextSibling {
+			l++
+		}
+		return
+	case *DocumentFragment:
+		return len(v.children)
+	}
+	n := x.(Node).base().n
+	switch n.Type {
+	case html.TextNode, html.CommentNode, html.RawNode:
+		return utf16Len(n.Data)
+	case html.DoctypeNode:
+		return 0
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		l++
+	}
+	return
+}
+
+// treeIndex returns the number of preceding siblings of x
+func treeIndex(x any) (i int) {
+	nd, ok := x.(Node)
+	if !ok {
+		return 0
+	}
+	b := nd.base()
+	if b.n.Parent == nil && b.df != nil {
+		for j, c := range b.df.children {
+			if c == b.n {
+				return j
+			}
+		}
+	}
+	for s := b.n.PrevSibling; s != nil; s = s.PrevSibling {
+		i++
+	}
+	return
+}
+
+// childAt returns the i-th child of x or nil
+func childAt(x any, i int) any {
+	c := firstChildOf(x)
+	for ; c != nil && i > 0; i-- {
+		c = nextSiblingOf(c)
+	}
+	return c
+}
+
+func rootOf(x any) any {
+	for p := parentOf(x); p != nil; p = parentOf(x) {
+		x = p
+	}
+	return x
+}
+
+// precedes is true if a comes before b in tree order
+func precedes(a, b any) bool {
+	return compareDocumentPosition(b, a)&positionPreceding != 0
+}
+
+// comparePoints returns -1, 0 or 1 if the boundary point a is before,
+// equal to or after b. Both must have the same root.
+func comparePoints(a, b boundary) int {
+	if a.node == b.node {
+		switch {
+		case a.offset < b.offset:
+			return -1
+		case a.offset > b.offset:
+			return 1
+		}
+		return 0
+	}
+	if precedes(b.node, a.node) {
+		return -comparePoints(b, a)
+	}
+	if isInclusiveAncestor(a.node, b.node) {
+		c := b.node
+		for parentOf(c) != a.node {
+			c = parentOf(c)
+		}
+		if treeIndex(c) < a.offset {
+			return 1
+		}
+	}
+	return -1
+}
+
+// checkPoint throws if (x, offset) isn't a valid boundary point
+func checkPoint(x any, offset int) {
+	if isDoctype(x) {
+		throwDOMException("InvalidNodeTypeError", "the node is a doctype")
+	}
+	if offset < 0 || offset > nodeLength(x) {
+		throwDOMException("IndexSizeError", "offset is out of range")
+	}
+}
+
+func (r *Range) setStart(x any, offset int) {
+	checkPoint(x, offset)
+	bp := boundary{x, offset}
+	if rootOf(x) != rootOf(r.end.node) || comparePoints(bp, r.end) > 0 {
+		r.end = bp
+	}
+	r.start = bp
+}
+
+func (r *Range) setEnd(x any, offset int) {
+	checkPoint(x, offset)
+	bp := boundary{x, offset}
+	if rootOf(x) != rootOf(r.start.node) || comparePoints(bp, r.start) < 0 {
+		r.start = bp
+	}
+	r.end = bp
+}
+
+// parentArg returns the parent of the node argument v or throws an
+// InvalidNodeTypeError
+func parentArg(v js.Value) (x, p any) {
+	x = nodeArg(v)
+	if p = parentOf(x); p == nil {
+		throwDOMException("InvalidNodeTypeError", "the node has no parent")
+	}
+	return
+}
+
+func (r *Range) SetStart(v, o js.Value) {
+	r.setStart(nodeArg(v), offsetArg(o))
+}
+
+func (r *Range) SetEnd(v, o js.Value) {
+	r.setEnd(nodeArg(v), offsetArg(o))
+}
+
+func (r *Range) SetStartBefore(v js.Value) {
+	x, p := parentArg(v)
+	r.setStart(p, treeIndex(x))
+}
+
+func (r *Range) SetStartAfter(v js.Value) {
+	x, p := parentArg(v)
+	r.setStart(p, treeIndex(x)+1)
+}
+
+func (r *Range) SetEndBefore(v js.Value) {
+	x, p := parentArg(v)
+	r.setEnd(p, treeIndex(x))
+}
+
+func (r *Range) SetEndAfter(v js.Value) {
+	x, p := parentArg(v)
+	r.setEnd(p, treeIndex(x)+1)
+}
+
+func (r *Range) Collapse(toStart js.Value) {
+	if toStart != nil && toStart.ToBoolean() {
+		r.end = r.start
+	} else {
+		r.start = r.end
+	}
+}
+
+func (r *Range) selectNode(x any) {
+	p := parentOf(x)
+	if p == nil {
+		throwDOMException("InvalidNodeTypeError", "the node has no parent")
+	}
+	i := treeIndex(x)
+	r.start = boundary{p, i}
+	r.end = boundary{p, i + 1}
+}
+
+func (r *Range) SelectNode(v js.Value) {
+	r.selectNode(nodeArg(v))
+}
+
+func (r *Range) selectNodeContents(x any) {
+	if isDoctype(x) {
+		throwDOMException("InvalidNodeTypeError", "the node is a doctype")
+	}
+	r.start = boundary{x, 0}
+	r.end = boundary{x, nodeLength(x)}
+}
+
+func (r *Range) SelectNodeContents(v js.Value) {
+	r.selectNodeContents(nodeArg(v))
+}
+
+func (r *Range) CompareBoundaryPoints(how int, v js.Value) int {
+	src := rangeArg(v)
+	var a, b boundary
+	switch uint16(how) {
+	case startToStart:
+		a, b = r.start, src.start
+	case startToEnd:
+		a, b = r.end, src.start
+	case endToEnd:
+		a, b = r.end, src.end
+	case endToStart:
+		a, b = r.start, src.end
+	default:
+		throwDOMException("NotSupportedError", "invalid comparison mode")
+	}
+	if rootOf(r.start.node) != rootOf(src.start.node) {
+		throwDOMException("WrongDocumentError", "the ranges are in different trees")
+	}
+	return comparePoints(a, b)
+}
+
+func (r *Range) ComparePoint(v, o js.Value) int {
+	x, offset := nodeArg(v), offsetArg(o)
+	if rootOf(x) != rootOf(r.start.node) {
+		throwDOMException("WrongDocumentError", "the node is in a different tree")
+	}
+	checkPoint(x, offset)
+	bp := boundary{x, offset}
+	switch {
+	case comparePoints(bp, r.start) < 0:
+		return -1
+	case comparePoints(bp, r.end) > 0:
+		return 1
+	}
+	return 0
+}
+
+func (r *Range) IsPointInRange(v, o js.Value) bool {
+	x, offset := nodeArg(v), offsetArg(o)
+	if rootOf(x) != rootOf(r.start.node) {
+		return false
+	}
+	checkPoint(x, offset)
+	bp := boundary{x, offset}
+	return comparePoints(bp, r.start) >= 0 && comparePoints(bp, r.end) <= 0
+}
+
+func (r *Range) IntersectsNode(v js.Value) bool {
+	x := nodeArg(v)
+	if rootOf(x) != rootOf(r.start.node) {
+		return false
+	}
+	p := parentOf(x)
+	if p == nil {
+		return true
+	}
+	i := treeIndex(x)
+	return comparePoints(boundary{p, i}, r.end) < 0 && comparePoints(boundary{p, i + 1}, r.start) > 0
+}
+
+func (r *Range) commonAncestor() any {
+	c := r.start.node
+	for !isInclusiveAncestor(c, r.end.node) {
+		c = parentOf(c)
+	}
+	return c
+}
+
+func (r *Range) CommonAncestorContainer() js.Value {
+	return treeNodeObj(r.commonAncestor())
+}
+
+// contains is true if x is within the range and not an ancestor of
+// one of its boundary points
+func (r *Range) contains(x any) bool {
+	return comparePoints(boundary{x, 0}, r.start) > 0 &&
+		comparePoints(boundary{x, nodeLength(x)}, r.end) < 0
+}
+
+// partiallyContains is true if x is an inclusive ancestor of exactly
+// one boundary point
+func (r *Range) partiallyContains(x any) bool {
+	return isInclusiveAncestor(x, r.start.node) != isInclusiveAncestor(x, r.end.node)
+}
+
+// containedNodes returns the contained nodes in tree order. If top is
+// set, nodes whose parent is contained are omitted.
+func (r *Range) containedNodes(top bool) (xs []any) {
+	ca := r.commonAncestor()
+	for x := firstChildOf(ca); x != nil; {
+		if r.contains(x) {
+			xs = append(xs, x)
+			if top {
+				x = following(x, ca, false)
+				continue
+			}
+		} else if comparePoints(boundary{x, 0}, r.end) >= 0 {
+			break
+		}
+		x = following(x, ca, true)
+	}
+	return
+}
+
+// collapsePoint returns where the range collapses to after its
+// contents are removed
+func (r *Range) collapsePoint() boundary {
+	if isInclusiveAncestor(r.start.node, r.end.node) {
+		return r.start
+	}
+	ref := r.start.node
+	for p := parentOf(ref); p != nil && !isInclusiveAncestor(p, r.end.node); p = parentOf(ref) {
+		ref = p
+	}
+	return boundary{parentOf(ref), treeIndex(ref) + 1}
+}
+
+// substringData returns the data of x from the offset i to j
+func substringData(x any, i, j int) string {
+	s := x.(Node).base().n.Data
+	return s[utf16Index(s, i):utf16Index(s, j)]
+}
+
+// cloneData returns a shallow clone of the character data x with the
+// data from the offset i to j
+func cloneData(x any, i, j int) Node {
+	cl := x.(Node).base().CloneNode()
+	cl.base().n.Data = substringData(x, i, j)
+	return cl
+}
+
+func deleteData(x any, i, n int) {
+	x.(Node).base().replaceData(i, n, "")
+}
+
+// contents clones the contents of the range into a fragment. If
+// extract is set, they are moved instead and the range collapses.
+func (r *Range) contents(extract bool) *DocumentFragment {
+	sn, so, en, eo := r.start.node, r.start.offset, r.end.node, r.end.offset
+	frag := NewDocumentFragment(ownerOf(sn))
+	if r.start == r.end {
+		return frag
+	}
+	if sn == en && isCharData(sn) {
+		frag.InsertBefore(cloneData(sn, so, eo), nil)
+		if extract {
+			deleteData(sn, so, eo-so)
+		}
+		return frag
+	}
+	ca := r.commonAncestor()
+	var firstPC, lastPC any
+	if !isInclusiveAncestor(sn, en) {
+		for c := firstChildOf(ca); c != nil; c = nextSiblingOf(c) {
+			if r.partiallyContains(c) {
+				firstPC = c
+				break
+			}
+		}
+	}
+	if !isInclusiveAncestor(en, sn) {
+		for c := lastChildOf(ca); c != nil; c = previousSiblingOf(c) {
+			if r.partiallyContains(c) {
+				lastPC = c
+				break
+			}
+		}
+	}
+	var contained []Node
+	for c := firstChildOf(ca); c != nil; c = nextSiblingOf(c) {
+		if r.contains(c) {
+			if isDoctype(c) {
+				throwDOMException("HierarchyRequestError", "the range contains a doctype")
+			}
+			contained = append(contained, c.(Node))
+		}
+	}
+	bp := r.collapsePoint()
+	if isCharData(firstPC) {
+		l := nodeLength(sn)
+		frag.InsertBefore(cloneData(sn, so, l), nil)
+		if extract {
+			deleteData(sn, so, l-so)
+		}
+	} else if firstPC != nil {
+		cl := firstPC.(Node).base().CloneNode()
+		frag.InsertBefore(cl, nil)
+		sub := &Range{abstractRange: abstractRange{
+			start: boundary{sn, so},
+			end:   boundary{firstPC, nodeLength(firstPC)},
+		}}
+		cl.base().AppendChild(sub.contents(extract))
+	}
+	for _, c := range contained {
+		if !extract {
+			c = c.base().CloneNode(true)
+		}
+		frag.InsertBefore(c, nil)
+	}
+	if isCharData(lastPC) {
+		frag.InsertBefore(cloneData(en, 0, eo), nil)
+		if extract {
+			deleteData(en, 0, eo)
+		}
+	} else if lastPC != nil {
+		cl := lastPC.(Node).base().CloneNode()
+		frag.InsertBefore(cl, nil)
+		sub := &Range{abstractRange: abstractRange{
+			start: boundary{lastPC, 0},
+			end:   boundary{en, eo},
+		}}
+		cl.base().AppendChild(sub.contents(extract))
+	}
+	if extract {
+		r.start, r.end = bp, bp
+	}
+	return frag
+}
+
+func (r *Range) CloneContents() *DocumentFragment {
+	return r.contents(false)
+}
+
+func (r *Range) ExtractContents() *DocumentFragment {
+	return r.contents(true)
+}
+
+func (r *Range) DeleteContents() {
+	if r.Collapsed() {
+		return
+	}
+	sn, so, en, eo := r.start.node, r.start.offset, r.end.node, r.end.offset
+	if sn == en && isCharData(sn) {
+		deleteData(sn, so, eo-so)
+		return
+	}
+	rm := r.containedNodes(true)
+	bp := r.collapsePoint()
+	if isCharData(sn) {
+		deleteData(sn, so, nodeLength(sn)-so)
+	}
+	for _, x := range rm {
+		ownerOf(x).detach(x.(Node).base().n)
+	}
+	if isCharData(en) {
+		deleteData(en, 0, eo)
+	}
+	r.start, r.end = bp, bp
+}
+
+// insertInto inserts x into the tree node p before ref (nil to append)
+func insertInto(p, x, ref any) {
+	switch v := p.(type) {
+	case *Document:
+		v.InsertBefore(x, ref)
+	case *DocumentFragment:
+		v.InsertBefore(x, ref)
+	case Node:
+		v.base().InsertBefore(x, ref)
+	}
+}
+
+func (r *Range) insertNode(x any) {
+	sn, so := r.start.node, r.start.offset
+	if t := treeNodeType(sn); t == 7 || t == 8 || t == 3 && parentOf(sn) == nil || sn == x {
+		throwDOMException("HierarchyRequestError", "the node can't be inserted at the start of the range")
+	}
+	var ref any
+	if isText(sn) {
+		ref = sn
+	} else {
+		ref = childAt(sn, so)
+	}
+	p := sn
+	if ref != nil {
+		p = parentOf(ref)
+	}
+	if _, ok := x.(*Document); ok || isInclusiveAncestor(x, p) {
+		throwDOMException("HierarchyRequestError", "the node can't be inserted")
+	}
+	var ns []*html.Node
+	if df, ok := x.(*DocumentFragment); ok {
+		ns = df.children
+	} else {
+		ns = []*html.Node{x.(Node).base().n}
+	}
+	var refn *html.Node
+	if rn, ok := ref.(Node); ok {
+		refn = rn.base().n
+	}
+	switch v := p.(type) {
+	case *Document:
+		if t := treeNodeType(x); parentOf(x) == p && (t == 1 || t == 10) {
+			throwDOMException("HierarchyRequestError", "the document already has the node")
+		}
+		v.Element().checkInsert(ns, refn, nil)
+	case *DocumentFragment:
+		v.checkInsert(ns)
+	case Node:
+		v.base().checkInsert(ns, refn, nil)
+	}
+	if isText(sn) {
+		ref = sn.(*Text).SplitText(so)
+	}
+	if x == ref {
+		ref = nextSiblingOf(ref)
+	}
+	if nd, ok := x.(Node); ok {
+		ownerOf(x).detach(nd.base().n)
+	}
+	offset := nodeLength(p)
+	if ref != nil {
+		offset = treeIndex(ref)
+	}
+	if _, ok := x.(*DocumentFragment); ok {
+		offset += nodeLength(x)
+	} else {
+		offset++
+	}
+	collapsed := r.Collapsed()
+	insertInto(p, x, ref)
+	if collapsed {
+		r.end = boundary{p, offset}
+	}
+}
+
+func (r *Range) InsertNode(v js.Value) {
+	r.insertNode(nodeArg(v))
+}
+
+func (r *Range) SurroundContents(v js.Value) {
+	x := nodeArg(v)
+	for _, bp := range []any{r.start.node, r.end.node} {
+		for c := bp; c != nil; c = parentOf(c) {
+			if !isText(c) && r.partiallyContains(c) {
+				throwDOMException("InvalidStateError", "the range partially contains a non-text node")
+			}
+		}
+	}
+	switch treeNodeType(x) {
+	case 9, 10, 11:
+		throwDOMException("InvalidNodeTypeError", "the node can't surround the range")
+	}
+	frag := r.ExtractContents()
+	nd := x.(Node)
+	for c := nd.base().n.FirstChild; c != nil; c = nd.base().n.FirstChild {
+		nd.base().d.removeChild(nd.base().n, c)
+	}
+	r.insertNode(x)
+	nd.base().AppendChild(frag)
+	r.selectNode(x)
+}
+
+func (r *Range) CloneRange() *Range {
+	cl := newRange(ownerOf(r.start.node))
+	cl.start, cl.end = r.start, r.end
+	return cl
+}
+
+// Detach does nothing
+func (r *Range) Detach() {}
+
+// ToString concatenates the data of the text within the range
+func (r *Range) ToString() string {
+	sn, so, en, eo := r.start.node, r.start.offset, r.end.node, r.end.offset
+	if sn == en && isText(sn) {
+		return substringData(sn, so, eo)
+	}
+	var b strings.Builder
+	if isText(sn) {
+		b.WriteString(substringData(sn, so, nodeLength(sn)))
+	}
+	for _, x := range r.containedNodes(false) {
+		if isText(x) {
+			b.WriteString(x.(Node).base().n.Data)
+		}
+	}
+	if isText(en) {
+		b.WriteString(substringData(en, 0, eo))
+	}
+	return b.String()
+}
+
+// boundaries returns pointers to the start and end of r
+func (r *Range) boundaries() []*boundary {
+	return []*boundary{&r.start, &r.end}
+}
+
+// rangesInsert updates the live ranges for the insertion of n nodes
+// before ref
+func rangesInsert(ref any, n int) {
+	p, i := parentOf(ref), treeIndex(ref)
+	for _, r := range liveRanges {
+		for _, bp := range r.boundaries() {
+			if bp.node == p && bp.offset > i {
+				bp.offset += n
+			}
+		}
+	}
+}
+
+// rangesRemove updates the live ranges before x is removed from its
+// parent
+func rangesRemove(x any) {
+	p := parentOf(x)
+	if p == nil {
+		return
+	}
+	i := treeIndex(x)
+	for _, r := range liveRanges {
+		for _, bp := range r.boundaries() {
+			if isInclusiveAncestor(x, bp.node) {
+				*bp = boundary{p, i}
+			} else if bp.node == p && bp.offset > i {
+				bp.offset--
+			}
+		}
+	}
+}
+
+// rangesReplaceData updates the live ranges after count code units of
+// the data of x at offset are replaced by n others
+func rangesReplaceData(x any, offset, count, n int) {
+	for _, r := range liveRanges {
+		for _, bp := range r.boundaries() {
+			if bp.node != x {
+				continue
+			}
+			if bp.offset > offset+count {
+				bp.offset += n - count
+			} else if bp.offset > offset {
+				bp.offset = offset
+			}
+		}
+	}
+}
+
+// rangesSplitText updates the live ranges after the text x is split
+// at offset and the remainder nx is inserted after it
+func rangesSplitText(x, nx any, offset int) {
+	p, i := parentOf(x), treeIndex(x)
+	for _, r := range liveRanges {
+		for _, bp := range r.boundaries() {
+			if bp.node == x && bp.offset > offset {
+				*bp = boundary{nx, bp.offset - offset}
+			} else if bp.node == p && bp.offset == i+1 {
+				bp.offset++
+			}
+		}
+	}
+}
+
+// rangesMerge updates the live ranges before the data of the text nx
+// is appended to its previous sibling x
+func rangesMerge(x, nx any) {
+	l := nodeLength(x)
+	p, i := parentOf(nx), treeIndex(nx)
+	for _, r := range liveRanges {
+		for _, bp := range r.boundaries() {
+			if bp.node == nx {
+				*bp = boundary{x, bp.offset + l}
+			} else if bp.node == p && bp.offset == i {
+				*bp = boundary{x, l}
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"testing"
+)
+
+func TestRange(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><body><div id="r"><p id="a">hello <b>big</b> world</p><p id="c">end</p></div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var a = document.getElementById('a');
+		var r = document.createRange();
+		var log = [r instanceof Range, r.startContainer === document, r.collapsed];
+		r.setStart(a.firstChild, 2);
+		r.setEnd(a.lastChild, 3);
+		log.push(r.toString(), r.commonAncestorContainer.id, r.collapsed);
+		var f = r.cloneContents();
+		log.push(f.childNodes.length, f.textContent, a.textContent);
+		a.firstChild.insertData(0, 'oh ');
+		log.push(r.startOffset, r.toString());
+		f = r.extractContents();
+		log.push(f.textContent, a.textContent, r.collapsed, r.startContainer.id, r.startOffset);
+		r.insertNode(document.createElement('i'));
+		log.push(a.innerHTML, r.endOffset);
+		r.selectNodeContents(document.getElementById('c'));
+		var s = document.createElement('u');
+		r.surroundContents(s);
+		log.push(document.getElementById('c').innerHTML, r.startOffset, r.endOffset);
+		s.remove();
+		log.push(r.startContainer.id, r.startOffset, r.endOffset);
+		try { r.setStart(a, 9) } catch (e) { log.push(e.name) }
+		var sr = new StaticRange({startContainer: a, startOffset: 9, endContainer: a, endOffset: 0});
+		log.push(sr.startOffset, sr.collapsed);
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := `true,true,true,llo big wo,a,false,3,llo big wo,hello big world,5,llo big wo,llo big wo,oh herld,true,a,1,oh he<i></i>rld,2,<u>end</u>,0,1,c,0,0,IndexSizeError,9,false`
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestSelection(t *testing.T) {
+	vm := js.New()
+	d, err := Init(vm, "https://example.com", `<html><body><p id="a">one two</p><div id="e" contenteditable>edit</div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var s = getSelection();
+		var a = document.getElementById('a');
+		var log = [s === document.getSelection(), s.type, s.rangeCount];
+		s.setBaseAndExtent(a.firstChild, 7, a.firstChild, 4);
+		log.push(s.type, s.direction, s.anchorOffset, s.focusOffset, s.toString());
+		a.firstChild.data = 'x';
+		log.push(s.anchorOffset, s.isCollapsed);
+		s.collapse(document.getElementById('e').firstChild, 2);
+		log.push(document.activeElement.id);
+		s.removeAllRanges();
+		document.getElementById('e').blur();
+		document.getElementById('e').focus();
+		log.push(s.rangeCount, s.anchorNode.id);
+		s.selectAllChildren(a);
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := `true,None,0,Range,backward,7,4,two,0,true,e,1,e`
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+	if s := SelectionString(d); s != "x" {
+		t.Fatalf("%v", s)
+	}
+}
@@ -96,18 +96,36 @@ func GetCall(recv Gettable, k string) (res js.Value, ok bool) {
 			c.getter = true
 			return vm.ToValue(res[0].Interface()), true
 		} else {
-			return vm.ToValue(func(args ...any) js.Value {
+			return vm.ToValue(func(call js.FunctionCall) js.Value {
 				mt := m.Type
-				as := make([]reflect.Value, 0, len(args)+1)
+				as := make([]reflect.Value, 0, len(call.Arguments)+1)
 				as = append(as, hcr)
-				for i, a := range args {
-					rv, err := reflectVal(mt.In(i), a)
+				for i := range call.Arguments {
+					if argType(mt, i) == jsValueType {
+						// passed unconverted e.g. to tell undefined
+						// from null
+						as = append(as, reflect.ValueOf(&call.Arguments[i]).Elem())
+						continue
+					}
+					// export each argument so arrays aren't spread into
+					// variadic parameters
+					a := call.Arguments[i].Export()
+					rv, err := reflectVal(argType(mt, i), a)
 					if err != nil {
 						log.Errorf("get call: reflect val %v: %v", a, err)
 						return js.Undefined()
 					}
 					as = append(as, rv)
 				}
+				n := mt.NumIn()
+				if mt.IsVariadic() {
+					n--
+				} else if len(as) > n {
+					as = as[:n]
+				}
+				for len(as) < n {
+					as = append(as, reflect.Zero(mt.In(len(as))))
+				}
 				res := m.Func.Call(as)
 				if len(res) == 0 {
 					return vm.ToValue(nil)
@@ -128,19 +146,44 @@ func reflectVal(typ reflect.Type, a any) (rv reflect.Value, err error) {
 	var aa any
 	switch v := a.(type) {
 	case int64:
-		aa = int(v)
-	case *DocumentFragment, *Element, *Event, *MouseEvent, string, bool, func(js.FunctionCall) js.Value, map[string]any, []any:
+		if typ.Kind() == reflect.Float64 {
+			aa = float64(v)
+		} else {
+			aa = int(v)
+		}
+	case float64:
+		if typ.Kind() == reflect.Int {
+			aa = int(v)
+		} else {
+			aa = v
+		}
+	case eventer, Node:
+		aa = a
+	case *Attr, *Document, *DocumentFragment, *Element, *Window, string, bool, func(js.FunctionCall) js.Value, map[string]any, []any:
 		aa = a
 	default:
 		if v != nil {
 			return rv, fmt.Errorf("unhandled arg type %T (%v)", v, v)
 		} else {
-			aa = reflect.New(typ).Elem()
+			return reflect.New(typ).Elem(), nil
 		}
 	}
 	return reflect.ValueOf(aa), nil
 }
 
+// argType of the i-th argument of method type mt (whose first
+// parameter is the receiver)
+func argType(mt reflect.Type, i int) reflect.Type {
+	j := i + 1
+	if mt.IsVariadic() && j >= mt.NumIn()-1 {
+		return mt.In(mt.NumIn() - 1).Elem()
+	}
+	if j >= mt.NumIn() {
+		return reflect.TypeOf((*any)(nil)).Elem()
+	}
+	return mt.In(j)
+}
+
 func jsVal(v any) (vv js.Value, err error) {
 	switch rv := v.(type) {
 	case *Element:
@@ -154,6 +197,8 @@ func jsVal(v any) (vv js.Value, err error) {
 			objs = append(objs, el.Obj())
 		}
 		return vm.ToValue(objs), nil
+	case Node:
+		return rv.Obj(), nil
 	case *Document:
 		if rv == nil {
 			break
@@ -174,22 +219,63 @@ func jsVal(v any) (vv js.Value, err error) {
 			break
 		}
 		return rv.Obj(), nil
-	case *Event:
+	case *DOMRectList:
 		if rv == nil {
 			break
 		}
 		return rv.Obj(), nil
+	case eventer:
+		if e := rv.event(); e != nil {
+			return e.Obj(), nil
+		}
 	case *HTMLCollection:
 		if rv == nil {
 			break
 		}
 		return rv.Obj(), nil
+	case *NodeList:
+		if rv == nil {
+			break
+		}
+		return rv.Obj(), nil
+	case *TreeWalker:
+		if rv == nil {
+			break
+		}
+		return rv.Obj(), nil
+	case *NodeIterator:
+		if rv == nil {
+			break
+		}
+		return rv.Obj(), nil
+	case *Range:
+		if rv == nil {
+			break
+		}
+		return rv.Obj(), nil
+	case *StaticRange:
+		if rv == nil {
+			break
+		}
+		return rv.Obj(), nil
+	case *Selection:
+		if rv == nil {
+			break
+		}
+		return rv.Obj(), nil
+	case *CSSStyleSheet:
+		if rv == nil {
+			break
+		}
+		return rv.Obj(), nil
+	case cssRule:
+		return rv.Obj(), nil
 	case *NamedNodeMap:
 		if rv == nil {
 			break
 		}
 		return vm.NewDynamicObject(rv), nil
-	case bool, string:
+	case bool, string, int, float64:
 		return vm.ToValue(rv), nil
 	case js.Value:
 		return rv, nil
@@ -237,3 +323,68 @@ func untitle(s string) string {
 	s = strings.ToLower(s[0:1]) + s[1:]
 	return s
 }
+
+// protoMethods defines the methods names on the prototype proto. They
+// are called through GetCall on the Go value behind this, so instances
+// share them and they can be shadowed by expandos.
+func protoMethods(proto *js.Object, names ...string) {
+	for _, k := range names {
+		k := k
+		proto.Set(k, func(call js.FunctionCall) js.Value {
+			recv := protoRecv(call.This)
+			res, ok := GetCall(recv, k)
+			f, isFunc := js.AssertFunction(res)
+			if !ok || !isFunc {
+				panic(vm.NewTypeError("Illegal invocation"))
+			}
+			v, err := f(call.This, call.Arguments...)
+			if err != nil {
+				panic(err)
+			}
+			return v
+		})
+	}
+}
+
+// protoGetters defines the getters names on the prototype proto like
+// protoMethods does for methods. Setters are forwarded to the Set
+// method of the Go value behind this.
+func protoGetters(proto *js.Object, names ...string) {
+	for _, k := range names {
+		k := k
+		getter := vm.ToValue(func(call js.FunctionCall) js.Value {
+			recv := protoRecv(call.This)
+			res, ok := GetCall(recv, k)
+			if !ok {
+				panic(vm.NewTypeError("Illegal invocation"))
+			}
+			return res
+		})
+		setter := vm.ToValue(func(call js.FunctionCall) js.Value {
+			if do, ok := protoRecv(call.This).(js.DynamicObject); ok {
+				do.Set(k, js.PropertyDescriptor{Value: call.Argument(0)})
+			}
+			return js.Undefined()
+		})
+		proto.DefineAccessorProperty(k, getter, setter, js.FLAG_TRUE, js.FLAG_TRUE)
+	}
+}
+
+var gettableType = reflect.TypeOf((*Gettable)(nil)).Elem()
+
+var jsValueType = reflect.TypeOf((*js.Value)(nil)).Elem()
+
+var proxyType = reflect.TypeOf(js.Proxy{})
+
+// protoRecv returns the Go value behind this or throws a TypeError.
+// Only dynamic objects are exported to avoid converting arbitrary
+// objects.
+func protoRecv(this js.Value) Gettable {
+	if this.ExportType() == proxyType {
+		this = this.Export().(js.Proxy).Target()
+	}
+	if t := this.ExportType(); t == nil || !t.Implements(gettableType) {
+		panic(vm.NewTypeError("Illegal invocation"))
+	}
+	return this.Export().(Gettable)
+}
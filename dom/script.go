@@ -0,0 +1,264 @@
+package dom
+
+import (
+	"fmt"
+	"github.com/psilva261/sparkle/js"
+	"github.com/psilva261/sparklefs/logger"
+	"golang.org/x/net/html"
+	"regexp"
+	"strings"
+)
+
+// ExecScript runs the source of a script inserted into the document.
+// The VM runs it directly if nil.
+var ExecScript func(src string) error
+
+// scriptFlags are the state of a script element
+type scriptFlags struct {
+	// alreadyStarted scripts have been executed and must not run again
+	alreadyStarted bool
+
+	// asyncCleared is set when the force async flag was cleared by the
+	// parser, by setting the async IDL attribute or by document.write
+	asyncCleared bool
+}
+
+// jsMIMETypes are the type attribute values of classic scripts
+var jsMIMETypes = map[string]bool{
+	"application/ecmascript":   true,
+	"application/javascript":   true,
+	"application/x-ecmascript": true,
+	"application/x-javascript": true,
+	"text/ecmascript":          true,
+	"text/javascript":          true,
+	"text/javascript1.0":       true,
+	"text/javascript1.1":       true,
+	"text/javascript1.2":       true,
+	"text/javascript1.3":       true,
+	"text/javascript1.4":       true,
+	"text/javascript1.5":       true,
+	"text/jscript":             true,
+	"text/livescript":          true,
+	"text/x-ecmascript":        true,
+	"text/x-javascript":        true,
+}
+
+var reModuleSyntax = regexp.MustCompile(`(?m)^\s*(import\s*[\w{*'"]|export\s)`)
+
+// pendingScript is an external script waiting for its source
+type pendingScript struct {
+	n      *html.Node
+	module bool
+	src    string
+	ready  bool
+	failed bool
+}
+
+// script returns the flags of the script element n
+func (d *Document) script(n *html.Node) *scriptFlags {
+	s, ok := d.scripts[n]
+	if !ok {
+		s = &scriptFlags{}
+		d.scripts[n] = s
+	}
+	return s
+}
+
+// markStarted sets the already started flag of the scripts in the
+// subtree of n. Scripts created by the parser are run by the host or
+// never and aren't force async.
+func (d *Document) markStarted(n *html.Node) {
+	if n.Type == html.ElementNode && n.Data == "script" {
+		s := d.script(n)
+		s.alreadyStarted, s.asyncCleared = true, true
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		d.markStarted(c)
+	}
+}
+
+// childText is the concatenation of the text children of n
+func childText(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+	}
+	return b.String()
+}
+
+// scriptType returns whether n is a module or a classic script. ok is
+// false for data blocks like JSON or templates.
+func scriptType(n *html.Node) (module, ok bool) {
+	t, hasType := attrOk(*n, "type")
+	lang, hasLang := attrOk(*n, "language")
+	switch {
+	case hasType && t == "", !hasType && (!hasLang || lang == ""):
+		return false, true
+	case !hasType:
+		t = "text/" + lang
+	}
+	t = strings.ToLower(strings.TrimSpace(t))
+	if t == "module" {
+		return true, true
+	}
+	return false, jsMIMETypes[t]
+}
+
+// isAsync is true unless the script is ordered by clearing the force
+// async flag without an async attribute
+func (d *Document) isAsync(n *html.Node) bool {
+	return hasAttr(*n, "async") || !d.script(n).asyncCleared
+}
+
+// prepareScripts prepares the scripts in the subtree of n in tree order
+// after it was inserted
+func (d *Document) prepareScripts(n *html.Node) {
+	if n.Type == html.TextNode && n.Parent != nil && n.Parent.Type == html.ElementNode && n.Parent.Data == "script" {
+		d.prepareScript(n.Parent)
+		return
+	}
+	if !connected(n) {
+		return
+	}
+	var ss []*html.Node
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "script" {
+			ss = append(ss, n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+	}
+	f(n)
+	for _, s := range ss {
+		d.prepareScript(s)
+	}
+}
+
+// prepareScript starts a connected script unless already started.
+// Inline scripts run immediately, external ones when fetched, either
+// in insertion order or as soon as possible if async.
+func (d *Document) prepareScript(n *html.Node) {
+	if d.script(n).alreadyStarted || !connected(n) || d.Window == nil {
+		return
+	}
+	src, hasSrc := attrOk(*n, "src")
+	if !hasSrc && childText(n) == "" {
+		return
+	}
+	module, ok := scriptType(n)
+	if !ok {
+		return
+	}
+	d.script(n).alreadyStarted = true
+	if !module && hasAttr(*n, "nomodule") {
+		return
+	}
+	ps := &pendingScript{n: n, module: module}
+	if !hasSrc {
+		ps.src, ps.ready = childText(n), true
+		d.executeScript(ps, false)
+		return
+	}
+	ordered := !d.isAsync(n)
+	if ordered {
+		d.orderedScripts = append(d.orderedScripts, ps)
+	}
+	done := func() {
+		if ordered {
+			d.runOrderedScripts()
+		} else {
+			d.executeScript(ps, true)
+		}
+	}
+	if strings.TrimSpace(src) == "" || XHR == nil {
+		log.Errorf("script: can't fetch '%v'", src)
+		ps.ready, ps.failed = true, true
+		done()
+		return
+	}
+	src = d.resolveURL(src)
+	XHR("GET", src, make(map[string]string), "", func(data, err string, status int) {
+		if err == "" && !statusOk(status) {
+			err = fmt.Sprintf("status %v", status)
+		}
+		if err != "" {
+			log.Errorf("fetch script %v: %v", src, err)
+			ps.failed = true
+		}
+		ps.src, ps.ready = data, true
+		done()
+	})
+}
+
+// runOrderedScripts executes the fetched scripts at the front of the
+// ordered list
+func (d *Document) runOrderedScripts() {
+	for len(d.orderedScripts) > 0 && d.orderedScripts[0].ready {
+		ps := d.orderedScripts[0]
+		d.orderedScripts = d.orderedScripts[1:]
+		d.executeScript(ps, true)
+	}
+}
+
+// executeScript runs ps with document.currentScript set to classic
+// scripts. load or error is fired at external scripts.
+func (d *Document) executeScript(ps *pendingScript, external bool) {
+	el := d.getEl(ps.n)
+	src := ps.src
+	if !ps.failed && ps.module {
+		if reModuleSyntax.MatchString(src) {
+			log.Errorf("script: import and export are not supported")
+			ps.failed = true
+		}
+		// module scope in strict mode
+		src = "(function() {'use strict';\n" + src + "\n}).call(undefined);"
+	}
+	if ps.failed {
+		el.DispatchEvent(newEvent("Event", "error", nil))
+		return
+	}
+	prev := d.currentScript
+	d.currentScript = nil
+	if !ps.module {
+		d.currentScript = el
+	}
+	var err error
+	if ExecScript != nil {
+		err = ExecScript(src)
+	} else {
+		_, err = vm.RunString(src)
+	}
+	d.currentScript = prev
+	if err != nil {
+		log.Errorf("execute script: %v", err)
+	}
+	if external {
+		el.DispatchEvent(newEvent("Event", "load", nil))
+	}
+}
+
+func (d *Document) CurrentScript() js.Value {
+	if d.currentScript == nil {
+		return js.Null()
+	}
+	return d.currentScript.Obj()
+}
+
+// scriptAsync is the async IDL attribute of a script
+func (el *Element) scriptAsync() bool {
+	return el.d.isAsync(el.n)
+}
+
+// scriptSet runs the steps after setting key of a script element
+func (el *Element) scriptSet(key string) {
+	switch key {
+	case "async":
+		el.d.script(el.n).asyncCleared = true
+	case "src", "text", "textContent", "innerText", "innerHTML":
+		el.d.prepareScript(el.n)
+	}
+}
@@ -0,0 +1,123 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"testing"
+)
+
+func TestScripts(t *testing.T) {
+	var fetches []func()
+	XHR = func(method, uri string, h map[string]string, data string, cb func(data, err string, status int)) {
+		src := map[string]string{
+			"https://example.com/a.js": "log.push('a', document.currentScript.id)",
+			"https://example.com/b.js": "log.push('b')",
+			"https://example.com/c.js": "log.push('c')",
+		}
+		fetches = append(fetches, func() {
+			if s, ok := src[uri]; ok {
+				cb(s, "", 200)
+			} else {
+				cb("log.push('404')", "", 404)
+			}
+		})
+	}
+	defer func() { XHR = nil }()
+	vm := js.New()
+	_, err := Init(vm, "https://example.com/", `<html><body><script>var parsed = true</script></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var log = [typeof parsed, document.currentScript];
+		function add(src, async) {
+			var s = document.createElement('script');
+			log.push(s.async);
+			if (!async) s.async = false;
+			s.src = src;
+			s.id = src;
+			s.onload = function() { log.push('load ' + src) };
+			s.onerror = function() { log.push('error ' + src) };
+			document.body.appendChild(s);
+			return s;
+		}
+		add('/a.js', false);
+		add('/x.js', false);
+		add('/b.js', false);
+		add('/c.js', true);
+		var i = document.createElement('script');
+		i.id = 'inline';
+		i.text = 'log.push(document.currentScript.id)';
+		document.body.appendChild(i);
+		var j = document.createElement('script');
+		j.type = 'application/json';
+		j.text = 'log.push("json")';
+		document.body.appendChild(j);
+		var n = document.createElement('script');
+		n.noModule = true;
+		n.setAttribute('nomodule', '');
+		n.text = 'log.push("nomodule")';
+		document.body.appendChild(n);
+		var m = document.createElement('script');
+		m.type = 'module';
+		m.text = 'log.push(this === undefined, document.currentScript)';
+		document.body.appendChild(m);
+		document.body.innerHTML += '<script>log.push("innerHTML")</script>';
+		log.join('|');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != `undefined||true|true|true|true|inline|true|` {
+		t.Fatalf("%v", v)
+	}
+	// deliver the responses in reverse
+	for i := len(fetches) - 1; i >= 0; i-- {
+		fetches[i]()
+	}
+	res, err = vm.RunString(`log.slice(9).join('|')`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != `c|load /c.js|a|/a.js|load /a.js|error /x.js|b|load /b.js` {
+		t.Fatalf("%v", v)
+	}
+	res, err = vm.RunString(`
+		var p = document.querySelector('script');
+		[p.async, document.body.lastChild.async, p.cloneNode().async, document.createElement('script').async].join('|');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != `false|false|true|true` {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestScriptURL(t *testing.T) {
+	var uris []string
+	XHR = func(method, uri string, h map[string]string, data string, cb func(data, err string, status int)) {
+		uris = append(uris, uri)
+		cb("log.push('"+uri+"')", "", 200)
+	}
+	defer func() { XHR = nil }()
+	vm := js.New()
+	_, err := Init(vm, "https://example.com/docs/page.html", `<html><body></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var log = [];
+		['js/a.js', '../b.js', '/c.js'].forEach(function(src) {
+			var s = document.createElement('script');
+			s.src = src;
+			document.body.appendChild(s);
+		});
+		log.join('|');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != `https://example.com/docs/js/a.js|https://example.com/b.js|https://example.com/c.js` {
+		t.Fatalf("%v", v)
+	}
+}
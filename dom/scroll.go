@@ -0,0 +1,269 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"golang.org/x/net/html"
+	"math"
+)
+
+// documentElement is the root element that scrolls the viewport
+func (d *Document) documentElement() *Element {
+	for c := d.doc.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			return d.getEl(c)
+		}
+	}
+	return nil
+}
+
+// SetViewport changes the size and device pixel ratio of the viewport.
+// A resize event is fired in the next animation frame if the size
+// changed.
+func (w *Window) SetViewport(width, height int, dpr float64) {
+	if dpr > 0 {
+		DevicePixelRatio = dpr
+	}
+	if width == ViewportWidth && height == ViewportHeight {
+		return
+	}
+	ViewportWidth, ViewportHeight = width, height
+	resetGeom()
+	w.Document.pendingResize = true
+	if de := w.Document.documentElement(); de != nil {
+		x, y := de.scrollOffset()
+		de.scrollTo(x, y)
+	}
+}
+
+// ScrollTo scrolls the viewport like the scrollbar of the browser
+func (w *Window) ScrollTo(x, y float64) {
+	if de := w.Document.documentElement(); de != nil {
+		de.scrollTo(x, y)
+	}
+}
+
+func (w *Window) scrollOffset() (x, y float64) {
+	if de := w.Document.documentElement(); de != nil {
+		return de.scrollOffset()
+	}
+	return
+}
+
+// screen has the size of the viewport
+func (w *Window) screen() *js.Object {
+	o := vm.NewObject()
+	o.Set("width", ViewportWidth)
+	o.Set("height", ViewportHeight)
+	o.Set("availWidth", ViewportWidth)
+	o.Set("availHeight", ViewportHeight)
+	o.Set("colorDepth", 24)
+	o.Set("pixelDepth", 24)
+	return o
+}
+
+// runResizeAndScrollSteps fires the pending resize and scroll events of
+// an animation frame
+func (d *Document) runResizeAndScrollSteps() (ok bool) {
+	if d.pendingResize {
+		d.pendingResize = false
+		d.Window.dispatchEvent(newEvent("Event", "resize", nil))
+		ok = true
+	}
+	ns := d.pendingScroll
+	d.pendingScroll = nil
+	for _, n := range ns {
+		if n.Parent != nil && n.Parent.Type == html.DocumentNode {
+			d.DispatchEvent(newEvent("Event", "scroll", map[string]any{"bubbles": true}))
+		} else {
+			d.getEl(n).DispatchEvent(newEvent("Event", "scroll", nil))
+		}
+		ok = true
+	}
+	return
+}
+
+func (el *Element) scrollOffset() (x, y float64) {
+	o := el.d.scrollOffsets[el.n]
+	return o[0], o[1]
+}
+
+// scrollable is true for the viewport and elements whose overflow is
+// not visible
+func (el *Element) scrollable() bool {
+	if el.isViewportElement() {
+		return true
+	}
+	cs := &ComputedStyle{el: el}
+	for _, p := range []string{"overflow-x", "overflow-y"} {
+		if v := cs.GetPropertyValue(p); v != "visible" && v != "clip" {
+			return true
+		}
+	}
+	return false
+}
+
+// scrollTo sets the scroll offset of el clamped to its scrollable
+// overflow and queues a scroll event if it changed
+func (el *Element) scrollTo(x, y float64) {
+	if !el.scrollable() {
+		return
+	}
+	sw, sh := el.scrollSize()
+	cw, ch := el.clientSize()
+	clamp := func(v, max float64) float64 {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			v = 0
+		}
+		v = math.Round(v*DevicePixelRatio) / DevicePixelRatio
+		max = math.Floor(max*DevicePixelRatio) / DevicePixelRatio
+		return math.Max(0, math.Min(v, math.Max(max, 0)))
+	}
+	x, y = clamp(x, sw-cw), clamp(y, sh-ch)
+	if ox, oy := el.scrollOffset(); x == ox && y == oy {
+		return
+	}
+	if el.d.scrollOffsets == nil {
+		el.d.scrollOffsets = make(map[*html.Node][2]float64)
+	}
+	el.d.scrollOffsets[el.n] = [2]float64{x, y}
+	for _, n := range el.d.pendingScroll {
+		if n == el.n {
+			return
+		}
+	}
+	el.d.pendingScroll = append(el.d.pendingScroll, el.n)
+}
+
+// clientBox is the border box of el relative to the viewport, i.e.
+// shifted by the scroll offsets of its ancestors
+func (el *Element) clientBox() (b box) {
+	b = el.box()
+	if !b.ok {
+		return
+	}
+	fixed := false
+	for n := el.n; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		e := el.d.getEl(n)
+		if n != el.n || e.isViewportElement() {
+			if e.isViewportElement() && fixed {
+				break
+			}
+			x, y := e.scrollOffset()
+			b.x1, b.x2 = b.x1-x, b.x2-x
+			b.y1, b.y2 = b.y1-y, b.y2-y
+		}
+		if (&ComputedStyle{el: e}).GetPropertyValue("position") == "fixed" {
+			fixed = true
+		}
+	}
+	return
+}
+
+// scrollArgs returns the offsets of scrollTo(x, y) or scrollTo({left,
+// top}) where x and y are kept if missing
+func scrollArgs(args []js.Value, x, y float64) (float64, float64) {
+	if len(args) == 0 {
+		return x, y
+	}
+	if o, ok := args[0].(*js.Object); ok && len(args) == 1 {
+		if v := o.Get("left"); v != nil && !js.IsUndefined(v) {
+			x = v.ToFloat()
+		}
+		if v := o.Get("top"); v != nil && !js.IsUndefined(v) {
+			y = v.ToFloat()
+		}
+		return x, y
+	}
+	x = args[0].ToFloat()
+	if len(args) > 1 {
+		y = args[1].ToFloat()
+	} else {
+		y = 0
+	}
+	return x, y
+}
+
+func (el *Element) ScrollTop() float64 {
+	_, y := el.scrollOffset()
+	return y
+}
+
+func (el *Element) ScrollLeft() float64 {
+	x, _ := el.scrollOffset()
+	return x
+}
+
+func (el *Element) ScrollTo(args ...js.Value) {
+	x, y := el.scrollOffset()
+	el.scrollTo(scrollArgs(args, x, y))
+}
+
+func (el *Element) Scroll(args ...js.Value) {
+	el.ScrollTo(args...)
+}
+
+func (el *Element) ScrollBy(args ...js.Value) {
+	x, y := el.scrollOffset()
+	dx, dy := scrollArgs(args, 0, 0)
+	el.scrollTo(x+dx, y+dy)
+}
+
+// align returns how far a port [p1, p2] must scroll so that [e1, e2]
+// is at the start, center, end or nearest edge
+func align(e1, e2, p1, p2 float64, how string) float64 {
+	switch how {
+	case "start":
+		return e1 - p1
+	case "end":
+		return e2 - p2
+	case "center":
+		return (e1+e2)/2 - (p1+p2)/2
+	}
+	bigger := e2-e1 > p2-p1
+	switch {
+	case e1 >= p1 && e2 <= p2, e1 <= p1 && e2 >= p2:
+		return 0
+	case e1 < p1 && !bigger, e2 > p2 && bigger:
+		return e1 - p1
+	default:
+		return e2 - p2
+	}
+}
+
+// ScrollIntoView scrolls the scroll containers of el and the viewport
+// until el is visible
+func (el *Element) ScrollIntoView(arg js.Value) {
+	block, inline := "start", "nearest"
+	if o, ok := arg.(*js.Object); ok {
+		if v := o.Get("block"); v != nil && !js.IsUndefined(v) {
+			block = v.String()
+		}
+		if v := o.Get("inline"); v != nil && !js.IsUndefined(v) {
+			inline = v.String()
+		}
+	} else if arg != nil && !js.IsUndefined(arg) && !arg.ToBoolean() {
+		block = "end"
+	}
+	if !connected(el.n) || !el.box().ok {
+		return
+	}
+	for n := el.n.Parent; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		c := el.d.getEl(n)
+		if !c.scrollable() {
+			continue
+		}
+		var port box
+		if c.isViewportElement() {
+			port = box{x2: float64(ViewportWidth), y2: float64(ViewportHeight)}
+		} else {
+			port = c.clientBox()
+			port.x1 += c.computedPx("border-left-width")
+			port.y1 += c.computedPx("border-top-width")
+			cw, ch := c.clientSize()
+			port.x2, port.y2 = port.x1+cw, port.y1+ch
+		}
+		b := el.clientBox()
+		x, y := c.scrollOffset()
+		c.scrollTo(x+align(b.x1, b.x2, port.x1, port.x2, inline), y+align(b.y1, b.y2, port.y1, port.y2, block))
+	}
+}
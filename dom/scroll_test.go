@@ -0,0 +1,86 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"testing"
+)
+
+func TestScroll(t *testing.T) {
+	vm := js.New()
+	d, err := Init(vm, "https://example.com", `<html><head><style>
+		body { margin: 0 }
+		#big { height: 2000px }
+		#box { overflow: auto; height: 100px }
+		#inner { height: 500px }
+	</style></head><body><div id="box"><div id="inner"></div><p id="p">x</p></div><div id="big"></div><div id="end">end</div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer d.Window.SetViewport(1280, 720, 1)
+	_, err = vm.RunString(`
+		var log = [];
+		var box = document.getElementById('box');
+		window.addEventListener('scroll', function() { log.push('scroll:' + scrollY) });
+		window.addEventListener('resize', function() { log.push('resize:' + innerWidth + 'x' + innerHeight) });
+		box.addEventListener('scroll', function() { log.push('box:' + box.scrollTop) });
+		log.push(scrollX, scrollY, devicePixelRatio, screen.width);
+		window.scrollTo(0, 100);
+		log.push(pageYOffset, document.documentElement.scrollTop, document.getElementById('big').getBoundingClientRect().top);
+		window.scrollBy({ top: 50 });
+		window.scrollTo(0, 100000);
+		log.push(scrollY);
+		box.scrollTop = 1000;
+		log.push(box.scrollTop, document.getElementById('inner').getBoundingClientRect().top);
+		document.getElementById('big').scrollTop = 10;
+		log.push(document.getElementById('big').scrollTop);
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	frame := func() string {
+		d.Window.RenderAnimationFrame()
+		res, err := vm.RunString(`var s = log.join('|'); log = []; s`)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		return res.String()
+	}
+	if v := frame(); v != `0|0|1|1280|100|100|0|1399|435|-1834|0|scroll:1399|box:435` {
+		t.Fatalf("%v", v)
+	}
+	if v := frame(); v != `` {
+		t.Fatalf("%v", v)
+	}
+	_, err = vm.RunString(`
+		document.getElementById('p').scrollIntoView({ block: 'nearest' });
+		log.push(box.scrollTop, scrollY);
+		document.getElementById('end').scrollIntoView();
+		log.push(scrollY);
+		document.getElementById('box').scrollIntoView(false);
+		log.push(scrollY);
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := frame(); v != `435|81|1399|0|scroll:0` {
+		t.Fatalf("%v", v)
+	}
+	d.Window.SetViewport(800, 600, 2)
+	d.Window.ScrollTo(0, 20.3)
+	if v := frame(); v != `resize:800x600|scroll:20.5` {
+		t.Fatalf("%v", v)
+	}
+	_, err = vm.RunString(`
+		window.scrollTo(0, 100);
+		var me = new MouseEvent('click', { clientX: 5, clientY: 10 });
+		document.body.addEventListener('click', function(e) { log.push(e.pageX, e.pageY) });
+		document.body.dispatchEvent(me);
+		log.push(new MouseEvent('click', { clientY: 10, view: window }).pageY);
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := frame(); v != `5|110|110|scroll:100` {
+		t.Fatalf("%v", v)
+	}
+}
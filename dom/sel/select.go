@@ -8,6 +8,14 @@ import (
 	"strings"
 )
 
+var (
+	// Focused reports whether n is the focused element of its document
+	Focused = func(n *html.Node) bool { return false }
+
+	// FocusWithin reports whether n or one of its descendants is focused
+	FocusWithin = func(n *html.Node) bool { return false }
+)
+
 func Select(sel string, el *html.Node, ignoreRoot, rootMustMatchFirst bool) (es []*html.Node, err error) {
 	sels := strings.Split(sel, ",")
 	for _, s := range sels {
@@ -256,6 +264,15 @@ func matchesPseudo(n *html.Node, q string, rootMustMatchFirst bool) (matches boo
 		// handled in SelectSingle (explode)
 	} else if q == ":scope" {
 		// handled in SelectSingle
+	} else if q == ":focus" || q == ":focus-visible" {
+		matches = Focused(n)
+	} else if q == ":focus-within" {
+		matches = FocusWithin(n)
+	} else if q == ":link" || q == ":any-link" {
+		// no history is kept, so every link is unvisited
+		matches = (n.Data == "a" || n.Data == "area") && hasAttr(*n, "href")
+	} else if q == ":visited" {
+		matches = false
 	} else {
 		log.Errorf("unknown pseudo selector %v", q)
 	}
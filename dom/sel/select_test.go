@@ -475,6 +475,37 @@ func TestSelect36(t *testing.T) {
 	}
 }
 
+func TestSelectLink(t *testing.T) {
+	htm := `
+<html>
+<body>
+<a id="l" href="/x"></a>
+<a id="n"></a>
+<area id="ar" href="/y">
+<link id="k" href="/z">
+</body>
+</html>
+	`
+	d, err := html.Parse(strings.NewReader(htm))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	body := grep(d, "body")
+	for q, exp := range map[string]string{":link": "l ar", "a:any-link": "l", ":visited": ""} {
+		es, err := Select(q, body, true, false)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		ids := make([]string, 0, len(es))
+		for _, e := range es {
+			ids = append(ids, attr(*e, "id"))
+		}
+		if act := strings.Join(ids, " "); act != exp {
+			t.Fatalf("%v: %v", q, act)
+		}
+	}
+}
+
 func TestSplitBlock(t *testing.T) {
 	tt := map[string][]string{
 		"a":                  []string{"a"},
@@ -0,0 +1,364 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"golang.org/x/net/html"
+)
+
+// Selection implements the Selection interface of a document. It holds
+// at most one range whose start is the focus if backward is set.
+type Selection struct {
+	d        *Document
+	obj      *js.Object
+	r        *Range
+	backward bool
+}
+
+// GetSelection returns the selection of d or nil if it has no window
+func (d *Document) GetSelection() *Selection {
+	if d.Window == nil {
+		return nil
+	}
+	if d.selection == nil {
+		d.selection = &Selection{d: d}
+	}
+	return d.selection
+}
+
+// SelectionString returns the text of the selection of d
+func SelectionString(d *Document) string {
+	if s := d.selection; s != nil && s.r != nil {
+		return s.r.ToString()
+	}
+	return ""
+}
+
+func (s *Selection) Obj() *js.Object {
+	if s.obj == nil {
+		s.obj = vm.NewDynamicObject(s)
+		s.obj.SetPrototype(selectionCtor.Get("prototype").(*js.Object))
+	}
+	return s.obj
+}
+
+func (s *Selection) Getters() map[string]bool {
+	return map[string]bool{
+		"anchorNode":   true,
+		"anchorOffset": true,
+		"focusNode":    true,
+		"focusOffset":  true,
+		"isCollapsed":  true,
+		"rangeCount":   true,
+		"type":         true,
+		"direction":    true,
+	}
+}
+
+func (s *Selection) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+func (s *Selection) Get(k string) (v js.Value) {
+	if res, ok := GetCall(s, k); ok {
+		return res
+	}
+	return nil
+}
+
+func (s *Selection) Set(k string, desc js.PropertyDescriptor) bool {
+	return true
+}
+
+func (s *Selection) Has(k string) bool {
+	return HasCall(s, k)
+}
+
+func (s *Selection) Delete(k string) bool {
+	return false
+}
+
+func (s *Selection) Keys() []string {
+	return []string{""}
+}
+
+func (s *Selection) anchor() boundary {
+	if s.backward {
+		return s.r.end
+	}
+	return s.r.start
+}
+
+func (s *Selection) focus() boundary {
+	if s.backward {
+		return s.r.start
+	}
+	return s.r.end
+}
+
+func (s *Selection) AnchorNode() js.Value {
+	if s.r == nil {
+		return js.Null()
+	}
+	return treeNodeObj(s.anchor().node)
+}
+
+func (s *Selection) AnchorOffset() int {
+	if s.r == nil {
+		return 0
+	}
+	return s.anchor().offset
+}
+
+func (s *Selection) FocusNode() js.Value {
+	if s.r == nil {
+		return js.Null()
+	}
+	return treeNodeObj(s.focus().node)
+}
+
+func (s *Selection) FocusOffset() int {
+	if s.r == nil {
+		return 0
+	}
+	return s.focus().offset
+}
+
+func (s *Selection) IsCollapsed() bool {
+	return s.r == nil || s.r.Collapsed()
+}
+
+func (s *Selection) RangeCount() int {
+	if s.r == nil {
+		return 0
+	}
+	return 1
+}
+
+func (s *Selection) Type() string {
+	switch {
+	case s.r == nil:
+		return "None"
+	case s.r.Collapsed():
+		return "Caret"
+	}
+	return "Range"
+}
+
+func (s *Selection) Direction() string {
+	switch {
+	case s.r == nil || s.r.Collapsed():
+		return "none"
+	case s.backward:
+		return "backward"
+	}
+	return "forward"
+}
+
+// setRange makes r the range of the selection. Selecting content of
+// an editing host focuses it.
+func (s *Selection) setRange(r *Range, backward bool) {
+	s.r, s.backward = r, backward
+	if r == nil {
+		return
+	}
+	nd, ok := s.focus().node.(Node)
+	if !ok {
+		return
+	}
+	if h := editingHost(nd.base().n); h != nil {
+		if el := s.d.getEl(h); s.d.focused != el {
+			s.d.setFocus(el)
+		}
+	}
+}
+
+// inDocument is true if x is in the document tree of the selection
+func (s *Selection) inDocument(x any) bool {
+	return rootOf(x) == s.d
+}
+
+func (s *Selection) GetRangeAt(i int) *Range {
+	if i < 0 || i >= s.RangeCount() {
+		throwDOMException("IndexSizeError", "index is out of range")
+	}
+	return s.r
+}
+
+func (s *Selection) AddRange(v js.Value) {
+	r := rangeArg(v)
+	if !s.inDocument(r.start.node) || s.r != nil {
+		return
+	}
+	s.setRange(r, false)
+}
+
+func (s *Selection) RemoveRange(v js.Value) {
+	if r := rangeArg(v); r != s.r {
+		throwDOMException("NotFoundError", "the range isn't selected")
+	}
+	s.r = nil
+}
+
+func (s *Selection) RemoveAllRanges() {
+	s.r = nil
+}
+
+func (s *Selection) Empty() {
+	s.r = nil
+}
+
+func (s *Selection) Collapse(v, o js.Value) {
+	if v != nil && js.IsNull(v) {
+		s.r = nil
+		return
+	}
+	x := nodeArg(v)
+	offset := 0
+	if o != nil {
+		offset = offsetArg(o)
+	}
+	checkPoint(x, offset)
+	if !s.inDocument(x) {
+		return
+	}
+	r := newRange(s.d)
+	r.start = boundary{x, offset}
+	r.end = r.start
+	s.setRange(r, false)
+}
+
+func (s *Selection) SetPosition(v, o js.Value) {
+	s.Collapse(v, o)
+}
+
+func (s *Selection) CollapseToStart() {
+	if s.r == nil {
+		throwDOMException("InvalidStateError", "the selection is empty")
+	}
+	r := newRange(s.d)
+	r.start, r.end = s.r.start, s.r.start
+	s.setRange(r, false)
+}
+
+func (s *Selection) CollapseToEnd() {
+	if s.r == nil {
+		throwDOMException("InvalidStateError", "the selection is empty")
+	}
+	r := newRange(s.d)
+	r.start, r.end = s.r.end, s.r.end
+	s.setRange(r, false)
+}
+
+func (s *Selection) Extend(v, o js.Value) {
+	x := nodeArg(v)
+	offset := 0
+	if o != nil {
+		offset = offsetArg(o)
+	}
+	if !s.inDocument(x) {
+		return
+	}
+	if s.r == nil {
+		throwDOMException("InvalidStateError", "the selection is empty")
+	}
+	checkPoint(x, offset)
+	anchor, focus := s.anchor(), boundary{x, offset}
+	r := newRange(s.d)
+	backward := false
+	if comparePoints(anchor, focus) <= 0 {
+		r.start, r.end = anchor, focus
+	} else {
+		r.start, r.end = focus, anchor
+		backward = true
+	}
+	s.setRange(r, backward)
+}
+
+func (s *Selection) SetBaseAndExtent(av, ao, fv, fo js.Value) {
+	a, f := nodeArg(av), nodeArg(fv)
+	anchor, focus := boundary{a, offsetArg(ao)}, boundary{f, offsetArg(fo)}
+	checkPoint(a, anchor.offset)
+	checkPoint(f, focus.offset)
+	if !s.inDocument(a) || !s.inDocument(f) {
+		return
+	}
+	r := newRange(s.d)
+	backward := false
+	if comparePoints(anchor, focus) <= 0 {
+		r.start, r.end = anchor, focus
+	} else {
+		r.start, r.end = focus, anchor
+		backward = true
+	}
+	s.setRange(r, backward)
+}
+
+func (s *Selection) SelectAllChildren(v js.Value) {
+	x := nodeArg(v)
+	if isDoctype(x) {
+		throwDOMException("InvalidNodeTypeError", "the node is a doctype")
+	}
+	if !s.inDocument(x) {
+		return
+	}
+	r := newRange(s.d)
+	r.start = boundary{x, 0}
+	r.end = boundary{x, nodeLength(x)}
+	s.setRange(r, false)
+}
+
+func (s *Selection) DeleteFromDocument() {
+	if s.r != nil {
+		s.r.DeleteContents()
+	}
+}
+
+func (s *Selection) ContainsNode(v, partial js.Value) bool {
+	x := nodeArg(v)
+	if s.r == nil || !s.inDocument(x) {
+		return false
+	}
+	first, last := boundary{x, 0}, boundary{x, nodeLength(x)}
+	if partial != nil && partial.ToBoolean() {
+		return comparePoints(s.r.start, last) <= 0 && comparePoints(s.r.end, first) >= 0
+	}
+	return comparePoints(s.r.start, first) <= 0 && comparePoints(s.r.end, last) >= 0
+}
+
+func (s *Selection) ToString() string {
+	return SelectionString(s.d)
+}
+
+// editingHost returns the outermost contenteditable element containing
+// n or nil
+func editingHost(n *html.Node) (h *html.Node) {
+	for ; n != nil; n = n.Parent {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		ce, ok := attrOk(*n, "contenteditable")
+		if ok && ce == "false" {
+			break
+		}
+		if ok {
+			h = n
+		}
+	}
+	return
+}
+
+// focusSelection collapses the selection to the start of the editing
+// host el when it gets the focus and the selection is elsewhere
+func (d *Document) focusSelection(el *Element) {
+	s := d.GetSelection()
+	if s == nil || editingHost(el.n) != el.n {
+		return
+	}
+	if s.r != nil && isInclusiveAncestor(el, s.r.start.node) && isInclusiveAncestor(el, s.r.end.node) {
+		return
+	}
+	r := newRange(d)
+	r.start = boundary{el, 0}
+	r.end = r.start
+	s.r, s.backward = r, false
+}
@@ -0,0 +1,550 @@
+package dom
+
+import (
+	"sort"
+	"strings"
+)
+
+// longhands of the supported shorthand properties in serialization
+// order
+var longhands = map[string][]string{
+	"margin":          sides("margin-*"),
+	"padding":         sides("padding-*"),
+	"inset":           sides("*"),
+	"border-width":    sides("border-*-width"),
+	"border-style":    sides("border-*-style"),
+	"border-color":    sides("border-*-color"),
+	"border-top":      borderSide("top"),
+	"border-right":    borderSide("right"),
+	"border-bottom":   borderSide("bottom"),
+	"border-left":     borderSide("left"),
+	"border":          append(append(sides("border-*-width"), sides("border-*-style")...), sides("border-*-color")...),
+	"outline":         {"outline-width", "outline-style", "outline-color"},
+	"overflow":        {"overflow-x", "overflow-y"},
+	"gap":             {"row-gap", "column-gap"},
+	"flex":            {"flex-grow", "flex-shrink", "flex-basis"},
+	"text-decoration": {"text-decoration-line", "text-decoration-style", "text-decoration-color"},
+	"background":      {"background-image", "background-position", "background-size", "background-repeat", "background-attachment", "background-color"},
+	"font":            {"font-style", "font-variant", "font-weight", "font-stretch", "font-size", "line-height", "font-family"},
+}
+
+// shorthandsOf a longhand, the ones with the most longhands first
+var shorthandsOf = make(map[string][]string)
+
+func init() {
+	for sh, lhs := range longhands {
+		for _, lh := range lhs {
+			shorthandsOf[lh] = append(shorthandsOf[lh], sh)
+		}
+	}
+	for _, shs := range shorthandsOf {
+		sort.Slice(shs, func(i, j int) bool {
+			if a, b := len(longhands[shs[i]]), len(longhands[shs[j]]); a != b {
+				return a > b
+			}
+			return shs[i] < shs[j]
+		})
+	}
+}
+
+func sides(f string) []string {
+	ps := make([]string, 0, 4)
+	for _, s := range []string{"top", "right", "bottom", "left"} {
+		ps = append(ps, strings.Replace(f, "*", s, 1))
+	}
+	return ps
+}
+
+func borderSide(s string) []string {
+	return []string{"border-" + s + "-width", "border-" + s + "-style", "border-" + s + "-color"}
+}
+
+func isWideKeyword(v string) bool {
+	switch strings.ToLower(v) {
+	case "inherit", "initial", "unset", "revert", "revert-layer":
+		return true
+	}
+	return false
+}
+
+// splitValue splits v at top-level whitespace, or at top-level commas
+// if sep is ','
+func splitValue(v string, sep rune) (ts []string) {
+	depth := 0
+	var quote rune
+	start := 0
+	for i, c := range v {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && (c == sep || sep == ' ' && (c == '\t' || c == '\n')):
+			if t := strings.TrimSpace(v[start:i]); t != "" || sep == ',' {
+				ts = append(ts, t)
+			}
+			start = i + 1
+		}
+	}
+	if t := strings.TrimSpace(v[start:]); t != "" || sep == ',' {
+		ts = append(ts, t)
+	}
+	return
+}
+
+func isBorderStyle(t string) bool {
+	switch t {
+	case "none", "hidden", "dotted", "dashed", "solid", "double", "groove", "ridge", "inset", "outset":
+		return true
+	}
+	return false
+}
+
+func isNumeric(t string) bool {
+	if t == "" {
+		return false
+	}
+	if strings.HasPrefix(t, "calc(") || strings.HasPrefix(t, "min(") || strings.HasPrefix(t, "max(") || strings.HasPrefix(t, "clamp(") {
+		return true
+	}
+	t = strings.TrimLeft(t, "+-")
+	return t != "" && (t[0] >= '0' && t[0] <= '9' || t[0] == '.')
+}
+
+func isColor(t string) bool {
+	t = strings.ToLower(t)
+	if colorKeywords[t] {
+		return true
+	}
+	if strings.HasPrefix(t, "#") {
+		h := t[1:]
+		switch len(h) {
+		case 3, 4, 6, 8:
+			return strings.Trim(h, "0123456789abcdef") == ""
+		}
+		return false
+	}
+	for _, f := range []string{"rgb(", "rgba(", "hsl(", "hsla(", "hwb(", "lab(", "lch(", "oklab(", "oklch(", "color(", "color-mix("} {
+		if strings.HasPrefix(t, f) && strings.HasSuffix(t, ")") {
+			return true
+		}
+	}
+	return false
+}
+
+// expand the value v of property p into its longhands. ok is false
+// if v is invalid for the shorthand.
+func expand(p, v string) (ds []decl, ok bool) {
+	lhs, isShort := longhands[p]
+	if !isShort {
+		if !validValue(p, v) {
+			return nil, false
+		}
+		return []decl{{prop: p, val: v}}, true
+	}
+	vals := make(map[string]string)
+	if isWideKeyword(v) || strings.Contains(v, "var(") {
+		for _, lh := range lhs {
+			vals[lh] = v
+		}
+	} else if vals, ok = expandValue(p, v); !ok {
+		return nil, false
+	}
+	for _, lh := range lhs {
+		val, ok := vals[lh]
+		if !ok {
+			val = "initial"
+		} else if !validValue(lh, val) {
+			return nil, false
+		}
+		ds = append(ds, decl{prop: lh, val: val})
+	}
+	return ds, true
+}
+
+func expandValue(p, v string) (vals map[string]string, ok bool) {
+	lhs := longhands[p]
+	vals = make(map[string]string)
+	ts := splitValue(v, ' ')
+	if len(ts) == 0 {
+		return nil, false
+	}
+	switch p {
+	case "margin", "padding", "inset", "border-width", "border-style", "border-color":
+		if len(ts) > 4 {
+			return nil, false
+		}
+		// top, right, bottom, left from 1 to 4 values
+		idx := [][]int{{0, 0, 0, 0}, {0, 1, 0, 1}, {0, 1, 2, 1}, {0, 1, 2, 3}}[len(ts)-1]
+		for i, lh := range lhs {
+			vals[lh] = ts[idx[i]]
+		}
+	case "overflow", "gap":
+		if len(ts) > 2 {
+			return nil, false
+		}
+		vals[lhs[0]] = ts[0]
+		vals[lhs[1]] = ts[len(ts)-1]
+	case "border", "border-top", "border-right", "border-bottom", "border-left", "outline":
+		var w, s, c string
+		for _, t := range ts {
+			switch {
+			case isBorderStyle(strings.ToLower(t)) && s == "":
+				s = t
+			case (isNumeric(t) || t == "thin" || t == "medium" || t == "thick") && w == "":
+				w = t
+			case c == "" && !isNumeric(t):
+				c = t
+			default:
+				return nil, false
+			}
+		}
+		for i, x := range []string{w, s, c} {
+			if x == "" {
+				continue
+			}
+			if p == "border" {
+				for _, side := range []string{"top", "right", "bottom", "left"} {
+					vals[borderSide(side)[i]] = x
+				}
+			} else {
+				vals[lhs[i]] = x
+			}
+		}
+	case "flex":
+		switch {
+		case len(ts) == 1 && ts[0] == "none":
+			ts = []string{"0", "0", "auto"}
+		case len(ts) == 1 && ts[0] == "auto":
+			ts = []string{"1", "1", "auto"}
+		case len(ts) == 1 && isNumeric(ts[0]) && strings.Trim(ts[0], "0123456789.+-") == "":
+			ts = []string{ts[0], "1", "0%"}
+		case len(ts) == 1:
+			ts = []string{"1", "1", ts[0]}
+		case len(ts) == 2 && strings.Trim(ts[1], "0123456789.+-") == "":
+			ts = []string{ts[0], ts[1], "0%"}
+		case len(ts) == 2:
+			ts = []string{ts[0], "1", ts[1]}
+		case len(ts) > 3:
+			return nil, false
+		}
+		for i, lh := range lhs {
+			vals[lh] = ts[i]
+		}
+	case "text-decoration":
+		var line []string
+		for _, t := range ts {
+			switch t {
+			case "none", "underline", "overline", "line-through", "blink":
+				line = append(line, t)
+			case "solid", "double", "dotted", "dashed", "wavy":
+				vals["text-decoration-style"] = t
+			default:
+				if _, ok := vals["text-decoration-color"]; ok {
+					return nil, false
+				}
+				vals["text-decoration-color"] = t
+			}
+		}
+		if len(line) > 0 {
+			vals["text-decoration-line"] = strings.Join(line, " ")
+		}
+	case "background":
+		return expandBackground(v)
+	case "font":
+		return expandFont(ts)
+	}
+	return vals, true
+}
+
+// expandBackground into the longhands, joining the values of several
+// layers with commas
+func expandBackground(v string) (vals map[string]string, ok bool) {
+	layers := splitValue(v, ',')
+	initial := map[string]string{
+		"background-image":      "none",
+		"background-position":   "0% 0%",
+		"background-size":       "auto",
+		"background-repeat":     "repeat",
+		"background-attachment": "scroll",
+	}
+	vals = make(map[string]string)
+	for i, l := range layers {
+		lv := make(map[string][]string)
+		var ts []string
+		for _, t := range splitValue(l, ' ') {
+			if before, after, ok := strings.Cut(t, "/"); ok && !strings.Contains(t, "(") {
+				for _, x := range []string{before, "/", after} {
+					if x != "" {
+						ts = append(ts, x)
+					}
+				}
+			} else {
+				ts = append(ts, t)
+			}
+		}
+		afterSlash := false
+		for _, t := range ts {
+			lt := strings.ToLower(t)
+			switch {
+			case t == "/":
+				afterSlash = true
+				continue
+			case afterSlash:
+				lv["background-size"] = append(lv["background-size"], t)
+				continue
+			case lt == "none" || strings.HasPrefix(lt, "url(") || strings.Contains(lt, "gradient("):
+				lv["background-image"] = append(lv["background-image"], t)
+			case lt == "repeat" || lt == "repeat-x" || lt == "repeat-y" || lt == "no-repeat" || lt == "space" || lt == "round":
+				lv["background-repeat"] = append(lv["background-repeat"], t)
+			case lt == "scroll" || lt == "fixed" || lt == "local":
+				lv["background-attachment"] = append(lv["background-attachment"], t)
+			case isColor(lt):
+				if i != len(layers)-1 || vals["background-color"] != "" {
+					return nil, false
+				}
+				vals["background-color"] = t
+			default:
+				lv["background-position"] = append(lv["background-position"], t)
+			}
+			afterSlash = false
+		}
+		for p, init := range initial {
+			x := strings.Join(lv[p], " ")
+			if x == "" && len(layers) > 1 {
+				x = init
+			}
+			if x == "" {
+				continue
+			}
+			if i > 0 {
+				x = vals[p] + ", " + x
+			}
+			vals[p] = x
+		}
+	}
+	return vals, true
+}
+
+func expandFont(ts []string) (vals map[string]string, ok bool) {
+	vals = make(map[string]string)
+	i := 0
+	for ; i < len(ts); i++ {
+		t := strings.ToLower(ts[i])
+		switch {
+		case t == "normal":
+		case t == "italic" || t == "oblique":
+			vals["font-style"] = ts[i]
+		case t == "small-caps":
+			vals["font-variant"] = ts[i]
+		case t == "bold" || t == "bolder" || t == "lighter" || len(t) == 3 && strings.Trim(t, "0123456789") == "":
+			vals["font-weight"] = ts[i]
+		case strings.HasSuffix(t, "condensed") || strings.HasSuffix(t, "expanded"):
+			vals["font-stretch"] = ts[i]
+		default:
+			goto size
+		}
+	}
+size:
+	if i >= len(ts) {
+		return nil, false
+	}
+	size, lh, hasLH := strings.Cut(ts[i], "/")
+	i++
+	if size == "" || !isNumeric(size) && !strings.HasSuffix(size, "small") && !strings.HasSuffix(size, "large") && size != "medium" && size != "larger" && size != "smaller" {
+		return nil, false
+	}
+	vals["font-size"] = size
+	if hasLH && lh == "" && i < len(ts) {
+		lh = ts[i]
+		i++
+	} else if !hasLH && i < len(ts) && strings.HasPrefix(ts[i], "/") {
+		lh = strings.TrimPrefix(ts[i], "/")
+		i++
+		if lh == "" && i < len(ts) {
+			lh = ts[i]
+			i++
+		}
+	}
+	if lh != "" {
+		vals["line-height"] = lh
+	}
+	if i >= len(ts) {
+		return nil, false
+	}
+	vals["font-family"] = strings.Join(ts[i:], " ")
+	return vals, true
+}
+
+// shorthandValue of sh from the declarations ds, or ok false if a
+// longhand is missing or they can't be combined
+func shorthandValue(ds []decl, sh string) (v string, important, ok bool) {
+	lhs := longhands[sh]
+	vals := make([]string, len(lhs))
+	for i, lh := range lhs {
+		found := false
+		for _, dl := range ds {
+			if dl.prop == lh {
+				if i > 0 && dl.important != important {
+					return "", false, false
+				}
+				vals[i] = dl.val
+				important = dl.important
+				found = true
+			}
+		}
+		if !found {
+			return "", false, false
+		}
+	}
+	v, ok = combine(sh, vals)
+	return v, important, ok
+}
+
+// combine the longhand values vals into a value of the shorthand sh
+func combine(sh string, vals []string) (string, bool) {
+	same := true
+	special := false
+	initial := false
+	for _, v := range vals {
+		same = same && v == vals[0]
+		initial = initial || v == "initial"
+		special = special || v != "initial" && isWideKeyword(v) || strings.Contains(v, "var(")
+	}
+	if special || same && initial {
+		return vals[0], same
+	}
+	// join the values that aren't initial
+	join := func(vs ...string) string {
+		var ss []string
+		for _, v := range vs {
+			if v != "initial" {
+				ss = append(ss, v)
+			}
+		}
+		return strings.Join(ss, " ")
+	}
+	switch sh {
+	case "margin", "padding", "inset", "border-width", "border-style", "border-color":
+		if initial {
+			return "", false
+		}
+		t, r, b, l := vals[0], vals[1], vals[2], vals[3]
+		switch {
+		case t == r && t == b && t == l:
+			return t, true
+		case t == b && r == l:
+			return t + " " + r, true
+		case r == l:
+			return t + " " + r + " " + b, true
+		}
+		return strings.Join(vals, " "), true
+	case "overflow", "gap":
+		if initial {
+			return "", false
+		}
+		if vals[0] == vals[1] {
+			return vals[0], true
+		}
+		return vals[0] + " " + vals[1], true
+	case "border":
+		for i := 1; i < 4; i++ {
+			if vals[i] != vals[0] || vals[4+i] != vals[4] || vals[8+i] != vals[8] {
+				return "", false
+			}
+		}
+		return join(vals[0], vals[4], vals[8]), true
+	case "background":
+		for _, v := range vals {
+			if len(splitValue(v, ',')) > 1 {
+				return "", false
+			}
+		}
+		img, pos, size, rep, att, col := vals[0], vals[1], vals[2], vals[3], vals[4], vals[5]
+		if size != "initial" {
+			if pos == "initial" {
+				pos = "0% 0%"
+			}
+			pos += " / " + size
+		}
+		return join(img, pos, rep, att, col), true
+	case "font":
+		size, lh, family := vals[4], vals[5], vals[6]
+		if size == "initial" || family == "initial" {
+			return "", false
+		}
+		if lh != "initial" {
+			size += "/" + lh
+		}
+		return join(vals[0], vals[1], vals[2], vals[3], size, family), true
+	}
+	return join(vals...), true
+}
+
+// addDecl appends the longhands of dl to ds and drops earlier
+// declarations of them unless those are important and dl isn't
+func addDecl(ds []decl, dl decl) []decl {
+	lds, ok := expand(dl.prop, dl.val)
+	if !ok || dl.val == "" {
+		return ds
+	}
+	for _, ld := range lds {
+		ld.important = dl.important
+		i := indexDecl(ds, ld.prop)
+		if i >= 0 && ds[i].important && !ld.important {
+			continue
+		}
+		if i >= 0 {
+			ds = append(ds[:i], ds[i+1:]...)
+		}
+		ds = append(ds, ld)
+	}
+	return ds
+}
+
+// setDecl sets the longhands of dl in ds, updating existing
+// declarations in place
+func setDecl(ds []decl, dl decl) ([]decl, bool) {
+	lds, ok := expand(dl.prop, dl.val)
+	if !ok {
+		return ds, false
+	}
+	for _, ld := range lds {
+		ld.important = dl.important
+		if i := indexDecl(ds, ld.prop); i >= 0 {
+			ds[i] = ld
+		} else {
+			ds = append(ds, ld)
+		}
+	}
+	return ds, true
+}
+
+// removeDecl removes p or its longhands from ds
+func removeDecl(ds []decl, p string) []decl {
+	ps := longhands[p]
+	if ps == nil {
+		ps = []string{p}
+	}
+	for _, p := range ps {
+		if i := indexDecl(ds, p); i >= 0 {
+			ds = append(ds[:i], ds[i+1:]...)
+		}
+	}
+	return ds
+}
+
+func indexDecl(ds []decl, p string) int {
+	for i, dl := range ds {
+		if dl.prop == p {
+			return i
+		}
+	}
+	return -1
+}
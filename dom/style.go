@@ -3,12 +3,10 @@ package dom
 import (
 	"embed"
 	"encoding/csv"
-	"fmt"
 	"github.com/psilva261/sparkle/js"
 	"github.com/psilva261/sparklefs/logger"
-	"github.com/tdewolff/parse/v2"
-	"github.com/tdewolff/parse/v2/css"
 	"golang.org/x/net/html"
+	"strconv"
 	"strings"
 )
 
@@ -36,18 +34,48 @@ func init() {
 	}
 }
 
-// Style represents a CSSStyleDeclaration object
+// Style represents a CSSStyleDeclaration object of a style attribute
+// or of a style rule if r is set
 type Style struct {
 	n *html.Node
+	r *CSSStyleRule
+}
+
+// decls of the attribute or rule in order, shorthands expanded
+func (s *Style) decls() []decl {
+	if s.r != nil {
+		return s.r.decls
+	}
+	return parseDecls(attr(*s.n, "style"))
+}
+
+func (s *Style) setDecls(ds []decl) {
+	if s.r != nil {
+		s.r.decls = ds
+		return
+	}
+	setAttr(s.n, "style", serializeDecls(ds))
+}
+
+func (s *Style) text() string {
+	return serializeDecls(s.decls())
+}
+
+func (s *Style) setText(st string) {
+	s.setDecls(parseDecls(st))
 }
 
 func (s *Style) Obj() *js.Object {
-	return vm.NewDynamicObject(s)
+	o := vm.NewDynamicObject(s)
+	o.SetPrototype(cssStyleDeclarationCtor.Get("prototype").(*js.Object))
+	return o
 }
 
 func (s *Style) Getters() map[string]bool {
 	return map[string]bool{
-		"length": true,
+		"cssText":    true,
+		"length":     true,
+		"parentRule": true,
 	}
 }
 
@@ -56,25 +84,70 @@ func (s *Style) Props() map[string]bool {
 }
 
 func (s *Style) Get(k string) (v js.Value) {
+	if !s.Getters()[k] && cssStyleDeclarationCtor.Get("prototype").(*js.Object).Get(k) != nil {
+		// methods are inherited so they can be patched
+		return nil
+	}
 	if res, ok := GetCall(s, k); ok {
 		return res
 	}
-	k = kebab(k)
-	st := attr(*s.n, "style")
-	m := parseStyle(st)
-	if s, ok := m[k]; ok && s != "" {
-		return vm.ToValue(s)
+	if i, err := strconv.Atoi(k); err == nil {
+		if i < 0 || i >= s.Length() {
+			return nil
+		}
+		return vm.ToValue(s.Item(i))
 	}
-	if _, ok := allProperties[k]; ok {
-		return vm.ToValue("")
+	if p := propName(k); isProperty(p) && !strings.HasPrefix(p, "--") {
+		return vm.ToValue(s.GetPropertyValue(p))
 	}
-	return vm.ToValue(nil)
+	return nil
+}
+
+func (s *Style) CssText() string {
+	return s.text()
 }
 
 func (s *Style) Length() int {
-	st := attr(*s.n, "style")
-	m := parseStyle(st)
-	return len(m)
+	return len(s.decls())
+}
+
+func (s *Style) ParentRule() js.Value {
+	if s.r == nil {
+		return js.Null()
+	}
+	return s.r.Obj()
+}
+
+func (s *Style) Item(i int) string {
+	ds := s.decls()
+	if i < 0 || i >= len(ds) {
+		return ""
+	}
+	return ds[i].prop
+}
+
+// propName returns the property name for a name like cssFloat,
+// backgroundColor or webkitTransform
+func propName(k string) string {
+	if strings.HasPrefix(k, "--") {
+		return k
+	}
+	if k == "cssFloat" {
+		return "float"
+	}
+	k = strings.ToLower(kebab(k))
+	for _, v := range []string{"webkit-", "moz-", "ms-"} {
+		if strings.HasPrefix(k, v) {
+			return "-" + k
+		}
+	}
+	return k
+}
+
+func isProperty(p string) bool {
+	_, known := cssProps[p]
+	_, short := longhands[p]
+	return known || short || allProperties[p] || strings.HasPrefix(p, "--") || strings.HasPrefix(p, "-webkit-")
 }
 
 func kebab(k string) (res string) {
@@ -107,66 +180,100 @@ func camel(k string) (res string) {
 func (s *Style) Set(k string, desc js.PropertyDescriptor) bool {
 	v := desc.Value
 	if k == "cssText" {
-		setAttr(s.n, "style", v.String())
+		s.setText(v.String())
 		return true
 	}
-	st := attr(*s.n, "style")
-	m := parseStyle(st)
-	k = kebab(k)
-	m[k] = v.String()
-	st = ""
-	for k, v := range m {
-		st += fmt.Sprintf("%v: %v; ", k, v)
+	if p := propName(k); isProperty(p) && !strings.HasPrefix(p, "--") {
+		s.SetProperty(p, v, "")
 	}
-	setAttr(s.n, "style", st)
 	return true
 }
 
 func (s *Style) Has(k string) (yes bool) {
-	log.Printf("style has? %v", k)
-	k = kebab(k)
-	st := attr(*s.n, "style")
-	m := parseStyle(st)
-	if s, ok := m[k]; ok && s != "" {
+	if i, err := strconv.Atoi(k); err == nil {
+		return i >= 0 && i < s.Length()
+	}
+	if p := propName(k); isProperty(p) && !strings.HasPrefix(p, "--") {
 		return true
 	}
 	return HasCall(s, k)
 }
 
-func (s *Style) Delete(key string) bool {
-	log.Printf("style delete %v", key)
-	return false
+func (s *Style) Delete(k string) bool {
+	if p := propName(k); isProperty(p) {
+		s.RemoveProperty(p)
+	}
+	return true
 }
 
-func (s *Style) Keys() []string {
-	log.Printf("style get keys")
-	return []string{""}
+func (s *Style) Keys() (ks []string) {
+	for i := range s.decls() {
+		ks = append(ks, strconv.Itoa(i))
+	}
+	return
 }
 
+// GetPropertyValue of p which is combined from the longhands if p is a
+// shorthand
 func (s *Style) GetPropertyValue(p string) string {
-	st := attr(*s.n, "style")
-	m := parseStyle(st)
-	v, _ := m[p]
-	return v
+	p = propName(p)
+	ds := s.decls()
+	if _, ok := longhands[p]; ok {
+		v, _, _ := shorthandValue(ds, p)
+		return v
+	}
+	if i := indexDecl(ds, p); i >= 0 {
+		return ds[i].val
+	}
+	return ""
 }
 
-func parseStyle(st string) (m map[string]string) {
-	m = make(map[string]string)
-	p := css.NewParser(parse.NewInputString(st), true)
-	for {
-		gt, _, data := p.Next()
-		if gt == css.ErrorGrammar {
-			break
-		} else if gt == css.AtRuleGrammar || gt == css.BeginAtRuleGrammar || gt == css.BeginRulesetGrammar || gt == css.DeclarationGrammar {
-			k := string(data)
-			v := ""
-			for _, val := range p.Values() {
-				v += string(val.Data)
-			}
-			m[k] = v
-		}
+func (s *Style) GetPropertyPriority(p string) string {
+	p = propName(p)
+	ds := s.decls()
+	imp := false
+	if _, ok := longhands[p]; ok {
+		_, imp, _ = shorthandValue(ds, p)
+	} else if i := indexDecl(ds, p); i >= 0 {
+		imp = ds[i].important
 	}
-	return
+	if imp {
+		return "important"
+	}
+	return ""
+}
+
+// SetProperty p to the value v, removing it if v is empty. Invalid
+// values and priorities are ignored.
+func (s *Style) SetProperty(p string, v js.Value, prio string) {
+	p = propName(p)
+	val := ""
+	if v != nil && !js.IsNull(v) && !js.IsUndefined(v) {
+		val = strings.TrimSpace(v.String())
+	}
+	if val == "" {
+		s.RemoveProperty(p)
+		return
+	}
+	prio = strings.ToLower(prio)
+	if prio != "" && prio != "important" || strings.ContainsAny(val, ";{}") {
+		return
+	}
+	if ds, ok := setDecl(s.decls(), decl{prop: p, val: val, important: prio != ""}); ok {
+		s.setDecls(ds)
+	}
+}
+
+// RemoveProperty p and return its former value
+func (s *Style) RemoveProperty(p string) string {
+	p = propName(p)
+	v := s.GetPropertyValue(p)
+	ds := s.decls()
+	n := len(ds)
+	if ds = removeDecl(ds, p); len(ds) != n {
+		s.setDecls(ds)
+	}
+	return v
 }
 
 type ComputedStyle struct {
@@ -174,7 +281,9 @@ type ComputedStyle struct {
 }
 
 func (cs *ComputedStyle) Obj() *js.Object {
-	return vm.NewDynamicObject(cs)
+	o := vm.NewDynamicObject(cs)
+	o.SetPrototype(cssStyleDeclarationCtor.Get("prototype").(*js.Object))
+	return o
 }
 
 func (cs *ComputedStyle) Getters() map[string]bool {
@@ -189,9 +298,9 @@ func (cs *ComputedStyle) Get(k string) (v js.Value) {
 	if res, ok := GetCall(cs, k); ok {
 		return res
 	}
-	res := cs.GetPropertyValue(k)
-	if res != "" {
-		return vm.ToValue(v)
+	k = kebab(k)
+	if _, ok := cssProps[k]; ok || allProperties[k] || k == "css-float" {
+		return vm.ToValue(cs.GetPropertyValue(k))
 	}
 	return vm.ToValue(nil)
 }
@@ -216,62 +325,19 @@ func (cs *ComputedStyle) Keys() []string {
 	return []string{""}
 }
 
+// GetPropertyValue asks the host for the value of k and falls back to
+// the cascade of the document's style sheets
 func (cs *ComputedStyle) GetPropertyValue(k string) string {
-	if Query == nil {
-		log.Errorf("nil Query func")
-		return ""
-	}
-	p, ok := path(cs.el)
-	if !ok {
-		log.Errorf("path lookup failed")
-		return ""
-	}
-	res, err := Query(p, k)
-	if err != nil {
-		log.Errorf("query: %v", err)
-		return ""
-	}
-	return res
-}
-
-type DOMRect struct{}
-
-func (dr *DOMRect) Obj() *js.Object {
-	return vm.NewDynamicObject(dr)
-}
-
-func (dr *DOMRect) Getters() map[string]bool {
-	return map[string]bool{
-		"length": true,
-	}
-}
-
-func (dr *DOMRect) Props() map[string]bool {
-	return map[string]bool{}
-}
-
-func (dr *DOMRect) Get(k string) (v js.Value) {
-	if res, ok := GetCall(dr, k); ok {
-		return res
-	}
-	return vm.ToValue(nil)
-}
-
-func (dr *DOMRect) Set(k string, desc js.PropertyDescriptor) bool {
-	return true
-}
-
-func (dr *DOMRect) Has(k string) bool {
-	if yes := HasCall(dr, k); yes {
-		return true
+	if Query != nil {
+		if p, ok := path(cs.el); ok {
+			res, err := Query(p, k)
+			if err == nil && res != "" {
+				return res
+			}
+			if err != nil {
+				log.Errorf("query: %v", err)
+			}
+		}
 	}
-	return false
-}
-
-func (dr *DOMRect) Delete(k string) bool {
-	return false
-}
-
-func (dr *DOMRect) Keys() []string {
-	return []string{""}
+	return cs.el.d.computedValue(cs.el.n, k)
 }
@@ -35,7 +35,7 @@ document.getElementById("demo").innerHTML =
 	t.Logf("res=%v", res)
 	p := grep(d.doc, "p")
 	t.Logf("p=%v", p)
-	el := Element{n: p}
+	el := d.getEl(p)
 	if s := el.Style(); s.Get("font-weight").String() != "bold" {
 		t.Fatalf("%v", s.Get("font-weight").String())
 	}
@@ -49,3 +49,74 @@ document.getElementById("demo").innerHTML =
 		t.Fatalf("%v", err)
 	}
 }
+
+func TestStyleDeclaration(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><body><p id="a" style="color: red; margin: 0 auto; --x: 1px"></p></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var s = document.getElementById('a').style;
+		var log = [s.cssText, s.length, s[0], s.item(1), s.marginLeft, s.margin, s.getPropertyValue('--x'), s.getPropertyValue('marginTop')];
+		s.setProperty('background-color', 'blue', 'important');
+		s.cssFloat = 'left';
+		s.color = 'green';
+		log.push(s.getPropertyPriority('background-color'), s.float, s.cssText);
+		log.push(s.removeProperty('margin'), s.marginTop, s.length);
+		s.setProperty('color', 'red', 'bogus');
+		s.borderTop = '1px solid';
+		log.push(s.color, s.borderTopStyle, s.borderTopColor, s.cssText);
+		s.cssText = 'font: italic bold 12px/30px Georgia, serif; background: url(a.png) no-repeat red';
+		log.push(s.fontFamily, s.lineHeight, s.backgroundColor, s.backgroundRepeat, s.font, document.getElementById('a').getAttribute('style'));
+		s.border = '2px dotted';
+		s.borderLeftWidth = '3px';
+		log.push(s.border, s.borderWidth, s.borderStyle, Object.keys(s).length === s.length);
+		document.getElementById('a').style = 'top: 1px';
+		log.push(s.cssText);
+		log.push(s instanceof CSSStyleDeclaration, Object.prototype.toString.call(s), typeof CSSStyleDeclaration.prototype.setProperty,
+			'cssText' in CSSStyleDeclaration.prototype, getComputedStyle(document.body) instanceof CSSStyleDeclaration);
+		log.join('|');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := `color: red; margin: 0 auto; --x: 1px;|6|color|margin-top|auto|0 auto|1px|0|important|left|color: green; margin: 0 auto; --x: 1px; background-color: blue !important; float: left;|0 auto||4|green|solid|initial|color: green; --x: 1px; background-color: blue !important; float: left; border-top: 1px solid;|Georgia, serif|30px|red|no-repeat|italic bold 12px/30px Georgia, serif|font: italic bold 12px/30px Georgia, serif; background: url(a.png) no-repeat red;||2px 2px 2px 3px|dotted|true|top: 1px;|true|[object CSSStyleDeclaration]|function|true|true`
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestInvalidStyleValues(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><body><p id="a" style="color: red; width: 2px"></p></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var s = document.getElementById('a').style;
+		s.color = 'bogus';
+		s.width = '10 px';
+		s.setProperty('display', 'blocky');
+		s.setProperty('margin', '1px bogus');
+		s.border = '1px solid #ggg';
+		var log = [s.color, s.width, s.display, s.margin, s.borderTopStyle, s.cssText];
+		s.color = 'DarkGray';
+		s.width = 'calc(100% - 2em)';
+		s.display = 'inline flex';
+		s.opacity = '.5';
+		s.zIndex = '1.5';
+		s.backgroundColor = '#0f08';
+		log.push(s.color, s.width, s.display, s.opacity, s.zIndex, s.backgroundColor);
+		s.cssText = 'top: 1px; top: up; left: inherit';
+		log.push(s.cssText);
+		log.join('|');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := `red|2px||||color: red; width: 2px;|DarkGray|calc(100% - 2em)|inline flex|.5||#0f08|top: 1px; left: inherit;`
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
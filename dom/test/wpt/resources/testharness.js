@@ -4395,9 +4395,7 @@
         if (expected_true !== true) {
             var msg = make_message(function_name, description,
                                    error, substitutions);
-            console.log('assert errorrrrr')
-            //throw new AssertionError(msg);
-            ____assert_fail(msg)
+            throw new AssertionError(msg);
         }
     }
 
@@ -0,0 +1,346 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"github.com/psilva261/sparklefs/logger"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	domTokenListCtor  *js.Object
+	domTokenListProto *js.Object
+	domStringMapCtor  *js.Object
+)
+
+// initDOMTokenList defines DOMTokenList, whose prototype provides the
+// methods and iteration of token lists, and DOMStringMap
+func initDOMTokenList() {
+	illegal := func(call js.ConstructorCall) *js.Object {
+		panic(vm.NewTypeError("Illegal constructor"))
+	}
+	domTokenListCtor = newCtor("DOMTokenList", illegal)
+	domStringMapCtor = newCtor("DOMStringMap", illegal)
+	for name, c := range map[string]*js.Object{"DOMTokenList": domTokenListCtor, "DOMStringMap": domStringMapCtor} {
+		proto := c.Get("prototype").(*js.Object)
+		proto.DefineDataPropertySymbol(js.SymToStringTag, vm.ToValue(name), js.FLAG_FALSE, js.FLAG_TRUE, js.FLAG_FALSE)
+	}
+	domTokenListProto = domTokenListCtor.Get("prototype").(*js.Object)
+	protoMembers(domTokenListProto, &DOMTokenList{}, nil)
+	protoMethods(domTokenListProto, "toString")
+	ap := vm.Get("Array").(*js.Object).Get("prototype").(*js.Object)
+	for _, k := range []string{"entries", "keys", "values", "forEach"} {
+		domTokenListProto.Set(k, ap.Get(k))
+	}
+	domTokenListProto.DefineDataPropertySymbol(js.SymIterator, ap.Get("values"), js.FLAG_TRUE, js.FLAG_TRUE, js.FLAG_FALSE)
+}
+
+// DOMTokenList is a live ordered set of the tokens of an attribute
+type DOMTokenList struct {
+	el   *Element
+	attr string
+}
+
+func (tl *DOMTokenList) Obj() *js.Object {
+	obj, ok := tlObjRefs[tl.el]
+	if ok {
+		return obj
+	}
+	obj = vm.NewDynamicObject(tl)
+	obj.SetPrototype(domTokenListProto)
+	tlObjRefs[tl.el] = obj
+	return obj
+}
+
+func (tl *DOMTokenList) tokens() (ts []string) {
+	seen := make(map[string]bool)
+	for _, t := range classes(attr(*tl.el.n, tl.attr)) {
+		if !seen[t] {
+			seen[t] = true
+			ts = append(ts, t)
+		}
+	}
+	return
+}
+
+// update runs the update steps which serialize the tokens into the
+// attribute
+func (tl *DOMTokenList) update(ts []string) {
+	if !hasAttr(*tl.el.n, tl.attr) && len(ts) == 0 {
+		return
+	}
+	setAttr(tl.el.n, tl.attr, strings.Join(ts, " "))
+}
+
+func validToken(t string) {
+	if t == "" {
+		throwDOMException("SyntaxError", "the token must not be empty")
+	}
+	if strings.ContainsAny(t, " \t\n\f\r") {
+		throwDOMException("InvalidCharacterError", "the token must not contain whitespace")
+	}
+}
+
+func indexOf(ts []string, t string) int {
+	for i, tt := range ts {
+		if tt == t {
+			return i
+		}
+	}
+	return -1
+}
+
+func (tl *DOMTokenList) Getters() map[string]bool {
+	return map[string]bool{
+		"length": true,
+		"value":  true,
+	}
+}
+
+func (tl *DOMTokenList) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+func (tl *DOMTokenList) Length() int {
+	return len(tl.tokens())
+}
+
+func (tl *DOMTokenList) Value() string {
+	return attr(*tl.el.n, tl.attr)
+}
+
+func (tl *DOMTokenList) Item(i int) js.Value {
+	ts := tl.tokens()
+	if i < 0 || i >= len(ts) {
+		return js.Null()
+	}
+	return vm.ToValue(ts[i])
+}
+
+func (tl *DOMTokenList) Contains(t string) bool {
+	return indexOf(tl.tokens(), t) >= 0
+}
+
+func (tl *DOMTokenList) Add(ts ...string) {
+	for _, t := range ts {
+		validToken(t)
+	}
+	cur := tl.tokens()
+	for _, t := range ts {
+		if indexOf(cur, t) < 0 {
+			cur = append(cur, t)
+		}
+	}
+	tl.update(cur)
+}
+
+func (tl *DOMTokenList) Remove(ts ...string) {
+	for _, t := range ts {
+		validToken(t)
+	}
+	cur := tl.tokens()
+	res := make([]string, 0, len(cur))
+	for _, t := range cur {
+		if indexOf(ts, t) < 0 {
+			res = append(res, t)
+		}
+	}
+	tl.update(res)
+}
+
+// Toggle removes the token if present or adds it otherwise. An optional
+// boolean forces adding or removing.
+func (tl *DOMTokenList) Toggle(t string, force ...any) bool {
+	validToken(t)
+	f, forced := false, false
+	if len(force) > 0 && force[0] != nil {
+		f, forced = force[0].(bool)
+	}
+	if tl.Contains(t) {
+		if !forced || !f {
+			tl.Remove(t)
+			return false
+		}
+		return true
+	}
+	if !forced || f {
+		tl.Add(t)
+		return true
+	}
+	return false
+}
+
+// Replace puts nu at the first position of old or nu and removes the
+// other occurrences of both
+func (tl *DOMTokenList) Replace(old, nu string) bool {
+	validToken(old)
+	validToken(nu)
+	cur := tl.tokens()
+	if indexOf(cur, old) < 0 {
+		return false
+	}
+	res := make([]string, 0, len(cur))
+	for _, t := range cur {
+		if t == old || t == nu {
+			if indexOf(res, nu) >= 0 {
+				continue
+			}
+			t = nu
+		}
+		res = append(res, t)
+	}
+	tl.update(res)
+	return true
+}
+
+func (tl *DOMTokenList) Supports(t string) bool {
+	panic(vm.NewTypeError("DOMTokenList.supports: no supported tokens defined"))
+}
+
+func (tl *DOMTokenList) ToString() string {
+	return tl.Value()
+}
+
+func (tl *DOMTokenList) Get(k string) js.Value {
+	if !tl.Getters()[k] && domTokenListProto.Get(k) != nil {
+		// methods and iteration are inherited from the prototype
+		return nil
+	}
+	if res, ok := GetCall(tl, k); ok {
+		return res
+	}
+	if i, err := strconv.Atoi(k); err == nil {
+		ts := tl.tokens()
+		if i >= 0 && i < len(ts) {
+			return vm.ToValue(ts[i])
+		}
+	}
+	return nil
+}
+
+func (tl *DOMTokenList) Set(k string, desc js.PropertyDescriptor) bool {
+	if k == "value" {
+		setAttr(tl.el.n, tl.attr, desc.Value.String())
+		return true
+	}
+	log.Printf("DOMTokenList: ignore set %v", k)
+	return false
+}
+
+func (tl *DOMTokenList) Has(k string) bool {
+	if i, err := strconv.Atoi(k); err == nil {
+		return i >= 0 && i < tl.Length()
+	}
+	return HasCall(tl, k)
+}
+
+func (tl *DOMTokenList) Delete(k string) bool {
+	return false
+}
+
+func (tl *DOMTokenList) Keys() (ks []string) {
+	for i := range tl.tokens() {
+		ks = append(ks, strconv.Itoa(i))
+	}
+	return
+}
+
+// DOMStringMap maps camel-cased names to data-* attributes
+type DOMStringMap struct {
+	el *Element
+}
+
+func (sm *DOMStringMap) Obj() *js.Object {
+	obj, ok := dsObjRefs[sm.el]
+	if ok {
+		return obj
+	}
+	obj = vm.NewDynamicObject(sm)
+	obj.SetPrototype(domStringMapCtor.Get("prototype").(*js.Object))
+	dsObjRefs[sm.el] = obj
+	return obj
+}
+
+// dataAttr converts a camel-cased name into its data-* attribute name
+func dataAttr(name string) string {
+	var b strings.Builder
+	b.WriteString("data-")
+	for _, r := range name {
+		if 'A' <= r && r <= 'Z' {
+			b.WriteRune('-')
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// dataName converts a data-* attribute name into its camel-cased name
+// or returns false if the attribute isn't exposed in the map
+func dataName(a string) (string, bool) {
+	if !strings.HasPrefix(a, "data-") {
+		return "", false
+	}
+	a = a[len("data-"):]
+	if strings.IndexFunc(a, unicode.IsUpper) >= 0 {
+		return "", false
+	}
+	var b strings.Builder
+	for i := 0; i < len(a); i++ {
+		if a[i] == '-' && i+1 < len(a) && 'a' <= a[i+1] && a[i+1] <= 'z' {
+			b.WriteByte(a[i+1] - 'a' + 'A')
+			i++
+			continue
+		}
+		b.WriteByte(a[i])
+	}
+	return b.String(), true
+}
+
+func (sm *DOMStringMap) Get(k string) js.Value {
+	a := dataAttr(k)
+	if !hasAttr(*sm.el.n, a) {
+		return nil
+	}
+	return vm.ToValue(attr(*sm.el.n, a))
+}
+
+func (sm *DOMStringMap) Set(k string, desc js.PropertyDescriptor) bool {
+	for i := 0; i+1 < len(k); i++ {
+		if k[i] == '-' && 'a' <= k[i+1] && k[i+1] <= 'z' {
+			throwDOMException("SyntaxError", "invalid dataset name "+k)
+		}
+	}
+	setAttr(sm.el.n, dataAttr(k), desc.Value.String())
+	return true
+}
+
+func (sm *DOMStringMap) Has(k string) bool {
+	return hasAttr(*sm.el.n, dataAttr(k))
+}
+
+func (sm *DOMStringMap) Delete(k string) bool {
+	rmAttr(sm.el.n, dataAttr(k))
+	return true
+}
+
+func (sm *DOMStringMap) Keys() (ks []string) {
+	for _, a := range sm.el.n.Attr {
+		if name, ok := dataName(a.Key); ok {
+			ks = append(ks, name)
+		}
+	}
+	return
+}
+
+func (el *Element) ClassList() *js.Object {
+	tl := &DOMTokenList{el: el, attr: "class"}
+	return tl.Obj()
+}
+
+func (el *Element) Dataset() *js.Object {
+	sm := &DOMStringMap{el: el}
+	return sm.Obj()
+}
@@ -0,0 +1,91 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"testing"
+)
+
+func TestClassList(t *testing.T) {
+	vm := js.New()
+	d, err := Init(vm, "https://example.com", `<html><body><div id="d" class="a  b a"></div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var el = document.getElementById('d');
+		var cl = el.classList;
+		var log = [cl === el.classList, cl.length, cl[1], cl.contains('b')];
+		cl.add('c', 'a');
+		cl.remove('b');
+		log.push(el.className);
+		log.push(cl.toggle('a'), cl.toggle('d', true), cl.toggle('d', true), cl.toggle('e', false));
+		log.push(cl.replace('c', 'f'), cl.replace('x', 'y'));
+		var ts = [];
+		for (var t of cl) {
+			ts.push(t);
+		}
+		log.push(ts.join('+'), Object.keys(cl).length, cl.item(5));
+		try {
+			cl.add('g h');
+		} catch (e) {
+			log.push(e.name);
+		}
+		var u = document.createElement('p');
+		u.className = 'p\u2003q';
+		u.classList.add('r\u00a0');
+		log.push(u.classList.length, u.classList.contains('r\u00a0'), document.getElementsByClassName('f\u2003').length);
+		u.className = 'a c b';
+		u.classList.replace('a', 'b');
+		log.push(u.className);
+		u.className = 'b c a';
+		u.classList.replace('a', 'b');
+		log.push(u.className);
+		var toggle = DOMTokenList.prototype.toggle;
+		DOMTokenList.prototype.toggle = function(t, force) {
+			return 'patched ' + toggle.call(this, t, force);
+		};
+		log.push(cl instanceof DOMTokenList, Object.prototype.toString.call(cl), String(cl), u.classList.toggle('x'));
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := "true,2,b,true,a c,false,true,true,false,true,false,f+d,2,,InvalidCharacterError,2,true,0,b c,b c,true,[object DOMTokenList],f d,patched true"
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+	if c := attr(*d.GetElementById("d").n, "class"); c != "f d" {
+		t.Fatalf("%v", c)
+	}
+}
+
+func TestDataset(t *testing.T) {
+	vm := js.New()
+	d, err := Init(vm, "https://example.com", `<html><body><div id="d" data-foo-bar="1" data-x="2" title="t"></div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var ds = document.getElementById('d').dataset;
+		var log = [ds.fooBar, ds.x, ds.title, 'x' in ds, Object.keys(ds).join('+')];
+		ds.someValue = 'v';
+		delete ds.x;
+		log.push(Object.keys(ds).join('+'));
+		try {
+			ds['a-b'] = 1;
+		} catch (e) {
+			log.push(e.name);
+		}
+		log.push(ds instanceof DOMStringMap, Object.prototype.toString.call(ds));
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != "1,2,,true,fooBar+x,fooBar+someValue,SyntaxError,true,[object DOMStringMap]" {
+		t.Fatalf("%v", v)
+	}
+	if v := attr(*d.GetElementById("d").n, "data-some-value"); v != "v" {
+		t.Fatalf("%v", v)
+	}
+}
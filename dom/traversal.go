@@ -2,20 +2,320 @@ package dom
 
 import (
 	"github.com/psilva261/sparkle/js"
+	"golang.org/x/net/html"
 )
 
-type TreeWalker struct{}
+// NodeFilter results
+const (
+	filterAccept = 1
+	filterReject = 2
+	filterSkip   = 3
+)
+
+var (
+	treeWalkerCtor   *js.Object
+	nodeIteratorCtor *js.Object
+)
 
-func NewTreeWalker() *TreeWalker {
-	return &TreeWalker{}
+// initTraversal defines the TreeWalker and NodeIterator interface
+// objects. Both are created through the document only.
+func initTraversal() {
+	illegal := func(call js.ConstructorCall) *js.Object {
+		panic(vm.NewTypeError("Illegal constructor"))
+	}
+	treeWalkerCtor = newCtor("TreeWalker", illegal)
+	nodeIteratorCtor = newCtor("NodeIterator", illegal)
+	ifaces := []struct {
+		name string
+		c    *js.Object
+		recv Gettable
+	}{
+		{"TreeWalker", treeWalkerCtor, &TreeWalker{}},
+		{"NodeIterator", nodeIteratorCtor, &NodeIterator{}},
+	}
+	for _, i := range ifaces {
+		proto := i.c.Get("prototype").(*js.Object)
+		proto.DefineDataPropertySymbol(js.SymToStringTag, vm.ToValue(i.name), js.FLAG_FALSE, js.FLAG_TRUE, js.FLAG_FALSE)
+		protoMembers(proto, i.recv, nil)
+	}
+}
+
+// treeNode returns the node wrapper (a Node, *Document or
+// *DocumentFragment) behind v or nil
+func treeNode(v any) any {
+	switch x := v.(type) {
+	case *js.Object:
+		if x == nil {
+			return nil
+		}
+		return treeNode(x.Export())
+	case *Document:
+		return x
+	case *DocumentFragment:
+		return x
+	case Node:
+		return x
+	}
+	return nil
+}
+
+// parentOf returns the parent of the tree node x or nil. Children of
+// fragments aren't linked to a parent html.Node, so the fragment is
+// found through the wrapper.
+func parentOf(x any) any {
+	nd, ok := x.(Node)
+	if !ok {
+		return nil
+	}
+	b := nd.base()
+	if p := b.n.Parent; p != nil {
+		if p == b.d.doc {
+			return b.d
+		}
+		return b.d.getNode(p)
+	}
+	if b.df != nil {
+		return b.df
+	}
+	return nil
+}
+
+func firstChildOf(x any) any {
+	switch v := x.(type) {
+	case *Document:
+		if c := v.doc.FirstChild; c != nil {
+			return v.getNode(c)
+		}
+	case *DocumentFragment:
+		if len(v.children) > 0 {
+			return v.d.getNode(v.children[0])
+		}
+	case Node:
+		if c := v.base().n.FirstChild; c != nil {
+			return v.base().d.getNode(c)
+		}
+	}
+	return nil
+}
+
+func lastChildOf(x any) any {
+	switch v := x.(type) {
+	case *Document:
+		if c := v.doc.LastChild; c != nil {
+			return v.getNode(c)
+		}
+	case *DocumentFragment:
+		if len(v.children) > 0 {
+			return v.d.getNode(v.children[len(v.children)-1])
+		}
+	case Node:
+		if c := v.base().n.LastChild; c != nil {
+			return v.base().d.getNode(c)
+		}
+	}
+	return nil
+}
+
+// siblingOf returns the next (or previous if prev is set) sibling of
+// the tree node x or nil
+func siblingOf(x any, prev bool) any {
+	nd, ok := x.(Node)
+	if !ok {
+		return nil
+	}
+	b := nd.base()
+	if b.n.Parent == nil && b.df != nil {
+		cs := b.df.children
+		for i, c := range cs {
+			if c != b.n {
+				continue
+			}
+			if prev && i > 0 {
+				return b.d.getNode(cs[i-1])
+			} else if !prev && i+1 < len(cs) {
+				return b.d.getNode(cs[i+1])
+			}
+			break
+		}
+		return nil
+	}
+	s := b.n.NextSibling
+	if prev {
+		s = b.n.PrevSibling
+	}
+	if s == nil {
+		return nil
+	}
+	return b.d.getNode(s)
+}
+
+func nextSiblingOf(x any) any {
+	return siblingOf(x, false)
+}
+
+func previousSiblingOf(x any) any {
+	return siblingOf(x, true)
+}
+
+// isInclusiveAncestor is true if a is x or one of its ancestors
+func isInclusiveAncestor(a, x any) bool {
+	for ; x != nil; x = parentOf(x) {
+		if x == a {
+			return true
+		}
+	}
+	return false
+}
+
+// following returns the node after x in tree order within root or nil.
+// The descendants of x are skipped if descendants is false.
+func following(x, root any, descendants bool) any {
+	if descendants {
+		if c := firstChildOf(x); c != nil {
+			return c
+		}
+	}
+	for ; x != nil && x != root; x = parentOf(x) {
+		if s := nextSiblingOf(x); s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+// preceding returns the node before x in tree order within root or nil
+func preceding(x, root any) any {
+	if x == root {
+		return nil
+	}
+	if s := previousSiblingOf(x); s != nil {
+		for c := lastChildOf(s); c != nil; c = lastChildOf(s) {
+			s = c
+		}
+		return s
+	}
+	return parentOf(x)
+}
+
+// ownerOf returns the node document of the tree node x
+func ownerOf(x any) *Document {
+	switch v := x.(type) {
+	case *Document:
+		return v
+	case *DocumentFragment:
+		return v.d
+	}
+	return x.(Node).base().d
+}
+
+func treeNodeObj(x any) js.Value {
+	if x == nil {
+		return js.Null()
+	}
+	return x.(interface{ Obj() *js.Object }).Obj()
+}
+
+func treeNodeType(x any) int {
+	return x.(interface{ NodeType() int }).NodeType()
+}
+
+// traverser is the state shared by TreeWalker and NodeIterator
+type traverser struct {
+	root       any
+	whatToShow uint32
+	filter     js.Value
+	active     bool
+}
+
+func newTraverser(v any, opts []js.Value) (t traverser) {
+	t.root = treeNode(v)
+	if t.root == nil {
+		panic(vm.NewTypeError("parameter 1 is not of type 'Node'"))
+	}
+	t.whatToShow = 0xFFFFFFFF
+	if len(opts) > 0 && opts[0] != nil && !js.IsUndefined(opts[0]) {
+		t.whatToShow = uint32(opts[0].ToInteger())
+	}
+	t.filter = js.Null()
+	if len(opts) > 1 && opts[1] != nil && !js.IsUndefined(opts[1]) && !js.IsNull(opts[1]) {
+		if _, ok := opts[1].(*js.Object); !ok {
+			panic(vm.NewTypeError("parameter 3 is not of type 'NodeFilter'"))
+		}
+		t.filter = opts[1]
+	}
+	return
+}
+
+// filterNode returns whether x is accepted, rejected or skipped.
+// Exceptions thrown by the filter are propagated.
+func (t *traverser) filterNode(x any) int {
+	if t.active {
+		throwDOMException("InvalidStateError", "the filter is already running")
+	}
+	if t.whatToShow&(1<<(treeNodeType(x)-1)) == 0 {
+		return filterSkip
+	}
+	if js.IsNull(t.filter) {
+		return filterAccept
+	}
+	t.active = true
+	defer func() { t.active = false }()
+	// errors of invoking the filter are thrown in its realm
+	if revoked(t.filter) {
+		throwTypeError(t.filter, "NodeFilter: proxy has been revoked")
+	}
+	var this js.Value = js.Undefined()
+	fn, ok := js.AssertFunction(t.filter)
+	if !ok {
+		this = t.filter
+		v := t.filter.(*js.Object).Get("acceptNode")
+		if fn, ok = js.AssertFunction(v); !ok || revoked(v) {
+			throwTypeError(t.filter, "NodeFilter: acceptNode is not a function")
+		}
+	}
+	res, err := fn(this, treeNodeObj(x))
+	if err != nil {
+		panic(err)
+	}
+	return int(uint16(res.ToInteger()))
+}
+
+func (t *traverser) Root() js.Value {
+	return treeNodeObj(t.root)
+}
+
+func (t *traverser) WhatToShow() int {
+	return int(t.whatToShow)
+}
+
+func (t *traverser) Filter() js.Value {
+	return t.filter
+}
+
+// TreeWalker implements the DOM TreeWalker interface
+type TreeWalker struct {
+	traverser
+	current any
+}
+
+func (d *Document) CreateTreeWalker(root any, opts ...js.Value) *TreeWalker {
+	tw := &TreeWalker{traverser: newTraverser(root, opts)}
+	tw.current = tw.root
+	return tw
 }
 
 func (tw *TreeWalker) Obj() *js.Object {
-	return vm.NewDynamicObject(tw)
+	o := vm.NewDynamicObject(tw)
+	o.SetPrototype(treeWalkerCtor.Get("prototype").(*js.Object))
+	return o
 }
 
 func (tw *TreeWalker) Getters() map[string]bool {
 	return map[string]bool{
+		"root":        true,
+		"whatToShow":  true,
+		"filter":      true,
+		"currentNode": true,
 	}
 }
 
@@ -27,10 +327,18 @@ func (tw *TreeWalker) Get(k string) (v js.Value) {
 	if res, ok := GetCall(tw, k); ok {
 		return res
 	}
-	return vm.ToValue(nil)
+	// inherited from the prototype, e.g. constructor
+	return nil
 }
 
 func (tw *TreeWalker) Set(k string, desc js.PropertyDescriptor) bool {
+	if k == "currentNode" {
+		x := treeNode(desc.Value.Export())
+		if x == nil {
+			panic(vm.NewTypeError("currentNode: value is not of type 'Node'"))
+		}
+		tw.current = x
+	}
 	return true
 }
 
@@ -48,3 +356,307 @@ func (tw *TreeWalker) Delete(k string) bool {
 func (tw *TreeWalker) Keys() []string {
 	return []string{""}
 }
+
+func (tw *TreeWalker) ToString() string {
+	return "[object TreeWalker]"
+}
+
+func (tw *TreeWalker) CurrentNode() js.Value {
+	return treeNodeObj(tw.current)
+}
+
+// accept sets the current node to x and returns it
+func (tw *TreeWalker) accept(x any) js.Value {
+	tw.current = x
+	return treeNodeObj(x)
+}
+
+func (tw *TreeWalker) ParentNode() js.Value {
+	for x := tw.current; x != nil && x != tw.root; {
+		x = parentOf(x)
+		if x != nil && tw.filterNode(x) == filterAccept {
+			return tw.accept(x)
+		}
+	}
+	return js.Null()
+}
+
+func (tw *TreeWalker) FirstChild() js.Value {
+	return tw.traverseChildren(false)
+}
+
+func (tw *TreeWalker) LastChild() js.Value {
+	return tw.traverseChildren(true)
+}
+
+func (tw *TreeWalker) NextSibling() js.Value {
+	return tw.traverseSiblings(false)
+}
+
+func (tw *TreeWalker) PreviousSibling() js.Value {
+	return tw.traverseSiblings(true)
+}
+
+// traverseChildren returns the first (or last) visible child of the
+// current node
+func (tw *TreeWalker) traverseChildren(last bool) js.Value {
+	first, next := firstChildOf, nextSiblingOf
+	if last {
+		first, next = lastChildOf, previousSiblingOf
+	}
+	x := first(tw.current)
+	for x != nil {
+		switch tw.filterNode(x) {
+		case filterAccept:
+			return tw.accept(x)
+		case filterSkip:
+			if c := first(x); c != nil {
+				x = c
+				continue
+			}
+		}
+		for x != nil {
+			if s := next(x); s != nil {
+				x = s
+				break
+			}
+			p := parentOf(x)
+			if p == nil || p == tw.root || p == tw.current {
+				return js.Null()
+			}
+			x = p
+		}
+	}
+	return js.Null()
+}
+
+// traverseSiblings returns the next (or previous) visible sibling of
+// the current node
+func (tw *TreeWalker) traverseSiblings(prev bool) js.Value {
+	first, next := firstChildOf, nextSiblingOf
+	if prev {
+		first, next = lastChildOf, previousSiblingOf
+	}
+	x := tw.current
+	if x == tw.root {
+		return js.Null()
+	}
+	for {
+		s := next(x)
+		for s != nil {
+			x = s
+			res := tw.filterNode(x)
+			if res == filterAccept {
+				return tw.accept(x)
+			}
+			s = first(x)
+			if res == filterReject || s == nil {
+				s = next(x)
+			}
+		}
+		x = parentOf(x)
+		if x == nil || x == tw.root {
+			return js.Null()
+		}
+		if tw.filterNode(x) == filterAccept {
+			return js.Null()
+		}
+	}
+}
+
+func (tw *TreeWalker) PreviousNode() js.Value {
+	x := tw.current
+	for x != tw.root {
+		for s := previousSiblingOf(x); s != nil; s = previousSiblingOf(x) {
+			x = s
+			res := tw.filterNode(x)
+			for c := lastChildOf(x); res != filterReject && c != nil; c = lastChildOf(x) {
+				x = c
+				res = tw.filterNode(x)
+			}
+			if res == filterAccept {
+				return tw.accept(x)
+			}
+		}
+		p := parentOf(x)
+		if x == tw.root || p == nil {
+			return js.Null()
+		}
+		x = p
+		if tw.filterNode(x) == filterAccept {
+			return tw.accept(x)
+		}
+	}
+	return js.Null()
+}
+
+func (tw *TreeWalker) NextNode() js.Value {
+	x := tw.current
+	res := filterAccept
+	for {
+		for c := firstChildOf(x); res != filterReject && c != nil; c = firstChildOf(x) {
+			x = c
+			if res = tw.filterNode(x); res == filterAccept {
+				return tw.accept(x)
+			}
+		}
+		var s any
+		for t := x; t != nil && s == nil; t = parentOf(t) {
+			if t == tw.root {
+				return js.Null()
+			}
+			s = nextSiblingOf(t)
+		}
+		if s == nil {
+			return js.Null()
+		}
+		x = s
+		if res = tw.filterNode(x); res == filterAccept {
+			return tw.accept(x)
+		}
+	}
+}
+
+// NodeIterator implements the DOM NodeIterator interface
+type NodeIterator struct {
+	traverser
+	reference any
+	before    bool
+}
+
+// CreateNodeIterator returns a node iterator which is updated on
+// removals in the node document of root
+func (d *Document) CreateNodeIterator(root any, opts ...js.Value) *NodeIterator {
+	it := &NodeIterator{
+		traverser: newTraverser(root, opts),
+		before:    true,
+	}
+	it.reference = it.root
+	od := ownerOf(it.root)
+	od.iterators = append(od.iterators, it)
+	return it
+}
+
+func (it *NodeIterator) Obj() *js.Object {
+	o := vm.NewDynamicObject(it)
+	o.SetPrototype(nodeIteratorCtor.Get("prototype").(*js.Object))
+	return o
+}
+
+func (it *NodeIterator) Getters() map[string]bool {
+	return map[string]bool{
+		"root":                       true,
+		"whatToShow":                 true,
+		"filter":                     true,
+		"referenceNode":              true,
+		"pointerBeforeReferenceNode": true,
+	}
+}
+
+func (it *NodeIterator) Props() map[string]bool {
+	return map[string]bool{}
+}
+
+func (it *NodeIterator) Get(k string) (v js.Value) {
+	if res, ok := GetCall(it, k); ok {
+		return res
+	}
+	// inherited from the prototype, e.g. constructor
+	return nil
+}
+
+func (it *NodeIterator) Set(k string, desc js.PropertyDescriptor) bool {
+	return true
+}
+
+func (it *NodeIterator) Has(k string) bool {
+	if yes := HasCall(it, k); yes {
+		return true
+	}
+	return false
+}
+
+func (it *NodeIterator) Delete(k string) bool {
+	return false
+}
+
+func (it *NodeIterator) Keys() []string {
+	return []string{""}
+}
+
+func (it *NodeIterator) ToString() string {
+	return "[object NodeIterator]"
+}
+
+func (it *NodeIterator) ReferenceNode() js.Value {
+	return treeNodeObj(it.reference)
+}
+
+func (it *NodeIterator) PointerBeforeReferenceNode() bool {
+	return it.before
+}
+
+// Detach does nothing as required by the DOM standard
+func (it *NodeIterator) Detach() {}
+
+func (it *NodeIterator) NextNode() js.Value {
+	return it.traverse(true)
+}
+
+func (it *NodeIterator) PreviousNode() js.Value {
+	return it.traverse(false)
+}
+
+func (it *NodeIterator) traverse(next bool) js.Value {
+	x, before := it.reference, it.before
+	for {
+		if next {
+			if !before {
+				if x = following(x, it.root, true); x == nil {
+					return js.Null()
+				}
+			}
+			before = false
+		} else {
+			if before {
+				if x = preceding(x, it.root); x == nil {
+					return js.Null()
+				}
+			}
+			before = true
+		}
+		if it.filterNode(x) == filterAccept {
+			break
+		}
+	}
+	it.reference, it.before = x, before
+	return treeNodeObj(x)
+}
+
+// preRemove moves the reference node out of x which is about to be
+// removed from its parent
+func (it *NodeIterator) preRemove(x any) {
+	if isInclusiveAncestor(x, it.root) || !isInclusiveAncestor(x, it.reference) {
+		return
+	}
+	if it.before {
+		if n := following(x, it.root, false); n != nil {
+			it.reference = n
+			return
+		}
+		it.before = false
+	}
+	it.reference = preceding(x, it.root)
+}
+
+// preRemove runs the removing steps of the node iterators of d for the
+// node n which is about to be removed from its parent
+func (d *Document) preRemove(n *html.Node) {
+	if len(d.iterators) == 0 {
+		return
+	}
+	x := d.getNode(n)
+	for _, it := range d.iterators {
+		it.preRemove(x)
+	}
+}
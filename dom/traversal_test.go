@@ -0,0 +1,107 @@
+package dom
+
+import (
+	"github.com/psilva261/sparkle/js"
+	"testing"
+)
+
+func TestTreeWalker(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><body><div id="r"><p id="a">x<b id="b"></b></p><!--c--><p id="c"><i id="d"></i></p></div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var r = document.getElementById('r');
+		var tw = document.createTreeWalker(r, NodeFilter.SHOW_ELEMENT);
+		var log = [tw.toString(), tw.root === r, tw.whatToShow, tw.filter];
+		var ids = [];
+		for (var n = tw.nextNode(); n; n = tw.nextNode()) ids.push(n.id);
+		log.push(ids.join(''), tw.currentNode.id, tw.previousNode().id, tw.parentNode().id, tw.parentNode(), tw.currentNode.id);
+		tw.currentNode = document.getElementById('a');
+		log.push(tw.nextSibling().id, tw.previousSibling().id, tw.lastChild().id);
+		var skipP = document.createTreeWalker(r, NodeFilter.SHOW_ALL, function(n) {
+			return n.nodeName == 'P' ? NodeFilter.FILTER_SKIP : NodeFilter.FILTER_ACCEPT;
+		});
+		log.push(skipP.firstChild().nodeName, skipP.nextSibling().id);
+		var rejectP = document.createTreeWalker(r, NodeFilter.SHOW_ELEMENT, {acceptNode: function(n) {
+			return n.nodeName == 'P' ? NodeFilter.FILTER_REJECT : NodeFilter.FILTER_ACCEPT;
+		}});
+		log.push(rejectP.nextNode());
+		try { document.createTreeWalker(null) } catch (e) { log.push(e.name) }
+		try { new TreeWalker() } catch (e) { log.push(e.name) }
+		log.push(tw instanceof TreeWalker, typeof TreeWalker.prototype.nextNode, 'currentNode' in TreeWalker.prototype);
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := `[object TreeWalker],true,1,,abcd,d,c,r,,r,c,a,b,#text,b,,TypeError,TypeError,true,function,true`
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestNodeIterator(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><body><div id="r"><p id="a"><b id="b"></b></p><p id="c"></p></div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var r = document.getElementById('r');
+		var it = document.createNodeIterator(r, NodeFilter.SHOW_ELEMENT);
+		var log = [it.toString(), it.referenceNode === r, it.pointerBeforeReferenceNode];
+		log.push(it.nextNode().id, it.nextNode().id, it.nextNode().id, it.referenceNode.id, it.pointerBeforeReferenceNode);
+		document.getElementById('a').remove();
+		log.push(it.referenceNode.id, it.pointerBeforeReferenceNode);
+		log.push(it.nextNode().id, it.nextNode(), it.previousNode().id, it.previousNode().id, it.previousNode());
+		var frag = document.createDocumentFragment();
+		frag.append(document.createElement('i'), 't');
+		var fit = document.createNodeIterator(frag);
+		log.push(fit.nextNode().nodeName, fit.nextNode().nodeName, fit.nextNode().nodeName, fit.nextNode());
+		var rec = document.createNodeIterator(r, NodeFilter.SHOW_ALL, function() { rec.nextNode(); return 1 });
+		try { rec.nextNode() } catch (e) { log.push(e.name) }
+		log.push(it instanceof NodeIterator, typeof NodeIterator.prototype.detach, 'referenceNode' in NodeIterator.prototype);
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	exp := `[object NodeIterator],true,true,r,a,b,b,false,r,false,c,,c,r,,#document-fragment,I,#text,,InvalidStateError,true,function,true`
+	if v := res.String(); v != exp {
+		t.Fatalf("%v", v)
+	}
+}
+
+func TestFilterRealm(t *testing.T) {
+	vm := js.New()
+	_, err := Init(vm, "https://example.com", `<html><body><iframe name="f"></iframe><div id="r"><p></p></div></body></html>`, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	res, err := vm.RunString(`
+		var log = [f === document.querySelector('iframe').contentWindow, f.TypeError === TypeError, r.id];
+		function walk(filter) {
+			try {
+				document.createTreeWalker(r, NodeFilter.SHOW_ELEMENT, filter).firstChild();
+			} catch (e) {
+				return e.constructor === f.TypeError ? 'frame' : e.constructor === TypeError ? 'window' : e;
+			}
+		}
+		log.push(walk(new f.Object), walk({}));
+		var p = f.Proxy.revocable(function() {}, {});
+		p.revoke();
+		log.push(walk(p.proxy));
+		var iframe = document.querySelector('iframe');
+		iframe.remove();
+		log.push(iframe.contentWindow, typeof f);
+		log.join(',');
+	`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if v := res.String(); v != "true,false,r,frame,window,frame,,undefined" {
+		t.Fatalf("%v", v)
+	}
+}
@@ -0,0 +1,224 @@
+package dom
+
+import (
+	"regexp"
+	"strings"
+)
+
+// grammar of a longhand value: one of the keywords or a value of the
+// enabled types. With multi each whitespace separated token is checked.
+type grammar struct {
+	keywords   []string
+	color      bool
+	length     bool
+	percentage bool
+	number     bool
+	integer    bool
+	multi      bool
+}
+
+var (
+	colorGrammar        = grammar{color: true}
+	borderStyleGrammar  = grammar{keywords: []string{"none", "hidden", "dotted", "dashed", "solid", "double", "groove", "ridge", "inset", "outset"}}
+	borderWidthGrammar  = grammar{keywords: []string{"thin", "medium", "thick"}, length: true}
+	insetGrammar        = grammar{keywords: []string{"auto"}, length: true, percentage: true}
+	sizeGrammar         = grammar{keywords: []string{"auto", "min-content", "max-content", "fit-content"}, length: true, percentage: true}
+	maxSizeGrammar      = grammar{keywords: []string{"none", "min-content", "max-content", "fit-content"}, length: true, percentage: true}
+	overflowAxisGrammar = grammar{keywords: []string{"visible", "hidden", "clip", "scroll", "auto"}}
+	lengthGrammar       = grammar{length: true, percentage: true}
+	flexFactorGrammar   = grammar{number: true}
+	displayGrammar      = grammar{keywords: []string{
+		"block", "inline", "run-in", "flow", "flow-root", "table", "flex",
+		"grid", "ruby", "list-item", "contents", "none", "inline-block",
+		"inline-table", "inline-flex", "inline-grid", "table-row-group",
+		"table-header-group", "table-footer-group", "table-row",
+		"table-cell", "table-column-group", "table-column", "table-caption",
+		"ruby-base", "ruby-text", "ruby-base-container",
+		"ruby-text-container", "-webkit-box", "-webkit-inline-box",
+		"-webkit-flex", "-webkit-inline-flex", "-ms-flexbox",
+		"-ms-inline-flexbox", "-moz-box", "-moz-inline-box",
+	}, multi: true}
+)
+
+// grammars of the validated longhands. Values of other properties
+// aren't checked.
+var grammars = map[string]grammar{
+	"color":                 colorGrammar,
+	"background-color":      colorGrammar,
+	"border-top-color":      colorGrammar,
+	"border-right-color":    colorGrammar,
+	"border-bottom-color":   colorGrammar,
+	"border-left-color":     colorGrammar,
+	"outline-color":         colorGrammar,
+	"text-decoration-color": colorGrammar,
+	"border-top-style":      borderStyleGrammar,
+	"border-right-style":    borderStyleGrammar,
+	"border-bottom-style":   borderStyleGrammar,
+	"border-left-style":     borderStyleGrammar,
+	"outline-style":         {keywords: append([]string{"auto"}, borderStyleGrammar.keywords...)},
+	"border-top-width":      borderWidthGrammar,
+	"border-right-width":    borderWidthGrammar,
+	"border-bottom-width":   borderWidthGrammar,
+	"border-left-width":     borderWidthGrammar,
+	"outline-width":         borderWidthGrammar,
+	"top":                   insetGrammar,
+	"right":                 insetGrammar,
+	"bottom":                insetGrammar,
+	"left":                  insetGrammar,
+	"margin-top":            insetGrammar,
+	"margin-right":          insetGrammar,
+	"margin-bottom":         insetGrammar,
+	"margin-left":           insetGrammar,
+	"padding-top":           lengthGrammar,
+	"padding-right":         lengthGrammar,
+	"padding-bottom":        lengthGrammar,
+	"padding-left":          lengthGrammar,
+	"width":                 sizeGrammar,
+	"height":                sizeGrammar,
+	"min-width":             sizeGrammar,
+	"min-height":            sizeGrammar,
+	"max-width":             maxSizeGrammar,
+	"max-height":            maxSizeGrammar,
+	"display":               displayGrammar,
+	"position":              {keywords: []string{"static", "relative", "absolute", "fixed", "sticky", "-webkit-sticky"}},
+	"float":                 {keywords: []string{"none", "left", "right", "inline-start", "inline-end"}},
+	"clear":                 {keywords: []string{"none", "left", "right", "both", "inline-start", "inline-end"}},
+	"visibility":            {keywords: []string{"visible", "hidden", "collapse"}},
+	"box-sizing":            {keywords: []string{"content-box", "border-box"}},
+	"overflow-x":            overflowAxisGrammar,
+	"overflow-y":            overflowAxisGrammar,
+	"opacity":               {number: true, percentage: true},
+	"z-index":               {keywords: []string{"auto"}, integer: true},
+	"order":                 {integer: true},
+	"flex-grow":             flexFactorGrammar,
+	"flex-shrink":           flexFactorGrammar,
+	"flex-basis":            {keywords: []string{"auto", "content", "min-content", "max-content", "fit-content"}, length: true, percentage: true},
+	"flex-direction":        {keywords: []string{"row", "row-reverse", "column", "column-reverse"}},
+	"flex-wrap":             {keywords: []string{"nowrap", "wrap", "wrap-reverse"}},
+	"font-size":             {keywords: []string{"xx-small", "x-small", "small", "medium", "large", "x-large", "xx-large", "xxx-large", "larger", "smaller", "math"}, length: true, percentage: true},
+	"font-weight":           {keywords: []string{"normal", "bold", "bolder", "lighter"}, number: true},
+	"line-height":           {keywords: []string{"normal"}, number: true, length: true, percentage: true},
+	"text-align":            {keywords: []string{"start", "end", "left", "right", "center", "justify", "match-parent", "justify-all", "-webkit-center", "-moz-center"}},
+	"vertical-align":        {keywords: []string{"baseline", "sub", "super", "text-top", "text-bottom", "middle", "top", "bottom"}, length: true, percentage: true},
+	"white-space":           {keywords: []string{"normal", "pre", "nowrap", "pre-wrap", "break-spaces", "pre-line"}},
+}
+
+// validValue is false if v doesn't match the grammar of the longhand p
+func validValue(p, v string) bool {
+	g, ok := grammars[p]
+	if !ok || isWideKeyword(v) || strings.Contains(v, "var(") {
+		return true
+	}
+	if !g.multi {
+		return g.match(v)
+	}
+	for _, t := range splitValue(v, ' ') {
+		if !g.match(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func (g grammar) match(v string) bool {
+	lv := strings.ToLower(v)
+	for _, k := range g.keywords {
+		if lv == k {
+			return true
+		}
+	}
+	switch {
+	case g.color && isColor(lv):
+		return true
+	case isMathFunction(lv):
+		return g.length || g.percentage || g.number || g.integer
+	}
+	num, unit, ok := dimension(lv)
+	if !ok {
+		return false
+	}
+	switch {
+	case unit == "":
+		return g.number || g.integer && !strings.ContainsAny(num, ".e") || g.length && strings.Trim(num, "+-.0") == ""
+	case unit == "%":
+		return g.percentage
+	}
+	return g.length && lengthUnits[unit]
+}
+
+var lengthUnits = map[string]bool{
+	"px": true, "em": true, "rem": true, "ex": true, "rex": true,
+	"ch": true, "rch": true, "cap": true, "rcap": true, "ic": true,
+	"ric": true, "lh": true, "rlh": true, "vw": true, "vh": true,
+	"vi": true, "vb": true, "vmin": true, "vmax": true, "svw": true,
+	"svh": true, "lvw": true, "lvh": true, "dvw": true, "dvh": true,
+	"cqw": true, "cqh": true, "cqi": true, "cqb": true, "cqmin": true,
+	"cqmax": true, "cm": true, "mm": true, "q": true, "in": true,
+	"pt": true, "pc": true,
+}
+
+var numberRe = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]+)?|\.[0-9]+)(e[+-]?[0-9]+)?`)
+
+// dimension splits v into a number and its unit which may be empty
+func dimension(v string) (num, unit string, ok bool) {
+	num = numberRe.FindString(v)
+	if num == "" {
+		return "", "", false
+	}
+	unit = v[len(num):]
+	if unit != "%" && strings.Trim(unit, "abcdefghijklmnopqrstuvwxyz") != "" {
+		return "", "", false
+	}
+	return num, unit, true
+}
+
+func isMathFunction(v string) bool {
+	for _, f := range []string{"calc(", "min(", "max(", "clamp(", "-webkit-calc(", "-moz-calc("} {
+		if strings.HasPrefix(v, f) && strings.HasSuffix(v, ")") {
+			return true
+		}
+	}
+	return false
+}
+
+// colorKeywords are the named and system colors
+var colorKeywords = make(map[string]bool)
+
+func init() {
+	for _, c := range strings.Fields(`
+		aliceblue antiquewhite aqua aquamarine azure beige bisque black
+		blanchedalmond blue blueviolet brown burlywood cadetblue chartreuse
+		chocolate coral cornflowerblue cornsilk crimson cyan darkblue
+		darkcyan darkgoldenrod darkgray darkgreen darkgrey darkkhaki
+		darkmagenta darkolivegreen darkorange darkorchid darkred
+		darksalmon darkseagreen darkslateblue darkslategray darkslategrey
+		darkturquoise darkviolet deeppink deepskyblue dimgray dimgrey
+		dodgerblue firebrick floralwhite forestgreen fuchsia gainsboro
+		ghostwhite gold goldenrod gray green greenyellow grey honeydew
+		hotpink indianred indigo ivory khaki lavender lavenderblush
+		lawngreen lemonchiffon lightblue lightcoral lightcyan
+		lightgoldenrodyellow lightgray lightgreen lightgrey lightpink
+		lightsalmon lightseagreen lightskyblue lightslategray
+		lightslategrey lightsteelblue lightyellow lime limegreen linen
+		magenta maroon mediumaquamarine mediumblue mediumorchid
+		mediumpurple mediumseagreen mediumslateblue mediumspringgreen
+		mediumturquoise mediumvioletred midnightblue mintcream mistyrose
+		moccasin navajowhite navy oldlace olive olivedrab orange orangered
+		orchid palegoldenrod palegreen paleturquoise palevioletred
+		papayawhip peachpuff peru pink plum powderblue purple rebeccapurple
+		red rosybrown royalblue saddlebrown salmon sandybrown seagreen
+		seashell sienna silver skyblue slateblue slategray slategrey snow
+		springgreen steelblue tan teal thistle tomato turquoise violet
+		wheat white whitesmoke yellow yellowgreen transparent currentcolor
+		accentcolor accentcolortext activetext buttonborder buttonface
+		buttontext canvas canvastext field fieldtext graytext highlight
+		highlighttext linktext mark marktext selecteditem selecteditemtext
+		visitedtext activeborder activecaption appworkspace background
+		buttonhighlight buttonshadow captiontext inactiveborder
+		inactivecaption inactivecaptiontext infobackground infotext menu
+		menutext scrollbar threeddarkshadow threedface threedhighlight
+		threedlightshadow threedshadow window windowframe windowtext
+	`) {
+		colorKeywords[c] = true
+	}
+}
@@ -2,6 +2,7 @@ package dom
 
 import (
 	"errors"
+	"fmt"
 	"github.com/psilva261/sparkle/console"
 	"github.com/psilva261/sparkle/eventloop"
 	"github.com/psilva261/sparkle/js"
@@ -9,14 +10,113 @@ import (
 	"github.com/psilva261/sparklefs/logger"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 )
 
-func W3C(testFn string) (err error) {
+// W3CResult is the result of a W3C DOM conformance test. Status is
+// PASS, FAIL (an assertion failed), ERROR (an exception was thrown) or
+// SKIP (a feature required by the test isn't supported).
+type W3CResult struct {
+	File    string `json:"file"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// w3cFileExts are tried in order when the harness loads a document
+var w3cFileExts = []string{".html", ".xhtml", ".xml", ".svg"}
+
+// w3cHarness defines the globals which DomTestCase.js expects from the
+// test environment
+const w3cHarness = `
+alert = console.log;
+__dirname = '';
+Path = {
+	resolve: function() {}
+};
+assertTrue = function(message, actual) {
+	assertEquals(message, true, actual);
+};
+assertFalse = function(message, actual) {
+	assertEquals(message, true, !actual);
+};
+assertNull = function(message, actual) {
+	assertEquals(message, null, actual);
+};
+assertNotNull = function(message, actual) {
+	assertNotEqual(message, null, actual);
+};
+info = function(msg) {
+	____w3c_skip(String(msg));
+};
+createConfiguredBuilder = function() {
+	return {
+		contentType: 'text/html',
+		skipIncompatibleTests: true,
+		hasFeature: function(feature, version) {
+			return document.implementation.hasFeature(feature, version);
+		},
+		getImplementation: function() {
+			return document.implementation;
+		},
+		setImplementationAttribute: function(attr, value) {
+			// Ignore
+		},
+		preload: function(docRef, name, href) {
+			return 1;
+		},
+		load: function(docRef, name, href) {
+			return initTheDocument(href);
+		}
+	};
+};
+`
+
+// errW3CAssertion is thrown into JS when an assertion fails
+var errW3CAssertion = errors.New("assertion failed")
+
+// RunW3C runs the W3C DOM test fn of the suite in dir (e.g.
+// test/w3c/level1/core) in its own runtime. Documents are loaded from
+// dir/files.
+func RunW3C(dir, fn string, timeout time.Duration) (res *W3CResult) {
+	res = &W3CResult{File: fn}
+	harness, err := os.ReadFile("test/w3c/harness/DomTestCase.js")
+	if err != nil {
+		res.Status, res.Message = "ERROR", err.Error()
+		return
+	}
+	code, err := os.ReadFile(filepath.Join(dir, fn))
+	if err != nil {
+		res.Status, res.Message = "ERROR", err.Error()
+		return
+	}
+
+	var mu sync.Mutex
+	done := make(chan struct{})
+	var once sync.Once
+	finish := func(status, msg string) {
+		once.Do(func() {
+			mu.Lock()
+			res.Status, res.Message = status, msg
+			mu.Unlock()
+			close(done)
+		})
+	}
+
+	var rt *js.Runtime
 	l := eventloop.NewEventLoop()
 	l.Start()
 	defer l.Stop()
 	l.RunOnLoop(func(vm *js.Runtime) {
+		defer func() {
+			if r := recover(); r != nil {
+				finish("ERROR", fmt.Sprintf("panic: %v", r))
+			}
+		}()
+		rt = vm
 		registry := require.NewRegistry(
 			require.WithLoader(
 				require.SourceLoader(w3cSrcLoader),
@@ -24,116 +124,174 @@ func W3C(testFn string) (err error) {
 		)
 		console.Enable(vm)
 		registry.Enable(vm)
-		bs, err := os.ReadFile("test/w3c/harness/DomTestCase.js")
-		if err != nil {
-			log.Fatalf(err.Error())
-		}
-		_, err = vm.RunString(string(bs))
-		if err != nil {
-			log.Fatalf(err.Error())
-		}
-		bs, err = os.ReadFile("test/w3c/level1/core/" + testFn)
-		if err != nil {
-			log.Fatalf(err.Error())
-		}
-		_, err = vm.RunString(string(bs))
-		if err != nil {
-			log.Fatalf(err.Error())
-		}
-		vm.Set("readFileSync", vm.ToValue(func(fn string) (v js.Value) {
-			bs, err := os.ReadFile("test/w3c/level1/core/" + fn)
-			if err != nil {
-				log.Fatalf(err.Error())
-			}
-			return vm.ToValue(string(bs))
-		}))
-		vm.Set("initTheDocument", vm.ToValue(func(htm string) (o *js.Object) {
-			d, err := Init(vm, "https://example.com", htm, "")
-			if err != nil {
-				log.Fatalf(err.Error())
+
+		var failure, skipped string
+		fail := func(msg string) {
+			if failure == "" {
+				failure = msg
 			}
-			return d.Obj()
-		}))
-		vm.Set("assertEquals", vm.ToValue(func(msg string, exp, act js.Value) {
+			panic(vm.NewGoError(errW3CAssertion))
+		}
+		vm.Set("assertEquals", func(msg string, exp, act js.Value) {
 			if !exp.Equals(act) {
-				log.Printf("%v", msg)
-				log.Fatalf("%v (exp. %v but got %v)", msg, exp, act)
+				fail(fmt.Sprintf("%v (exp. %v but got %v)", msg, exp, act))
 			}
-		}))
-		vm.Set("assertNotEqual", vm.ToValue(func(msg string, exp, act js.Value) {
+		})
+		vm.Set("assertNotEqual", func(msg string, exp, act js.Value) {
 			if exp.Equals(act) {
-				log.Printf("%v", msg)
-				log.Fatalf("%v (exp. %v but did not want to get %v)", msg, exp, act)
+				fail(fmt.Sprintf("%v (exp. %v but did not want to get %v)", msg, exp, act))
 			}
-		}))
-		_, err = vm.RunString(`
-alert = console.log;
-__dirname = '';
-Path = {
-	resolve: function() {
+		})
+		vm.Set("____w3c_skip", func(msg string) {
+			skipped = msg
+		})
+		vm.Set("initTheDocument", func(href string) *js.Object {
+			for _, ext := range w3cFileExts {
+				bs, err := os.ReadFile(filepath.Join(dir, "files", href+ext))
+				if err != nil {
+					continue
+				}
+				d, err := Init(vm, "http://example.com/files/"+href+ext, string(bs), "")
+				if err != nil {
+					panic(vm.NewGoError(err))
+				}
+				return d.Obj()
+			}
+			panic(vm.NewGoError(fmt.Errorf("document %v not found", href)))
+		})
 
+		// an empty document provides the implementation before the
+		// test loads its documents
+		if _, err := Init(vm, "about:blank", "", ""); err != nil {
+			finish("ERROR", fmt.Sprintf("init: %v", err))
+			return
+		}
+		for _, s := range []struct {
+			src, code string
+		}{
+			{"DomTestCase.js", string(harness)},
+			{fn, string(code)},
+			{"harness", w3cHarness},
+			{"setUpPage", `setUpPage()`},
+			{"runTest", `runTest()`},
+		} {
+			if _, err := vm.RunString(s.code); err != nil {
+				if failure != "" {
+					finish("FAIL", failure)
+				} else {
+					finish("ERROR", fmt.Sprintf("%v: %v", s.src, err))
+				}
+				return
+			}
+		}
+		// tests may catch the thrown assertion themselves
+		switch {
+		case failure != "":
+			finish("FAIL", failure)
+		case skipped != "":
+			finish("SKIP", skipped)
+		default:
+			finish("PASS", "")
+		}
+	})
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		if rt != nil {
+			rt.Interrupt("timeout")
+		}
+		finish("ERROR", "timeout")
 	}
-};
-assertTrue = function(message, actual) {
-	assertEquals(message, true, actual);
-};
-assertFalse = function(message, actual) {
-	assertEquals(message, true, !actual);
-},
-assertNull = function(message, actual) {
-	assertEquals(message, null, actual);
-};
-assertNotNull = function(message, actual) {
-	assertNotEqual(actual, null, message);
-};
-impl = {
-	hasFeature: function(feature, version) {
-		console.log('hasFeature(' + feature + ', ' + version + ')');
-		if (feature == 'XML') {
-			return false;
+	mu.Lock()
+	defer mu.Unlock()
+	r := *res
+	return &r
+}
+
+// W3C runs the level 1 core test testFn and returns an error unless it
+// passed or was skipped
+func W3C(testFn string) (err error) {
+	res := RunW3C("test/w3c/level1/core", testFn, 10*time.Second)
+	if res.Status == "FAIL" || res.Status == "ERROR" {
+		return fmt.Errorf("%v: %v: %v", testFn, res.Status, res.Message)
+	}
+	return
+}
+
+// RunW3CSuite runs all tests of the suite in dir (non-recursively, so
+// tests moved into subdirectories like obsolete are excluded)
+func RunW3CSuite(dir string, timeout time.Duration) (rs []*W3CResult, err error) {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, de := range des {
+		if de.IsDir() || filepath.Ext(de.Name()) != ".js" {
+			continue
 		}
-		return true;
+		rs = append(rs, RunW3C(dir, de.Name(), timeout))
 	}
-};
-createConfiguredBuilder = function() {
-  return {
-    contentType: 'text/html',
-    hasFeature: function(feature, version) {
-      return impl.hasFeature(feature, version);
-    },
-    getImplementation: function() {
-      return impl;
-    },
-    setImplementationAttribute: function(attr, value) {
-      // Ignore
-    },
-    preload: function(docRef, name, href) {
-      return 1;
-    },
-    load: function(docRef, name, href) {
-      var doc = 'files/' + href + '.html';
-      var html = readFileSync(doc);
-      var url = 'http://example.com/'+['files',href].join('/')+'.html';
-      return initTheDocument(html);
-    }
-  };
-};
-		`)
+	return
+}
+
+// W3CSuites returns the suite directories like level1/core or
+// level2/events below root which contain tests
+func W3CSuites(root string) (suites []string, err error) {
+	levels, err := filepath.Glob(filepath.Join(root, "level*", "*"))
+	if err != nil {
+		return
+	}
+	for _, dir := range levels {
+		des, err := os.ReadDir(dir)
 		if err != nil {
-			log.Fatalf(err.Error())
+			continue
 		}
-		_, err = vm.RunString(`setUpPage()`)
-		if err != nil {
-			log.Fatalf(err.Error())
+		for _, de := range des {
+			if !de.IsDir() && filepath.Ext(de.Name()) == ".js" {
+				suites = append(suites, dir)
+				break
+			}
 		}
-		_, err = vm.RunString(`runTest()`)
-		if err != nil {
-			log.Fatalf(err.Error())
+	}
+	sort.Strings(suites)
+	return
+}
+
+// W3CPassRate returns the number of passed tests and of tests which
+// were not skipped
+func W3CPassRate(rs []*W3CResult) (passed, total int) {
+	for _, r := range rs {
+		switch r.Status {
+		case "PASS":
+			passed++
+		case "SKIP":
+			continue
 		}
-	})
+		total++
+	}
 	return
 }
 
+// W3CSummary formats the pass rate of each suite
+func W3CSummary(results map[string][]*W3CResult) string {
+	var b strings.Builder
+	suites := make([]string, 0, len(results))
+	for s := range results {
+		suites = append(suites, s)
+	}
+	sort.Strings(suites)
+	for _, s := range suites {
+		passed, total := W3CPassRate(results[s])
+		pct := 0.0
+		if total > 0 {
+			pct = 100 * float64(passed) / float64(total)
+		}
+		fmt.Fprintf(&b, "%v: %v/%v passed (%.1f%%), %v skipped\n", s, passed, total, pct, len(results[s])-total)
+	}
+	return b.String()
+}
+
 func w3cSrcLoader(fn string) ([]byte, error) {
 	path := filepath.FromSlash("../test/w3c/" + fn)
 	data, err := os.ReadFile(path)
@@ -1,12 +1,21 @@
 package dom
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
+var w3cUpdate = flag.Bool("w3c.update", false, "update the W3C baseline with the pass counts")
+
+// w3cBaseline maps the suites to their minimum number of passed tests
+const w3cBaseline = "test/w3c-baseline.json"
+
 func TestAll(t *testing.T) {
 	files, err := ioutil.ReadDir("test/w3c/level1/core")
 	if err != nil {
@@ -35,3 +44,51 @@ func TestAll(t *testing.T) {
 		}
 	}
 }
+
+func TestW3CSuites(t *testing.T) {
+	suites, err := W3CSuites("test/w3c")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	results := make(map[string][]*W3CResult)
+	for _, s := range suites {
+		rs, err := RunW3CSuite(s, 10*time.Second)
+		if err != nil {
+			t.Fatalf("%v: %v", s, err)
+		}
+		for _, r := range rs {
+			if r.Status == "FAIL" || r.Status == "ERROR" {
+				t.Logf("%v/%v: %v %v", s, r.File, r.Status, r.Message)
+			}
+		}
+		results[strings.TrimPrefix(s, "test/w3c/")] = rs
+	}
+	t.Logf("\n%v", W3CSummary(results))
+	passed := make(map[string]int)
+	for s, rs := range results {
+		passed[s], _ = W3CPassRate(rs)
+	}
+	if *w3cUpdate {
+		bs, err := json.MarshalIndent(passed, "", "  ")
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if err := os.WriteFile(w3cBaseline, append(bs, '\n'), 0644); err != nil {
+			t.Fatalf("%v", err)
+		}
+		return
+	}
+	bs, err := os.ReadFile(w3cBaseline)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	baseline := make(map[string]int)
+	if err := json.Unmarshal(bs, &baseline); err != nil {
+		t.Fatalf("%v", err)
+	}
+	for s, n := range baseline {
+		if passed[s] < n {
+			t.Errorf("%v: %v passed, baseline is %v", s, passed[s], n)
+		}
+	}
+}
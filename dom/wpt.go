@@ -3,9 +3,6 @@ package dom
 import (
 	"errors"
 	"fmt"
-	"github.com/psilva261/sparkle/console"
-	"github.com/psilva261/sparkle/eventloop"
-	"github.com/psilva261/sparkle/js"
 	"github.com/psilva261/sparkle/require"
 	"github.com/psilva261/sparklefs/logger"
 	"golang.org/x/net/html"
@@ -15,56 +12,22 @@ import (
 	"syscall"
 )
 
-func Main(dir, fn string) (err error) {
-	log.Debug = true
-	bs, err := os.ReadFile(dir + "/" + fn)
-	if err != nil {
-		return
-	}
-	htm := string(bs)
-	scripts, err := loadScripts(dir, htm)
-	if err != nil {
-		return
-	}
-	_ = scripts
-	l := eventloop.NewEventLoop()
-	l.Start()
-	defer l.Stop()
-	l.RunOnLoop(func(vm *js.Runtime) {
-		registry := require.NewRegistry(
-			require.WithLoader(
-				require.SourceLoader(srcLoader),
-			),
-		)
-		console.Enable(vm)
-		registry.Enable(vm)
-		d, err := Init(vm, "https://example.com", htm, "")
-		if err != nil {
-			log.Fatalf(err.Error())
-		}
-		_ = d
-		fmt.Printf("")
-		vm.Set("____assert_fail", vm.ToValue(func(msg string) {
-			//fmt.Printf("%v\n", render(d.Doc()))
-			log.Fatalf(msg)
-		}))
-		for _, s := range scripts {
-			_, err := vm.RunString(s)
-			if err != nil {
-				log.Fatalf("run script: %v", err)
-			}
-		}
-	})
-	return
+// script of a test file
+type script struct {
+	src  string
+	code string
 }
 
-func loadScripts(dir, htm string) (scripts []string, err error) {
+func loadScripts(dir, htm string) (scripts []script, err error) {
 	doc, err := html.Parse(strings.NewReader(htm))
 	if err != nil {
 		return
 	}
 	var f func(*html.Node)
 	f = func(n *html.Node) {
+		if err != nil {
+			return
+		}
 		if n.Type == html.ElementNode && n.Data == "script" {
 			if hasAttr(*n, "src") {
 				fn := attr(*n, "src")
@@ -73,17 +36,18 @@ func loadScripts(dir, htm string) (scripts []string, err error) {
 				} else {
 					fn = dir + "/" + fn
 				}
-				bs, err := os.ReadFile(fn)
-				if err != nil {
-					log.Fatalf("read %v: %v", fn, err)
+				bs, e := os.ReadFile(fn)
+				if e != nil {
+					err = fmt.Errorf("read %v: %w", fn, e)
+					return
 				}
-				scripts = append(scripts, string(bs))
+				scripts = append(scripts, script{src: fn, code: string(bs)})
 			} else {
 				s := ""
 				for c := n.FirstChild; c != nil; c = c.NextSibling {
 					s += c.Data
 				}
-				scripts = append(scripts, s)
+				scripts = append(scripts, script{code: s})
 			}
 		}
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
@@ -107,9 +71,3 @@ func srcLoader(fn string) ([]byte, error) {
 	}
 	return data, err
 }
-
-func main() {
-	if err := Main("test/wpt/dom/nodes", "Element-remove.html"); err != nil {
-		log.Fatalf("%v", err)
-	}
-}
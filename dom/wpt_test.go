@@ -1,11 +1,119 @@
 package dom
 
 import (
+	"flag"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 )
 
-func TestWptNodes(t *testing.T) {
-	fns := []string{
+var (
+	wptDirs    = flag.String("wpt", "", "comma separated WPT directories in test/wpt/dom to check against the expectations or 'all' for all directories with expectations")
+	wptUpdate  = flag.Bool("wpt.update", false, "update the WPT expectations with the results")
+	wptReport  = flag.String("wpt.report", "", "write a JSON report of the WPT results to this file")
+	wptTimeout = flag.Duration("wpt.timeout", 2*time.Second, "time a WPT file may run no script and finish no subtest before it times out")
+)
+
+const wptExpectations = "test/wpt-expectations.json"
+
+// TestWptConformance takes minutes, so it only runs with -wpt
+func TestWptConformance(t *testing.T) {
+	if *wptDirs == "" {
+		t.Skip("no -wpt directories")
+	}
+	exp, err := ReadWPTExpectations(wptExpectations)
+	if err != nil && !*wptUpdate {
+		t.Fatalf("%v", err)
+	}
+	dirs := expectedDirs(exp)
+	if *wptDirs != "all" {
+		dirs = strings.Split(*wptDirs, ",")
+	}
+	var rs []*WPTResult
+	for _, d := range dirs {
+		drs, err := RunWPTDir("test/wpt/dom/"+d, *wptTimeout)
+		if err != nil {
+			t.Fatalf("%v: %v", d, err)
+		}
+		passed, total := PassRate(drs)
+		t.Logf("%v: %v/%v subtests passed", d, passed, total)
+		rs = append(rs, drs...)
+	}
+	if *wptReport != "" {
+		if err := WriteWPTJSON(*wptReport, rs); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	if *wptUpdate {
+		if exp == nil {
+			exp = make(WPTExpectations)
+		}
+		for fn, m := range Expectations(rs) {
+			exp[fn] = m
+		}
+		if err := WriteWPTJSON(wptExpectations, exp); err != nil {
+			t.Fatalf("%v", err)
+		}
+		return
+	}
+	regressions, timeouts, improvements := CompareWPT(rs, exp)
+	for _, r := range improvements {
+		t.Logf("now passing: %v", r)
+	}
+	for _, r := range timeouts {
+		t.Logf("timed out: %v", r)
+	}
+	for _, r := range regressions {
+		t.Errorf("not passing anymore: %v", r)
+	}
+}
+
+// expectedDirs returns the directories in test/wpt/dom with expectations
+func expectedDirs(exp WPTExpectations) (dirs []string) {
+	seen := make(map[string]bool)
+	for fn := range exp {
+		d := strings.SplitN(strings.TrimPrefix(fn, "test/wpt/dom/"), "/", 2)[0]
+		if !seen[d] {
+			seen[d] = true
+			dirs = append(dirs, d)
+		}
+	}
+	sort.Strings(dirs)
+	return
+}
+
+func TestCompareWPT(t *testing.T) {
+	exp := WPTExpectations{
+		"a.html": {Status: "OK", Subtests: map[string]string{"x": "PASS", "y": "FAIL", "z": "PASS"}},
+		"b.html": {Status: "TIMEOUT", Subtests: map[string]string{"x": "PASS"}},
+		"c.html": {Status: "OK", Subtests: map[string]string{}},
+	}
+	exp["d.html"] = &WPTExpectation{Status: "OK", Subtests: map[string]string{"x": "PASS"}}
+	exp["e.html"] = &WPTExpectation{Status: "TIMEOUT", Subtests: map[string]string{}}
+	rs := []*WPTResult{
+		{File: "a.html", Status: "OK", Subtests: []WPTSubtest{{Name: "x", Status: "FAIL"}, {Name: "y", Status: "PASS"}, {Name: "z", Status: "PASS"}}},
+		{File: "b.html", Status: "TIMEOUT"},
+		{File: "c.html", Status: "ERROR"},
+		{File: "d.html", Status: "TIMEOUT"},
+		{File: "e.html", Status: "OK"},
+	}
+	regressions, timeouts, improvements := CompareWPT(rs, exp)
+	if v := strings.Join(regressions, "|"); v != "a.html: x|c.html: harness status ERROR, expected OK" {
+		t.Fatalf("%v", v)
+	}
+	if v := strings.Join(timeouts, "|"); v != "b.html: x|d.html: harness status TIMEOUT, expected OK|d.html: x|e.html: harness status OK, expected TIMEOUT" {
+		t.Fatalf("%v", v)
+	}
+	if v := strings.Join(improvements, "|"); v != "a.html: y" {
+		t.Fatalf("%v", v)
+	}
+}
+
+// wptCurated are test files of the directories in test/wpt/dom which
+// are checked against the expectations even with -short
+var wptCurated = map[string][]string{
+	"nodes": {
 		"Document-createElement.html",
 		"Document-createEvent.https.html",
 		"Document-createTextNode.html",
@@ -20,12 +128,11 @@ func TestWptNodes(t *testing.T) {
 		"Element-matches.html",
 		"Element-remove.html",
 		"Node-childNodes.html",
-		//"Node-cloneNode.html",
+		"Node-cloneNode.html",
 		"Node-isEqualNode.html",
 		"Node-isSameNode.html",
 		"Node-parentNode.html",
 		"NodeList-Iterable.html",
-		"ParentNode-querySelector-All-content.html",
 		"ParentNode-querySelector-All.html",
 		"ParentNode-querySelector-scope.html",
 		"getElementsByClassName-01.htm",
@@ -42,21 +149,10 @@ func TestWptNodes(t *testing.T) {
 		"getElementsByClassName-15.htm",
 		"getElementsByClassName-16.htm",
 		"getElementsByClassName-17.htm",
-	}
-	for _, fn := range fns {
-		t.Run(fn, func(t *testing.T) {
-			t.Logf("========= %v =======", fn)
-			err := Main("test/wpt/dom/nodes", fn)
-			if err != nil {
-				t.Fatalf("%v", err)
-			}
-		})
-	}
-}
-
-func TestWptEvents(t *testing.T) {
-	fns := []string{
+	},
+	"events": {
 		"Event-initEvent.html",
+		"Event-init-while-dispatching.html",
 		"Event-defaultPrevented.html",
 		"Event-dispatch-click.html",
 		"Event-dispatch-bubbles-true.html",
@@ -64,25 +160,40 @@ func TestWptEvents(t *testing.T) {
 		"Event-dispatch-order.html",
 		"Event-propagation.html",
 		"EventTarget-this-of-listener.html",
-	}
-	for _, fn := range fns {
-		t.Logf("========= %v =======", fn)
-		err := Main("test/wpt/dom/events", fn)
-		if err != nil {
-			t.Fatalf("%v", err)
-		}
-	}
+	},
+	"traversal": {
+		"NodeFilter-constants.html",
+		"NodeIterator-removal.html",
+		"TreeWalker-acceptNode-filter.html",
+		"TreeWalker-basic.html",
+		"TreeWalker-currentNode.html",
+		"TreeWalker-previousNodeLastChildReject.html",
+		"TreeWalker-previousSiblingLastChildSkip.html",
+		"TreeWalker-traversal-reject.html",
+		"TreeWalker-traversal-skip-most.html",
+		"TreeWalker-traversal-skip.html",
+		"TreeWalker-walking-outside-a-tree.html",
+	},
 }
 
-func TestWptTraversal(t *testing.T) {
-	fns := []string{
-		"NodeFilter-constants.html",
+func TestWptCurated(t *testing.T) {
+	exp, err := ReadWPTExpectations(wptExpectations)
+	if err != nil {
+		t.Fatalf("%v", err)
 	}
-	for _, fn := range fns {
-		t.Logf("========= %v =======", fn)
-		err := Main("test/wpt/dom/traversal", fn)
-		if err != nil {
-			t.Fatalf("%v", err)
+	var rs []*WPTResult
+	for d, fns := range wptCurated {
+		for _, fn := range fns {
+			r := RunWPT("test/wpt/dom/"+d, fn, *wptTimeout)
+			r.File = "test/wpt/dom/" + d + "/" + fn
+			rs = append(rs, r)
 		}
 	}
+	regressions, timeouts, _ := CompareWPT(rs, exp)
+	for _, r := range timeouts {
+		t.Logf("timed out: %v", r)
+	}
+	for _, r := range regressions {
+		t.Errorf("not passing anymore: %v", r)
+	}
 }
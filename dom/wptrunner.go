@@ -0,0 +1,357 @@
+package dom
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/psilva261/sparkle/console"
+	"github.com/psilva261/sparkle/eventloop"
+	"github.com/psilva261/sparkle/js"
+	"github.com/psilva261/sparkle/require"
+	"github.com/psilva261/sparklefs/logger"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WPTSubtest is the result of a single test() of a testharness.js file
+type WPTSubtest struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// WPTResult is the result of a testharness.js file
+type WPTResult struct {
+	File     string       `json:"file"`
+	Status   string       `json:"status"`
+	Message  string       `json:"message,omitempty"`
+	Subtests []WPTSubtest `json:"subtests"`
+}
+
+// status names of testharness.js tests and of the harness
+var (
+	wptTestStatus    = []string{"PASS", "FAIL", "TIMEOUT", "NOTRUN", "PRECONDITION_FAILED"}
+	wptHarnessStatus = []string{"OK", "ERROR", "TIMEOUT", "PRECONDITION_FAILED"}
+)
+
+func statusName(names []string, i int) string {
+	if 0 <= i && i < len(names) {
+		return names[i]
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", i)
+}
+
+// wptReporter registers result and completion callbacks which report
+// to Go. It runs right after testharness.js was loaded. Timeouts are
+// explicit so that RunWPT can time out the harness with timeout().
+const wptReporter = `
+	setup({explicit_timeout: true, output: false});
+	add_result_callback(function(t) {
+		____wpt_result(String(t.name), t.status, t.message ? String(t.message) : '');
+	});
+	add_completion_callback(function(tests, status) {
+		____wpt_done(status.status, status.message ? String(status.message) : '');
+	});
+`
+
+// wptLimit is the longest time a testharness.js file may run
+const wptLimit = time.Minute
+
+// RunWPT runs the testharness.js test file fn in dir and collects the
+// results. The harness is timed out once no subtest finished and no
+// script ran for timeout, so that files waiting for events which never
+// happen don't hold up the run while long files keep running.
+func RunWPT(dir, fn string, timeout time.Duration) (res *WPTResult) {
+	res = &WPTResult{
+		File:     fn,
+		Subtests: make([]WPTSubtest, 0),
+	}
+	bs, err := os.ReadFile(filepath.Join(dir, fn))
+	if err != nil {
+		res.Status, res.Message = "ERROR", err.Error()
+		return
+	}
+	htm := string(bs)
+	scripts, err := loadScripts(dir, htm)
+	if err != nil {
+		res.Status, res.Message = "ERROR", err.Error()
+		return
+	}
+
+	var mu sync.Mutex
+	done := make(chan struct{})
+	var once sync.Once
+	finish := func(status, msg string) {
+		once.Do(func() {
+			mu.Lock()
+			res.Status, res.Message = status, msg
+			mu.Unlock()
+			close(done)
+		})
+	}
+	// guard recovers from panics in Go code called by the test
+	guard := func(f func()) {
+		defer func() {
+			if r := recover(); r != nil {
+				finish("ERROR", fmt.Sprintf("panic: %v", r))
+			}
+		}()
+		f()
+	}
+
+	var rt *js.Runtime
+	l := eventloop.NewEventLoop()
+	l.Start()
+	defer l.Stop()
+	l.RunOnLoop(func(vm *js.Runtime) {
+		guard(func() {
+			rt = vm
+			registry := require.NewRegistry(
+				require.WithLoader(
+					require.SourceLoader(srcLoader),
+				),
+			)
+			console.Enable(vm)
+			registry.Enable(vm)
+			d, err := Init(vm, "https://example.com", htm, "")
+			if err != nil {
+				finish("ERROR", fmt.Sprintf("init: %v", err))
+				return
+			}
+			vm.Set("____wpt_result", func(name string, status int, msg string) {
+				mu.Lock()
+				defer mu.Unlock()
+				res.Subtests = append(res.Subtests, WPTSubtest{
+					Name:    name,
+					Status:  statusName(wptTestStatus, status),
+					Message: msg,
+				})
+			})
+			vm.Set("____wpt_done", func(status int, msg string) {
+				finish(statusName(wptHarnessStatus, status), msg)
+			})
+			for _, s := range scripts {
+				// uncaught errors end the harness like its error
+				// handler does
+				if _, err := vm.RunString(s.code); err != nil {
+					log.Printf("%v: run script %v: %v", fn, s.src, err)
+					finish("ERROR", err.Error())
+					return
+				}
+				if strings.HasSuffix(s.src, "/testharness.js") {
+					if _, err := vm.RunString(wptReporter); err != nil {
+						finish("ERROR", fmt.Sprintf("reporter: %v", err))
+						return
+					}
+				}
+			}
+			if err := d.Close(); err != nil {
+				log.Printf("%v: close: %v", fn, err)
+			}
+		})
+	})
+
+	// stalled waits until the harness completes (false) or stalls
+	// (true). It stalls when no subtest finished and the loop was idle
+	// for timeout, or after wptLimit.
+	stalled := func() bool {
+		poll := time.NewTicker(timeout / 10)
+		defer poll.Stop()
+		limit := time.After(wptLimit)
+		idle := make(chan struct{}, 1)
+		idle <- struct{}{}
+		n, last := 0, time.Now()
+		for {
+			select {
+			case <-done:
+				return false
+			case <-limit:
+				return true
+			case <-poll.C:
+			}
+			mu.Lock()
+			m := len(res.Subtests)
+			mu.Unlock()
+			// the loop is busy running scripts until it ran the probe
+			busy := true
+			select {
+			case <-idle:
+				busy = false
+				l.RunOnLoop(func(*js.Runtime) { idle <- struct{}{} })
+			default:
+			}
+			if busy || m > n {
+				n, last = m, time.Now()
+			} else if time.Since(last) >= timeout {
+				return true
+			}
+		}
+	}
+	if stalled() {
+		// let the harness time out the remaining tests
+		l.RunOnLoop(func(vm *js.Runtime) {
+			guard(func() {
+				if _, err := vm.RunString(`timeout()`); err != nil {
+					log.Printf("%v: timeout: %v", fn, err)
+				}
+			})
+		})
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			if rt != nil {
+				rt.Interrupt("timeout")
+			}
+			finish("TIMEOUT", "harness did not complete")
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	r := *res
+	r.Subtests = append([]WPTSubtest{}, res.Subtests...)
+	return &r
+}
+
+// testharnessFile is true for .html/.htm files which include
+// testharness.js
+func testharnessFile(fn string) bool {
+	if ext := filepath.Ext(fn); ext != ".html" && ext != ".htm" {
+		return false
+	}
+	bs, err := os.ReadFile(fn)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(bs), "/resources/testharness.js")
+}
+
+// RunWPTDir runs all testharness.js files under dir
+func RunWPTDir(dir string, timeout time.Duration) (rs []*WPTResult, err error) {
+	err = filepath.WalkDir(dir, func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if de.IsDir() || !testharnessFile(path) {
+			return nil
+		}
+		r := RunWPT(filepath.Dir(path), de.Name(), timeout)
+		r.File = filepath.ToSlash(path)
+		rs = append(rs, r)
+		return nil
+	})
+	return
+}
+
+// WPTExpectation is the expected harness status of a test file and
+// the expected status of its subtests
+type WPTExpectation struct {
+	Status   string            `json:"status"`
+	Subtests map[string]string `json:"subtests"`
+}
+
+// WPTExpectations maps test files to their expectations
+type WPTExpectations map[string]*WPTExpectation
+
+// Expectations returns the results as expectations
+func Expectations(rs []*WPTResult) WPTExpectations {
+	exp := make(WPTExpectations)
+	for _, r := range rs {
+		e := &WPTExpectation{
+			Status:   r.Status,
+			Subtests: make(map[string]string),
+		}
+		for _, st := range r.Subtests {
+			e.Subtests[st.Name] = st.Status
+		}
+		exp[r.File] = e
+	}
+	return exp
+}
+
+// ReadWPTExpectations from a JSON file
+func ReadWPTExpectations(fn string) (exp WPTExpectations, err error) {
+	bs, err := os.ReadFile(fn)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(bs, &exp)
+	return
+}
+
+// WriteWPTJSON writes v (a report or expectations) as indented JSON
+func WriteWPTJSON(fn string, v any) error {
+	bs, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fn, append(bs, '\n'), 0644)
+}
+
+// CompareWPT compares results with expectations. Regressions are
+// harness statuses which differ from the expected ones and expected
+// passes which don't pass anymore, improvements are new passes.
+// Timeouts depend on the load of the machine, so harness statuses
+// which differ only by timing out and expected passes missing from
+// files which timed out are returned as timeouts instead.
+func CompareWPT(rs []*WPTResult, exp WPTExpectations) (regressions, timeouts, improvements []string) {
+	for _, r := range rs {
+		e := exp[r.File]
+		if e == nil {
+			e = &WPTExpectation{}
+		}
+		if r.Status != e.Status {
+			msg := fmt.Sprintf("%v: harness status %v, expected %v", r.File, r.Status, e.Status)
+			if timedOut(r.Status, e.Status) {
+				timeouts = append(timeouts, msg)
+			} else {
+				regressions = append(regressions, msg)
+			}
+		}
+		passed := make(map[string]bool)
+		for _, st := range r.Subtests {
+			if st.Status != "PASS" {
+				continue
+			}
+			passed[st.Name] = true
+			if e.Subtests[st.Name] != "PASS" {
+				improvements = append(improvements, r.File+": "+st.Name)
+			}
+		}
+		for name, status := range e.Subtests {
+			if status != "PASS" || passed[name] {
+				continue
+			}
+			if r.Status == "TIMEOUT" {
+				timeouts = append(timeouts, r.File+": "+name)
+			} else {
+				regressions = append(regressions, r.File+": "+name)
+			}
+		}
+	}
+	sort.Strings(regressions)
+	sort.Strings(timeouts)
+	sort.Strings(improvements)
+	return
+}
+
+// timedOut is true if the harness statuses differ only by a timeout
+func timedOut(status, expected string) bool {
+	return status == "TIMEOUT" && expected == "OK" || status == "OK" && expected == "TIMEOUT"
+}
+
+// PassRate returns the number of passed and total subtests
+func PassRate(rs []*WPTResult) (passed, total int) {
+	for _, r := range rs {
+		for _, st := range r.Subtests {
+			if st.Status == "PASS" {
+				passed++
+			}
+			total++
+		}
+	}
+	return
+}
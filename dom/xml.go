@@ -0,0 +1,425 @@
+package dom
+
+import (
+	"encoding/xml"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"strings"
+)
+
+const (
+	svgNS         = "http://www.w3.org/2000/svg"
+	mathNS        = "http://www.w3.org/1998/Math/MathML"
+	xmlNS         = "http://www.w3.org/XML/1998/namespace"
+	xmlnsNS       = "http://www.w3.org/2000/xmlns/"
+	parseErrorNS  = "http://www.mozilla.org/newlayout/xml/parsererror.xml"
+	xhtmlMimeType = "application/xhtml+xml"
+)
+
+// isXMLType is true if the MIME type t is parsed as XML
+func isXMLType(t string) bool {
+	switch t {
+	case "application/xml", "text/xml", "image/svg+xml", xhtmlMimeType:
+		return true
+	}
+	return false
+}
+
+// parseXML parses s into a document node. Elements get their resolved
+// namespace URI and their qualified name as data like elements created
+// with createElementNS.
+func parseXML(s string) (doc *html.Node, err error) {
+	doc = &html.Node{Type: html.DocumentNode}
+	dec := xml.NewDecoder(strings.NewReader(s))
+	dec.Strict = true
+	scopes := []map[string]string{{"xml": xmlNS, "xmlns": xmlnsNS}}
+	lookup := func(prefix string) (string, bool) {
+		for i := len(scopes) - 1; i >= 0; i-- {
+			if ns, ok := scopes[i][prefix]; ok {
+				return ns, true
+			}
+		}
+		return "", prefix == ""
+	}
+	p := doc
+	for {
+		off := dec.InputOffset()
+		t, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch v := t.(type) {
+		case xml.StartElement:
+			if p == doc && grepFirst(doc, html.ElementNode) != nil {
+				return nil, fmt.Errorf("line %v: junk after document element", line(dec))
+			}
+			scope := make(map[string]string)
+			for _, a := range v.Attr {
+				switch {
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+					scope[""] = a.Value
+				case a.Name.Space == "xmlns":
+					scope[a.Name.Local] = a.Value
+				}
+			}
+			scopes = append(scopes, scope)
+			ns, ok := lookup(v.Name.Space)
+			if !ok {
+				return nil, fmt.Errorf("line %v: unbound prefix %v", line(dec), v.Name.Space)
+			}
+			n := &html.Node{
+				Type:      html.ElementNode,
+				Data:      qualifiedName(v.Name),
+				Namespace: ns,
+			}
+			for _, a := range v.Attr {
+				at := html.Attribute{Key: qualifiedName(a.Name), Val: a.Value}
+				if a.Name.Space != "" && a.Name.Space != "xmlns" {
+					if at.Namespace, ok = lookup(a.Name.Space); !ok {
+						return nil, fmt.Errorf("line %v: unbound prefix %v", line(dec), a.Name.Space)
+					}
+				}
+				for _, b := range n.Attr {
+					if b.Key == at.Key {
+						return nil, fmt.Errorf("line %v: duplicate attribute %v", line(dec), at.Key)
+					}
+				}
+				n.Attr = append(n.Attr, at)
+			}
+			p.AppendChild(n)
+			p = n
+		case xml.EndElement:
+			if p == doc || p.Data != qualifiedName(v.Name) {
+				return nil, fmt.Errorf("line %v: mismatched tag %v", line(dec), qualifiedName(v.Name))
+			}
+			scopes = scopes[:len(scopes)-1]
+			p = p.Parent
+		case xml.CharData:
+			if p == doc {
+				if strings.TrimSpace(string(v)) != "" {
+					return nil, fmt.Errorf("line %v: text outside of the document element", line(dec))
+				}
+				continue
+			}
+			if strings.HasPrefix(s[off:], "<![CDATA[") {
+				p.AppendChild(newCDATA(string(v)))
+			} else if c := p.LastChild; c != nil && c.Type == html.TextNode && !isCDATA(c) {
+				c.Data += string(v)
+			} else {
+				p.AppendChild(&html.Node{Type: html.TextNode, Data: string(v)})
+			}
+		case xml.Comment:
+			p.AppendChild(&html.Node{Type: html.CommentNode, Data: string(v)})
+		case xml.ProcInst:
+			if v.Target == "xml" {
+				continue
+			}
+			p.AppendChild(&html.Node{
+				Type: html.RawNode,
+				Data: string(v.Inst),
+				Attr: []html.Attribute{{Key: "target", Val: v.Target}},
+			})
+		case xml.Directive:
+			if dt := parseDoctype(string(v)); dt != nil && p == doc {
+				doc.AppendChild(dt)
+			}
+		}
+	}
+	if p != doc {
+		return nil, fmt.Errorf("line %v: unclosed element %v", line(dec), p.Data)
+	}
+	if grepFirst(doc, html.ElementNode) == nil {
+		return nil, fmt.Errorf("no document element")
+	}
+	return doc, nil
+}
+
+// newCDATA returns a CDATA section. It's stored as text node with a
+// cdata attribute.
+func newCDATA(data string) *html.Node {
+	return &html.Node{
+		Type: html.TextNode,
+		Data: data,
+		Attr: []html.Attribute{{Key: "cdata"}},
+	}
+}
+
+func isCDATA(n *html.Node) bool {
+	return n.Type == html.TextNode && hasAttr(*n, "cdata")
+}
+
+func line(dec *xml.Decoder) int {
+	l, _ := dec.InputPos()
+	return l
+}
+
+func qualifiedName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+// grepFirst returns the first child of n with type t
+func grepFirst(n *html.Node, t html.NodeType) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == t {
+			return c
+		}
+	}
+	return nil
+}
+
+// parseDoctype parses the directive d if it's a DOCTYPE
+func parseDoctype(d string) *html.Node {
+	fs := strings.Fields(d)
+	if len(fs) < 2 || fs[0] != "DOCTYPE" {
+		return nil
+	}
+	n := &html.Node{Type: html.DoctypeNode, Data: fs[1]}
+	rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(d[len("DOCTYPE"):]), fs[1]))
+	literal := func() string {
+		rest = strings.TrimSpace(rest)
+		if rest == "" || rest[0] != '"' && rest[0] != '\'' {
+			return ""
+		}
+		i := strings.IndexByte(rest[1:], rest[0])
+		if i < 0 {
+			return ""
+		}
+		s := rest[1 : i+1]
+		rest = rest[i+2:]
+		return s
+	}
+	switch {
+	case strings.HasPrefix(rest, "PUBLIC"):
+		rest = rest[len("PUBLIC"):]
+		if pub := literal(); pub != "" {
+			n.Attr = append(n.Attr, html.Attribute{Key: "public", Val: pub})
+		}
+		if sys := literal(); sys != "" {
+			n.Attr = append(n.Attr, html.Attribute{Key: "system", Val: sys})
+		}
+	case strings.HasPrefix(rest, "SYSTEM"):
+		rest = rest[len("SYSTEM"):]
+		if sys := literal(); sys != "" {
+			n.Attr = append(n.Attr, html.Attribute{Key: "system", Val: sys})
+		}
+	}
+	return n
+}
+
+// parseErrorDoc is the document returned for malformed XML
+func parseErrorDoc(err error) *html.Node {
+	doc := &html.Node{Type: html.DocumentNode}
+	pe := &html.Node{
+		Type:      html.ElementNode,
+		Data:      "parsererror",
+		Namespace: parseErrorNS,
+	}
+	pe.AppendChild(&html.Node{Type: html.TextNode, Data: err.Error()})
+	doc.AppendChild(pe)
+	return doc
+}
+
+// nullNS marks elements in the null namespace of HTML documents where
+// an empty Namespace means the HTML namespace
+const nullNS = "\x00"
+
+// namespace returns the namespace URI of the element n. Elements
+// without namespace are HTML elements in HTML documents.
+func (d *Document) namespace(n *html.Node) string {
+	switch n.Namespace {
+	case "":
+		if d.isHTML() {
+			return xhtmlNS
+		}
+		return ""
+	case nullNS:
+		return ""
+	case "svg":
+		return svgNS
+	case "math":
+		return mathNS
+	}
+	return n.Namespace
+}
+
+// validateQName throws an InvalidCharacterError if qn isn't a valid
+// qualified name and a NamespaceError if its prefix doesn't match the
+// namespace ns
+func validateQName(ns, qn string) {
+	prefix, local, hasPrefix := strings.Cut(qn, ":")
+	if !hasPrefix {
+		prefix, local = "", qn
+	}
+	if !isNCName(local) || hasPrefix && !isNCName(prefix) {
+		throwDOMException("InvalidCharacterError", "invalid qualified name "+qn)
+	}
+	switch {
+	case hasPrefix && ns == "":
+		throwDOMException("NamespaceError", "prefix without namespace")
+	case prefix == "xml" && ns != xmlNS:
+		throwDOMException("NamespaceError", "the xml prefix needs the XML namespace")
+	case (qn == "xmlns" || prefix == "xmlns") != (ns == xmlnsNS):
+		throwDOMException("NamespaceError", "xmlns is reserved for the XMLNS namespace")
+	}
+}
+
+// isName is true if s is an XML name
+func isName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !isNameStartChar(r) && r != ':' && (i == 0 || !isNameChar(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNCName is true if s is a name without colons
+func isNCName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !isNameStartChar(r) && (i == 0 || !isNameChar(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNameStartChar(r rune) bool {
+	switch {
+	case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', r == '_':
+		return true
+	case 0xC0 <= r && r <= 0xD6, 0xD8 <= r && r <= 0xF6, 0xF8 <= r && r <= 0x2FF,
+		0x370 <= r && r <= 0x37D, 0x37F <= r && r <= 0x1FFF, 0x200C <= r && r <= 0x200D,
+		0x2070 <= r && r <= 0x218F, 0x2C00 <= r && r <= 0x2FEF, 0x3001 <= r && r <= 0xD7FF,
+		0xF900 <= r && r <= 0xFDCF, 0xFDF0 <= r && r <= 0xFFFD, 0x10000 <= r && r <= 0xEFFFF:
+		return true
+	}
+	return false
+}
+
+func isNameChar(r rune) bool {
+	switch {
+	case isNameStartChar(r), '0' <= r && r <= '9', r == '-', r == '.', r == 0xB7:
+		return true
+	case 0x300 <= r && r <= 0x36F, 0x203F <= r && r <= 0x2040:
+		return true
+	}
+	return false
+}
+
+var voidElements = map[string]bool{
+	"area": true, "base": true, "basefont": true, "bgsound": true,
+	"br": true, "col": true, "embed": true, "frame": true, "hr": true,
+	"img": true, "input": true, "keygen": true, "link": true,
+	"meta": true, "param": true, "source": true, "track": true,
+	"wbr": true,
+}
+
+// serializeXML writes the XML serialization of n to b. ns is the
+// inherited default namespace and prefixes maps the prefixes declared
+// by the ancestors to their namespace.
+func (d *Document) serializeXML(b *strings.Builder, n *html.Node, ns string, prefixes map[string]string) {
+	switch n.Type {
+	case html.DocumentNode:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			d.serializeXML(b, c, ns, prefixes)
+		}
+	case html.TextNode:
+		if isCDATA(n) {
+			b.WriteString("<![CDATA[" + n.Data + "]]>")
+		} else {
+			b.WriteString(escapeXML(n.Data, false))
+		}
+	case html.CommentNode:
+		b.WriteString("<!--" + n.Data + "-->")
+	case html.RawNode:
+		b.WriteString("<?" + attr(*n, "target") + " " + n.Data + "?>")
+	case html.DoctypeNode:
+		b.WriteString("<!DOCTYPE " + n.Data)
+		pub, sys := attr(*n, "public"), attr(*n, "system")
+		if pub != "" {
+			b.WriteString(` PUBLIC "` + pub + `"`)
+		} else if sys != "" {
+			b.WriteString(" SYSTEM")
+		}
+		if sys != "" {
+			b.WriteString(` "` + sys + `"`)
+		}
+		b.WriteString(">")
+	case html.ElementNode:
+		d.serializeElement(b, n, ns, prefixes)
+	}
+}
+
+func (d *Document) serializeElement(b *strings.Builder, n *html.Node, ns string, prefixes map[string]string) {
+	elNS := d.namespace(n)
+	qn := n.Data
+	prefix, _, hasPrefix := strings.Cut(qn, ":")
+	scope := make(map[string]string, len(prefixes))
+	for k, v := range prefixes {
+		scope[k] = v
+	}
+	var attrs strings.Builder
+	declared := false
+	for _, a := range n.Attr {
+		k := a.Key
+		switch a.Namespace {
+		case "xlink", "xml", "xmlns":
+			// foreign attributes from the HTML parser
+			k = a.Namespace + ":" + a.Key
+		}
+		if k == "xmlns" {
+			declared = true
+			ns = a.Val
+		} else if p, ok := strings.CutPrefix(k, "xmlns:"); ok {
+			scope[p] = a.Val
+		}
+		attrs.WriteString(" " + k + `="` + escapeXML(a.Val, true) + `"`)
+	}
+	b.WriteString("<" + qn)
+	if hasPrefix {
+		if scope[prefix] != elNS {
+			b.WriteString(" xmlns:" + prefix + `="` + escapeXML(elNS, true) + `"`)
+			scope[prefix] = elNS
+		}
+	} else if !declared && elNS != ns {
+		b.WriteString(` xmlns="` + escapeXML(elNS, true) + `"`)
+		ns = elNS
+	}
+	b.WriteString(attrs.String())
+	if n.FirstChild == nil {
+		switch {
+		case elNS != xhtmlNS:
+			b.WriteString("/>")
+		case voidElements[n.Data]:
+			b.WriteString(" />")
+		default:
+			b.WriteString("></" + qn + ">")
+		}
+		return
+	}
+	b.WriteString(">")
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		d.serializeXML(b, c, ns, scope)
+	}
+	b.WriteString("</" + qn + ">")
+}
+
+func escapeXML(s string, attr bool) string {
+	r := []string{"&", "&amp;", "<", "&lt;", ">", "&gt;"}
+	if attr {
+		r = append(r, `"`, "&quot;")
+	}
+	return strings.NewReplacer(r...).Replace(s)
+}
@@ -1,10 +1,6 @@
 window.history = {
 	replaceState: function() {}
 };
-window.screen = {
-	width: 1280,
-	height: 1024
-};
 window.screenX = 0;
 window.screenY = 25;
 
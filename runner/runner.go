@@ -24,6 +24,12 @@ import (
 var (
 	convert6to5 = os.Getenv("SPARKLEFS_6TO5")
 	timeout     = 60 * time.Second
+
+	// frameInterval between animation frames
+	frameInterval = 16 * time.Millisecond
+
+	// trackLimit is the longest time TrackChanges waits for mutations
+	trackLimit = 3 * time.Second
 )
 
 //go:embed domintf.js
@@ -82,6 +88,11 @@ func (r *Runner) Start() {
 	r.loop = eventloop.NewEventLoop()
 
 	r.loop.Start()
+	r.loop.SetInterval(func(*js.Runtime) {
+		if r.doc != nil {
+			r.doc.Window.RenderAnimationFrame()
+		}
+	}, frameInterval)
 	log.Printf("event loop started")
 }
 
@@ -157,32 +168,48 @@ func (r *Runner) initVM(vm *js.Runtime) (err error) {
 	}
 
 	type S struct {
-		Buf      string                                                                `json:"buf"`
-		HTML     string                                                                `json:"html"`
-		Origin   string                                                                `json:"origin"`
-		Referrer func() string                                                         `json:"referrer"`
-		Style    func(string, string, string, string) string                           `json:"style"`
-		XHR      func(string, string, map[string]string, string, func(string, string)) `json:"xhr"`
-		Mutated  func(t int, target string, tag string, node map[string]string)        `json:"mutated"`
-		Btoa     func([]byte) string                                                   `json:"btoa"`
+		Buf      string                                                                     `json:"buf"`
+		HTML     string                                                                     `json:"html"`
+		Origin   string                                                                     `json:"origin"`
+		Referrer func() string                                                              `json:"referrer"`
+		Style    func(string, string, string, string) string                                `json:"style"`
+		XHR      func(string, string, map[string]string, string, func(string, string, int)) `json:"xhr"`
+		Mutated  func(t int, target string, tag string, node map[string]string)             `json:"mutated"`
+		Btoa     func([]byte) string                                                        `json:"btoa"`
 	}
 
 	//vm.SetFieldNameMapper(js.TagFieldNameMapper("json", true))
-	dom.Geom = r.geom
+	dom.Geom = r.geomOrLayout
 	dom.Query = r.query
+	dom.XHR = r.xhr
+	dom.ExecScript = func(script string) error {
+		return r.runScript(vm, script)
+	}
 	vm.Set("mycel", S{
 		HTML:     r.html,
 		Origin:   r.url,
 		Referrer: func() string { return r.url },
-		XHR:  r.xhr,
-		Btoa: Btoa,
+		XHR:      r.xhr,
+		Btoa:     Btoa,
 	})
 
 	return
 }
 
+// geomOrLayout asks the host for the geometry of sel and falls back
+// to the built-in layout if there is no host or it has no answer
+func (r *Runner) geomOrLayout(sel string) (val string, err error) {
+	if r.geom != nil {
+		if val, err = r.geom(sel); err == nil {
+			return
+		}
+		log.Printf("geom %v: %v", sel, err)
+	}
+	return r.doc.LayoutGeom(sel)
+}
+
 var (
-	reCompatCommentOpen = regexp.MustCompile(`^\s*<!--`)
+	reCompatCommentOpen  = regexp.MustCompile(`^\s*<!--`)
 	reCompatCommentClose = regexp.MustCompile(`-->\s*$`)
 )
 
@@ -242,14 +269,39 @@ func (r *Runner) Exec56(script string, initial bool) (res string, err error) {
 }
 
 func (r *Runner) Exec6(script string, initial bool) (res string, err error) {
+	if script, err = to5(script); err != nil {
+		return "", err
+	}
+	return r.Exec(script, initial)
+}
+
+// to5 converts the ES6 script to ES5
+func to5(script string) (string, error) {
 	cmd := exec.Command("6to5")
 	cmd.Stdin = strings.NewReader(script)
 	var out bytes.Buffer
 	cmd.Stdout = &out
-	if err = cmd.Run(); err != nil {
+	if err := cmd.Run(); err != nil {
 		return "", fmt.Errorf("6to5: %w", err)
 	}
-	return r.Exec(out.String(), initial)
+	return out.String(), nil
+}
+
+// runScript runs a script inserted into the document. It's called by
+// dom on the event loop.
+func (r *Runner) runScript(vm *js.Runtime, script string) (err error) {
+	if convert6to5 != "" {
+		if script, err = to5(script); err != nil {
+			return
+		}
+	}
+	script = reCompatCommentOpen.ReplaceAllString(script, "//")
+	script = reCompatCommentClose.ReplaceAllString(script, "//")
+	if _, err = vm.RunString(script); err != nil {
+		IntrospectError(err, script)
+		return fmt.Errorf("run script: %w", err)
+	}
+	return
 }
 
 // CloseDoc fires DOMContentLoaded to trigger $(document).ready(..)
@@ -277,22 +329,13 @@ func (r *Runner) TriggerClick(selector string) (newHTML string, ok bool, err err
 			return
 		}
 		var consumed bool
+		var focused bool
 		var h string
-		var e *dom.MouseEvent
 		var e2 *dom.Event
-		var e3 *dom.Event
+		focused = el.ClickFocus()
 		if consumed = el.Clic(); consumed {
 			goto done
 		}
-		e = &dom.MouseEvent{
-			Event: dom.Event{
-				Type: "click",
-			},
-		}
-		el.DispatchEvent(e)
-		if consumed = e.Consumed; consumed {
-			goto done
-		}
 		e2 = &dom.Event{
 			Type: "mouseup",
 		}
@@ -300,18 +343,11 @@ func (r *Runner) TriggerClick(selector string) (newHTML string, ok bool, err err
 		if consumed = e2.Consumed; consumed {
 			goto done
 		}
-		e3 = &dom.Event{
-			Type: "focus",
-		}
-		el.DispatchEvent(e3)
-		if consumed = e3.Consumed; consumed {
-			goto done
-		}
 		if h = el.OuterHTML(); len(h) > 20 {
 			h = h[:20] + "..."
 		}
 	done:
-		consumedCh <- consumed
+		consumedCh <- consumed || focused
 		errCh <- nil
 	})
 	if err := <-errCh; err != nil {
@@ -328,6 +364,109 @@ func (r *Runner) TriggerClick(selector string) (newHTML string, ok bool, err err
 	return
 }
 
+// TriggerTab moves the focus like pressing the tab key (or shift-tab
+// when backward), and returns the result html
+func (r *Runner) TriggerTab(backward bool) (newHTML string, ok bool, err error) {
+	consumedCh := make(chan bool, 1)
+	r.loop.RunOnLoop(func(vm *js.Runtime) {
+		consumedCh <- r.doc.Tab(backward)
+	})
+	if <-consumedCh {
+		log.Printf("event consumed")
+		newHTML, ok, err = r.TrackChanges()
+	} else {
+		log.Printf("event not consumed")
+	}
+	return
+}
+
+// TriggerInput sets the value of the form control matching selector
+// like user input, and returns the result html
+func (r *Runner) TriggerInput(selector, val string) (newHTML string, ok bool, err error) {
+	consumedCh := make(chan bool, 1)
+	errCh := make(chan error, 1)
+	r.loop.RunOnLoop(func(vm *js.Runtime) {
+		el := r.doc.Element().QuerySelector(selector)
+		if el == nil {
+			errCh <- fmt.Errorf("could not find '%v'", selector)
+			return
+		}
+		el.UserInput(val)
+		// the value itself changed even without listeners
+		consumedCh <- true
+		errCh <- nil
+	})
+	if err := <-errCh; err != nil {
+		return "", false, err
+	}
+	if <-consumedCh {
+		newHTML, ok, err = r.TrackChanges()
+	}
+	return
+}
+
+// Scroll the viewport to x, y as notified by the scrollbar of the
+// browser
+func (r *Runner) Scroll(x, y float64) (newHTML string, ok bool, err error) {
+	return r.frame(func() {
+		r.doc.Window.ScrollTo(x, y)
+	})
+}
+
+// Resize the viewport to w x h CSS pixels with the device pixel ratio
+// dpr
+func (r *Runner) Resize(w, h int, dpr float64) (newHTML string, ok bool, err error) {
+	return r.frame(func() {
+		r.doc.Window.SetViewport(w, h, dpr)
+	})
+}
+
+// frame runs f and renders an animation frame to fire the resulting
+// events
+func (r *Runner) frame(f func()) (newHTML string, ok bool, err error) {
+	renderedCh := make(chan bool, 1)
+	r.loop.RunOnLoop(func(vm *js.Runtime) {
+		if r.doc == nil {
+			renderedCh <- false
+			return
+		}
+		f()
+		renderedCh <- r.doc.Window.RenderAnimationFrame()
+	})
+	if <-renderedCh {
+		newHTML, ok, err = r.TrackChanges()
+	}
+	return
+}
+
+// SetViewport sets the viewport size and device pixel ratio of
+// documents not started yet
+func SetViewport(w, h int, dpr float64) {
+	dom.ViewportWidth, dom.ViewportHeight = w, h
+	if dpr > 0 {
+		dom.DevicePixelRatio = dpr
+	}
+}
+
+// Submission returns the request of a form submission which wasn't
+// prevented or nil
+func (r *Runner) Submission() (req *http.Request) {
+	reqCh := make(chan *http.Request, 1)
+	r.loop.RunOnLoop(func(vm *js.Runtime) {
+		reqCh <- dom.Submission(r.doc)
+	})
+	return <-reqCh
+}
+
+// Selection returns the text of the selection
+func (r *Runner) Selection() string {
+	ch := make(chan string, 1)
+	r.loop.RunOnLoop(func(vm *js.Runtime) {
+		ch <- dom.SelectionString(r.doc)
+	})
+	return <-ch
+}
+
 // Put change into html (e.g. from input field mutation)
 func (r *Runner) PutAttr(selector, attr, val string) (ok bool, err error) {
 	res, err := r.Exec(`
@@ -342,7 +481,14 @@ func (r *Runner) PutAttr(selector, attr, val string) (ok bool, err error) {
 	return
 }
 
+// TrackChanges waits until there were no mutations for a second or
+// trackLimit is reached, and returns the result html. Mutations of the
+// animation frames, which keep running on the loop, are tracked but
+// don't prolong the wait.
 func (r *Runner) TrackChanges() (html string, changed bool, err error) {
+	idle := time.NewTimer(time.Second)
+	defer idle.Stop()
+	deadline := time.After(trackLimit)
 outer:
 	for {
 		// TODO: either add other change types like ajax begin/end or
@@ -350,48 +496,34 @@ outer:
 		select {
 		case m := <-dom.Mutations():
 			changed = true
-			if strings.ToLower(m.Tag) == "script" {
-				s := ""
-				src, ok := m.Node["src"]
-				if ok {
-					ch := make(chan string)
-					log.Printf("<script> GET %v", src)
-					r.xhr("GET", src, make(map[string]string), "", func(data, err string) {
-						if err != "" {
-							log.Printf("xhr %v: %v", src, err)
-							log.Printf("data: %v", data)
-						}
-						ch <- data
-					})
-					s = <-ch
-				} else if inner, ok := m.Node["innerHTML"]; ok {
-					s = inner
-				}
-				if strings.TrimSpace(s) != "" {
-					sp := s
-					if len(sp) > 20 {
-						sp = sp[:20] + "..."
-					}
-					if _, err := r.Exec56(s, false); err != nil {
-						log.Printf("exec %v: %v", src, err)
-					}
-				}
-			} else {
-				changed = true
+			if m.Frame {
+				continue
+			}
+			if !idle.Stop() {
+				<-idle.C
 			}
-		case <-time.After(time.Second):
+			idle.Reset(time.Second)
+		case <-idle.C:
+			break outer
+		case <-deadline:
+			log.Printf("track changes: still mutating after %v", trackLimit)
 			break outer
 		}
 	}
 
 	if changed {
-		html = r.doc.Element().OuterHTML()
+		// render on the loop which might be running a frame
+		htmlCh := make(chan string, 1)
+		r.loop.RunOnLoop(func(vm *js.Runtime) {
+			htmlCh <- dom.RenderState(r.doc.Element())
+		})
+		html = <-htmlCh
 	}
 	r.outputHtml = html
 	return
 }
 
-func (r *Runner) xhr(method, uri string, h map[string]string, data string, cb func(data string, err string)) {
+func (r *Runner) xhr(method, uri string, h map[string]string, data string, cb func(data, err string, status int)) {
 	uri = strings.TrimPrefix(uri, ".")
 	if !strings.HasPrefix(uri, "http") && !strings.HasPrefix(uri, "/") {
 		// TODO: use instead origin/url prefix
@@ -400,25 +532,30 @@ func (r *Runner) xhr(method, uri string, h map[string]string, data string, cb fu
 	req, err := http.NewRequest(method /*u.String()*/, uri, strings.NewReader(data))
 	if err != nil {
 		err = fmt.Errorf("new http req: %v", err)
-		cb("", err.Error())
+		cb("", err.Error(), 0)
 		return
 	}
 	for k, v := range h {
 		req.Header.Add(k, v)
 	}
+	if r.xhrq == nil {
+		cb("", "nil xhrq func", 0)
+		return
+	}
 	go func() {
+		var data, errStr string
+		var status int
 		resp, err := r.xhrq(req)
-		if err != nil {
-			err = fmt.Errorf("xhrq: %v", err)
-			cb("", err.Error())
-			return
-		}
-		//defer resp.Body.Close()
-		bs, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			err = fmt.Errorf("read all: %v", err)
-			cb("", err.Error())
-			return
+		if err == nil {
+			//defer resp.Body.Close()
+			status = resp.StatusCode
+			var bs []byte
+			if bs, err = ioutil.ReadAll(resp.Body); err != nil {
+				errStr = fmt.Sprintf("read all: %v", err)
+			}
+			data = string(bs)
+		} else {
+			errStr = fmt.Sprintf("xhrq: %v", err)
 		}
 		r.loop.RunOnLoop(func(*js.Runtime) {
 			defer func() {
@@ -426,7 +563,7 @@ func (r *Runner) xhr(method, uri string, h map[string]string, data string, cb fu
 					log.Printf("recovered in xhr: %v", r)
 				}
 			}()
-			cb(string(bs), "")
+			cb(data, errStr, status)
 		})
 	}()
 }
@@ -443,7 +580,7 @@ func (r *Runner) docPath(path string) (dp string, err error) {
 		}
 		i, err := strconv.Atoi(el)
 		if err == nil {
-			q += fmt.Sprintf(".children[%d]", i)
+			q += fmt.Sprintf(".childNodes[%d]", i)
 		} else {
 			q += fmt.Sprintf(".%v", el)
 		}
@@ -501,8 +638,8 @@ func (r *Runner) List(path string) (l []string) {
 		let ks = getProperties(q);
 		let ms = getMethods(q);
 		let i;
-		if (q.children) {
-			for (i = 0; i < q.children.length; i++) {
+		if (q.childNodes) {
+			for (i = 0; i < q.childNodes.length; i++) {
 				items.push(i);
 			}
 		}
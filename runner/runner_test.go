@@ -102,6 +102,80 @@ func TestTrackChanges(t *testing.T) {
 	d.Stop()
 }
 
+func TestTrackChangesNewAttr(t *testing.T) {
+	d := New("https://example.com", simpleHTML, nil, nil, nil)
+	d.Start()
+	defer d.Stop()
+	if _, err := d.Exec(``, true); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, _, err := d.TrackChanges(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	for _, tt := range []struct {
+		s   string
+		exp string
+	}{
+		{"document.getElementById('title').classList.add('x')", `class="x"`},
+		{"document.getElementById('title').dataset.y = '1'", `data-y="1"`},
+	} {
+		if _, err := d.Exec(tt.s, false); err != nil {
+			t.Fatalf(err.Error())
+		}
+		html, changed, err := d.TrackChanges()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if !changed {
+			t.Fatalf("%v: not changed", tt.s)
+		}
+		if !strings.Contains(html, `class="x"`) || !strings.Contains(html, tt.exp) {
+			t.Fatalf(html)
+		}
+	}
+}
+
+func TestTrackChangesAnimationLoop(t *testing.T) {
+	d := New("https://example.com", simpleHTML, nil, nil, nil)
+	d.Start()
+	defer d.Stop()
+	if _, err := d.Exec(``, true); err != nil {
+		t.Fatalf(err.Error())
+	}
+	_, err := d.Exec(`
+		var n = 0;
+		function step() {
+			document.getElementById('title').textContent = 'frame ' + (++n);
+			requestAnimationFrame(step);
+		}
+		requestAnimationFrame(step);
+	`, false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	start := time.Now()
+	html, changed, err := d.TrackChanges()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	// only the frames are mutating, so the wait isn't prolonged
+	if el := time.Since(start); el >= trackLimit {
+		t.Fatalf("blocked for %v", el)
+	}
+	if !changed || !strings.Contains(html, "frame ") {
+		t.Fatalf("%v %v", changed, html)
+	}
+	// frames keep running between ctl commands
+	res, err := d.Exec("n", false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	time.Sleep(100 * time.Millisecond)
+	if res2, _ := d.Exec("n", false); res2 == res {
+		t.Fatalf("%v == %v", res2, res)
+	}
+}
+
 /*func TestWindowEqualsGlobal(t *testing.T) {
 	const h = `
 	<html>
@@ -626,7 +700,7 @@ func TestTriggerClick2(t *testing.T) {
 }
 
 func TestTriggerClickSubmit(t *testing.T) {
-	for _, sel := range []string{"#btn" /*, "#submit"*/} {
+	for sel, exp := range map[string]string{"#submit": "true", "#btn": "false"} {
 		jQuery, err := ioutil.ReadFile("jquery-3.5.1.js")
 		if err != nil {
 			t.Fatalf("%v", err)
@@ -681,8 +755,8 @@ func TestTriggerClickSubmit(t *testing.T) {
 		if err != nil {
 			t.Fatalf(err.Error())
 		}
-		if res != "true" {
-			t.Fatalf(res)
+		if res != exp {
+			t.Fatalf("%v: %v", sel, res)
 		}
 		d.Stop()
 	}
@@ -869,3 +943,19 @@ func attr(n html.Node, key string) (val string) {
 	}
 	return
 }
+
+func TestLayoutGeom(t *testing.T) {
+	geom := func(sel string) (string, error) {
+		return "", fmt.Errorf("no host")
+	}
+	d := New("https://example.com", simpleHTML, nil, geom, nil)
+	d.Start()
+	defer d.Stop()
+	res, err := d.Exec(`document.body.offsetWidth + 'x' + document.body.getBoundingClientRect().x`, true)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if res != "1264x8" {
+		t.Fatalf("%v", res)
+	}
+}